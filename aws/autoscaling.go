@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 
 	"github.com/mozilla-services/reaper/filters"
@@ -26,10 +25,16 @@ type AutoScalingGroup struct {
 
 	// autoscaling.Instance exposes minimal info
 	Instances []reapable.ID
+	// the number of Instances whose HealthStatus is Healthy
+	HealthyInstances int64
 }
 
 // NewAutoScalingGroup creates an AutoScalingGroup from the AWS API's autoscaling.Group
 func NewAutoScalingGroup(region string, asg *autoscaling.Group) *AutoScalingGroup {
+	if asg.AutoScalingGroupName == nil {
+		log.Warning("Skipping an AutoScalingGroup without an AutoScalingGroupName in %s", region)
+		return nil
+	}
 	a := AutoScalingGroup{
 		Resource: Resource{
 			region: reapable.Region(region),
@@ -41,16 +46,23 @@ func NewAutoScalingGroup(region string, asg *autoscaling.Group) *AutoScalingGrou
 	}
 
 	for _, instance := range asg.Instances {
-		a.Instances = append(a.Instances, reapable.ID(*instance.InstanceId))
+		// pending instances may not have an id yet
+		if instance.InstanceId != nil {
+			a.Instances = append(a.Instances, reapable.ID(*instance.InstanceId))
+		}
+		if aws.StringValue(instance.HealthStatus) == "Healthy" {
+			a.HealthyInstances++
+		}
 	}
 
 	for _, tag := range asg.Tags {
-		a.Resource.Tags[*tag.Key] = *tag.Value
+		a.Resource.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
 
 	if a.Tagged("aws:cloudformation:stack-name") {
 		a.Dependency = true
 		a.IsInCloudformation = true
+		a.CloudformationStackName = a.Tag("aws:cloudformation:stack-name")
 	}
 
 	if a.Tagged(reaperTag) {
@@ -64,39 +76,64 @@ func NewAutoScalingGroup(region string, asg *autoscaling.Group) *AutoScalingGrou
 	return &a
 }
 
+// ReapableDescription is a method of reapable.Reapable
+// it includes the AutoScalingGroup's age and estimated cost, see describe
+func (a *AutoScalingGroup) ReapableDescription() string {
+	return a.describe(a)
+}
+
+// CreatedAt is part of the reapable.Aged interface
+func (a *AutoScalingGroup) CreatedAt() (time.Time, bool) {
+	if a.CreatedTime == nil {
+		return time.Time{}, false
+	}
+	return *a.CreatedTime, true
+}
+
+// StopLabel is the text of the AutoScalingGroup's stop link in events,
+// AutoScalingGroupStopLabel or Scale to 0, as stopping scales it to 0
+func (a *AutoScalingGroup) StopLabel() string {
+	if config.AutoScalingGroupStopLabel != "" {
+		return config.AutoScalingGroupStopLabel
+	}
+	return "Scale to 0"
+}
+
+// ReapableType is part of the reapable.Typed interface
+func (a *AutoScalingGroup) ReapableType() string {
+	return "AutoScalingGroup"
+}
+
 // ReapableEventText is part of the events.Reapable interface
 func (a *AutoScalingGroup) ReapableEventText() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableASGEventText)
+	return reapableEventText(a, "ASGEventText", reapableASGEventText)
 }
 
 // ReapableEventTextShort is part of the events.Reapable interface
 func (a *AutoScalingGroup) ReapableEventTextShort() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableASGEventTextShort)
+	return reapableEventText(a, "ASGEventTextShort", reapableASGEventTextShort)
 }
 
 // ReapableEventEmail is part of the events.Reapable interface
 func (a *AutoScalingGroup) ReapableEventEmail() (owner mail.Address, subject string, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-
 	subject = fmt.Sprintf("AWS Resource %s is going to be Reaped!", a.ReapableDescriptionTiny())
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableASGEventHTML)
+	body, err = reapableEventHTML(a, "ASGEventHTML", reapableASGEventHTML)
 	return
 }
 
 // ReapableEventEmailShort is part of the events.Reapable interface
 func (a *AutoScalingGroup) ReapableEventEmailShort() (owner mail.Address, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableASGEventHTMLShort)
+	body, err = reapableEventHTML(a, "ASGEventHTMLShort", reapableASGEventHTMLShort)
 	return
 }
 
@@ -154,15 +191,17 @@ const reapableASGEventHTML = `
 <body>
 	<p>AutoScalingGroup <a href="{{ .AutoScalingGroup.AWSConsoleURL }}">{{ if .AutoScalingGroup.Name }}"{{.AutoScalingGroup.Name}}" {{ end }} in {{.AutoScalingGroup.Region}}</a> is scheduled to be terminated.</p>
 
+	{{ with .AutoScalingGroup.ReapReason }}<p>It was {{ . }}.</p>{{ end }}
+
 	<p>
-		You can ignore this message and your AutoScalingGroup will advance to the next state after <strong>{{.AutoScalingGroup.ReaperState.Until}}</strong>. If you do not take action it will be terminated!
+		You can ignore this message and your AutoScalingGroup will advance to the next state after <strong>{{ until .AutoScalingGroup.ReaperState.Until }}</strong> ({{ relativeUntil .AutoScalingGroup.ReaperState.Until }}). If you do not take action it will be terminated!
 	</p>
 
 	<p>
 		You may also choose to:
 		<ul>
 			<li><a href="{{ .TerminateLink }}">Terminate it now</a></li>
-			<li><a href="{{ .StopLink }}">Scale it to 0</a></li>
+			<li><a href="{{ .StopLink }}">{{ .AutoScalingGroup.StopLabel }}</a> now</li>
 			<li><a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a></li>
 			<li><a href="{{ .IgnoreLink3 }}">Ignore it for 3 more days</a></li>
 			<li><a href="{{ .IgnoreLink7}}">Ignore it for 7 more days</a></li>
@@ -179,31 +218,33 @@ const reapableASGEventHTML = `
 const reapableASGEventHTMLShort = `
 <html>
 <body>
-	<p>AutoScalingGroup <a href="{{ .AutoScalingGroup.AWSConsoleURL }}">{{ if .AutoScalingGroup.Name }}"{{.AutoScalingGroup.Name}}" {{ end }}</a> in {{.AutoScalingGroup.Region}}</a> is scheduled to be terminated after <strong>{{.AutoScalingGroup.ReaperState.Until}}</strong>.
+	<p>AutoScalingGroup <a href="{{ .AutoScalingGroup.AWSConsoleURL }}">{{ if .AutoScalingGroup.Name }}"{{.AutoScalingGroup.Name}}" {{ end }}</a> in {{.AutoScalingGroup.Region}}</a> is scheduled to be terminated after <strong>{{ until .AutoScalingGroup.ReaperState.Until }}</strong> ({{ relativeUntil .AutoScalingGroup.ReaperState.Until }}).
 		<br />
 		<a href="{{ .TerminateLink }}">Terminate</a>,
-		<a href="{{ .StopLink }}">Stop</a>,
+		<a href="{{ .StopLink }}">{{ .AutoScalingGroup.StopLabel }}</a>,
 		<a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a>,
 		<a href="{{ .IgnoreLink3 }}">3 days</a>,
 		<a href="{{ .IgnoreLink7}}"> 7 days</a>,
 		<a href="{{ .WhitelistLink }}">Whitelist</a> it.
+		{{ with .AutoScalingGroup.ReapReason }}<br />It was {{ . }}.{{ end }}
 	</p>
 </body>
 </html>
 `
 
 const reapableASGEventTextShort = `%%%
-AutoScalingGroup [{{.AutoScalingGroup.ID}}]({{.AutoScalingGroup.AWSConsoleURL}}) in region: [{{.AutoScalingGroup.Region}}](https://{{.AutoScalingGroup.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.AutoScalingGroup.Region}}).{{if .AutoScalingGroup.Owned}} Owned by {{.AutoScalingGroup.Owner}}.\n{{end}}
-[Whitelist]({{ .WhitelistLink }}), [Scale to 0]({{ .StopLink }}), or [Terminate]({{ .TerminateLink }}) this AutoScalingGroup.
+AutoScalingGroup [{{.AutoScalingGroup.ID}}]({{.AutoScalingGroup.AWSConsoleURL}}) in region: [{{.AutoScalingGroup.Region}}](https://{{.AutoScalingGroup.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.AutoScalingGroup.Region}}).{{if .AutoScalingGroup.Owner}} Owned by {{.AutoScalingGroup.Owner}}.\n{{end}}
+[Whitelist]({{ .WhitelistLink }}), [{{ .AutoScalingGroup.StopLabel }}]({{ .StopLink }}), or [Terminate]({{ .TerminateLink }}) this AutoScalingGroup.
 %%%`
 
 const reapableASGEventText = `%%%
 Reaper has discovered an AutoScalingGroup qualified as reapable: [{{.AutoScalingGroup.ID}}]({{.AutoScalingGroup.AWSConsoleURL}}) in region: [{{.AutoScalingGroup.Region}}](https://{{.AutoScalingGroup.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.AutoScalingGroup.Region}}).\n
-{{if .AutoScalingGroup.Owned}}Owned by {{.AutoScalingGroup.Owner}}.\n{{end}}
+{{if .AutoScalingGroup.Owner}}Owned by {{.AutoScalingGroup.Owner}}.\n{{end}}
+{{ with .AutoScalingGroup.ReapReason }}It was {{ . }}.\n{{ end }}
 {{ if .AutoScalingGroup.AWSConsoleURL}}{{.AutoScalingGroup.AWSConsoleURL}}\n{{end}}
 [AWS Console URL]({{.AutoScalingGroup.AWSConsoleURL}})\n
 [Whitelist]({{ .WhitelistLink }}) this AutoScalingGroup.
-[Scale to 0]({{ .StopLink }}) this AutoScalingGroup.
+[{{ .AutoScalingGroup.StopLabel }}]({{ .StopLink }}) this AutoScalingGroup.
 [Terminate]({{ .TerminateLink }}) this AutoScalingGroup.
 %%%`
 
@@ -242,19 +283,76 @@ func (a *AutoScalingGroup) sizeGreaterThan(size int64) bool {
 	return false
 }
 
+// scaledUp returns whether the AutoScalingGroup's DesiredCapacity is above 0
+// so that its instances' health is meaningful
+func (a *AutoScalingGroup) scaledUp() bool {
+	return a.sizeGreaterThan(0)
+}
+
+// allInstancesUnhealthy returns whether an AutoScalingGroup that should have
+// instances has no healthy ones, including when it has none at all
+func (a *AutoScalingGroup) allInstancesUnhealthy() bool {
+	return a.scaledUp() && a.HealthyInstances == 0
+}
+
+// pinned returns whether the AutoScalingGroup is a fixed size, with its
+// MinSize, MaxSize and DesiredCapacity equal, and false for ok if any is unset
+func (a *AutoScalingGroup) pinned() (pinned bool, ok bool) {
+	if a.MinSize == nil || a.MaxSize == nil || a.DesiredCapacity == nil {
+		return false, false
+	}
+	return *a.MinSize == *a.MaxSize && *a.MaxSize == *a.DesiredCapacity, true
+}
+
+// elastic returns whether the AutoScalingGroup can scale, with its MinSize
+// below its MaxSize, and false for ok if either is unset
+func (a *AutoScalingGroup) elastic() (elastic bool, ok bool) {
+	if a.MinSize == nil || a.MaxSize == nil {
+		return false, false
+	}
+	return *a.MinSize < *a.MaxSize, true
+}
+
+func (a *AutoScalingGroup) hasSuspendedProcesses() bool {
+	return len(a.SuspendedProcesses) > 0
+}
+
+func (a *AutoScalingGroup) suspendedProcess(name string) bool {
+	for _, process := range a.SuspendedProcesses {
+		if process != nil && process.ProcessName != nil && *process.ProcessName == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Save is part of reapable.Saveable, which embedded in reapable.Reapable
+// Save tags the AutoScalingGroup's reaperTag, unless it is already tagged with s
 func (a *AutoScalingGroup) Save(s *state.State) (bool, error) {
-	return tagAutoScalingGroup(a.Region(), a.ID(), reaperTag, a.reaperState.String())
+	value := s.String()
+	if a.Tag(reaperTag) == value {
+		log.Debug("%s is already saved", a.ReapableDescriptionTiny())
+		return true, nil
+	}
+	ok, err := tagAutoScalingGroup(a.Region(), a.ID(), reaperTag, value)
+	if ok {
+		a.setTag(reaperTag, value)
+	}
+	return ok, err
 }
 
 // Unsave is part of reapable.Saveable, which embedded in reapable.Reapable
 func (a *AutoScalingGroup) Unsave() (bool, error) {
 	log.Info("Unsaving %s", a.ReapableDescriptionTiny())
-	return untagAutoScalingGroup(a.Region(), a.ID(), reaperTag)
+	ok, err := untagAutoScalingGroup(a.Region(), a.ID(), reaperTag)
+	if ok {
+		a.deleteTag(reaperTag)
+	}
+	return ok, err
 }
 
 func untagAutoScalingGroup(region reapable.Region, id reapable.ID, key string) (bool, error) {
-	api := autoscaling.New(session.New(&aws.Config{Region: aws.String(string(region))}))
+	api := autoScalingClient(string(region))
 	deletereq := &autoscaling.DeleteTagsInput{
 		Tags: []*autoscaling.Tag{
 			&autoscaling.Tag{
@@ -275,7 +373,7 @@ func untagAutoScalingGroup(region reapable.Region, id reapable.ID, key string) (
 
 func tagAutoScalingGroup(region reapable.Region, id reapable.ID, key, value string) (bool, error) {
 	log.Info("Tagging AutoScalingGroup %s in %s with %s:%s", region.String(), id.String(), key, value)
-	api := autoscaling.New(session.New(&aws.Config{Region: aws.String(region.String())}))
+	api := autoScalingClient(region.String())
 	createreq := &autoscaling.CreateOrUpdateTagsInput{
 		Tags: []*autoscaling.Tag{
 			&autoscaling.Tag{
@@ -321,20 +419,80 @@ func (a *AutoScalingGroup) Filter(filter filters.Filter) bool {
 		if i, err := filter.Int64Value(0); err == nil && a.sizeGreaterThanOrEqualTo(i) {
 			matched = true
 		}
+	case "HealthyInstancesLessThan":
+		if i, err := filter.Int64Value(0); err == nil && a.scaledUp() && a.HealthyInstances < i {
+			matched = true
+		}
+	case "AllInstancesUnhealthy":
+		if b, err := filter.BoolValue(0); err == nil && a.allInstancesUnhealthy() == b {
+			matched = true
+		}
+	case "IsPinned":
+		if b, err := filter.BoolValue(0); err == nil {
+			if pinned, ok := a.pinned(); ok && pinned == b {
+				matched = true
+			}
+		}
+	case "IsElastic":
+		if b, err := filter.BoolValue(0); err == nil {
+			if elastic, ok := a.elastic(); ok && elastic == b {
+				matched = true
+			}
+		}
+	case "HasSuspendedProcesses":
+		if b, err := filter.BoolValue(0); err == nil && a.hasSuspendedProcesses() == b {
+			matched = true
+		}
+	case "SuspendedProcess":
+		if a.suspendedProcess(filter.Arguments[0]) {
+			matched = true
+		}
 	case "CreatedTimeInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.CreatedTime != nil && time.Since(*a.CreatedTime) < d {
 			matched = true
 		}
 	case "CreatedTimeNotInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.CreatedTime != nil && time.Since(*a.CreatedTime) > d {
 			matched = true
 		}
+	case "CreatedOutsideBusinessHours":
+		b, err := filter.BoolValue(0)
+		if outside, ok := createdOutsideBusinessHours(a); err == nil && ok && outside == b {
+			matched = true
+		}
+	// uses RFC3339 format
+	case "CreatedBefore":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.Before(t) {
+			matched = true
+		}
+	case "CreatedAfter":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.After(t) {
+			matched = true
+		}
+	case "LaunchConfigOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil {
+			if created, ok := a.launchConfigCreatedTime(); ok && time.Since(created) > d {
+				matched = true
+			}
+		}
+	case "ScalingActivityFailed":
+		b, err := filter.BoolValue(0)
+		if failed, ok := a.scalingActivityFailed(); err == nil && ok && failed == b {
+			matched = true
+		}
 	case "InCloudformation":
 		if b, err := filter.BoolValue(0); err == nil && a.IsInCloudformation == b {
 			matched = true
 		}
+	case "CloudformationStackNameMatches":
+		if a.cloudformationStackNameMatches(filter) {
+			matched = true
+		}
 	case "Region":
 		for _, region := range filter.Arguments {
 			if a.Region() == reapable.Region(region) {
@@ -360,16 +518,50 @@ func (a *AutoScalingGroup) Filter(filter filters.Filter) bool {
 		if !a.Tagged(filter.Arguments[0]) {
 			matched = true
 		}
+	case "MissingAnyTag":
+		if a.missingAnyTag(filter.Arguments) {
+			matched = true
+		}
+	case "MissingAllTags":
+		if a.missingAllTags(filter.Arguments) {
+			matched = true
+		}
 	case "TagNotEqual":
 		if a.Tag(filter.Arguments[0]) != filter.Arguments[1] {
 			matched = true
 		}
+	case "TagsAllEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) == len(pairs) {
+			matched = true
+		}
+	case "TagsAnyEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) > 0 {
+			matched = true
+		}
+	case "TagInSet":
+		if a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "TagNotInSet":
+		if !a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
 	case "ReaperState":
-		if a.reaperState.State.String() == filter.Arguments[0] {
+		if a.ReaperState().State.String() == filter.Arguments[0] {
+			matched = true
+		}
+	case "StateOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.stateOlderThan(d, time.Now()) {
+			matched = true
+		}
+	case "UntilWithin":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.untilWithin(d, time.Now()) {
 			matched = true
 		}
 	case "NotReaperState":
-		if a.reaperState.State.String() != filter.Arguments[0] {
+		if a.ReaperState().State.String() != filter.Arguments[0] {
 			matched = true
 		}
 	case "Named":
@@ -393,7 +585,7 @@ func (a *AutoScalingGroup) Filter(filter filters.Filter) bool {
 			matched = true
 		}
 	default:
-		log.Error(fmt.Sprintf("No function %s could be found for filtering AutoScalingGroups.", filter.Function))
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering AutoScalingGroups.", filter.Function))
 	}
 	return matched
 }
@@ -410,14 +602,17 @@ func (a *AutoScalingGroup) AWSConsoleURL() *url.URL {
 
 func (a *AutoScalingGroup) scaleToSize(size int64, minSize int64) (bool, error) {
 	log.Info("Scaling AutoScalingGroup %s to size %d.", a.ReapableDescriptionTiny(), size)
-	as := autoscaling.New(session.New(&aws.Config{Region: aws.String(a.Region().String())}))
+	as := autoScalingClient(a.Region().String())
 	input := &autoscaling.UpdateAutoScalingGroupInput{
 		AutoScalingGroupName: aws.String(a.ID().String()),
 		DesiredCapacity:      &size,
 		MinSize:              &minSize,
 	}
 
-	_, err := as.UpdateAutoScalingGroup(input)
+	err := retry("update AutoScalingGroup "+a.ReapableDescriptionTiny(), func() error {
+		_, err := as.UpdateAutoScalingGroup(input)
+		return err
+	})
 	if err != nil {
 		log.Error("could not update AutoScalingGroup ", a.ReapableDescriptionTiny())
 		return false, err
@@ -428,11 +623,14 @@ func (a *AutoScalingGroup) scaleToSize(size int64, minSize int64) (bool, error)
 // Terminate is a method of reapable.Terminable, which is embedded in reapable.Reapable
 func (a *AutoScalingGroup) Terminate() (bool, error) {
 	log.Info("Terminating AutoScalingGroup %s", a.ReapableDescriptionTiny())
-	as := autoscaling.New(session.New(&aws.Config{Region: aws.String(a.Region().String())}))
+	as := autoScalingClient(a.Region().String())
 	input := &autoscaling.DeleteAutoScalingGroupInput{
 		AutoScalingGroupName: aws.String(a.ID().String()),
 	}
-	_, err := as.DeleteAutoScalingGroup(input)
+	err := retry("delete AutoScalingGroup "+a.ReapableDescriptionTiny(), func() error {
+		_, err := as.DeleteAutoScalingGroup(input)
+		return err
+	})
 	if err != nil {
 		log.Error("could not delete AutoScalingGroup ", a.ReapableDescriptionTiny())
 		return false, err
@@ -441,22 +639,41 @@ func (a *AutoScalingGroup) Terminate() (bool, error) {
 }
 
 // Whitelist is a method of reapable.Whitelistable, which is embedded in reapable.Reapable
+// if PropagateAutoScalingGroupWhitelist is set, the tag is also propagated to
+// instances launched later, and the AutoScalingGroup's current instances are tagged
 func (a *AutoScalingGroup) Whitelist() (bool, error) {
 	log.Info("Whitelisting AutoScalingGroup %s", a.ReapableDescriptionTiny())
-	api := autoscaling.New(session.New(&aws.Config{Region: aws.String(a.Region().String())}))
+	api := newAutoScalingAPI(a.Region().String())
 	createreq := &autoscaling.CreateOrUpdateTagsInput{
 		Tags: []*autoscaling.Tag{
 			&autoscaling.Tag{
 				ResourceId:        aws.String(a.ID().String()),
 				ResourceType:      aws.String("auto-scaling-group"),
-				PropagateAtLaunch: aws.Bool(false),
+				PropagateAtLaunch: aws.Bool(config.PropagateAutoScalingGroupWhitelist),
 				Key:               aws.String(config.WhitelistTag),
 				Value:             aws.String("true"),
 			},
 		},
 	}
-	_, err := api.CreateOrUpdateTags(createreq)
-	return err == nil, err
+	if _, err := api.CreateOrUpdateTags(createreq); err != nil {
+		return false, err
+	}
+	a.setTag(config.WhitelistTag, "true")
+	if !config.PropagateAutoScalingGroupWhitelist {
+		return true, nil
+	}
+
+	// PropagateAtLaunch only tags instances launched from now on
+	for _, id := range a.Instances {
+		log.Info("Whitelisting Instance %s in AutoScalingGroup %s", id, a.ReapableDescriptionTiny())
+		if ok, err := tag(a.Region().String(), id.String(), config.WhitelistTag, "true"); !ok {
+			if err == nil {
+				err = fmt.Errorf("Could not verify the %s tag of %s", config.WhitelistTag, id)
+			}
+			return false, err
+		}
+	}
+	return true, nil
 }
 
 // Stop is a method of reapable.Stoppable, which is embedded in reapable.Reapable
@@ -465,3 +682,49 @@ func (a *AutoScalingGroup) Stop() (bool, error) {
 	// use existing min size
 	return a.scaleToSize(0, 0)
 }
+
+// ScaleSchedule returns the cron specs of the AutoScalingGroup's schedule-down
+// and schedule-up tags, "" for either that isn't tagged
+func (a *AutoScalingGroup) ScaleSchedule() (down string, up string) {
+	return strings.TrimSpace(a.Tag(scheduleDownTag)), strings.TrimSpace(a.Tag(scheduleUpTag))
+}
+
+// ScaleDown records the AutoScalingGroup's desired capacity and min size
+// in its scaledDownTag, then scales it to 0
+// an AutoScalingGroup that is already scaled to 0 is left as is
+func (a *AutoScalingGroup) ScaleDown() (bool, error) {
+	size := aws.Int64Value(a.DesiredCapacity)
+	if size == 0 {
+		log.Info("AutoScalingGroup %s is already scaled down", a.ReapableDescriptionTiny())
+		return true, nil
+	}
+	value := fmt.Sprintf("%d,%d", size, aws.Int64Value(a.MinSize))
+	if ok, err := tagAutoScalingGroup(a.Region(), a.ID(), scaledDownTag, value); !ok {
+		return false, err
+	}
+	a.setTag(scaledDownTag, value)
+	return a.scaleToSize(0, 0)
+}
+
+// ScaleUp scales the AutoScalingGroup back to the size recorded by ScaleDown,
+// and removes its scaledDownTag
+// an AutoScalingGroup that wasn't scaled down by ScaleDown is left as is
+func (a *AutoScalingGroup) ScaleUp() (bool, error) {
+	value := a.Tag(scaledDownTag)
+	if value == "" {
+		log.Info("AutoScalingGroup %s was not scaled down by its schedule", a.ReapableDescriptionTiny())
+		return true, nil
+	}
+	var size, minSize int64
+	if _, err := fmt.Sscanf(value, "%d,%d", &size, &minSize); err != nil {
+		return false, fmt.Errorf("Could not parse the %s tag %q of %s: %s", scaledDownTag, value, a.ReapableDescriptionTiny(), err.Error())
+	}
+	if ok, err := a.scaleToSize(size, minSize); !ok {
+		return false, err
+	}
+	ok, err := untagAutoScalingGroup(a.Region(), a.ID(), scaledDownTag)
+	if ok {
+		a.deleteTag(scaledDownTag)
+	}
+	return ok, err
+}
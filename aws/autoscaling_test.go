@@ -0,0 +1,358 @@
+package aws
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+func newTestAutoScalingGroup(name string, suspended ...string) *AutoScalingGroup {
+	group := &autoscaling.Group{
+		AutoScalingGroupName: aws.String(name),
+	}
+	for _, process := range suspended {
+		group.SuspendedProcesses = append(group.SuspendedProcesses, &autoscaling.SuspendedProcess{
+			ProcessName: aws.String(process),
+		})
+	}
+	return NewAutoScalingGroup("us-west-2", group)
+}
+
+func TestAutoScalingGroupSuspendedProcessFilters(t *testing.T) {
+	suspended := newTestAutoScalingGroup("suspended", "Launch")
+	healthy := newTestAutoScalingGroup("healthy")
+
+	hasSuspended := *filters.NewFilter("HasSuspendedProcesses", []string{"true"})
+	if !suspended.Filter(hasSuspended) {
+		t.Error("expected ASG with Launch suspended to match HasSuspendedProcesses")
+	}
+	if healthy.Filter(hasSuspended) {
+		t.Error("expected ASG without suspended processes not to match HasSuspendedProcesses")
+	}
+
+	noneSuspended := *filters.NewFilter("HasSuspendedProcesses", []string{"false"})
+	if !healthy.Filter(noneSuspended) {
+		t.Error("expected ASG without suspended processes to match HasSuspendedProcesses(false)")
+	}
+
+	launch := *filters.NewFilter("SuspendedProcess", []string{"Launch"})
+	if !suspended.Filter(launch) {
+		t.Error("expected ASG with Launch suspended to match SuspendedProcess(Launch)")
+	}
+	if healthy.Filter(launch) {
+		t.Error("expected ASG without suspended processes not to match SuspendedProcess(Launch)")
+	}
+
+	terminate := *filters.NewFilter("SuspendedProcess", []string{"Terminate"})
+	if suspended.Filter(terminate) {
+		t.Error("expected ASG with only Launch suspended not to match SuspendedProcess(Terminate)")
+	}
+}
+
+func TestAutoScalingGroupPinnedFilters(t *testing.T) {
+	newGroup := func(name string, min, max, desired *int64) *AutoScalingGroup {
+		a := newTestAutoScalingGroup(name)
+		a.MinSize, a.MaxSize, a.DesiredCapacity = min, max, desired
+		return a
+	}
+	pinned := newGroup("pinned", aws.Int64(2), aws.Int64(2), aws.Int64(2))
+	elastic := newGroup("elastic", aws.Int64(1), aws.Int64(4), aws.Int64(2))
+	partial := newGroup("partial", nil, aws.Int64(2), aws.Int64(2))
+
+	isPinned := *filters.NewFilter("IsPinned", []string{"true"})
+	notPinned := *filters.NewFilter("IsPinned", []string{"false"})
+	isElastic := *filters.NewFilter("IsElastic", []string{"true"})
+	notElastic := *filters.NewFilter("IsElastic", []string{"false"})
+
+	if !pinned.Filter(isPinned) || pinned.Filter(notPinned) {
+		t.Error("expected an ASG with min == max == desired to be pinned")
+	}
+	if pinned.Filter(isElastic) || !pinned.Filter(notElastic) {
+		t.Error("expected an ASG with min == max not to be elastic")
+	}
+	if !elastic.Filter(isElastic) || elastic.Filter(notElastic) {
+		t.Error("expected an ASG with min < max to be elastic")
+	}
+	if elastic.Filter(isPinned) || !elastic.Filter(notPinned) {
+		t.Error("expected an ASG with min < max not to be pinned")
+	}
+	for _, f := range []filters.Filter{isPinned, notPinned, isElastic, notElastic} {
+		if partial.Filter(f) {
+			t.Errorf("expected an ASG without a MinSize not to match %s", f.Function)
+		}
+	}
+}
+
+func TestApplyFiltersReportsErrors(t *testing.T) {
+	a := newTestAutoScalingGroup("errors")
+	a.DesiredCapacity = aws.Int64(3)
+
+	tests := []struct {
+		name   string
+		filter *filters.Filter
+	}{
+		{"non-numeric argument", filters.NewFilter("SizeGreaterThan", []string{"three"})},
+		{"missing argument", filters.NewFilter("SizeGreaterThan", []string{})},
+		{"unknown function", filters.NewFilter("SizeIsNice", []string{"1"})},
+	}
+
+	for _, test := range tests {
+		matched, err := filters.ApplyFilters(a, filters.FilterGroup{"filter": *test.filter})
+		if matched {
+			t.Errorf("%s: expected no match", test.name)
+		}
+		if _, ok := err.(filters.FilterError); !ok {
+			t.Errorf("%s: expected a FilterError, got %v", test.name, err)
+		}
+	}
+
+	matched, err := filters.ApplyFilters(a, filters.FilterGroup{
+		"filter": *filters.NewFilter("SizeGreaterThan", []string{"2"}),
+	})
+	if !matched || err != nil {
+		t.Errorf("expected a match without error, got %t, %v", matched, err)
+	}
+}
+
+func TestNewAutoScalingGroupPartiallyPopulated(t *testing.T) {
+	if a := NewAutoScalingGroup("us-west-2", &autoscaling.Group{}); a != nil {
+		t.Error("expected an AutoScalingGroup without a name to be skipped")
+	}
+
+	a := NewAutoScalingGroup("us-west-2", &autoscaling.Group{
+		AutoScalingGroupName: aws.String("pending"),
+		Instances: []*autoscaling.Instance{
+			&autoscaling.Instance{},
+			&autoscaling.Instance{InstanceId: aws.String("i-1")},
+		},
+		Tags: []*autoscaling.TagDescription{
+			&autoscaling.TagDescription{Key: aws.String("Owner")},
+		},
+	})
+	if a == nil {
+		t.Fatal("expected an AutoScalingGroup")
+	}
+	if len(a.Instances) != 1 || a.Instances[0] != "i-1" {
+		t.Errorf("expected only instance i-1, got %v", a.Instances)
+	}
+	if !a.Tagged("Owner") {
+		t.Error("expected a tag without a value to be kept")
+	}
+}
+
+func TestAutoScalingGroupHealthFilters(t *testing.T) {
+	newGroup := func(name string, desired int64, health ...string) *AutoScalingGroup {
+		group := &autoscaling.Group{
+			AutoScalingGroupName: aws.String(name),
+			DesiredCapacity:      aws.Int64(desired),
+		}
+		for i, status := range health {
+			group.Instances = append(group.Instances, &autoscaling.Instance{
+				InstanceId:   aws.String(fmt.Sprintf("i-%d", i)),
+				HealthStatus: aws.String(status),
+			})
+		}
+		return NewAutoScalingGroup("us-west-2", group)
+	}
+	healthy := newGroup("healthy", 2, "Healthy", "Healthy")
+	degraded := newGroup("degraded", 2, "Healthy", "Unhealthy")
+	unhealthy := newGroup("unhealthy", 2, "Unhealthy", "Unhealthy")
+	empty := newGroup("empty", 2)
+	scaledToZero := newGroup("scaled-to-zero", 0)
+
+	tests := []struct {
+		a        *AutoScalingGroup
+		filter   *filters.Filter
+		expected bool
+	}{
+		{healthy, filters.NewFilter("AllInstancesUnhealthy", []string{"true"}), false},
+		{degraded, filters.NewFilter("AllInstancesUnhealthy", []string{"true"}), false},
+		{unhealthy, filters.NewFilter("AllInstancesUnhealthy", []string{"true"}), true},
+		{empty, filters.NewFilter("AllInstancesUnhealthy", []string{"true"}), true},
+		{scaledToZero, filters.NewFilter("AllInstancesUnhealthy", []string{"true"}), false},
+		{scaledToZero, filters.NewFilter("AllInstancesUnhealthy", []string{"false"}), true},
+		{healthy, filters.NewFilter("HealthyInstancesLessThan", []string{"1"}), false},
+		{degraded, filters.NewFilter("HealthyInstancesLessThan", []string{"2"}), true},
+		{unhealthy, filters.NewFilter("HealthyInstancesLessThan", []string{"1"}), true},
+		{scaledToZero, filters.NewFilter("HealthyInstancesLessThan", []string{"1"}), false},
+	}
+	for _, test := range tests {
+		if test.a.Filter(*test.filter) != test.expected {
+			t.Errorf("%s %s(%v): expected %t", test.a.Name, test.filter.Function, test.filter.Arguments, test.expected)
+		}
+	}
+}
+
+// testAutoScalingLaunchConfigs describes launch configurations from a fixed list
+// other methods of AutoScalingAPI are not implemented
+type testAutoScalingLaunchConfigs struct {
+	autoscalingiface.AutoScalingAPI
+	launchConfigs []*autoscaling.LaunchConfiguration
+	describes     int
+}
+
+func (c *testAutoScalingLaunchConfigs) DescribeLaunchConfigurations(input *autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	c.describes++
+	var launchConfigs []*autoscaling.LaunchConfiguration
+	for _, lc := range c.launchConfigs {
+		for _, name := range input.LaunchConfigurationNames {
+			if *lc.LaunchConfigurationName == *name {
+				launchConfigs = append(launchConfigs, lc)
+			}
+		}
+	}
+	return &autoscaling.DescribeLaunchConfigurationsOutput{LaunchConfigurations: launchConfigs}, nil
+}
+
+// setTestAutoScaling replaces the AutoScaling client, returning a func that restores it
+func setTestAutoScaling(api autoscalingiface.AutoScalingAPI) (restore func()) {
+	original := newAutoScalingAPI
+	newAutoScalingAPI = func(region string) autoscalingiface.AutoScalingAPI {
+		return api
+	}
+	return func() { newAutoScalingAPI = original }
+}
+
+func TestLaunchConfigOlderThan(t *testing.T) {
+	api := &testAutoScalingLaunchConfigs{launchConfigs: []*autoscaling.LaunchConfiguration{
+		&autoscaling.LaunchConfiguration{
+			LaunchConfigurationName: aws.String("lc-old"),
+			CreatedTime:             aws.Time(time.Now().Add(-365 * 24 * time.Hour)),
+		},
+		&autoscaling.LaunchConfiguration{
+			LaunchConfigurationName: aws.String("lc-new"),
+			CreatedTime:             aws.Time(time.Now().Add(-time.Hour)),
+		},
+	}}
+	defer setTestAutoScaling(api)()
+
+	old := newTestAutoScalingGroup("old")
+	old.LaunchConfigurationName = aws.String("lc-old")
+	alsoOld := newTestAutoScalingGroup("also-old")
+	alsoOld.LaunchConfigurationName = aws.String("lc-old")
+	recent := newTestAutoScalingGroup("recent")
+	recent.LaunchConfigurationName = aws.String("lc-new")
+	missing := newTestAutoScalingGroup("missing")
+	missing.LaunchConfigurationName = aws.String("lc-deleted")
+	none := newTestAutoScalingGroup("none")
+
+	olderThan := *filters.NewFilter("LaunchConfigOlderThan", []string{"720h"})
+	if !old.Filter(olderThan) || !alsoOld.Filter(olderThan) {
+		t.Error("expected ASGs with a year old launch configuration to match LaunchConfigOlderThan(720h)")
+	}
+	if recent.Filter(olderThan) {
+		t.Error("expected an ASG with a new launch configuration not to match LaunchConfigOlderThan(720h)")
+	}
+	if missing.Filter(olderThan) || none.Filter(olderThan) {
+		t.Error("expected ASGs without a launch configuration not to match LaunchConfigOlderThan(720h)")
+	}
+	if missing.Filter(olderThan); api.describes != 3 {
+		t.Errorf("expected each launch configuration to be described once, got %d describes", api.describes)
+	}
+}
+
+// testAutoScalingActivities describes scaling activities by AutoScalingGroup name
+// other methods of AutoScalingAPI are not implemented
+type testAutoScalingActivities struct {
+	autoscalingiface.AutoScalingAPI
+	activities map[string][]string
+	describes  int
+}
+
+func (c *testAutoScalingActivities) DescribeScalingActivities(input *autoscaling.DescribeScalingActivitiesInput) (*autoscaling.DescribeScalingActivitiesOutput, error) {
+	c.describes++
+	resp := &autoscaling.DescribeScalingActivitiesOutput{}
+	for _, status := range c.activities[*input.AutoScalingGroupName] {
+		resp.Activities = append(resp.Activities, &autoscaling.Activity{StatusCode: aws.String(status)})
+	}
+	return resp, nil
+}
+
+func TestScalingActivityFailed(t *testing.T) {
+	api := &testAutoScalingActivities{activities: map[string][]string{
+		"broken":  {autoscaling.ScalingActivityStatusCodeSuccessful, autoscaling.ScalingActivityStatusCodeFailed},
+		"healthy": {autoscaling.ScalingActivityStatusCodeSuccessful, autoscaling.ScalingActivityStatusCodeSuccessful},
+	}}
+	defer setTestAutoScaling(api)()
+	clearScalingActivityFailures()
+
+	broken := newTestAutoScalingGroup("broken")
+	healthy := newTestAutoScalingGroup("healthy")
+
+	failed := *filters.NewFilter("ScalingActivityFailed", []string{"true"})
+	if !broken.Filter(failed) {
+		t.Error("expected an ASG with a failed scaling activity to match ScalingActivityFailed(true)")
+	}
+	if healthy.Filter(failed) {
+		t.Error("expected an ASG with only successful scaling activities not to match ScalingActivityFailed(true)")
+	}
+	if !healthy.Filter(*filters.NewFilter("ScalingActivityFailed", []string{"false"})) {
+		t.Error("expected an ASG with only successful scaling activities to match ScalingActivityFailed(false)")
+	}
+	if broken.Filter(failed); api.describes != 2 {
+		t.Errorf("expected each ASG's scaling activities to be described once, got %d describes", api.describes)
+	}
+
+	clearScalingActivityFailures()
+	if broken.Filter(failed); api.describes != 3 {
+		t.Errorf("expected scaling activities to be described again after the cache is cleared, got %d describes", api.describes)
+	}
+}
+
+// testAutoScalingTags records CreateOrUpdateTags calls
+// other methods of AutoScalingAPI are not implemented
+type testAutoScalingTags struct {
+	autoscalingiface.AutoScalingAPI
+	tags []*autoscaling.Tag
+}
+
+func (c *testAutoScalingTags) CreateOrUpdateTags(input *autoscaling.CreateOrUpdateTagsInput) (*autoscaling.CreateOrUpdateTagsOutput, error) {
+	c.tags = append(c.tags, input.Tags...)
+	return &autoscaling.CreateOrUpdateTagsOutput{}, nil
+}
+
+func TestWhitelistPropagatesToInstances(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+	config.WhitelistTag = "REAPER_SPARE_ME"
+
+	for _, propagate := range []bool{false, true} {
+		config.PropagateAutoScalingGroupWhitelist = propagate
+		asgAPI := &testAutoScalingTags{}
+		ec2API := &testEC2Tags{}
+		restoreAutoScaling := setTestAutoScaling(asgAPI)
+		restoreEC2 := setTestEC2(ec2API)
+
+		a := newTestAutoScalingGroup("web")
+		a.Instances = []reapable.ID{"i-1", "i-2"}
+		if ok, err := a.Whitelist(); !ok || err != nil {
+			t.Errorf("expected Whitelist to succeed, got %t, %v", ok, err)
+		}
+
+		if len(asgAPI.tags) != 1 || *asgAPI.tags[0].PropagateAtLaunch != propagate {
+			t.Errorf("expected the whitelist tag to have PropagateAtLaunch %t, got %v", propagate, asgAPI.tags)
+		}
+		tagged := 0
+		if propagate {
+			tagged = 2
+		}
+		if len(ec2API.created) != tagged {
+			t.Errorf("expected %d instances to be tagged with PropagateAutoScalingGroupWhitelist %t, got %d", tagged, propagate, len(ec2API.created))
+		}
+		for _, tag := range ec2API.created {
+			if *tag.Key != "REAPER_SPARE_ME" {
+				t.Errorf("expected instances to be tagged with the whitelist tag, got %s", *tag.Key)
+			}
+		}
+
+		restoreEC2()
+		restoreAutoScaling()
+	}
+}
@@ -12,15 +12,29 @@ import (
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/mozilla-services/reaper/events"
 	"github.com/mozilla-services/reaper/reapable"
 	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
 )
 
 const (
 	reaperTag           = "REAPER"
 	reaperTagSeparator  = "|"
 	reaperTagTimeFormat = "2006-01-02 03:04PM MST"
+
+	// ttlTag and expiresAtTag cap a Resource's lifetime, see ExpiresAt
+	ttlTag       = "reaper-ttl"
+	expiresAtTag = "expires-at"
+
+	// scheduleDownTag and scheduleUpTag are cron specs of when an
+	// AutoScalingGroup is scaled down to 0 and back up, see ScaleSchedule
+	scheduleDownTag = "schedule-down"
+	scheduleUpTag   = "schedule-up"
+	// scaledDownTag records the size of an AutoScalingGroup scaled down
+	// by its schedule, which it is scaled back up to
+	scaledDownTag = "reaper-scaled-down"
 )
 
 var (
@@ -30,17 +44,80 @@ var (
 	sess    = session.New()
 )
 
+// awsConfig returns the client config of every AWS service client for
+// a region, sending requests to EndpointURL instead of AWS if it is set,
+// such as for LocalStack
+func awsConfig(region string) *aws.Config {
+	c := aws.NewConfig().WithRegion(region)
+	if config == nil || config.EndpointURL == "" {
+		return c
+	}
+	c = c.WithEndpoint(config.EndpointURL)
+	if strings.HasPrefix(config.EndpointURL, "http://") {
+		c = c.WithDisableSSL(true)
+	}
+	return c
+}
+
+// newEC2API returns an EC2 client for a region
+// replaceable in tests
+var newEC2API = func(region string) ec2iface.EC2API {
+	return ec2Client(region)
+}
+
+// newCountStatistic emits a count statistic
+// replaceable in tests
+var newCountStatistic = events.NewCountStatistic
+
 // Config stores configuration for the aws package
 type Config struct {
 	Notifications    events.NotificationsConfig
 	HTTP             events.HTTPConfig
 	Regions          []string
+	AllRegions       bool
+	ExcludeRegions   []string
 	WhitelistTag     string
+	OwnerTags        []string
 	DefaultOwner     string
 	DefaultEmailHost string
 	DryRun           bool
 
+	// NotifyTag is the tag key of a Resource's NotificationChannel,
+	// defaults to reaper-notify
+	NotifyTag string
+
 	WithoutCloudformationResources bool
+	CloudTrailEnrichment           bool
+	DeleteImageBackingSnapshots    bool
+
+	// InstanceStopLabel and AutoScalingGroupStopLabel are the text of the
+	// stop link in events, which stops instances and scales AutoScalingGroups
+	// to 0, see StopLabel
+	InstanceStopLabel         string
+	AutoScalingGroupStopLabel string
+
+	// PropagateAutoScalingGroupWhitelist is whether whitelisting an
+	// AutoScalingGroup also whitelists its instances
+	PropagateAutoScalingGroupWhitelist bool
+
+	// CustomTemplates are event templates by name, which override
+	// the built-in ones, see LoadTemplates
+	CustomTemplates map[string]string
+
+	// RetryMaxAttempts and RetryBackoff control how mutating API calls
+	// are retried on throttling and transient errors, see retry
+	RetryMaxAttempts int
+	RetryBackoff     state.Duration
+
+	// DiscoveryCursors is a directory the progress of discovery is saved to,
+	// so that a restart resumes discovery instead of starting over, see loadCursor
+	DiscoveryCursors string
+
+	// BusinessHours are when resources are expected to be created
+	BusinessHours BusinessHoursConfig
+
+	// EndpointURL overrides the endpoint of every AWS service, see awsConfig
+	EndpointURL string
 }
 
 // NewConfig returns a new Config for the aws package
@@ -63,12 +140,20 @@ func AllCloudformations() chan *Cloudformation {
 		wg.Add(1)
 		go func(region string) {
 			defer wg.Done()
+			defer recoverDiscovery("cloudformations", region)
 			// add region to waitgroup
-			api := cloudformation.New(sess, aws.NewConfig().WithRegion(region))
-			err := api.DescribeStacksPages(&cloudformation.DescribeStacksInput{}, func(resp *cloudformation.DescribeStacksOutput, lastPage bool) bool {
+			api := cloudformationClient(region)
+
+			// resume from the saved cursor, if there is one
+			input := &cloudformation.DescribeStacksInput{NextToken: loadCursor("cloudformations", region)}
+
+			err := api.DescribeStacksPages(input, func(resp *cloudformation.DescribeStacksOutput, lastPage bool) bool {
 				for _, stack := range resp.Stacks {
-					ch <- NewCloudformation(region, stack)
+					if c := NewCloudformation(region, stack); c != nil {
+						ch <- c
+					}
 				}
+				saveCursor("cloudformations", region, resp.NextToken)
 				// if we are at the last page, we should not continue
 				// the return value of this func is "shouldContinue"
 				if lastPage {
@@ -78,8 +163,11 @@ func AllCloudformations() chan *Cloudformation {
 				return true
 			})
 			if err != nil {
-				// probably should do something here...
-				log.Error(err.Error())
+				// other regions continue
+				discoveryFailed("cloudformations", region, err)
+			} else {
+				// finished, the next scan starts over
+				clearCursor("cloudformations", region)
 			}
 		}(region)
 	}
@@ -104,7 +192,7 @@ func cloudformationResources(region, id string) chan *cloudformation.StackResour
 		return ch
 	}
 
-	api := cloudformation.New(sess, aws.NewConfig().WithRegion(region))
+	api := cloudformationClient(region)
 	go func() {
 		<-timeout
 
@@ -163,6 +251,9 @@ func AutoScalingGroupInstanceIDs(a *AutoScalingGroup) map[reapable.Region]map[re
 // *AutoScalingGroups are created for every *autoscaling.AutoScalingGroup
 // and are passed to a channel
 func AllAutoScalingGroups() chan *AutoScalingGroup {
+	// scaling activities are described again each cycle
+	clearScalingActivityFailures()
+
 	ch := make(chan *AutoScalingGroup, len(config.Regions))
 	// waitgroup for all regions
 	wg := sync.WaitGroup{}
@@ -170,12 +261,20 @@ func AllAutoScalingGroups() chan *AutoScalingGroup {
 		wg.Add(1)
 		go func(region string) {
 			defer wg.Done()
+			defer recoverDiscovery("asgs", region)
 			// add region to waitgroup
-			api := autoscaling.New(sess, aws.NewConfig().WithRegion(region))
-			err := api.DescribeAutoScalingGroupsPages(&autoscaling.DescribeAutoScalingGroupsInput{}, func(resp *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+			api := autoScalingClient(region)
+
+			// resume from the saved cursor, if there is one
+			input := &autoscaling.DescribeAutoScalingGroupsInput{NextToken: loadCursor("asgs", region)}
+
+			err := api.DescribeAutoScalingGroupsPages(input, func(resp *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
 				for _, asg := range resp.AutoScalingGroups {
-					ch <- NewAutoScalingGroup(region, asg)
+					if a := NewAutoScalingGroup(region, asg); a != nil {
+						ch <- a
+					}
 				}
+				saveCursor("asgs", region, resp.NextToken)
 				// if we are at the last page, we should not continue
 				// the return value of this func is "shouldContinue"
 				if lastPage {
@@ -185,8 +284,11 @@ func AllAutoScalingGroups() chan *AutoScalingGroup {
 				return true
 			})
 			if err != nil {
-				// probably should do something here...
-				log.Error(err.Error())
+				// other regions continue
+				discoveryFailed("asgs", region, err)
+			} else {
+				// finished, the next scan starts over
+				clearCursor("asgs", region)
 			}
 		}(region)
 	}
@@ -204,6 +306,9 @@ func AllAutoScalingGroups() chan *AutoScalingGroup {
 // *Instances are created for each *ec2.Instance
 // and are passed to a channel
 func AllInstances() chan *Instance {
+	// AMIs are described again each cycle
+	clearInstanceImages()
+
 	ch := make(chan *Instance, len(config.Regions))
 	// waitgroup for all regions
 	wg := sync.WaitGroup{}
@@ -211,15 +316,23 @@ func AllInstances() chan *Instance {
 		wg.Add(1)
 		go func(region string) {
 			defer wg.Done()
+			defer recoverDiscovery("instances", region)
 			// add region to waitgroup
-			api := ec2.New(sess, aws.NewConfig().WithRegion(region))
+			api := newEC2API(region)
+
+			// resume from the saved cursor, if there is one
+			input := &ec2.DescribeInstancesInput{NextToken: loadCursor("instances", region)}
+
 			// DescribeInstancesPages does autopagination
-			err := api.DescribeInstancesPages(&ec2.DescribeInstancesInput{}, func(resp *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			err := api.DescribeInstancesPages(input, func(resp *ec2.DescribeInstancesOutput, lastPage bool) bool {
 				for _, res := range resp.Reservations {
 					for _, instance := range res.Instances {
-						ch <- NewInstance(region, instance)
+						if i := NewInstance(region, instance); i != nil {
+							ch <- i
+						}
 					}
 				}
+				saveCursor("instances", region, resp.NextToken)
 				// if we are at the last page, we should not continue
 				// the return value of this func is "shouldContinue"
 				if lastPage {
@@ -228,8 +341,11 @@ func AllInstances() chan *Instance {
 				return true
 			})
 			if err != nil {
-				// probably should do something here...
-				log.Error(err.Error())
+				// other regions continue
+				discoveryFailed("instances", region, err)
+			} else {
+				// finished, the next scan starts over
+				clearCursor("instances", region)
 			}
 		}(region)
 	}
@@ -246,6 +362,9 @@ func AllInstances() chan *Instance {
 // *Volumes are created for each *ec2.Volume
 // and are passed to a channel
 func AllVolumes() chan *Volume {
+	// snapshots are described again each cycle
+	clearVolumeSnapshots()
+
 	ch := make(chan *Volume, len(config.Regions))
 	// waitgroup for all regions
 	wg := sync.WaitGroup{}
@@ -253,13 +372,21 @@ func AllVolumes() chan *Volume {
 		wg.Add(1)
 		go func(region string) {
 			defer wg.Done()
+			defer recoverDiscovery("volumes", region)
 			// add region to waitgroup
-			api := ec2.New(sess, aws.NewConfig().WithRegion(region))
+			api := newEC2API(region)
+
+			// resume from the saved cursor, if there is one
+			input := &ec2.DescribeVolumesInput{NextToken: loadCursor("volumes", region)}
+
 			// DescribeVolumesPages does autopagination
-			err := api.DescribeVolumesPages(&ec2.DescribeVolumesInput{}, func(resp *ec2.DescribeVolumesOutput, lastPage bool) bool {
+			err := api.DescribeVolumesPages(input, func(resp *ec2.DescribeVolumesOutput, lastPage bool) bool {
 				for _, vol := range resp.Volumes {
-					ch <- NewVolume(region, vol)
+					if v := NewVolume(region, vol); v != nil {
+						ch <- v
+					}
 				}
+				saveCursor("volumes", region, resp.NextToken)
 				// if we are at the last page, we should not continue
 				// the return value of this func is "shouldContinue"
 				if lastPage {
@@ -268,8 +395,125 @@ func AllVolumes() chan *Volume {
 				return true
 			})
 			if err != nil {
-				// probably should do something here...
-				log.Error(err.Error())
+				// other regions continue
+				discoveryFailed("volumes", region, err)
+			} else {
+				// finished, the next scan starts over
+				clearCursor("volumes", region)
+			}
+		}(region)
+	}
+	go func() {
+		// in a separate goroutine, wait for all regions to finish
+		// when they finish, close the chan
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+// AllImages describes every AMI owned by the account in the requested regions
+// *Images are created for each *ec2.Image
+// and are passed to a channel
+func AllImages() chan *Image {
+	ch := make(chan *Image, len(config.Regions))
+	// waitgroup for all regions
+	wg := sync.WaitGroup{}
+	for _, region := range config.Regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+			defer recoverDiscovery("images", region)
+			// add region to waitgroup
+			api := newEC2API(region)
+			// public and shared AMIs can't be deregistered, only list our own
+			// DescribeImages is not paginated, every Image is returned
+			resp, err := api.DescribeImages(&ec2.DescribeImagesInput{
+				Owners: []*string{aws.String("self")},
+			})
+			if err != nil {
+				// other regions continue
+				discoveryFailed("images", region, err)
+				return
+			}
+			for _, image := range resp.Images {
+				if i := NewImage(region, image); i != nil {
+					ch <- i
+				}
+			}
+		}(region)
+	}
+	go func() {
+		// in a separate goroutine, wait for all regions to finish
+		// when they finish, close the chan
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+// securityGroupLaunchConfigs describes every launch configuration in a region,
+// to find the SecurityGroups they use
+func securityGroupLaunchConfigs(region string) ([]*autoscaling.LaunchConfiguration, error) {
+	var launchConfigs []*autoscaling.LaunchConfiguration
+	err := newAutoScalingAPI(region).DescribeLaunchConfigurationsPages(&autoscaling.DescribeLaunchConfigurationsInput{},
+		func(resp *autoscaling.DescribeLaunchConfigurationsOutput, lastPage bool) bool {
+			launchConfigs = append(launchConfigs, resp.LaunchConfigurations...)
+			return !lastPage
+		})
+	return launchConfigs, err
+}
+
+// LaunchConfigurationImageIDs returns the ids of the AMIs used by
+// every launch configuration in the requested regions
+// a region whose launch configurations couldn't be described is recorded as
+// a discovery failure, see DiscoveryFailedIn
+// launch templates aren't described, since this version of the SDK predates them
+func LaunchConfigurationImageIDs() map[reapable.Region]map[reapable.ID]bool {
+	ids := make(map[reapable.Region]map[reapable.ID]bool)
+	for _, region := range config.Regions {
+		ids[reapable.Region(region)] = make(map[reapable.ID]bool)
+		api := autoScalingClient(region)
+		err := api.DescribeLaunchConfigurationsPages(&autoscaling.DescribeLaunchConfigurationsInput{},
+			func(resp *autoscaling.DescribeLaunchConfigurationsOutput, lastPage bool) bool {
+				for _, lc := range resp.LaunchConfigurations {
+					if lc.ImageId != nil {
+						ids[reapable.Region(region)][reapable.ID(*lc.ImageId)] = true
+					}
+				}
+				return !lastPage
+			})
+		if err != nil {
+			discoveryFailed("launchconfigurations", region, err)
+		}
+	}
+	return ids
+}
+
+// AllNetworkInterfaces describes every network interface in the requested regions
+// *NetworkInterfaces are created for each *ec2.NetworkInterface
+// and are passed to a channel
+func AllNetworkInterfaces() chan *NetworkInterface {
+	ch := make(chan *NetworkInterface, len(config.Regions))
+	// waitgroup for all regions
+	wg := sync.WaitGroup{}
+	for _, region := range config.Regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+			defer recoverDiscovery("networkinterfaces", region)
+			api := newEC2API(region)
+			// DescribeNetworkInterfaces is not paginated, every NetworkInterface is returned
+			resp, err := api.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{})
+			if err != nil {
+				// other regions continue
+				discoveryFailed("networkinterfaces", region, err)
+				return
+			}
+			for _, eni := range resp.NetworkInterfaces {
+				if n := NewNetworkInterface(region, eni); n != nil {
+					ch <- n
+				}
 			}
 		}(region)
 	}
@@ -293,15 +537,36 @@ func AllSecurityGroups() chan *SecurityGroup {
 		wg.Add(1)
 		go func(region string) {
 			defer wg.Done()
+			defer recoverDiscovery("securitygroups", region)
 			// add region to waitgroup
-			api := ec2.New(sess, aws.NewConfig().WithRegion(region))
+			api := newEC2API(region)
+			// DescribeSecurityGroups is not paginated, every SecurityGroup is returned
 			resp, err := api.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{})
+			if err != nil {
+				// other regions continue
+				discoveryFailed("securitygroups", region, err)
+				return
+			}
+			var sgs []*SecurityGroup
 			for _, sg := range resp.SecurityGroups {
-				ch <- NewSecurityGroup(region, sg)
+				if s := NewSecurityGroup(region, sg); s != nil {
+					sgs = append(sgs, s)
+				}
 			}
+
+			// without the network interfaces and launch configurations,
+			// no SecurityGroup is Orphaned
+			enis, err := api.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{})
 			if err != nil {
-				// probably should do something here...
-				log.Error(err.Error())
+				discoveryFailed("networkinterfaces", region, err)
+			} else if launchConfigs, err := securityGroupLaunchConfigs(region); err != nil {
+				discoveryFailed("launchconfigurations", region, err)
+			} else {
+				resolveSecurityGroupUsage(sgs, enis.NetworkInterfaces, launchConfigs)
+			}
+
+			for _, s := range sgs {
+				ch <- s
 			}
 		}(region)
 	}
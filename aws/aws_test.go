@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// testEC2Pages returns instances and volumes one page at a time
+// other methods of EC2API are not implemented
+type testEC2Pages struct {
+	ec2iface.EC2API
+	instancePages [][]*ec2.Instance
+	volumePages   [][]*ec2.Volume
+}
+
+func (c *testEC2Pages) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	for i, page := range c.instancePages {
+		resp := &ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{&ec2.Reservation{Instances: page}},
+		}
+		if !fn(resp, i == len(c.instancePages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (c *testEC2Pages) DescribeVolumesPages(input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool) error {
+	for i, page := range c.volumePages {
+		if !fn(&ec2.DescribeVolumesOutput{Volumes: page}, i == len(c.volumePages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestAllInstancesPages(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{Regions: []string{"us-west-2"}})
+	defer setTestEC2(&testEC2Pages{instancePages: [][]*ec2.Instance{
+		{&ec2.Instance{InstanceId: aws.String("i-1")}, &ec2.Instance{InstanceId: aws.String("i-2")}},
+		{&ec2.Instance{InstanceId: aws.String("i-3")}},
+	}})()
+
+	ids := make(map[string]bool)
+	for i := range AllInstances() {
+		ids[i.ID().String()] = true
+	}
+	if len(ids) != 3 || !ids["i-1"] || !ids["i-2"] || !ids["i-3"] {
+		t.Errorf("expected the instances of both pages, got %v", ids)
+	}
+}
+
+func TestAllVolumesPages(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{Regions: []string{"us-west-2"}})
+	defer setTestEC2(&testEC2Pages{volumePages: [][]*ec2.Volume{
+		{&ec2.Volume{VolumeId: aws.String("vol-1")}},
+		{&ec2.Volume{VolumeId: aws.String("vol-2")}},
+	}})()
+
+	ids := make(map[string]bool)
+	for v := range AllVolumes() {
+		ids[v.ID().String()] = true
+	}
+	if len(ids) != 2 || !ids["vol-1"] || !ids["vol-2"] {
+		t.Errorf("expected the volumes of both pages, got %v", ids)
+	}
+}
+
+func TestAWSConfigEndpointURL(t *testing.T) {
+	defer SetConfig(config)
+
+	SetConfig(&Config{})
+	if c := awsConfig("us-west-2"); c.Endpoint != nil || aws.StringValue(c.Region) != "us-west-2" {
+		t.Errorf("expected only the region without EndpointURL, got %+v", *c)
+	}
+
+	SetConfig(&Config{EndpointURL: "http://localhost:4566"})
+	c := awsConfig("us-west-2")
+	if aws.StringValue(c.Endpoint) != "http://localhost:4566" || !aws.BoolValue(c.DisableSSL) {
+		t.Errorf("expected the EndpointURL without SSL, got %+v", *c)
+	}
+	if api := ec2.New(sess, c); api.Endpoint != "http://localhost:4566" {
+		t.Errorf("expected clients to use the EndpointURL, got %s", api.Endpoint)
+	}
+
+	SetConfig(&Config{EndpointURL: "https://localstack.example.com"})
+	if c := awsConfig("us-west-2"); aws.BoolValue(c.DisableSSL) {
+		t.Error("expected SSL for an https EndpointURL")
+	}
+}
@@ -0,0 +1,126 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+// BusinessHoursConfig describes when resources are expected to be created,
+// see the CreatedOutsideBusinessHours filter
+type BusinessHoursConfig struct {
+	// Hours is a daily "15:04-15:04" range, defaults to 09:00-17:00
+	Hours string
+	// Days are the weekdays with business hours, such as "Monday",
+	// defaults to Monday to Friday
+	Days []string
+	// Timezone is the IANA name of the zone Hours and Days are in, defaults to UTC
+	Timezone string
+
+	// parsed is cached by Validate, so that Outside doesn't parse
+	// for every resource
+	parsed *businessHours
+}
+
+// businessHours is a parsed BusinessHoursConfig
+type businessHours struct {
+	location   *time.Location
+	start, end time.Duration
+	days       map[time.Weekday]bool
+}
+
+// parse returns the location, hours and days of BusinessHoursConfig
+func (b *BusinessHoursConfig) parse() (*businessHours, error) {
+	parsed := &businessHours{location: time.UTC, days: make(map[time.Weekday]bool)}
+	if b.Timezone != "" {
+		location, err := time.LoadLocation(b.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		parsed.location = location
+	}
+
+	hours := b.Hours
+	if hours == "" {
+		hours = "09:00-17:00"
+	}
+	parts := strings.Split(hours, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("BusinessHours %q must be of the form 15:04-15:04", hours)
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("BusinessHours %q: %s", hours, err.Error())
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, fmt.Errorf("BusinessHours %q: %s", hours, err.Error())
+	}
+	parsed.start = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	parsed.end = time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute
+	if parsed.start >= parsed.end {
+		return nil, fmt.Errorf("BusinessHours %q must start before they end", hours)
+	}
+
+	if len(b.Days) == 0 {
+		for w := time.Monday; w <= time.Friday; w++ {
+			parsed.days[w] = true
+		}
+	}
+	for _, d := range b.Days {
+		found := false
+		for w := time.Sunday; w <= time.Saturday; w++ {
+			if strings.EqualFold(w.String(), d) {
+				parsed.days[w] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("BusinessHours day %q is not a weekday", d)
+		}
+	}
+	return parsed, nil
+}
+
+// Validate returns an error if BusinessHoursConfig can't be parsed,
+// and otherwise caches it for Outside
+// it must be called before the config is shared
+func (b *BusinessHoursConfig) Validate() error {
+	parsed, err := b.parse()
+	if err != nil {
+		return err
+	}
+	b.parsed = parsed
+	return nil
+}
+
+// Outside returns whether t is outside business hours
+// an invalid BusinessHoursConfig is never outside, see Validate
+// a BusinessHoursConfig that wasn't validated is parsed on each call
+func (b *BusinessHoursConfig) Outside(t time.Time) bool {
+	parsed := b.parsed
+	if parsed == nil {
+		var err error
+		if parsed, err = b.parse(); err != nil {
+			return false
+		}
+	}
+	t = t.In(parsed.location)
+	if !parsed.days[t.Weekday()] {
+		return true
+	}
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	return sinceMidnight < parsed.start || sinceMidnight >= parsed.end
+}
+
+// createdOutsideBusinessHours returns whether a resource was created outside
+// BusinessHours, and false for ok if its creation time is unknown
+func createdOutsideBusinessHours(a reapable.Aged) (outside bool, ok bool) {
+	created, ok := a.CreatedAt()
+	if !ok {
+		return false, false
+	}
+	return config.BusinessHours.Outside(created), true
+}
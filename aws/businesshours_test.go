@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+func TestBusinessHoursOutside(t *testing.T) {
+	b := &BusinessHoursConfig{Hours: "09:00-18:00", Timezone: "America/Los_Angeles"}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	pacific, _ := time.LoadLocation("America/Los_Angeles")
+	tests := []struct {
+		t       time.Time
+		outside bool
+	}{
+		// Friday June 3rd 2016 to Monday June 6th 2016
+		{time.Date(2016, 6, 3, 17, 59, 0, 0, pacific), false},
+		{time.Date(2016, 6, 3, 18, 0, 0, 0, pacific), true},
+		// Saturday in UTC, but still Friday afternoon in the configured zone
+		{time.Date(2016, 6, 4, 0, 30, 0, 0, time.UTC), false},
+		{time.Date(2016, 6, 4, 2, 0, 0, 0, pacific), true},
+		{time.Date(2016, 6, 5, 11, 0, 0, 0, pacific), true},
+		{time.Date(2016, 6, 6, 8, 59, 0, 0, pacific), true},
+		{time.Date(2016, 6, 6, 9, 0, 0, 0, pacific), false},
+	}
+	for _, test := range tests {
+		if outside := b.Outside(test.t); outside != test.outside {
+			t.Errorf("expected Outside(%s) to be %t", test.t.In(pacific).Format(time.RFC1123), test.outside)
+		}
+	}
+
+	// Outside uses what Validate parsed, rather than parsing again
+	b.Timezone = "Mars/Olympus_Mons"
+	if !b.Outside(time.Date(2016, 6, 5, 11, 0, 0, 0, pacific)) {
+		t.Error("expected Outside to use the BusinessHours parsed by Validate")
+	}
+
+	for _, invalid := range []*BusinessHoursConfig{
+		{Hours: "17:00-09:00"},
+		{Hours: "9-5"},
+		{Days: []string{"Caturday"}},
+		{Timezone: "Mars/Olympus_Mons"},
+	} {
+		if invalid.Validate() == nil {
+			t.Errorf("expected %+v to be invalid", *invalid)
+		}
+	}
+}
+
+func TestCreatedOutsideBusinessHoursFilter(t *testing.T) {
+	defer SetConfig(config)
+	c := newTestConfig()
+	c.BusinessHours = BusinessHoursConfig{Days: []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}}
+	SetConfig(c)
+
+	newInstance := func(id string, launched *time.Time) *Instance {
+		return NewInstance("us-west-2", &ec2.Instance{InstanceId: aws.String(id), LaunchTime: launched})
+	}
+	weekday := newInstance("i-weekday", aws.Time(time.Date(2016, 6, 3, 10, 0, 0, 0, time.UTC)))
+	weekend := newInstance("i-weekend", aws.Time(time.Date(2016, 6, 5, 2, 0, 0, 0, time.UTC)))
+	unknown := newInstance("i-unknown", nil)
+
+	outside := *filters.NewFilter("CreatedOutsideBusinessHours", []string{"true"})
+	inside := *filters.NewFilter("CreatedOutsideBusinessHours", []string{"false"})
+	if !weekend.Filter(outside) || weekend.Filter(inside) {
+		t.Error("expected an instance launched at 2am on a Sunday to be created outside business hours")
+	}
+	if weekday.Filter(outside) || !weekday.Filter(inside) {
+		t.Error("expected an instance launched at 10am on a Friday to be created inside business hours")
+	}
+	if unknown.Filter(outside) || unknown.Filter(inside) {
+		t.Error("expected an instance without a LaunchTime never to match")
+	}
+}
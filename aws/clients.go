@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// clientKey identifies a cached client
+// the endpoint is part of the key so that a new Config's EndpointURL
+// isn't served clients of the old one
+type clientKey struct {
+	service  string
+	region   string
+	endpoint string
+}
+
+// clients caches the AWS service clients of the package, which are safe
+// for concurrent use, so that every call in a region shares one client
+// created from sess and awsConfig
+var clients = struct {
+	sync.Mutex
+	byKey map[clientKey]interface{}
+}{byKey: make(map[clientKey]interface{})}
+
+// client returns the cached client of a service in a region,
+// creating it with newClient the first time
+func client(service, region string, newClient func(*aws.Config) interface{}) interface{} {
+	key := clientKey{service: service, region: region}
+	if config != nil {
+		key.endpoint = config.EndpointURL
+	}
+
+	clients.Lock()
+	defer clients.Unlock()
+	if c, ok := clients.byKey[key]; ok {
+		return c
+	}
+	c := newClient(awsConfig(region))
+	clients.byKey[key] = c
+	return c
+}
+
+// ec2Client returns the EC2 client of a region
+func ec2Client(region string) *ec2.EC2 {
+	return client("ec2", region, func(c *aws.Config) interface{} {
+		return ec2.New(sess, c)
+	}).(*ec2.EC2)
+}
+
+// autoScalingClient returns the AutoScaling client of a region
+func autoScalingClient(region string) *autoscaling.AutoScaling {
+	return client("autoscaling", region, func(c *aws.Config) interface{} {
+		return autoscaling.New(sess, c)
+	}).(*autoscaling.AutoScaling)
+}
+
+// cloudformationClient returns the CloudFormation client of a region
+func cloudformationClient(region string) *cloudformation.CloudFormation {
+	return client("cloudformation", region, func(c *aws.Config) interface{} {
+		return cloudformation.New(sess, c)
+	}).(*cloudformation.CloudFormation)
+}
+
+// cloudTrailClient returns the CloudTrail client of a region
+func cloudTrailClient(region string) *cloudtrail.CloudTrail {
+	return client("cloudtrail", region, func(c *aws.Config) interface{} {
+		return cloudtrail.New(sess, c)
+	}).(*cloudtrail.CloudTrail)
+}
+
+// SNSClient returns the SNS client of a region
+// exported for the SNSEventReporter, see events.SetSNSAPI
+func SNSClient(region string) *sns.SNS {
+	return client("sns", region, func(c *aws.Config) interface{} {
+		return sns.New(sess, c)
+	}).(*sns.SNS)
+}
@@ -0,0 +1,30 @@
+package aws
+
+import "testing"
+
+func TestClientsAreCached(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{})
+
+	if ec2Client("us-west-2") != ec2Client("us-west-2") {
+		t.Error("expected repeated calls for a region to reuse its EC2 client")
+	}
+	if autoScalingClient("us-west-2") != autoScalingClient("us-west-2") {
+		t.Error("expected repeated calls for a region to reuse its AutoScaling client")
+	}
+	if SNSClient("us-west-2") != SNSClient("us-west-2") {
+		t.Error("expected repeated calls for a region to reuse its SNS client")
+	}
+	if ec2Client("us-west-2") == ec2Client("us-east-1") {
+		t.Error("expected each region to have its own client")
+	}
+
+	local := &Config{EndpointURL: "http://localhost:4566"}
+	SetConfig(local)
+	if api := ec2Client("us-west-2"); api.Endpoint != local.EndpointURL {
+		t.Errorf("expected a new client for a new EndpointURL, got endpoint %s", api.Endpoint)
+	}
+	if api := SNSClient("us-west-2"); api.Endpoint != local.EndpointURL {
+		t.Errorf("expected the SNS client to use the EndpointURL, got endpoint %s", api.Endpoint)
+	}
+}
@@ -30,11 +30,15 @@ type Cloudformation struct {
 
 // NewCloudformation creates a new Cloudformation from the AWS API's cloudformation.Stack
 func NewCloudformation(region string, stack *cloudformation.Stack) *Cloudformation {
+	if stack.StackId == nil {
+		log.Warning("Skipping a Cloudformation without a StackId in %s", region)
+		return nil
+	}
 	a := Cloudformation{
 		Resource: Resource{
 			region:      reapable.Region(region),
 			id:          reapable.ID(*stack.StackId),
-			Name:        *stack.StackName,
+			Name:        aws.StringValue(stack.StackName),
 			Tags:        make(map[string]string),
 			reaperState: state.NewStateWithUntil(time.Now().Add(config.Notifications.FirstStateDuration.Duration)),
 		},
@@ -51,7 +55,7 @@ func NewCloudformation(region string, stack *cloudformation.Stack) *Cloudformati
 	}()
 
 	for _, tag := range stack.Tags {
-		a.Resource.Tags[*tag.Key] = *tag.Value
+		a.Resource.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
 
 	if a.Tagged(reaperTag) {
@@ -65,39 +69,92 @@ func NewCloudformation(region string, stack *cloudformation.Stack) *Cloudformati
 	return &a
 }
 
+// nestedStackResources returns the resources of a nested stack
+// replaceable in tests
+var nestedStackResources = func(region, stackID string) (resources []cloudformation.StackResource) {
+	for resource := range cloudformationResources(region, stackID) {
+		resources = append(resources, *resource)
+	}
+	return resources
+}
+
+// PhysicalResourceIDs returns the physical ids of the Cloudformation's resources
+// including the resources of nested stacks, resolved recursively
+func (a *Cloudformation) PhysicalResourceIDs() []reapable.ID {
+	a.RLock()
+	resources := a.Resources
+	a.RUnlock()
+
+	visited := map[string]bool{a.ID().String(): true}
+	return physicalResourceIDs(a.Region().String(), resources, visited)
+}
+
+func physicalResourceIDs(region string, resources []cloudformation.StackResource, visited map[string]bool) []reapable.ID {
+	var ids []reapable.ID
+	for _, resource := range resources {
+		if resource.PhysicalResourceId == nil {
+			continue
+		}
+		id := *resource.PhysicalResourceId
+		ids = append(ids, reapable.ID(id))
+
+		if resource.ResourceType != nil && *resource.ResourceType == "AWS::CloudFormation::Stack" && !visited[id] {
+			visited[id] = true
+			ids = append(ids, physicalResourceIDs(region, nestedStackResources(region, id), visited)...)
+		}
+	}
+	return ids
+}
+
+// ReapableDescription is a method of reapable.Reapable
+// it includes the Cloudformation's age and estimated cost, see describe
+func (a *Cloudformation) ReapableDescription() string {
+	return a.describe(a)
+}
+
+// CreatedAt is part of the reapable.Aged interface
+func (a *Cloudformation) CreatedAt() (time.Time, bool) {
+	if a.CreationTime == nil {
+		return time.Time{}, false
+	}
+	return *a.CreationTime, true
+}
+
+// ReapableType is part of the reapable.Typed interface
+func (a *Cloudformation) ReapableType() string {
+	return "Cloudformation"
+}
+
 // ReapableEventText is part of the events.Reapable interface
 func (a *Cloudformation) ReapableEventText() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableCloudformationEventText)
+	return reapableEventText(a, "CloudformationEventText", reapableCloudformationEventText)
 }
 
 // ReapableEventTextShort is part of the events.Reapable interface
 func (a *Cloudformation) ReapableEventTextShort() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableCloudformationEventTextShort)
+	return reapableEventText(a, "CloudformationEventTextShort", reapableCloudformationEventTextShort)
 }
 
 // ReapableEventEmail is part of the events.Reapable interface
 func (a *Cloudformation) ReapableEventEmail() (owner mail.Address, subject string, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-
 	subject = fmt.Sprintf("AWS Resource %s is going to be Reaped!", a.ReapableDescriptionTiny())
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableCloudformationEventHTML)
+	body, err = reapableEventHTML(a, "CloudformationEventHTML", reapableCloudformationEventHTML)
 	return
 }
 
 // ReapableEventEmailShort is part of the events.Reapable interface
 func (a *Cloudformation) ReapableEventEmailShort() (owner mail.Address, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableCloudformationEventHTMLShort)
+	body, err = reapableEventHTML(a, "CloudformationEventHTMLShort", reapableCloudformationEventHTMLShort)
 	return
 }
 
@@ -141,8 +198,10 @@ const reapableCloudformationEventHTML = `
 <body>
 	<p>Cloudformation <a href="{{ .Cloudformation.AWSConsoleURL }}">{{ if .Cloudformation.Name }}"{{.Cloudformation.Name}}" {{ end }} in {{.Cloudformation.Region}}</a> is scheduled to be terminated.</p>
 
+	{{ with .Cloudformation.ReapReason }}<p>It was {{ . }}.</p>{{ end }}
+
 	<p>
-		You can ignore this message and your Cloudformation will advance to the next state after <strong>{{.Cloudformation.ReaperState.Until.UTC.Format "Jan 2, 2006 at 3:04pm (MST)"}}</strong>. If you do not take action it will be terminated!
+		You can ignore this message and your Cloudformation will advance to the next state after <strong>{{ until .Cloudformation.ReaperState.Until }}</strong> ({{ relativeUntil .Cloudformation.ReaperState.Until }}). If you do not take action it will be terminated!
 	</p>
 
 	<p>
@@ -165,26 +224,28 @@ const reapableCloudformationEventHTML = `
 const reapableCloudformationEventHTMLShort = `
 <html>
 <body>
-	<p>Cloudformation <a href="{{ .Cloudformation.AWSConsoleURL }}">{{ if .Cloudformation.Name }}"{{.Cloudformation.Name}}" {{ end }}</a> in {{.Cloudformation.Region}}</a> is scheduled to be terminated after <strong>{{.Cloudformation.ReaperState.Until.UTC.Format "Jan 2, 2006 at 3:04pm (MST)"}}</strong>.
+	<p>Cloudformation <a href="{{ .Cloudformation.AWSConsoleURL }}">{{ if .Cloudformation.Name }}"{{.Cloudformation.Name}}" {{ end }}</a> in {{.Cloudformation.Region}}</a> is scheduled to be terminated after <strong>{{ until .Cloudformation.ReaperState.Until }}</strong> ({{ relativeUntil .Cloudformation.ReaperState.Until }}).
 		<br />
 		<a href="{{ .TerminateLink }}">Terminate</a>,
 		<a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a>,
 		<a href="{{ .IgnoreLink3 }}">3 days</a>,
 		<a href="{{ .IgnoreLink7}}"> 7 days</a>, or
 		<a href="{{ .WhitelistLink }}">Whitelist</a> it.
+		{{ with .Cloudformation.ReapReason }}<br />It was {{ . }}.{{ end }}
 	</p>
 </body>
 </html>
 `
 
 const reapableCloudformationEventTextShort = `%%%
-Cloudformation [{{.Cloudformation.ID}}]({{.Cloudformation.AWSConsoleURL}}) in region: [{{.Cloudformation.Region}}](https://{{.Cloudformation.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Cloudformation.Region}}).{{if .Cloudformation.Owned}} Owned by {{.Cloudformation.Owner}}.\n{{end}}
+Cloudformation [{{.Cloudformation.ID}}]({{.Cloudformation.AWSConsoleURL}}) in region: [{{.Cloudformation.Region}}](https://{{.Cloudformation.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Cloudformation.Region}}).{{if .Cloudformation.Owner}} Owned by {{.Cloudformation.Owner}}.\n{{end}}
 [Whitelist]({{ .WhitelistLink }}), or [Terminate]({{ .TerminateLink }}) this Cloudformation.
 %%%`
 
 const reapableCloudformationEventText = `%%%
 Reaper has discovered a Cloudformation qualified as reapable: [{{.Cloudformation.ID}}]({{.Cloudformation.AWSConsoleURL}}) in region: [{{.Cloudformation.Region}}](https://{{.Cloudformation.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Cloudformation.Region}}).\n
-{{if .Cloudformation.Owned}}Owned by {{.Cloudformation.Owner}}.\n{{end}}
+{{if .Cloudformation.Owner}}Owned by {{.Cloudformation.Owner}}.\n{{end}}
+{{ with .Cloudformation.ReapReason }}It was {{ . }}.\n{{ end }}
 {{ if .Cloudformation.AWSConsoleURL}}{{.Cloudformation.AWSConsoleURL}}\n{{end}}
 [AWS Console URL]({{.Cloudformation.AWSConsoleURL}})\n
 [Whitelist]({{ .WhitelistLink }}) this Cloudformation.
@@ -234,16 +295,33 @@ func (a *Cloudformation) Filter(filter filters.Filter) bool {
 		if a.StackStatus != nil && *a.StackStatus != filter.Arguments[0] {
 			matched = true
 		}
+	case "StackStatus":
+		for _, status := range filter.Arguments {
+			if a.StackStatus != nil && *a.StackStatus == status {
+				matched = true
+			}
+		}
 	case "CreatedTimeInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.CreationTime != nil && time.Since(*a.CreationTime) < d {
 			matched = true
 		}
 	case "CreatedTimeNotInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.CreationTime != nil && time.Since(*a.CreationTime) > d {
 			matched = true
 		}
+	// uses RFC3339 format
+	case "CreatedBefore":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.Before(t) {
+			matched = true
+		}
+	case "CreatedAfter":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.After(t) {
+			matched = true
+		}
 	case "Region":
 		for _, region := range filter.Arguments {
 			if a.Region() == reapable.Region(region) {
@@ -269,16 +347,50 @@ func (a *Cloudformation) Filter(filter filters.Filter) bool {
 		if !a.Tagged(filter.Arguments[0]) {
 			matched = true
 		}
+	case "MissingAnyTag":
+		if a.missingAnyTag(filter.Arguments) {
+			matched = true
+		}
+	case "MissingAllTags":
+		if a.missingAllTags(filter.Arguments) {
+			matched = true
+		}
 	case "TagNotEqual":
 		if a.Tag(filter.Arguments[0]) != filter.Arguments[1] {
 			matched = true
 		}
+	case "TagsAllEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) == len(pairs) {
+			matched = true
+		}
+	case "TagsAnyEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) > 0 {
+			matched = true
+		}
+	case "TagInSet":
+		if a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "TagNotInSet":
+		if !a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
 	case "ReaperState":
-		if a.reaperState.State.String() == filter.Arguments[0] {
+		if a.ReaperState().State.String() == filter.Arguments[0] {
+			matched = true
+		}
+	case "StateOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.stateOlderThan(d, time.Now()) {
+			matched = true
+		}
+	case "UntilWithin":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.untilWithin(d, time.Now()) {
 			matched = true
 		}
 	case "NotReaperState":
-		if a.reaperState.State.String() != filter.Arguments[0] {
+		if a.ReaperState().State.String() != filter.Arguments[0] {
 			matched = true
 		}
 	case "Named":
@@ -289,6 +401,11 @@ func (a *Cloudformation) Filter(filter filters.Filter) bool {
 		if a.Name != filter.Arguments[0] {
 			matched = true
 		}
+	case "CloudformationStackNameMatches":
+		// a Cloudformation matches by its own name
+		if re, err := filter.RegexpValue(0); err == nil && re.MatchString(a.Name) {
+			matched = true
+		}
 	case "IsDependency":
 		if b, err := filter.BoolValue(0); err == nil && a.Dependency == b {
 			matched = true
@@ -302,7 +419,7 @@ func (a *Cloudformation) Filter(filter filters.Filter) bool {
 			matched = true
 		}
 	default:
-		log.Error(fmt.Sprintf("No function %s could be found for filtering Cloudformations.", filter.Function))
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering Cloudformations.", filter.Function))
 	}
 	return matched
 }
@@ -321,12 +438,15 @@ func (a *Cloudformation) AWSConsoleURL() *url.URL {
 // Terminate is a method of reapable.Terminable, which is embedded in reapable.Reapable
 func (a *Cloudformation) Terminate() (bool, error) {
 	log.Info("Terminating Cloudformation %s", a.ReapableDescriptionTiny())
-	as := cloudformation.New(sess, aws.NewConfig().WithRegion(a.Region().String()))
+	as := cloudformationClient(a.Region().String())
 
 	input := &cloudformation.DeleteStackInput{
 		StackName: aws.String(a.ID().String()),
 	}
-	_, err := as.DeleteStack(input)
+	err := retry("delete Cloudformation "+a.ReapableDescriptionTiny(), func() error {
+		_, err := as.DeleteStack(input)
+		return err
+	})
 	if err != nil {
 		log.Error("could not delete Cloudformation ", a.ReapableDescriptionTiny())
 		return false, err
@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// newTestCloudformation returns a Cloudformation without
+// looking up its resources, unlike NewCloudformation
+func newTestCloudformation(status string, created time.Time) *Cloudformation {
+	return &Cloudformation{
+		Stack: cloudformation.Stack{
+			StackStatus:  aws.String(status),
+			CreationTime: aws.Time(created),
+		},
+	}
+}
+
+func TestCloudformationStackStatusFilter(t *testing.T) {
+	failed := *filters.NewFilter("StackStatus", []string{"ROLLBACK_COMPLETE", "UPDATE_ROLLBACK_FAILED"})
+
+	tests := []struct {
+		status   string
+		expected bool
+	}{
+		{"ROLLBACK_COMPLETE", true},
+		{"UPDATE_ROLLBACK_FAILED", true},
+		{"CREATE_COMPLETE", false},
+	}
+
+	for _, test := range tests {
+		a := newTestCloudformation(test.status, time.Now())
+		if a.Filter(failed) != test.expected {
+			t.Errorf("%s: expected StackStatus to be %t", test.status, test.expected)
+		}
+	}
+}
+
+func TestCloudformationCreatedTimeFilters(t *testing.T) {
+	old := newTestCloudformation("CREATE_COMPLETE", time.Now().Add(-48*time.Hour))
+	recent := newTestCloudformation("CREATE_COMPLETE", time.Now().Add(-time.Hour))
+
+	inTheLastDay := *filters.NewFilter("CreatedTimeInTheLast", []string{"24h"})
+	notInTheLastDay := *filters.NewFilter("CreatedTimeNotInTheLast", []string{"24h"})
+
+	if old.Filter(inTheLastDay) || !old.Filter(notInTheLastDay) {
+		t.Error("expected a stack created 48h ago to only match CreatedTimeNotInTheLast 24h")
+	}
+	if !recent.Filter(inTheLastDay) || recent.Filter(notInTheLastDay) {
+		t.Error("expected a stack created 1h ago to only match CreatedTimeInTheLast 24h")
+	}
+}
+
+func TestCloudformationNestedStackResources(t *testing.T) {
+	original := nestedStackResources
+	defer func() { nestedStackResources = original }()
+
+	nested := map[string][]cloudformation.StackResource{
+		"nested-stack": []cloudformation.StackResource{
+			{PhysicalResourceId: aws.String("i-nested"), ResourceType: aws.String("AWS::EC2::Instance")},
+			{PhysicalResourceId: aws.String("deeper-stack"), ResourceType: aws.String("AWS::CloudFormation::Stack")},
+		},
+		"deeper-stack": []cloudformation.StackResource{
+			{PhysicalResourceId: aws.String("vol-deeper"), ResourceType: aws.String("AWS::EC2::Volume")},
+			// a cycle should not recurse forever
+			{PhysicalResourceId: aws.String("nested-stack"), ResourceType: aws.String("AWS::CloudFormation::Stack")},
+		},
+	}
+	lookups := 0
+	nestedStackResources = func(region, stackID string) []cloudformation.StackResource {
+		lookups++
+		return nested[stackID]
+	}
+
+	parent := newTestCloudformation("CREATE_COMPLETE", time.Now())
+	parent.StackId = aws.String("parent-stack")
+	parent.Resources = []cloudformation.StackResource{
+		{PhysicalResourceId: aws.String("sg-parent"), ResourceType: aws.String("AWS::EC2::SecurityGroup")},
+		{PhysicalResourceId: aws.String("nested-stack"), ResourceType: aws.String("AWS::CloudFormation::Stack")},
+		{ResourceType: aws.String("AWS::EC2::Instance")},
+	}
+
+	ids := make(map[string]bool)
+	for _, id := range parent.PhysicalResourceIDs() {
+		ids[id.String()] = true
+	}
+	for _, id := range []string{"sg-parent", "nested-stack", "i-nested", "deeper-stack", "vol-deeper"} {
+		if !ids[id] {
+			t.Errorf("expected %s to be in the Cloudformation", id)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("expected 2 nested stack lookups, got %d", lookups)
+	}
+
+	// the nested stack's instance, tagged with the nested stack's name by
+	// Cloudformation, is protected like the parent's resources
+	// untagged, it is protected by the reaper package from PhysicalResourceIDs
+	i := newTestInstance("i-nested", map[string]string{"aws:cloudformation:stack-name": "nested-stack"})
+	if !i.Dependency || !i.IsInCloudformation {
+		t.Errorf("expected the nested stack's instance to be a dependency in a Cloudformation, got Dependency %t and IsInCloudformation %t",
+			i.Dependency, i.IsInCloudformation)
+	}
+	if i.CloudformationStackName != "nested-stack" {
+		t.Errorf("expected the nested stack's instance to be in nested-stack, got %q", i.CloudformationStackName)
+	}
+}
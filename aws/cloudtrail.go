@@ -0,0 +1,122 @@
+package aws
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudtrail/cloudtrailiface"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+var (
+	// newCloudTrailAPI returns a CloudTrail client for a region
+	// replaceable in tests
+	newCloudTrailAPI = func(region string) cloudtrailiface.CloudTrailAPI {
+		return cloudTrailClient(region)
+	}
+
+	// LookupEvents is limited to 2 requests per second per account
+	cloudTrailTimeout = time.Tick(500 * time.Millisecond)
+
+	// cloudTrailDeadline bounds the lookup of a resource's creator,
+	// including waiting for the rate limit
+	// replaceable in tests
+	cloudTrailDeadline = 30 * time.Second
+
+	// createdBy caches the creating principal of a resource by region and id
+	// an empty string is cached when no creation event was found
+	createdBy      = make(map[reapable.Region]map[reapable.ID]string)
+	createdByMutex sync.Mutex
+)
+
+// CreatedBy returns the principal that created the Resource, according to CloudTrail
+// returns an empty string if CloudTrailEnrichment is disabled, the Resource
+// has no creation event (see createEventName), or the lookup fails
+func (a *Resource) CreatedBy() string {
+	if !config.CloudTrailEnrichment || a.createEventName == "" {
+		return ""
+	}
+
+	// the lock is only held for the cache, so that a slow lookup doesn't
+	// hold up the other resources'
+	createdByMutex.Lock()
+	principal, ok := createdBy[a.region][a.id]
+	createdByMutex.Unlock()
+	if ok {
+		return principal
+	}
+
+	principal, err := lookupCreatedBy(a.region, a.id, a.createEventName)
+	if err != nil {
+		// don't cache failures, the next lookup may succeed
+		log.Error("CloudTrail lookup for %s failed: %s", a.ReapableDescriptionTiny(), err.Error())
+		return ""
+	}
+
+	createdByMutex.Lock()
+	defer createdByMutex.Unlock()
+	if createdBy[a.region] == nil {
+		createdBy[a.region] = make(map[reapable.ID]string)
+	}
+	createdBy[a.region][a.id] = principal
+	return principal
+}
+
+// lookupCreatedBy queries CloudTrail for the username on the
+// eventName event that created the resource with id
+// it fails if the lookup takes longer than cloudTrailDeadline
+func lookupCreatedBy(region reapable.Region, id reapable.ID, eventName string) (string, error) {
+	deadline := time.After(cloudTrailDeadline)
+	expired := fmt.Errorf("timed out after %s", cloudTrailDeadline.String())
+	api := newCloudTrailAPI(region.String())
+	input := &cloudtrail.LookupEventsInput{
+		LookupAttributes: []*cloudtrail.LookupAttribute{
+			&cloudtrail.LookupAttribute{
+				AttributeKey:   aws.String(cloudtrail.LookupAttributeKeyResourceName),
+				AttributeValue: aws.String(id.String()),
+			},
+		},
+	}
+
+	type result struct {
+		resp *cloudtrail.LookupEventsOutput
+		err  error
+	}
+	for {
+		select {
+		case <-cloudTrailTimeout:
+		case <-deadline:
+			return "", expired
+		}
+		// buffered, so that a request that outlives the deadline doesn't block
+		ch := make(chan result, 1)
+		go func(input cloudtrail.LookupEventsInput) {
+			resp, err := api.LookupEvents(&input)
+			ch <- result{resp, err}
+		}(*input)
+		var resp *cloudtrail.LookupEventsOutput
+		select {
+		case r := <-ch:
+			if r.err != nil {
+				return "", r.err
+			}
+			resp = r.resp
+		case <-deadline:
+			return "", expired
+		}
+		for _, event := range resp.Events {
+			if event.EventName != nil && *event.EventName == eventName && event.Username != nil {
+				return *event.Username, nil
+			}
+		}
+		if resp.NextToken == nil {
+			return "", nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudtrail/cloudtrailiface"
+)
+
+// testCloudTrail answers LookupEvents with a fixed list of events,
+// and blocks until block is closed, if it is set
+// other methods of CloudTrailAPI are not implemented
+type testCloudTrail struct {
+	cloudtrailiface.CloudTrailAPI
+	events  []*cloudtrail.Event
+	lookups int
+	block   chan bool
+}
+
+func (c *testCloudTrail) LookupEvents(input *cloudtrail.LookupEventsInput) (*cloudtrail.LookupEventsOutput, error) {
+	c.lookups++
+	if c.block != nil {
+		<-c.block
+	}
+	var events []*cloudtrail.Event
+	for _, event := range c.events {
+		for _, resource := range event.Resources {
+			if *resource.ResourceName == *input.LookupAttributes[0].AttributeValue {
+				events = append(events, event)
+			}
+		}
+	}
+	return &cloudtrail.LookupEventsOutput{Events: events}, nil
+}
+
+func newTestEvent(name, username, resource string) *cloudtrail.Event {
+	return &cloudtrail.Event{
+		EventName: aws.String(name),
+		Username:  aws.String(username),
+		Resources: []*cloudtrail.Resource{
+			&cloudtrail.Resource{ResourceName: aws.String(resource)},
+		},
+	}
+}
+
+// setTestCloudTrail replaces the CloudTrail client, returning a func that restores it
+func setTestCloudTrail(api *testCloudTrail) (restore func()) {
+	original := newCloudTrailAPI
+	newCloudTrailAPI = func(region string) cloudtrailiface.CloudTrailAPI {
+		return api
+	}
+	return func() { newCloudTrailAPI = original }
+}
+
+func TestCreatedBy(t *testing.T) {
+	api := &testCloudTrail{events: []*cloudtrail.Event{
+		newTestEvent("StopInstances", "someone-else", "i-cloudtrail1"),
+		newTestEvent("RunInstances", "jdoe", "i-cloudtrail1"),
+	}}
+	defer setTestCloudTrail(api)()
+	defer SetConfig(config)
+	SetConfig(&Config{CloudTrailEnrichment: true, DefaultEmailHost: "example.com"})
+
+	i := newTestInstance("i-cloudtrail1", nil)
+	if creator := i.CreatedBy(); creator != "jdoe" {
+		t.Errorf("expected CreatedBy to be jdoe, got %q", creator)
+	}
+	if owner := i.Owner(); owner == nil || owner.Address != "jdoe@example.com" {
+		t.Errorf("expected Owner to fall back to jdoe@example.com, got %v", owner)
+	}
+	if api.lookups != 1 {
+		t.Errorf("expected CreatedBy to be cached after 1 lookup, got %d lookups", api.lookups)
+	}
+
+	// owner tags take precedence over CloudTrail
+	tagged := newTestInstance("i-cloudtrail2", map[string]string{"Owner": "owner@example.com"})
+	if owner := tagged.Owner(); owner == nil || owner.Address != "owner@example.com" {
+		t.Errorf("expected Owner to be owner@example.com, got %v", owner)
+	}
+	if api.lookups != 1 {
+		t.Errorf("expected no lookup for a tagged instance, got %d lookups", api.lookups)
+	}
+}
+
+func TestCreatedByDisabled(t *testing.T) {
+	api := &testCloudTrail{events: []*cloudtrail.Event{
+		newTestEvent("RunInstances", "jdoe@example.com", "i-cloudtrail3"),
+	}}
+	defer setTestCloudTrail(api)()
+	defer SetConfig(config)
+	SetConfig(&Config{})
+
+	i := newTestInstance("i-cloudtrail3", nil)
+	if creator := i.CreatedBy(); creator != "" {
+		t.Errorf("expected no CreatedBy when CloudTrailEnrichment is disabled, got %q", creator)
+	}
+	if api.lookups != 0 {
+		t.Errorf("expected no lookups, got %d", api.lookups)
+	}
+}
+
+func TestCreatedByDeadline(t *testing.T) {
+	api := &testCloudTrail{
+		events: []*cloudtrail.Event{newTestEvent("RunInstances", "jdoe", "i-cloudtrail4")},
+		block:  make(chan bool),
+	}
+	defer close(api.block)
+	defer setTestCloudTrail(api)()
+	defer SetConfig(config)
+	SetConfig(&Config{CloudTrailEnrichment: true})
+	defer func(d time.Duration) { cloudTrailDeadline = d }(cloudTrailDeadline)
+	cloudTrailDeadline = time.Second
+
+	done := make(chan string)
+	go func() { done <- newTestInstance("i-cloudtrail4", nil).CreatedBy() }()
+
+	select {
+	case creator := <-done:
+		if creator != "" {
+			t.Errorf("expected no CreatedBy after the deadline, got %q", creator)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the lookup to give up after the deadline")
+	}
+}
@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// discoveryCursor is the progress of discovering a service's resources
+// in a region: the NextToken of the next page
+// the resources of the pages before it aren't saved, their tags would be
+// stale by the time discovery resumes
+type discoveryCursor struct {
+	Saved     time.Time `json:"saved"`
+	NextToken string    `json:"next_token"`
+}
+
+// cursorPath returns the file a service's cursor in a region is saved to
+func cursorPath(service, region string) string {
+	return filepath.Join(config.DiscoveryCursors, fmt.Sprintf("%s-%s.json", service, region))
+}
+
+// loadCursor returns the NextToken to resume discovering service in region from
+// the resources of the pages before it aren't discovered this cycle, so the
+// region is recorded as incompletely discovered, see DiscoveryFailedIn
+// if DiscoveryCursors is unset, or there is no cursor, or the cursor is
+// older than the scan Interval, discovery starts over and nil is returned
+func loadCursor(service, region string) *string {
+	if config.DiscoveryCursors == "" {
+		return nil
+	}
+
+	path := cursorPath(service, region)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		log.Error("Could not read discovery cursor %s: %s", path, err.Error())
+		return nil
+	}
+
+	var cursor discoveryCursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		log.Error("Could not parse discovery cursor %s: %s", path, err.Error())
+		return nil
+	}
+	// a cursor saved before the last scan is stale
+	if interval := config.Notifications.Interval.Duration; interval > 0 && time.Since(cursor.Saved) > interval {
+		log.Info("Discarding discovery cursor %s saved at %s", path, cursor.Saved.String())
+		return nil
+	}
+	if cursor.NextToken == "" {
+		return nil
+	}
+
+	log.Info("Resuming discovery of %s in %s from a cursor saved at %s", service, region, cursor.Saved.String())
+	discoveryIncomplete(region)
+	return aws.String(cursor.NextToken)
+}
+
+// saveCursor saves the NextToken of the next page of service in region,
+// if DiscoveryCursors is set
+// the cursor is written to a temporary file that replaces the last one,
+// so that a restart never finds a partially written cursor
+func saveCursor(service, region string, nextToken *string) {
+	if config.DiscoveryCursors == "" || aws.StringValue(nextToken) == "" {
+		return
+	}
+
+	b, err := json.Marshal(discoveryCursor{
+		Saved:     time.Now(),
+		NextToken: aws.StringValue(nextToken),
+	})
+	if err != nil {
+		log.Error("Could not save discovery cursor for %s in %s: %s", service, region, err.Error())
+		return
+	}
+
+	path := cursorPath(service, region)
+	f, err := ioutil.TempFile(config.DiscoveryCursors, filepath.Base(path))
+	if err != nil {
+		log.Error("Could not save discovery cursor %s: %s", path, err.Error())
+		return
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		log.Error("Could not save discovery cursor %s: %s", path, err.Error())
+	}
+}
+
+// clearCursor removes the cursor of service in region
+// once discovery has finished
+func clearCursor(service, region string) {
+	if config.DiscoveryCursors == "" {
+		return
+	}
+	path := cursorPath(service, region)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Error("Could not remove discovery cursor %s: %s", path, err.Error())
+	}
+}
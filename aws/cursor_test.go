@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// testEC2Cursor returns instances one page at a time, starting from the page
+// named by NextToken, and fails after failAfter pages, as if interrupted
+// other methods of EC2API are not implemented
+type testEC2Cursor struct {
+	ec2iface.EC2API
+	pages     map[string][]*ec2.Instance
+	next      map[string]string
+	failAfter int
+
+	nextTokens []string
+}
+
+func (c *testEC2Cursor) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	token := aws.StringValue(input.NextToken)
+	c.nextTokens = append(c.nextTokens, token)
+	for n := 0; ; n++ {
+		if c.failAfter > 0 && n == c.failAfter {
+			return errors.New("interrupted")
+		}
+		resp := &ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{&ec2.Reservation{Instances: c.pages[token]}},
+		}
+		next := c.next[token]
+		if next != "" {
+			resp.NextToken = aws.String(next)
+		}
+		if !fn(resp, next == "") || next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+func TestAllInstancesResumesFromCursor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reaper-cursors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer SetConfig(config)
+	c := &Config{Regions: []string{"us-west-2"}, DiscoveryCursors: dir}
+	c.Notifications.Interval.Duration = time.Hour
+	SetConfig(c)
+
+	api := &testEC2Cursor{
+		pages: map[string][]*ec2.Instance{
+			"":       {&ec2.Instance{InstanceId: aws.String("i-1")}, &ec2.Instance{InstanceId: aws.String("i-2")}},
+			"page-2": {&ec2.Instance{InstanceId: aws.String("i-3")}},
+			"page-3": {&ec2.Instance{InstanceId: aws.String("i-4")}},
+		},
+		next:      map[string]string{"": "page-2", "page-2": "page-3"},
+		failAfter: 1,
+	}
+	defer setTestEC2(api)()
+	_, restore := recordDiscoveryFailures()
+	defer restore()
+
+	// interrupted after the first page
+	for range AllInstances() {
+	}
+	if _, err := os.Stat(cursorPath("instances", "us-west-2")); err != nil {
+		t.Fatalf("expected a saved cursor, got %s", err.Error())
+	}
+
+	// a restart resumes from the second page, describing only its instances
+	// afresh, and the region is incompletely discovered
+	api.failAfter = 0
+	ResetDiscoveryFailures()
+	ids := make(map[string]int)
+	for i := range AllInstances() {
+		ids[i.ID().String()]++
+	}
+	if api.nextTokens[1] != "page-2" {
+		t.Errorf("expected discovery to resume from page-2, got %q", api.nextTokens[1])
+	}
+	if len(ids) != 2 || ids["i-3"] != 1 || ids["i-4"] != 1 {
+		t.Errorf("expected only the instances of the resumed pages, got %v", ids)
+	}
+	if !DiscoveryFailedIn("us-west-2") {
+		t.Error("expected a resumed region to be incompletely discovered")
+	}
+
+	// finished, so the next scan starts over
+	if _, err := os.Stat(cursorPath("instances", "us-west-2")); !os.IsNotExist(err) {
+		t.Errorf("expected the cursor to be removed, got %v", err)
+	}
+	for range AllInstances() {
+	}
+	if api.nextTokens[2] != "" {
+		t.Errorf("expected discovery to start over, got %q", api.nextTokens[2])
+	}
+}
+
+func TestStaleCursorIsDiscarded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reaper-cursors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer SetConfig(config)
+	c := &Config{Regions: []string{"us-west-2"}, DiscoveryCursors: dir}
+	c.Notifications.Interval.Duration = time.Nanosecond
+	SetConfig(c)
+
+	saveCursor("instances", "us-west-2", aws.String("page-2"))
+	time.Sleep(time.Millisecond)
+
+	if token := loadCursor("instances", "us-west-2"); token != nil {
+		t.Errorf("expected a stale cursor to be discarded, got %q", aws.StringValue(token))
+	}
+}
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// authErrorCodes are the AWS error codes of invalid credentials
+// and missing permissions
+var authErrorCodes = map[string]bool{
+	"AuthFailure":                 true,
+	"UnauthorizedOperation":       true,
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"InvalidClientTokenId":        true,
+	"SignatureDoesNotMatch":       true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"UnrecognizedClientException": true,
+	"OptInRequired":               true,
+	"NoCredentialProviders":       true,
+}
+
+// authError returns whether err is an AWS error caused by credentials
+// or permissions
+func authError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return authErrorCodes[aerr.Code()]
+	}
+	return false
+}
+
+// discoveryFailures are the regions discovery failed in since
+// ResetDiscoveryFailures
+var discoveryFailures = struct {
+	sync.Mutex
+	regions map[string]bool
+}{regions: make(map[string]bool)}
+
+// ResetDiscoveryFailures forgets the regions discovery failed in,
+// called at the start of each cycle
+func ResetDiscoveryFailures() {
+	discoveryFailures.Lock()
+	defer discoveryFailures.Unlock()
+	discoveryFailures.regions = make(map[string]bool)
+}
+
+// DiscoveryFailedIn returns whether discovering any service's resources
+// in region failed since ResetDiscoveryFailures, so that its resources
+// may be missing
+func DiscoveryFailedIn(region string) bool {
+	discoveryFailures.Lock()
+	defer discoveryFailures.Unlock()
+	return discoveryFailures.regions[region]
+}
+
+// discoveryFailed logs that discovering a service's resources in a region
+// failed, and emits a reaper.discovery.regionfailed statistic
+// the resources of other regions are unaffected
+func discoveryFailed(service, region string, err error) {
+	discoveryFailures.Lock()
+	discoveryFailures.regions[region] = true
+	discoveryFailures.Unlock()
+
+	reason := "error"
+	if authError(err) {
+		reason = "auth"
+		log.Error("Discovering %s in %s failed, check the credentials and permissions for %s: %s", service, region, region, err.Error())
+	} else {
+		log.Error("Discovering %s in %s failed: %s", service, region, err.Error())
+	}
+
+	if err := newCountStatistic("reaper.discovery.regionfailed",
+		[]string{fmt.Sprintf("region:%s,service:%s,reason:%s", region, service, reason)}); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+// discoveryIncomplete records that some of a region's resources are
+// missing this cycle without a failure, such as when discovery resumed
+// from a cursor, so that they aren't treated as gone
+func discoveryIncomplete(region string) {
+	discoveryFailures.Lock()
+	defer discoveryFailures.Unlock()
+	discoveryFailures.regions[region] = true
+}
+
+// recoverDiscovery recovers from a panic discovering a service's resources
+// in a region, so that the other regions are unaffected
+// must be deferred by each region's goroutine
+func recoverDiscovery(service, region string) {
+	if r := recover(); r != nil {
+		discoveryFailed(service, region, fmt.Errorf("panic: %v", r))
+	}
+}
@@ -0,0 +1,102 @@
+package aws
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// testEC2Region describes a single instance and volume named after its
+// region, or fails with err, or panics
+// other methods of EC2API are not implemented
+type testEC2Region struct {
+	ec2iface.EC2API
+	region string
+	err    error
+}
+
+func (c *testEC2Region) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	if c.err != nil {
+		return c.err
+	}
+	fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{&ec2.Reservation{
+		Instances: []*ec2.Instance{&ec2.Instance{InstanceId: aws.String("i-" + c.region)}},
+	}}}, true)
+	return nil
+}
+
+func (c *testEC2Region) DescribeVolumesPages(input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool) error {
+	if c.err != nil {
+		panic(c.err.Error())
+	}
+	fn(&ec2.DescribeVolumesOutput{Volumes: []*ec2.Volume{&ec2.Volume{VolumeId: aws.String("vol-" + c.region)}}}, true)
+	return nil
+}
+
+// setTestEC2Regions replaces the EC2 client of each region,
+// returning a func that restores it
+func setTestEC2Regions(apis map[string]*testEC2Region) (restore func()) {
+	original := newEC2API
+	newEC2API = func(region string) ec2iface.EC2API {
+		return apis[region]
+	}
+	return func() { newEC2API = original }
+}
+
+// recordDiscoveryFailures records the tags of reaper.discovery.regionfailed
+// statistics until restore is called
+func recordDiscoveryFailures() (recorded *[][]string, restore func()) {
+	recorded = new([][]string)
+	var mutex sync.Mutex
+	original := newCountStatistic
+	newCountStatistic = func(name string, tags []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if name == "reaper.discovery.regionfailed" {
+			*recorded = append(*recorded, tags)
+		}
+		return nil
+	}
+	return recorded, func() { newCountStatistic = original }
+}
+
+func TestDiscoveryRegionFailed(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{Regions: []string{"us-west-2", "us-east-1", "eu-west-1"}})
+	defer setTestEC2Regions(map[string]*testEC2Region{
+		"us-west-2": &testEC2Region{region: "us-west-2"},
+		"us-east-1": &testEC2Region{region: "us-east-1", err: awserr.New("AuthFailure", "AWS was not able to validate the provided access credentials", nil)},
+		"eu-west-1": &testEC2Region{region: "eu-west-1"},
+	})()
+
+	recorded, restore := recordDiscoveryFailures()
+	defer restore()
+
+	ids := make(map[string]bool)
+	for i := range AllInstances() {
+		ids[i.ID().String()] = true
+	}
+	if len(ids) != 2 || !ids["i-us-west-2"] || !ids["i-eu-west-1"] {
+		t.Errorf("expected the instances of the other regions, got %v", ids)
+	}
+	if len(*recorded) != 1 || (*recorded)[0][0] != "region:us-east-1,service:instances,reason:auth" {
+		t.Errorf("expected an auth failure for instances in us-east-1, got %v", *recorded)
+	}
+
+	// a panic in one region doesn't affect the others either
+	*recorded = nil
+	ids = make(map[string]bool)
+	for v := range AllVolumes() {
+		ids[v.ID().String()] = true
+	}
+	if len(ids) != 2 || !ids["vol-us-west-2"] || !ids["vol-eu-west-1"] {
+		t.Errorf("expected the volumes of the other regions, got %v", ids)
+	}
+	if len(*recorded) != 1 || (*recorded)[0][0] != "region:us-east-1,service:volumes,reason:error" {
+		t.Errorf("expected a failure for volumes in us-east-1, got %v", *recorded)
+	}
+}
@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+// FindReapable describes a single resource by region and id, without
+// discovering every resource
+// the type is inferred from the id: instances (i-), volumes (vol-),
+// security groups (sg-), images (ami-), network interfaces (eni-),
+// Cloudformations (their stack ARN),
+// and otherwise AutoScalingGroups by name
+func FindReapable(region reapable.Region, id reapable.ID) (events.Reapable, error) {
+	var r events.Reapable
+	var err error
+	switch s := id.String(); {
+	case strings.HasPrefix(s, "i-"):
+		r, err = findInstance(region.String(), s)
+	case strings.HasPrefix(s, "vol-"):
+		r, err = findVolume(region.String(), s)
+	case strings.HasPrefix(s, "sg-"):
+		r, err = findSecurityGroup(region.String(), s)
+	case strings.HasPrefix(s, "ami-"):
+		r, err = findImage(region.String(), s)
+	case strings.HasPrefix(s, "eni-"):
+		r, err = findNetworkInterface(region.String(), s)
+	case strings.HasPrefix(s, "arn:aws:cloudformation:"):
+		r, err = findCloudformation(region.String(), s)
+	default:
+		r, err = findAutoScalingGroup(region.String(), s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, reapable.ReapableNotFoundError{ErrorText: fmt.Sprintf("Could not find resource %s in %s", id.String(), region.String())}
+	}
+	return r, nil
+}
+
+func findInstance(region, id string) (events.Reapable, error) {
+	resp, err := newEC2API(region).DescribeInstances(&ec2.DescribeInstancesInput{InstanceIds: []*string{aws.String(id)}})
+	if err != nil {
+		return nil, err
+	}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			if i := NewInstance(region, instance); i != nil {
+				return i, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func findVolume(region, id string) (events.Reapable, error) {
+	resp, err := newEC2API(region).DescribeVolumes(&ec2.DescribeVolumesInput{VolumeIds: []*string{aws.String(id)}})
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range resp.Volumes {
+		if v := NewVolume(region, volume); v != nil {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+func findSecurityGroup(region, id string) (events.Reapable, error) {
+	resp, err := newEC2API(region).DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{GroupIds: []*string{aws.String(id)}})
+	if err != nil {
+		return nil, err
+	}
+	for _, sg := range resp.SecurityGroups {
+		if s := NewSecurityGroup(region, sg); s != nil {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func findImage(region, id string) (events.Reapable, error) {
+	resp, err := newEC2API(region).DescribeImages(&ec2.DescribeImagesInput{ImageIds: []*string{aws.String(id)}})
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range resp.Images {
+		if i := NewImage(region, image); i != nil {
+			return i, nil
+		}
+	}
+	return nil, nil
+}
+
+func findNetworkInterface(region, id string) (events.Reapable, error) {
+	resp, err := newEC2API(region).DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{NetworkInterfaceIds: []*string{aws.String(id)}})
+	if err != nil {
+		return nil, err
+	}
+	for _, eni := range resp.NetworkInterfaces {
+		if n := NewNetworkInterface(region, eni); n != nil {
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+func findCloudformation(region, id string) (events.Reapable, error) {
+	api := cloudformationClient(region)
+	resp, err := api.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(id)})
+	if err != nil {
+		return nil, err
+	}
+	for _, stack := range resp.Stacks {
+		if c := NewCloudformation(region, stack); c != nil {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func findAutoScalingGroup(region, id string) (events.Reapable, error) {
+	resp, err := newAutoScalingAPI(region).DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range resp.AutoScalingGroups {
+		if a := NewAutoScalingGroup(region, group); a != nil {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
@@ -61,6 +61,18 @@ func makeStopLink(region reapable.Region, id reapable.ID, tokenSecret, apiURL st
 	return makeURL(apiURL, "stop", stop), nil
 }
 
+// makeSnoozeOwnerLink creates a tokenized link for ignoring every resource of an owner for a duration
+func makeSnoozeOwnerLink(owner, tokenSecret, apiURL string, duration time.Duration) (string, error) {
+	snooze, err := token.Tokenize(tokenSecret, token.NewSnoozeOwnerJob(owner, duration))
+	if err != nil {
+		log.Error("Error creating snooze owner link: ", err)
+		return "", err
+	}
+
+	action := "snooze_" + duration.String()
+	return makeURL(apiURL, action, snooze), nil
+}
+
 func makeURL(host, action, token string) string {
 	if host == "" {
 		log.Error("makeURL: host is empty")
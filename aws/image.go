@@ -0,0 +1,415 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// Image is a Reapable, Filterable
+// embeds AWS API's ec2.Image
+type Image struct {
+	Resource
+	ec2.Image
+
+	// ids of the EBS snapshots backing the Image
+	BackingSnapshotIDs []string
+}
+
+// NewImage creates an Image from the AWS API's ec2.Image
+// returns nil if the ec2.Image has no id
+func NewImage(region string, image *ec2.Image) *Image {
+	if image.ImageId == nil {
+		log.Warning("Skipping an Image without an ImageId in %s", region)
+		return nil
+	}
+	a := Image{
+		Resource: Resource{
+			region: reapable.Region(region),
+			id:     reapable.ID(*image.ImageId),
+			Tags:   make(map[string]string),
+		},
+		Image: *image,
+	}
+
+	if image.Name != nil {
+		a.Resource.Name = *image.Name
+	}
+
+	for _, tag := range image.Tags {
+		a.Resource.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs != nil && mapping.Ebs.SnapshotId != nil {
+			a.BackingSnapshotIDs = append(a.BackingSnapshotIDs, *mapping.Ebs.SnapshotId)
+		}
+	}
+
+	if a.Tagged(reaperTag) {
+		// restore previously tagged state
+		a.reaperState = state.NewStateWithTag(a.Tag(reaperTag))
+	} else {
+		// initial state
+		a.reaperState = state.NewStateWithUntilAndState(
+			time.Now().Add(config.Notifications.FirstStateDuration.Duration),
+			state.FirstState)
+	}
+
+	return &a
+}
+
+// ReapableDescription is a method of reapable.Reapable
+// it includes the Image's age and estimated cost, see describe
+func (a *Image) ReapableDescription() string {
+	return a.describe(a)
+}
+
+// CreatedAt is part of the reapable.Aged interface
+// it parses the Image's CreationDate
+func (a *Image) CreatedAt() (time.Time, bool) {
+	return imageCreationDate(&a.Image)
+}
+
+// imageCreationDate parses an ec2.Image's CreationDate
+func imageCreationDate(image *ec2.Image) (time.Time, bool) {
+	if image.CreationDate == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, *image.CreationDate)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// ReapableType is part of the reapable.Typed interface
+func (a *Image) ReapableType() string {
+	return "Image"
+}
+
+// ReapableEventText is part of the events.Reapable interface
+func (a *Image) ReapableEventText() (*bytes.Buffer, error) {
+	return reapableEventText(a, "ImageEventText", reapableImageEventText)
+}
+
+// ReapableEventTextShort is part of the events.Reapable interface
+func (a *Image) ReapableEventTextShort() (*bytes.Buffer, error) {
+	return reapableEventText(a, "ImageEventTextShort", reapableImageEventTextShort)
+}
+
+// ReapableEventEmail is part of the events.Reapable interface
+func (a *Image) ReapableEventEmail() (owner mail.Address, subject string, body *bytes.Buffer, err error) {
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
+		return
+	}
+	subject = fmt.Sprintf("AWS Resource %s is going to be Reaped!", a.ReapableDescriptionTiny())
+	body, err = reapableEventHTML(a, "ImageEventHTML", reapableImageEventHTML)
+	return
+}
+
+// ReapableEventEmailShort is part of the events.Reapable interface
+func (a *Image) ReapableEventEmailShort() (owner mail.Address, body *bytes.Buffer, err error) {
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
+		return
+	}
+	body, err = reapableEventHTML(a, "ImageEventHTMLShort", reapableImageEventHTMLShort)
+	return
+}
+
+type imageEventData struct {
+	Config        *Config
+	Image         *Image
+	TerminateLink string
+	WhitelistLink string
+	IgnoreLink1   string
+	IgnoreLink3   string
+	IgnoreLink7   string
+}
+
+func (a *Image) getTemplateData() (interface{}, error) {
+	ignore1, err := makeIgnoreLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL, time.Duration(1*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	ignore3, err := makeIgnoreLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL, time.Duration(3*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	ignore7, err := makeIgnoreLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL, time.Duration(7*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	terminate, err := makeTerminateLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	whitelist, err := makeWhitelistLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &imageEventData{
+		Config:        config,
+		Image:         a,
+		TerminateLink: terminate,
+		WhitelistLink: whitelist,
+		IgnoreLink1:   ignore1,
+		IgnoreLink3:   ignore3,
+		IgnoreLink7:   ignore7,
+	}, nil
+}
+
+const reapableImageEventHTML = `
+<html>
+<body>
+	<p>Your AMI <a href="{{ .Image.AWSConsoleURL }}">{{ if .Image.Resource.Name }}"{{.Image.Resource.Name}}" {{ end }}{{.Image.ID}} in {{.Image.Region}}</a> is scheduled to be deregistered.</p>
+
+	{{ with .Image.ReapReason }}<p>It was {{ . }}.</p>{{ end }}
+
+	<p>
+		You can ignore this message and your AMI will advance to the next state after <strong>{{ until .Image.ReaperState.Until }}</strong> ({{ relativeUntil .Image.ReaperState.Until }}). If you do not take action it will be deregistered!
+	</p>
+
+	<p>
+		You may also choose to:
+		<ul>
+			<li><a href="{{ .TerminateLink }}">Deregister it now</a></li>
+			<li><a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a></li>
+			<li><a href="{{ .IgnoreLink3 }}">Ignore it for 3 more days</a></li>
+			<li><a href="{{ .IgnoreLink7}}">Ignore it for 7 more days</a></li>
+		</ul>
+	</p>
+
+	<p>
+		If you want the Reaper to ignore this AMI tag it with {{ .Config.WhitelistTag }} with any value, or click <a href="{{ .WhitelistLink }}">here</a>.
+	</p>
+</body>
+</html>
+`
+
+const reapableImageEventHTMLShort = `
+<html>
+<body>
+	<p>AMI <a href="{{ .Image.AWSConsoleURL }}">{{ if .Image.Resource.Name }}"{{.Image.Resource.Name}}" {{ end }}{{.Image.ID}}</a> in {{.Image.Region}} is scheduled to be deregistered after <strong>{{ until .Image.ReaperState.Until }}</strong> ({{ relativeUntil .Image.ReaperState.Until }}).
+		<br />
+		<a href="{{ .TerminateLink }}">Deregister</a>,
+		<a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a>,
+		<a href="{{ .IgnoreLink3 }}">3 days</a>,
+		<a href="{{ .IgnoreLink7}}"> 7 days</a>, or
+		<a href="{{ .WhitelistLink }}">Whitelist</a> it.
+		{{ with .Image.ReapReason }}<br />It was {{ . }}.{{ end }}
+	</p>
+</body>
+</html>
+`
+
+const reapableImageEventTextShort = `%%%
+AMI {{if .Image.Resource.Name}}"{{.Image.Resource.Name}}" {{end}}[{{.Image.ID}}]({{.Image.AWSConsoleURL}}) in region: [{{.Image.Region}}](https://{{.Image.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Image.Region}}).{{if .Image.Owner}} Owned by {{.Image.Owner}}.{{end}}\n
+[Whitelist]({{ .WhitelistLink }}) or [Deregister]({{ .TerminateLink }}) this AMI.
+%%%`
+
+const reapableImageEventText = `%%%
+Reaper has discovered an AMI qualified as reapable: {{if .Image.Resource.Name}}"{{.Image.Resource.Name}}" {{end}}[{{.Image.ID}}]({{.Image.AWSConsoleURL}}) in region: [{{.Image.Region}}](https://{{.Image.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Image.Region}}).\n
+{{if .Image.Owner}}Owned by {{.Image.Owner}}.\n{{end}}
+{{ with .Image.ReapReason }}It was {{ . }}.\n{{ end }}
+{{ if .Image.CreationDate}}Created: {{.Image.CreationDate}}.\n{{end}}
+[Whitelist]({{ .WhitelistLink }}) this AMI.
+[Deregister]({{ .TerminateLink }}) this AMI.
+%%%`
+
+// Filter is part of the filter.Filterable interface
+func (a *Image) Filter(filter filters.Filter) bool {
+	matched := false
+	// map function names to function calls
+	switch filter.Function {
+	case "CreatedTimeInTheLast":
+		d, err := filter.DurationValue(0)
+		if t, ok := a.CreatedAt(); err == nil && ok && time.Since(t) < d {
+			matched = true
+		}
+	case "CreatedTimeNotInTheLast":
+		d, err := filter.DurationValue(0)
+		if t, ok := a.CreatedAt(); err == nil && ok && time.Since(t) > d {
+			matched = true
+		}
+	// uses RFC3339 format
+	case "CreatedBefore":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.Before(t) {
+			matched = true
+		}
+	case "CreatedAfter":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.After(t) {
+			matched = true
+		}
+	case "Region":
+		for _, region := range filter.Arguments {
+			if a.Region() == reapable.Region(region) {
+				matched = true
+			}
+		}
+	case "NotRegion":
+		// was this resource's region one of those in the NOT list
+		regionSpecified := false
+		for _, region := range filter.Arguments {
+			if a.Region() == reapable.Region(region) {
+				regionSpecified = true
+			}
+		}
+		if !regionSpecified {
+			matched = true
+		}
+	case "Tagged":
+		if a.Tagged(filter.Arguments[0]) {
+			matched = true
+		}
+	case "NotTagged":
+		if !a.Tagged(filter.Arguments[0]) {
+			matched = true
+		}
+	case "MissingAnyTag":
+		if a.missingAnyTag(filter.Arguments) {
+			matched = true
+		}
+	case "MissingAllTags":
+		if a.missingAllTags(filter.Arguments) {
+			matched = true
+		}
+	case "TagNotEqual":
+		if a.Tag(filter.Arguments[0]) != filter.Arguments[1] {
+			matched = true
+		}
+	case "TagsAllEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) == len(pairs) {
+			matched = true
+		}
+	case "TagsAnyEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) > 0 {
+			matched = true
+		}
+	case "TagInSet":
+		if a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "TagNotInSet":
+		if !a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "ReaperState":
+		if a.ReaperState().State.String() == filter.Arguments[0] {
+			matched = true
+		}
+	case "NotReaperState":
+		if a.ReaperState().State.String() != filter.Arguments[0] {
+			matched = true
+		}
+	case "StateOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.stateOlderThan(d, time.Now()) {
+			matched = true
+		}
+	case "UntilWithin":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.untilWithin(d, time.Now()) {
+			matched = true
+		}
+	case "Named":
+		if a.Resource.Name == filter.Arguments[0] {
+			matched = true
+		}
+	case "NotNamed":
+		if a.Resource.Name != filter.Arguments[0] {
+			matched = true
+		}
+	case "NameContains":
+		if strings.Contains(a.Resource.Name, filter.Arguments[0]) {
+			matched = true
+		}
+	case "NotNameContains":
+		if !strings.Contains(a.Resource.Name, filter.Arguments[0]) {
+			matched = true
+		}
+	case "CloudformationStackNameMatches":
+		if a.cloudformationStackNameMatches(filter) {
+			matched = true
+		}
+	case "IsDependency":
+		if b, err := filter.BoolValue(0); err == nil && a.Dependency == b {
+			matched = true
+		}
+	default:
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering Images.", filter.Function))
+	}
+	return matched
+}
+
+// AWSConsoleURL returns the url that can be used to access the resource on the AWS Console
+func (a *Image) AWSConsoleURL() *url.URL {
+	url, err := url.Parse(fmt.Sprintf("https://%s.console.aws.amazon.com/ec2/v2/home?region=%s#Images:imageId=%s",
+		a.Region().String(), a.Region().String(), url.QueryEscape(a.ID().String())))
+	if err != nil {
+		log.Error("Error generating AWSConsoleURL. ", err)
+	}
+	return url
+}
+
+// Terminate is a method of reapable.Terminable, which is embedded in reapable.Reapable
+// Terminate deregisters the Image and, if DeleteImageBackingSnapshots is set,
+// deletes the snapshots backing it
+func (a *Image) Terminate() (bool, error) {
+	log.Info("Deregistering Image %s", a.ReapableDescriptionTiny())
+	api := newEC2API(a.Region().String())
+	err := retry("deregister Image "+a.ReapableDescriptionTiny(), func() error {
+		_, err := api.DeregisterImage(&ec2.DeregisterImageInput{
+			ImageId: aws.String(a.ID().String()),
+		})
+		return err
+	})
+	if err != nil {
+		log.Error("could not deregister Image %s", a.ReapableDescriptionTiny())
+		return false, err
+	}
+
+	if !config.DeleteImageBackingSnapshots {
+		return true, nil
+	}
+
+	// snapshots can only be deleted once the Image is deregistered
+	for _, id := range a.BackingSnapshotIDs {
+		log.Info("Deleting snapshot %s backing Image %s", id, a.ReapableDescriptionTiny())
+		err := retry("delete snapshot "+id, func() error {
+			_, err := api.DeleteSnapshot(&ec2.DeleteSnapshotInput{
+				SnapshotId: aws.String(id),
+			})
+			return err
+		})
+		if err != nil {
+			return false, fmt.Errorf("Image %s was deregistered, but its snapshot %s could not be deleted: %s",
+				a.ReapableDescriptionTiny(), id, err.Error())
+		}
+	}
+	return true, nil
+}
+
+// Stop is a method of reapable.Stoppable, which is embedded in reapable.Reapable
+// noop
+func (a *Image) Stop() (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,125 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// testEC2 records DeregisterImage and DeleteSnapshot calls
+// other methods of EC2API are not implemented
+type testEC2 struct {
+	ec2iface.EC2API
+	deregistered     []string
+	deletedSnapshots []string
+	deregisterErr    error
+}
+
+func (c *testEC2) DeregisterImage(input *ec2.DeregisterImageInput) (*ec2.DeregisterImageOutput, error) {
+	if c.deregisterErr != nil {
+		return nil, c.deregisterErr
+	}
+	c.deregistered = append(c.deregistered, *input.ImageId)
+	return &ec2.DeregisterImageOutput{}, nil
+}
+
+func (c *testEC2) DeleteSnapshot(input *ec2.DeleteSnapshotInput) (*ec2.DeleteSnapshotOutput, error) {
+	c.deletedSnapshots = append(c.deletedSnapshots, *input.SnapshotId)
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
+// setTestEC2 replaces the EC2 client, returning a func that restores it
+func setTestEC2(api ec2iface.EC2API) (restore func()) {
+	original := newEC2API
+	newEC2API = func(region string) ec2iface.EC2API {
+		return api
+	}
+	return func() { newEC2API = original }
+}
+
+func newTestImage(id string, created time.Time, snapshotIDs ...string) *Image {
+	image := &ec2.Image{
+		ImageId:      aws.String(id),
+		Name:         aws.String("test-" + id),
+		CreationDate: aws.String(created.UTC().Format(time.RFC3339)),
+	}
+	for _, snapshotID := range snapshotIDs {
+		image.BlockDeviceMappings = append(image.BlockDeviceMappings, &ec2.BlockDeviceMapping{
+			Ebs: &ec2.EbsBlockDevice{SnapshotId: aws.String(snapshotID)},
+		})
+	}
+	// instance store mappings have no snapshot
+	image.BlockDeviceMappings = append(image.BlockDeviceMappings, &ec2.BlockDeviceMapping{
+		VirtualName: aws.String("ephemeral0"),
+	})
+	return NewImage("us-west-2", image)
+}
+
+func TestImageTerminateKeepsSnapshots(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{})
+	api := &testEC2{}
+	defer setTestEC2(api)()
+
+	ok, err := newTestImage("ami-1", time.Now(), "snap-1", "snap-2").Terminate()
+	if !ok || err != nil {
+		t.Fatalf("expected Terminate to succeed, got %t, %v", ok, err)
+	}
+	if len(api.deregistered) != 1 || api.deregistered[0] != "ami-1" {
+		t.Errorf("expected ami-1 to be deregistered, got %v", api.deregistered)
+	}
+	if len(api.deletedSnapshots) != 0 {
+		t.Errorf("expected no snapshots to be deleted, got %v", api.deletedSnapshots)
+	}
+}
+
+func TestImageTerminateDeletesSnapshots(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{DeleteImageBackingSnapshots: true})
+	api := &testEC2{}
+	defer setTestEC2(api)()
+
+	ok, err := newTestImage("ami-1", time.Now(), "snap-1", "snap-2").Terminate()
+	if !ok || err != nil {
+		t.Fatalf("expected Terminate to succeed, got %t, %v", ok, err)
+	}
+	if len(api.deletedSnapshots) != 2 || api.deletedSnapshots[0] != "snap-1" || api.deletedSnapshots[1] != "snap-2" {
+		t.Errorf("expected snap-1 and snap-2 to be deleted, got %v", api.deletedSnapshots)
+	}
+}
+
+func TestImageTerminateDeregisterFails(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{DeleteImageBackingSnapshots: true})
+	api := &testEC2{deregisterErr: errors.New("InvalidAMIID.Unavailable")}
+	defer setTestEC2(api)()
+
+	if ok, err := newTestImage("ami-1", time.Now(), "snap-1").Terminate(); ok || err == nil {
+		t.Errorf("expected Terminate to fail, got %t, %v", ok, err)
+	}
+	if len(api.deletedSnapshots) != 0 {
+		t.Errorf("expected no snapshots to be deleted after a failed deregister, got %v", api.deletedSnapshots)
+	}
+}
+
+func TestImageFilters(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{})
+
+	i := newTestImage("ami-1", time.Now().Add(-48*time.Hour))
+	if !i.Filter(*filters.NewFilter("CreatedTimeNotInTheLast", []string{"24h"})) {
+		t.Error("expected an image created 48h ago not to be created in the last 24h")
+	}
+	if i.Filter(*filters.NewFilter("CreatedTimeInTheLast", []string{"24h"})) {
+		t.Error("expected an image created 48h ago not to be created in the last 24h")
+	}
+	if !i.Filter(*filters.NewFilter("NameContains", []string{"test"})) {
+		t.Error("expected NameContains to match the image name")
+	}
+}
@@ -24,33 +24,47 @@ type Instance struct {
 	ec2.Instance
 	SecurityGroups map[reapable.ID]string
 	AutoScaled     bool
+
+	// InWhitelistedAutoScalingGroup is whether the Instance is protected by
+	// its AutoScalingGroup's whitelist tag, see PropagateAutoScalingGroupWhitelist
+	InWhitelistedAutoScalingGroup bool
+
+	// estimated, in USD, 0 if unknown
+	HourlyCost float64
 }
 
 // NewInstance creates an Instance from the AWS API's ec2.Instance
 func NewInstance(region string, instance *ec2.Instance) *Instance {
+	if instance.InstanceId == nil {
+		log.Warning("Skipping an Instance without an InstanceId in %s", region)
+		return nil
+	}
 	a := Instance{
 		Resource: Resource{
 			id:     reapable.ID(*instance.InstanceId),
 			region: reapable.Region(region), // passed in cause not possible to extract out of api
 			Tags:   make(map[string]string),
+
+			createEventName: "RunInstances",
 		},
 		SecurityGroups: make(map[reapable.ID]string),
 		Instance:       *instance,
 	}
 
 	for _, sg := range instance.SecurityGroups {
-		if sg != nil {
-			a.SecurityGroups[reapable.ID(*sg.GroupId)] = *sg.GroupName
+		if sg != nil && sg.GroupId != nil {
+			a.SecurityGroups[reapable.ID(*sg.GroupId)] = aws.StringValue(sg.GroupName)
 		}
 	}
 
 	for _, tag := range instance.Tags {
-		a.Resource.Tags[*tag.Key] = *tag.Value
+		a.Resource.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
 
 	if a.Tagged("aws:cloudformation:stack-name") {
 		a.Dependency = true
 		a.IsInCloudformation = true
+		a.CloudformationStackName = a.Tag("aws:cloudformation:stack-name")
 	}
 
 	if a.Tagged("aws:autoscaling:groupName") {
@@ -92,38 +106,126 @@ func (a *Instance) Stopping() bool { return *a.State.Code == 64 }
 // Stopped returns whether an instance's State is Stopped
 func (a *Instance) Stopped() bool { return *a.State.Code == 80 }
 
+// Transitioning returns whether an instance is changing State, Pending,
+// ShuttingDown or Stopping
+func (a *Instance) Transitioning() bool { return a.Pending() || a.ShuttingDown() || a.Stopping() }
+
+// IsSpot returns whether an instance was launched by a spot instance request
+// spot instances cannot be stopped
+func (a *Instance) IsSpot() bool { return a.SpotInstanceRequestId != nil }
+
+// instanceSizes orders the sizes of instance types, smallest first
+var instanceSizes = map[string]int{
+	"nano":     0,
+	"micro":    1,
+	"small":    2,
+	"medium":   3,
+	"large":    4,
+	"xlarge":   5,
+	"2xlarge":  6,
+	"4xlarge":  7,
+	"8xlarge":  8,
+	"9xlarge":  9,
+	"10xlarge": 10,
+	"12xlarge": 11,
+	"16xlarge": 12,
+	"18xlarge": 13,
+	"24xlarge": 14,
+	"32xlarge": 15,
+}
+
+// instanceFamily returns the family of an instance type, "m4" for "m4.large"
+func instanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// instanceSize returns the rank of an instance type's size in instanceSizes
+// size may be an instance type ("m4.large") or only a size ("large")
+func instanceSize(size string) (int, bool) {
+	if parts := strings.SplitN(size, ".", 2); len(parts) == 2 {
+		size = parts[1]
+	}
+	rank, ok := instanceSizes[size]
+	return rank, ok
+}
+
+// instanceTypeLargerThan returns whether the size of instanceType is larger than size
+// unknown sizes never match
+func instanceTypeLargerThan(instanceType, size string) bool {
+	typeRank, ok := instanceSize(instanceType)
+	if !ok {
+		return false
+	}
+	sizeRank, ok := instanceSize(size)
+	if !ok {
+		return false
+	}
+	return typeRank > sizeRank
+}
+
+// ReapableDescription is a method of reapable.Reapable
+// it includes the Instance's age and estimated cost, see describe
+func (a *Instance) ReapableDescription() string {
+	return a.describe(a)
+}
+
+// CreatedAt is part of the reapable.Aged interface
+func (a *Instance) CreatedAt() (time.Time, bool) {
+	if a.LaunchTime == nil {
+		return time.Time{}, false
+	}
+	return *a.LaunchTime, true
+}
+
+// StopLabel is the text of the Instance's stop link in events,
+// InstanceStopLabel or Stop
+func (a *Instance) StopLabel() string {
+	if config.InstanceStopLabel != "" {
+		return config.InstanceStopLabel
+	}
+	return "Stop"
+}
+
+// ReapableType is part of the reapable.Typed interface
+func (a *Instance) ReapableType() string {
+	return "Instance"
+}
+
+// EstimatedHourlyCost is part of the reapable.Costed interface
+func (a *Instance) EstimatedHourlyCost() (float64, bool) {
+	return a.HourlyCost, a.HourlyCost > 0
+}
+
 // ReapableEventText is part of the events.Reapable interface
 func (a *Instance) ReapableEventText() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableInstanceEventText)
+	return reapableEventText(a, "InstanceEventText", reapableInstanceEventText)
 }
 
 // ReapableEventTextShort is part of the events.Reapable interface
 func (a *Instance) ReapableEventTextShort() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableInstanceEventTextShort)
+	return reapableEventText(a, "InstanceEventTextShort", reapableInstanceEventTextShort)
 }
 
 // ReapableEventEmail is part of the events.Reapable interface
 func (a *Instance) ReapableEventEmail() (owner mail.Address, subject string, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
 	subject = fmt.Sprintf("AWS Resource %s is going to be Reaped!", a.ReapableDescriptionTiny())
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableInstanceEventHTML)
+	body, err = reapableEventHTML(a, "InstanceEventHTML", reapableInstanceEventHTML)
 	return
 }
 
 // ReapableEventEmailShort is part of the events.Reapable interface
 func (a *Instance) ReapableEventEmailShort() (owner mail.Address, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableInstanceEventHTMLShort)
+	body, err = reapableEventHTML(a, "InstanceEventHTMLShort", reapableInstanceEventHTMLShort)
 	return
 }
 
@@ -182,15 +284,17 @@ const reapableInstanceEventHTML = `
 <body>
 	<p>Your AWS Instance <a href="{{ .Instance.AWSConsoleURL }}">{{ if .Instance.Name }}"{{.Instance.Name}}" {{ end }}{{.Instance.ID}} in {{.Instance.Region}}</a> is scheduled to be terminated.</p>
 
+	{{ with .Instance.ReapReason }}<p>It was {{ . }}.</p>{{ end }}
+
 	<p>
-		You can ignore this message and your instance will advance to the next state after <strong>{{.Instance.ReaperState.Until.UTC.Format "Jan 2, 2006 at 3:04pm (MST)"}}</strong>. If you do not take action it will be terminated!
+		You can ignore this message and your instance will advance to the next state after <strong>{{ until .Instance.ReaperState.Until }}</strong> ({{ relativeUntil .Instance.ReaperState.Until }}). If you do not take action it will be terminated!
 	</p>
 
 	<p>
 		You may also choose to:
 		<ul>
 			<li><a href="{{ .TerminateLink }}">Terminate it now</a></li>
-			<li><a href="{{ .StopLink }}">Stop it now</a></li>
+			{{ if not .Instance.IsSpot }}<li><a href="{{ .StopLink }}">{{ .Instance.StopLabel }}</a> now</li>{{ end }}
 			<li><a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a></li>
 			<li><a href="{{ .IgnoreLink3 }}">Ignore it for 3 more days</a></li>
 			<li><a href="{{ .IgnoreLink7}}">Ignore it for 7 more days</a></li>
@@ -207,34 +311,36 @@ const reapableInstanceEventHTML = `
 const reapableInstanceEventHTMLShort = `
 <html>
 <body>
-	<p>Instance <a href="{{ .Instance.AWSConsoleURL }}">{{ if .Instance.Name }}"{{.Instance.Name}}" {{ end }}{{.Instance.ID}}</a> in {{.Instance.Region}} is scheduled to be terminated after <strong>{{.Instance.ReaperState.Until.UTC.Format "Jan 2, 2006 at 3:04pm (MST)"}}</strong>.
+	<p>Instance <a href="{{ .Instance.AWSConsoleURL }}">{{ if .Instance.Name }}"{{.Instance.Name}}" {{ end }}{{.Instance.ID}}</a> in {{.Instance.Region}} is scheduled to be terminated after <strong>{{ until .Instance.ReaperState.Until }}</strong> ({{ relativeUntil .Instance.ReaperState.Until }}).
 		<br />
 		<a href="{{ .TerminateLink }}">Terminate</a>,
-		<a href="{{ .StopLink }}">Stop</a>,
+		{{ if not .Instance.IsSpot }}<a href="{{ .StopLink }}">{{ .Instance.StopLabel }}</a>,{{ end }}
 		<a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a>,
 		<a href="{{ .IgnoreLink3 }}">3 days</a>,
 		<a href="{{ .IgnoreLink7}}"> 7 days</a>, or
 		<a href="{{ .WhitelistLink }}">Whitelist</a> it.
+		{{ with .Instance.ReapReason }}<br />It was {{ . }}.{{ end }}
 	</p>
 </body>
 </html>
 `
 
 const reapableInstanceEventTextShort = `%%%
-Instance {{if .Instance.Name}}"{{.Instance.Name}}" {{end}}[{{.Instance.ID}}]({{.Instance.AWSConsoleURL}}) in region: [{{.Instance.Region}}](https://{{.Instance.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Instance.Region}}).{{if .Instance.Owned}} Owned by {{.Instance.Owner}}.{{end}}\n
+Instance {{if .Instance.Name}}"{{.Instance.Name}}" {{end}}[{{.Instance.ID}}]({{.Instance.AWSConsoleURL}}) in region: [{{.Instance.Region}}](https://{{.Instance.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Instance.Region}}).{{if .Instance.Owner}} Owned by {{.Instance.Owner}}.{{end}}\n
 Instance Type: {{ .Instance.InstanceType}}, {{ .Instance.State.Name}}{{ if .Instance.PublicIpAddress}}, Public IP: {{.Instance.PublicIpAddress}}.\n{{end}}
-[Whitelist]({{ .WhitelistLink }}), [Stop]({{ .StopLink }}), or [Terminate]({{ .TerminateLink }}) this instance.
+[Whitelist]({{ .WhitelistLink }}), {{ if not .Instance.IsSpot }}[{{ .Instance.StopLabel }}]({{ .StopLink }}), {{ end }}or [Terminate]({{ .TerminateLink }}) this instance.
 %%%`
 
 const reapableInstanceEventText = `%%%
 Reaper has discovered an instance qualified as reapable: {{if .Instance.Name}}"{{.Instance.Name}}" {{end}}[{{.Instance.ID}}]({{.Instance.AWSConsoleURL}}) in region: [{{.Instance.Region}}](https://{{.Instance.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Instance.Region}}).\n
 {{if .Instance.Owner}}Owned by {{.Instance.Owner}}.\n{{end}}
+{{ with .Instance.ReapReason }}It was {{ . }}.\n{{ end }}
 State: {{ .Instance.State.Name}}.\n
 Instance Type: {{ .Instance.InstanceType}}.\n
 {{ if .Instance.PublicIpAddress}}This instance's public IP: {{.Instance.PublicIpAddress}}\n{{end}}
 {{ if .Instance.AWSConsoleURL}}{{.Instance.AWSConsoleURL}}\n{{end}}
 [Whitelist]({{ .WhitelistLink }}).
-[Stop]({{ .StopLink }}) this instance.
+{{ if not .Instance.IsSpot }}[{{ .Instance.StopLabel }}]({{ .StopLink }}) this instance.{{ end }}
 [Terminate]({{ .TerminateLink }}) this instance.
 %%%`
 
@@ -248,6 +354,15 @@ func (a *Instance) AWSConsoleURL() *url.URL {
 	return url
 }
 
+// instanceProfileARN returns the ARN of the Instance's IAM instance profile,
+// or "" if it was launched without one
+func (a *Instance) instanceProfileARN() string {
+	if a.IamInstanceProfile == nil {
+		return ""
+	}
+	return aws.StringValue(a.IamInstanceProfile.Arn)
+}
+
 func (a *Instance) Filter(filter filters.Filter) bool {
 	matched := false
 	// map function names to function calls
@@ -260,6 +375,26 @@ func (a *Instance) Filter(filter filters.Filter) bool {
 		if a.InstanceType != nil && *a.InstanceType == filter.Arguments[0] {
 			matched = true
 		}
+	case "InstanceTypeIs":
+		for _, instanceType := range filter.Arguments {
+			if a.InstanceType != nil && *a.InstanceType == instanceType {
+				matched = true
+			}
+		}
+	case "InstanceFamilyIs":
+		for _, family := range filter.Arguments {
+			if a.InstanceType != nil && instanceFamily(*a.InstanceType) == family {
+				matched = true
+			}
+		}
+	case "InstanceTypeLargerThan":
+		if a.InstanceType != nil && instanceTypeLargerThan(*a.InstanceType, filter.Arguments[0]) {
+			matched = true
+		}
+	case "IsSpot":
+		if b, err := filter.BoolValue(0); err == nil && a.IsSpot() == b {
+			matched = true
+		}
 	case "HasPublicIpAddress":
 		if b, err := filter.BoolValue(0); err == nil && b == (a.PublicIpAddress != nil) {
 			matched = true
@@ -268,10 +403,67 @@ func (a *Instance) Filter(filter filters.Filter) bool {
 		if a.PublicIpAddress != nil && *a.PublicIpAddress == filter.Arguments[0] {
 			matched = true
 		}
+	case "EstimatedMonthlyCostGreaterThan":
+		if monthlyCostGreaterThan(a, &filter) {
+			matched = true
+		}
+	case "InVPC":
+		if a.VpcId != nil && anyIn([]string{*a.VpcId}, filter.Arguments) {
+			matched = true
+		}
+	case "NotInVPC":
+		if a.VpcId == nil || !anyIn([]string{*a.VpcId}, filter.Arguments) {
+			matched = true
+		}
+	case "InSubnet":
+		if a.SubnetId != nil && anyIn([]string{*a.SubnetId}, filter.Arguments) {
+			matched = true
+		}
+	case "AmiOlderThan":
+		if d, err := filter.DurationValue(0); err == nil && a.imageOlderThan(d) {
+			matched = true
+		}
+	case "AmiMissing":
+		b, err := filter.BoolValue(0)
+		if missing, ok := a.imageMissing(); err == nil && ok && missing == b {
+			matched = true
+		}
+	case "InstanceProfileIs":
+		if arn := a.instanceProfileARN(); arn != "" && anyIn([]string{arn}, filter.Arguments) {
+			matched = true
+		}
+	case "InstanceProfileContains":
+		for _, substring := range filter.Arguments {
+			if arn := a.instanceProfileARN(); arn != "" && strings.Contains(arn, substring) {
+				matched = true
+			}
+		}
+	case "NoInstanceProfile":
+		if b, err := filter.BoolValue(0); err == nil && (a.instanceProfileARN() == "") == b {
+			matched = true
+		}
+	case "KeyPairIs":
+		if name := aws.StringValue(a.KeyName); name != "" && anyIn([]string{name}, filter.Arguments) {
+			matched = true
+		}
+	case "KeyPairContains":
+		for _, substring := range filter.Arguments {
+			if name := aws.StringValue(a.KeyName); name != "" && strings.Contains(name, substring) {
+				matched = true
+			}
+		}
+	case "NoKeyPair":
+		if b, err := filter.BoolValue(0); err == nil && (aws.StringValue(a.KeyName) == "") == b {
+			matched = true
+		}
 	case "InCloudformation":
 		if b, err := filter.BoolValue(0); err == nil && a.IsInCloudformation == b {
 			matched = true
 		}
+	case "CloudformationStackNameMatches":
+		if a.cloudformationStackNameMatches(filter) {
+			matched = true
+		}
 	case "AutoScaled":
 		if b, err := filter.BoolValue(0); err == nil && a.AutoScaled == b {
 			matched = true
@@ -279,22 +471,38 @@ func (a *Instance) Filter(filter filters.Filter) bool {
 	// uses RFC3339 format
 	// https://www.ietf.org/rfc/rfc3339.txt
 	case "LaunchTimeBefore":
-		t, err := time.Parse(time.RFC3339, filter.Arguments[0])
+		t, err := filter.TimeValue(0)
 		if err == nil && a.LaunchTime != nil && t.After(*a.LaunchTime) {
 			matched = true
 		}
 	case "LaunchTimeAfter":
-		t, err := time.Parse(time.RFC3339, filter.Arguments[0])
+		t, err := filter.TimeValue(0)
 		if err == nil && a.LaunchTime != nil && t.Before(*a.LaunchTime) {
 			matched = true
 		}
 	case "LaunchTimeInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.LaunchTime != nil && time.Since(*a.LaunchTime) < d {
 			matched = true
 		}
+	case "CreatedOutsideBusinessHours":
+		b, err := filter.BoolValue(0)
+		if outside, ok := createdOutsideBusinessHours(a); err == nil && ok && outside == b {
+			matched = true
+		}
+	// uses RFC3339 format
+	case "CreatedBefore":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.Before(t) {
+			matched = true
+		}
+	case "CreatedAfter":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.After(t) {
+			matched = true
+		}
 	case "LaunchTimeNotInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.LaunchTime != nil && time.Since(*a.LaunchTime) > d {
 			matched = true
 		}
@@ -323,16 +531,50 @@ func (a *Instance) Filter(filter filters.Filter) bool {
 		if !a.Tagged(filter.Arguments[0]) {
 			matched = true
 		}
+	case "MissingAnyTag":
+		if a.missingAnyTag(filter.Arguments) {
+			matched = true
+		}
+	case "MissingAllTags":
+		if a.missingAllTags(filter.Arguments) {
+			matched = true
+		}
 	case "TagNotEqual":
 		if a.Tag(filter.Arguments[0]) != filter.Arguments[1] {
 			matched = true
 		}
+	case "TagsAllEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) == len(pairs) {
+			matched = true
+		}
+	case "TagsAnyEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) > 0 {
+			matched = true
+		}
+	case "TagInSet":
+		if a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "TagNotInSet":
+		if !a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
 	case "ReaperState":
-		if a.reaperState.State.String() == filter.Arguments[0] {
+		if a.ReaperState().State.String() == filter.Arguments[0] {
+			matched = true
+		}
+	case "StateOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.stateOlderThan(d, time.Now()) {
+			matched = true
+		}
+	case "UntilWithin":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.untilWithin(d, time.Now()) {
 			matched = true
 		}
 	case "NotReaperState":
-		if a.reaperState.State.String() != filter.Arguments[0] {
+		if a.ReaperState().State.String() != filter.Arguments[0] {
 			matched = true
 		}
 	case "Named":
@@ -356,7 +598,7 @@ func (a *Instance) Filter(filter filters.Filter) bool {
 			matched = true
 		}
 	default:
-		log.Error(fmt.Sprintf("No function %s could be found for filtering Instances.", filter.Function))
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering Instances.", filter.Function))
 	}
 	return matched
 }
@@ -364,12 +606,17 @@ func (a *Instance) Filter(filter filters.Filter) bool {
 // Terminate is a method of reapable.Terminable, which is embedded in reapable.Reapable
 func (a *Instance) Terminate() (bool, error) {
 	log.Info("Terminating Instance %s", a.ReapableDescriptionTiny())
-	api := ec2.New(sess, aws.NewConfig().WithRegion(a.Region().String()))
+	api := ec2Client(a.Region().String())
 	req := &ec2.TerminateInstancesInput{
 		InstanceIds: []*string{aws.String(a.ID().String())},
 	}
 
-	resp, err := api.TerminateInstances(req)
+	var resp *ec2.TerminateInstancesOutput
+	err := retry("terminate Instance "+a.ReapableDescriptionTiny(), func() error {
+		var err error
+		resp, err = api.TerminateInstances(req)
+		return err
+	})
 
 	if err != nil {
 		return false, err
@@ -385,12 +632,17 @@ func (a *Instance) Terminate() (bool, error) {
 // Start starts an instance
 func (a *Instance) Start() (bool, error) {
 	log.Info("Starting Instance %s", a.ReapableDescriptionTiny())
-	api := ec2.New(sess, aws.NewConfig().WithRegion(string(a.Region())))
+	api := ec2Client(string(a.Region()))
 	req := &ec2.StartInstancesInput{
 		InstanceIds: []*string{aws.String(a.ID().String())},
 	}
 
-	resp, err := api.StartInstances(req)
+	var resp *ec2.StartInstancesOutput
+	err := retry("start Instance "+a.ReapableDescriptionTiny(), func() error {
+		var err error
+		resp, err = api.StartInstances(req)
+		return err
+	})
 
 	if err != nil {
 		return false, err
@@ -405,13 +657,36 @@ func (a *Instance) Start() (bool, error) {
 
 // Stop is a method of reapable.Stoppable, which is embedded in reapable.Reapable
 func (a *Instance) Stop() (bool, error) {
-	log.Info("Stopping Instance %s", a.ReapableDescriptionTiny())
-	api := ec2.New(sess, aws.NewConfig().WithRegion(string(a.Region())))
+	return a.stop(false)
+}
+
+// ForceStop is part of reapable.ForceStopper
+// ForceStop stops an instance without waiting for it to shut down cleanly
+func (a *Instance) ForceStop() (bool, error) {
+	return a.stop(true)
+}
+
+func (a *Instance) stop(force bool) (bool, error) {
+	if a.IsSpot() {
+		return false, fmt.Errorf("Instance %s is a spot instance and cannot be stopped.", a.ReapableDescriptionTiny())
+	}
+	if force {
+		log.Info("Force stopping Instance %s", a.ReapableDescriptionTiny())
+	} else {
+		log.Info("Stopping Instance %s", a.ReapableDescriptionTiny())
+	}
+	api := ec2Client(string(a.Region()))
 	req := &ec2.StopInstancesInput{
 		InstanceIds: []*string{aws.String(a.ID().String())},
+		Force:       aws.Bool(force),
 	}
 
-	resp, err := api.StopInstances(req)
+	var resp *ec2.StopInstancesOutput
+	err := retry("stop Instance "+a.ReapableDescriptionTiny(), func() error {
+		var err error
+		resp, err = api.StopInstances(req)
+		return err
+	})
 
 	if err != nil {
 		return false, err
@@ -0,0 +1,318 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+func newTestConfig() *Config {
+	return &Config{
+		HTTP: events.HTTPConfig{
+			TokenSecret: "test secret",
+			APIURL:      "http://localhost",
+			Token:       "t",
+			Action:      "a",
+		},
+		WhitelistTag: "REAPER_SPARE_ME",
+	}
+}
+
+func TestReapableEventEmailOwner(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	owned := newTestInstance("i-owned", map[string]string{"Owner": "owner@example.com"})
+	unowned := newTestInstance("i-unowned", nil)
+
+	owner, _, body, err := owned.ReapableEventEmail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.Address != "owner@example.com" {
+		t.Errorf("expected owner@example.com, got %s", owner.Address)
+	}
+	if body == nil || body.Len() == 0 {
+		t.Error("expected an email body")
+	}
+
+	// unowned without a default owner
+	_, _, _, err = unowned.ReapableEventEmail()
+	if _, ok := err.(reapable.UnownedError); !ok {
+		t.Errorf("expected an UnownedError, got %v", err)
+	}
+
+	// unowned with a default owner
+	config.Notifications.DefaultOwner = "Escalations <escalations@example.com>"
+	owner, _, _, err = unowned.ReapableEventEmail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.Address != "escalations@example.com" {
+		t.Errorf("expected escalations@example.com, got %s", owner.Address)
+	}
+	owner, _, err = unowned.ReapableEventEmailShort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.Address != "escalations@example.com" {
+		t.Errorf("expected escalations@example.com, got %s", owner.Address)
+	}
+	text, err := unowned.ReapableEventText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "Owned by") || !strings.Contains(text.String(), "escalations@example.com") {
+		t.Errorf("expected the default owner in the event text, got %s", text.String())
+	}
+
+	// the resource's own owner takes precedence over the default
+	owner, _, _, err = owned.ReapableEventEmail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.Address != "owner@example.com" {
+		t.Errorf("expected owner@example.com, got %s", owner.Address)
+	}
+}
+
+func TestInstanceTypeFilters(t *testing.T) {
+	newTypedInstance := func(instanceType string) *Instance {
+		i := newTestInstance("i-"+instanceType, nil)
+		i.InstanceType = aws.String(instanceType)
+		return i
+	}
+
+	tests := []struct {
+		instanceType string
+		filter       *filters.Filter
+		expected     bool
+	}{
+		{"m4.large", filters.NewFilter("InstanceTypeIs", []string{"t2.micro", "m4.large"}), true},
+		{"m4.xlarge", filters.NewFilter("InstanceTypeIs", []string{"t2.micro", "m4.large"}), false},
+		{"m5.2xlarge", filters.NewFilter("InstanceFamilyIs", []string{"m5", "r5"}), true},
+		{"r5.large", filters.NewFilter("InstanceFamilyIs", []string{"m5", "r5"}), true},
+		{"m4.large", filters.NewFilter("InstanceFamilyIs", []string{"m5", "r5"}), false},
+		{"m4.xlarge", filters.NewFilter("InstanceTypeLargerThan", []string{"large"}), true},
+		{"c4.2xlarge", filters.NewFilter("InstanceTypeLargerThan", []string{"m4.xlarge"}), true},
+		{"t2.large", filters.NewFilter("InstanceTypeLargerThan", []string{"large"}), false},
+		{"t2.nano", filters.NewFilter("InstanceTypeLargerThan", []string{"micro"}), false},
+		{"r4.16xlarge", filters.NewFilter("InstanceTypeLargerThan", []string{"12xlarge"}), true},
+		// unknown sizes never match
+		{"x1.hugemongous", filters.NewFilter("InstanceTypeLargerThan", []string{"large"}), false},
+		{"m4.xlarge", filters.NewFilter("InstanceTypeLargerThan", []string{"enormous"}), false},
+		{"m4", filters.NewFilter("InstanceTypeLargerThan", []string{"large"}), false},
+	}
+
+	for _, test := range tests {
+		i := newTypedInstance(test.instanceType)
+		if i.Filter(*test.filter) != test.expected {
+			t.Errorf("%s %s(%v): expected %t", test.instanceType, test.filter.Function, test.filter.Arguments, test.expected)
+		}
+	}
+}
+
+func TestVPCFilters(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	i := newTestInstance("i-1", nil)
+	i.VpcId = aws.String("vpc-dev")
+	i.SubnetId = aws.String("subnet-dev")
+	sg := NewSecurityGroup("us-west-2", &ec2.SecurityGroup{GroupId: aws.String("sg-1"), VpcId: aws.String("vpc-prod")})
+	attached := NewVolume("us-west-2", &ec2.Volume{VolumeId: aws.String("vol-1")})
+	attached.AttachedVpcIDs = []string{"vpc-dev"}
+	attached.AttachedSubnetIDs = []string{"subnet-dev"}
+	detached := NewVolume("us-west-2", &ec2.Volume{VolumeId: aws.String("vol-2")})
+
+	tests := []struct {
+		filterable filters.Filterable
+		filter     *filters.Filter
+		expected   bool
+	}{
+		{i, filters.NewFilter("InVPC", []string{"vpc-prod", "vpc-dev"}), true},
+		{i, filters.NewFilter("InVPC", []string{"vpc-prod"}), false},
+		{i, filters.NewFilter("NotInVPC", []string{"vpc-prod"}), true},
+		{i, filters.NewFilter("NotInVPC", []string{"vpc-dev"}), false},
+		{i, filters.NewFilter("InSubnet", []string{"subnet-dev"}), true},
+		{i, filters.NewFilter("InSubnet", []string{"subnet-prod"}), false},
+		{sg, filters.NewFilter("InVPC", []string{"vpc-prod"}), true},
+		{sg, filters.NewFilter("NotInVPC", []string{"vpc-prod"}), false},
+		{attached, filters.NewFilter("InVPC", []string{"vpc-dev"}), true},
+		{attached, filters.NewFilter("NotInVPC", []string{"vpc-dev"}), false},
+		{attached, filters.NewFilter("InSubnet", []string{"subnet-dev"}), true},
+		{detached, filters.NewFilter("InVPC", []string{"vpc-dev"}), false},
+		{detached, filters.NewFilter("NotInVPC", []string{"vpc-prod"}), true},
+	}
+
+	for _, test := range tests {
+		if test.filterable.Filter(*test.filter) != test.expected {
+			t.Errorf("%T %s(%v): expected %t", test.filterable, test.filter.Function, test.filter.Arguments, test.expected)
+		}
+	}
+}
+
+func TestInstanceProfileFilters(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	admin := newTestInstance("i-admin", nil)
+	admin.IamInstanceProfile = &ec2.IamInstanceProfile{
+		Arn: aws.String("arn:aws:iam::123456789012:instance-profile/deprecated-admin"),
+		Id:  aws.String("AIPA1"),
+	}
+	none := newTestInstance("i-none", nil)
+
+	tests := []struct {
+		instance *Instance
+		filter   *filters.Filter
+		expected bool
+	}{
+		{admin, filters.NewFilter("InstanceProfileIs", []string{"arn:aws:iam::123456789012:instance-profile/deprecated-admin"}), true},
+		{admin, filters.NewFilter("InstanceProfileIs", []string{"arn:aws:iam::123456789012:instance-profile/web"}), false},
+		{admin, filters.NewFilter("InstanceProfileContains", []string{"web", "deprecated-"}), true},
+		{admin, filters.NewFilter("InstanceProfileContains", []string{"web"}), false},
+		{admin, filters.NewFilter("NoInstanceProfile", []string{"true"}), false},
+		{admin, filters.NewFilter("NoInstanceProfile", []string{"false"}), true},
+		{none, filters.NewFilter("InstanceProfileIs", []string{""}), false},
+		{none, filters.NewFilter("InstanceProfileContains", []string{""}), false},
+		{none, filters.NewFilter("NoInstanceProfile", []string{"true"}), true},
+		{none, filters.NewFilter("NoInstanceProfile", []string{"false"}), false},
+	}
+
+	for _, test := range tests {
+		if test.instance.Filter(*test.filter) != test.expected {
+			t.Errorf("%s %s(%v): expected %t", test.instance.ID(), test.filter.Function, test.filter.Arguments, test.expected)
+		}
+	}
+}
+
+func TestSpotInstanceTemplates(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	onDemand := newTestInstance("i-ondemand", map[string]string{"Owner": "owner@example.com"})
+	spot := newTestInstance("i-spot", map[string]string{"Owner": "owner@example.com"})
+	spot.SpotInstanceRequestId = aws.String("sir-1234")
+
+	if onDemand.IsSpot() || !spot.IsSpot() {
+		t.Fatal("expected only the instance with a spot request to be spot")
+	}
+
+	isSpot := *filters.NewFilter("IsSpot", []string{"true"})
+	if onDemand.Filter(isSpot) || !spot.Filter(isSpot) {
+		t.Error("expected only the spot instance to match IsSpot")
+	}
+
+	for _, test := range []struct {
+		instance *Instance
+		hasStop  bool
+	}{
+		{onDemand, true},
+		{spot, false},
+	} {
+		_, _, html, err := test.instance.ReapableEventEmail()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, htmlShort, err := test.instance.ReapableEventEmailShort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := test.instance.ReapableEventText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		textShort, err := test.instance.ReapableEventTextShort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, body := range map[string]string{
+			"html":       html.String(),
+			"html short": htmlShort.String(),
+			"text":       text.String(),
+			"text short": textShort.String(),
+		} {
+			if strings.Contains(body, "Stop") != test.hasStop {
+				t.Errorf("%s %s: expected a stop link to be %t", test.instance.ID(), name, test.hasStop)
+			}
+		}
+	}
+
+	if _, err := spot.Stop(); err == nil {
+		t.Error("expected stopping a spot instance to fail")
+	}
+}
+
+func TestNewInstancePartiallyPopulated(t *testing.T) {
+	if i := NewInstance("us-west-2", &ec2.Instance{}); i != nil {
+		t.Error("expected an Instance without an id to be skipped")
+	}
+
+	i := NewInstance("us-west-2", &ec2.Instance{
+		InstanceId:     aws.String("i-pending"),
+		SecurityGroups: []*ec2.GroupIdentifier{&ec2.GroupIdentifier{GroupName: aws.String("no-id")}},
+	})
+	if i == nil {
+		t.Fatal("expected an Instance")
+	}
+	if len(i.SecurityGroups) != 0 {
+		t.Errorf("expected security groups without ids to be skipped, got %v", i.SecurityGroups)
+	}
+}
+
+func TestEstimatedMonthlyCostWithoutPrice(t *testing.T) {
+	i := newTestInstance("i-1", nil)
+	if i.Filter(*filters.NewFilter("EstimatedMonthlyCostGreaterThan", []string{"0"})) {
+		t.Error("expected an instance without a price not to match")
+	}
+}
+
+func TestInstanceTransitioning(t *testing.T) {
+	for code, transitioning := range map[int64]bool{0: true, 16: false, 32: true, 48: false, 64: true, 80: false} {
+		i := newTestInstance("i-1", nil)
+		i.State = &ec2.InstanceState{Code: aws.Int64(code)}
+		if i.Transitioning() != transitioning {
+			t.Errorf("expected Transitioning to be %t for state code %d", transitioning, code)
+		}
+	}
+}
+
+func TestKeyPairFilters(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	shared := newTestInstance("i-shared", nil)
+	shared.KeyName = aws.String("shared-ops-2015")
+	none := newTestInstance("i-none", nil)
+
+	tests := []struct {
+		instance *Instance
+		filter   *filters.Filter
+		expected bool
+	}{
+		{shared, filters.NewFilter("KeyPairIs", []string{"deploy", "shared-ops-2015"}), true},
+		{shared, filters.NewFilter("KeyPairIs", []string{"shared-ops"}), false},
+		{shared, filters.NewFilter("KeyPairContains", []string{"deploy", "shared-ops"}), true},
+		{shared, filters.NewFilter("KeyPairContains", []string{"deploy"}), false},
+		{shared, filters.NewFilter("NoKeyPair", []string{"true"}), false},
+		{shared, filters.NewFilter("NoKeyPair", []string{"false"}), true},
+		{none, filters.NewFilter("KeyPairIs", []string{""}), false},
+		{none, filters.NewFilter("KeyPairContains", []string{""}), false},
+		{none, filters.NewFilter("NoKeyPair", []string{"true"}), true},
+		{none, filters.NewFilter("NoKeyPair", []string{"false"}), false},
+	}
+
+	for _, test := range tests {
+		if test.instance.Filter(*test.filter) != test.expected {
+			t.Errorf("%s %s(%v): expected %t", test.instance.ID(), test.filter.Function, test.filter.Arguments, test.expected)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// instanceImage is what is known of the AMI an instance was launched from
+type instanceImage struct {
+	created time.Time
+	// missing is whether the AMI can no longer be described,
+	// such as after it was deregistered
+	missing bool
+}
+
+var (
+	// instanceImages caches the AMIs of instances by region and image id
+	// it is cleared each cycle by AllInstances
+	instanceImages      = make(map[reapable.Region]map[string]instanceImage)
+	instanceImagesMutex sync.Mutex
+)
+
+// clearInstanceImages empties the cache of instances' AMIs,
+// so that AMIs are described again each cycle
+func clearInstanceImages() {
+	instanceImagesMutex.Lock()
+	defer instanceImagesMutex.Unlock()
+	instanceImages = make(map[reapable.Region]map[string]instanceImage)
+}
+
+// image returns the AMI the Instance was launched from,
+// and false if it has none or the lookup fails
+func (a *Instance) image() (instanceImage, bool) {
+	id := aws.StringValue(a.ImageId)
+	if id == "" {
+		return instanceImage{}, false
+	}
+
+	// the lock is only held for the cache, so that a slow lookup doesn't
+	// hold up the other instances'
+	instanceImagesMutex.Lock()
+	image, ok := instanceImages[a.region][id]
+	instanceImagesMutex.Unlock()
+	if ok {
+		return image, true
+	}
+
+	image, err := lookupInstanceImage(a.region, id)
+	if err != nil {
+		// don't cache failures, the next lookup may succeed
+		log.Error("AMI lookup for %s failed: %s", a.ReapableDescriptionTiny(), err.Error())
+		return instanceImage{}, false
+	}
+
+	instanceImagesMutex.Lock()
+	defer instanceImagesMutex.Unlock()
+	if instanceImages[a.region] == nil {
+		instanceImages[a.region] = make(map[string]instanceImage)
+	}
+	instanceImages[a.region][id] = image
+	return image, true
+}
+
+// lookupInstanceImage describes the AMI id
+// an AMI that doesn't exist is missing rather than an error
+func lookupInstanceImage(region reapable.Region, id string) (instanceImage, error) {
+	resp, err := newEC2API(region.String()).DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String(id)},
+	})
+	if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == "InvalidAMIID.NotFound" || aerr.Code() == "InvalidAMIID.Unavailable") {
+		return instanceImage{missing: true}, nil
+	} else if err != nil {
+		return instanceImage{}, err
+	}
+	for _, image := range resp.Images {
+		if aws.StringValue(image.ImageId) != id {
+			continue
+		}
+		// an AMI without a CreationDate has a zero created time
+		created, _ := imageCreationDate(image)
+		return instanceImage{created: created}, nil
+	}
+	return instanceImage{missing: true}, nil
+}
+
+// imageOlderThan returns whether the Instance's AMI was created longer than d ago
+// instances whose AMI is missing or has no CreationDate never are
+func (a *Instance) imageOlderThan(d time.Duration) bool {
+	image, ok := a.image()
+	return ok && !image.missing && !image.created.IsZero() && time.Since(image.created) > d
+}
+
+// imageMissing returns whether the Instance's AMI can no longer be described,
+// and false if the lookup fails
+func (a *Instance) imageMissing() (missing bool, ok bool) {
+	image, ok := a.image()
+	return image.missing, ok
+}
@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// testEC2Images describes AMIs from a fixed list, failing like EC2
+// for AMIs that aren't in it
+// other methods of EC2API are not implemented
+type testEC2Images struct {
+	ec2iface.EC2API
+	images    []*ec2.Image
+	describes int
+}
+
+func (c *testEC2Images) DescribeImages(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	c.describes++
+	var images []*ec2.Image
+	for _, image := range c.images {
+		for _, id := range input.ImageIds {
+			if *image.ImageId == *id {
+				images = append(images, image)
+			}
+		}
+	}
+	if len(images) == 0 {
+		return nil, awserr.New("InvalidAMIID.NotFound", "The image id does not exist", nil)
+	}
+	return &ec2.DescribeImagesOutput{Images: images}, nil
+}
+
+func TestAmiFilters(t *testing.T) {
+	api := &testEC2Images{images: []*ec2.Image{
+		&ec2.Image{ImageId: aws.String("ami-old"), CreationDate: aws.String(time.Now().Add(-365 * 24 * time.Hour).Format(time.RFC3339))},
+		&ec2.Image{ImageId: aws.String("ami-new"), CreationDate: aws.String(time.Now().Add(-time.Hour).Format(time.RFC3339))},
+	}}
+	defer setTestEC2(api)()
+	clearInstanceImages()
+
+	newInstance := func(id, imageID string) *Instance {
+		i := newTestInstance(id, nil)
+		i.ImageId = aws.String(imageID)
+		return i
+	}
+	old := newInstance("i-old", "ami-old")
+	alsoOld := newInstance("i-also-old", "ami-old")
+	recent := newInstance("i-new", "ami-new")
+	missing := newInstance("i-missing", "ami-deregistered")
+
+	olderThan := *filters.NewFilter("AmiOlderThan", []string{"720h"})
+	if !old.Filter(olderThan) || !alsoOld.Filter(olderThan) {
+		t.Error("expected instances on a year old AMI to match AmiOlderThan(720h)")
+	}
+	if recent.Filter(olderThan) {
+		t.Error("expected an instance on a new AMI not to match AmiOlderThan(720h)")
+	}
+	if missing.Filter(olderThan) {
+		t.Error("expected an instance on a missing AMI not to match AmiOlderThan(720h)")
+	}
+
+	amiMissing := *filters.NewFilter("AmiMissing", []string{"true"})
+	if !missing.Filter(amiMissing) {
+		t.Error("expected an instance on a deregistered AMI to match AmiMissing")
+	}
+	if old.Filter(amiMissing) {
+		t.Error("expected an instance on an existing AMI not to match AmiMissing")
+	}
+	if !old.Filter(*filters.NewFilter("AmiMissing", []string{"false"})) {
+		t.Error("expected an instance on an existing AMI to match AmiMissing(false)")
+	}
+
+	if api.describes != 3 {
+		t.Errorf("expected each AMI to be described once, got %d describes", api.describes)
+	}
+
+	// the next cycle describes AMIs again
+	clearInstanceImages()
+	old.Filter(olderThan)
+	if api.describes != 4 {
+		t.Errorf("expected AMIs to be described again after the cache is cleared, got %d describes", api.describes)
+	}
+}
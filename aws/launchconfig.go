@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+var (
+	// newAutoScalingAPI returns an AutoScaling client for a region
+	// replaceable in tests
+	newAutoScalingAPI = func(region string) autoscalingiface.AutoScalingAPI {
+		return autoScalingClient(region)
+	}
+
+	// launchConfigCreated caches the CreatedTime of launch configurations
+	// by region and name
+	// a zero time is cached when the launch configuration doesn't exist
+	launchConfigCreated      = make(map[reapable.Region]map[string]time.Time)
+	launchConfigCreatedMutex sync.Mutex
+)
+
+// launchConfigCreatedTime returns when the AutoScalingGroup's launch
+// configuration was created, and false if it has none or the lookup fails
+func (a *AutoScalingGroup) launchConfigCreatedTime() (time.Time, bool) {
+	name := aws.StringValue(a.LaunchConfigurationName)
+	if name == "" {
+		return time.Time{}, false
+	}
+
+	// the lock is only held for the cache, so that a slow lookup doesn't
+	// hold up the other AutoScalingGroups'
+	launchConfigCreatedMutex.Lock()
+	created, ok := launchConfigCreated[a.region][name]
+	launchConfigCreatedMutex.Unlock()
+	if ok {
+		return created, !created.IsZero()
+	}
+
+	created, err := lookupLaunchConfigCreatedTime(a.region, name)
+	if err != nil {
+		// don't cache failures, the next lookup may succeed
+		log.Error("Launch configuration lookup for %s failed: %s", a.ReapableDescriptionTiny(), err.Error())
+		return time.Time{}, false
+	}
+
+	launchConfigCreatedMutex.Lock()
+	defer launchConfigCreatedMutex.Unlock()
+	if launchConfigCreated[a.region] == nil {
+		launchConfigCreated[a.region] = make(map[string]time.Time)
+	}
+	launchConfigCreated[a.region][name] = created
+	return created, !created.IsZero()
+}
+
+// lookupLaunchConfigCreatedTime describes the launch configuration name
+// returns a zero time if it doesn't exist
+func lookupLaunchConfigCreatedTime(region reapable.Region, name string) (time.Time, error) {
+	resp, err := newAutoScalingAPI(region.String()).DescribeLaunchConfigurations(&autoscaling.DescribeLaunchConfigurationsInput{
+		LaunchConfigurationNames: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, lc := range resp.LaunchConfigurations {
+		if aws.StringValue(lc.LaunchConfigurationName) == name && lc.CreatedTime != nil {
+			return *lc.CreatedTime, nil
+		}
+	}
+	return time.Time{}, nil
+}
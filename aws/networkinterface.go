@@ -0,0 +1,376 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// NetworkInterface is a Reapable, Filterable
+// embeds AWS API's ec2.NetworkInterface
+type NetworkInterface struct {
+	Resource
+	ec2.NetworkInterface
+}
+
+// NewNetworkInterface creates a NetworkInterface from the AWS API's ec2.NetworkInterface
+// attached and requester managed NetworkInterfaces are dependencies
+func NewNetworkInterface(region string, eni *ec2.NetworkInterface) *NetworkInterface {
+	if eni.NetworkInterfaceId == nil {
+		log.Warning("Skipping a NetworkInterface without a NetworkInterfaceId in %s", region)
+		return nil
+	}
+	a := NetworkInterface{
+		Resource: Resource{
+			region: reapable.Region(region),
+			id:     reapable.ID(*eni.NetworkInterfaceId),
+			Name:   aws.StringValue(eni.Description),
+			Tags:   make(map[string]string),
+
+			createEventName: "CreateNetworkInterface",
+		},
+		NetworkInterface: *eni,
+	}
+
+	for _, tag := range eni.TagSet {
+		a.Resource.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	if a.Tagged("Name") {
+		a.Name = a.Tag("Name")
+	}
+	if a.Tagged("aws:cloudformation:stack-name") {
+		a.IsInCloudformation = true
+		a.CloudformationStackName = a.Tag("aws:cloudformation:stack-name")
+	}
+	// interfaces created by AWS services, such as load balancers,
+	// can only be deleted by them
+	if !a.Detached() || aws.BoolValue(eni.RequesterManaged) || a.IsInCloudformation {
+		a.Dependency = true
+	}
+	if a.Tagged(reaperTag) {
+		// restore previously tagged state
+		a.reaperState = state.NewStateWithTag(a.Tag(reaperTag))
+	} else {
+		// initial state
+		a.reaperState = state.NewState()
+	}
+
+	return &a
+}
+
+// ReapableType is part of the reapable.Typed interface
+func (a *NetworkInterface) ReapableType() string {
+	return "NetworkInterface"
+}
+
+// Detached returns whether the NetworkInterface is attached to nothing
+func (a *NetworkInterface) Detached() bool {
+	return a.Attachment == nil || aws.StringValue(a.Attachment.Status) == "detached"
+}
+
+// ReapableEventText is part of the events.Reapable interface
+func (a *NetworkInterface) ReapableEventText() (*bytes.Buffer, error) {
+	return reapableEventText(a, "NetworkInterfaceEventText", reapableNetworkInterfaceEventText)
+}
+
+// ReapableEventTextShort is part of the events.Reapable interface
+func (a *NetworkInterface) ReapableEventTextShort() (*bytes.Buffer, error) {
+	return reapableEventText(a, "NetworkInterfaceEventTextShort", reapableNetworkInterfaceEventTextShort)
+}
+
+// ReapableEventEmail is part of the events.Reapable interface
+func (a *NetworkInterface) ReapableEventEmail() (owner mail.Address, subject string, body *bytes.Buffer, err error) {
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
+		return
+	}
+	subject = fmt.Sprintf("AWS Resource %s is going to be Reaped!", a.ReapableDescriptionTiny())
+	body, err = reapableEventHTML(a, "NetworkInterfaceEventHTML", reapableNetworkInterfaceEventHTML)
+	return
+}
+
+// ReapableEventEmailShort is part of the events.Reapable interface
+func (a *NetworkInterface) ReapableEventEmailShort() (owner mail.Address, body *bytes.Buffer, err error) {
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
+		return
+	}
+	body, err = reapableEventHTML(a, "NetworkInterfaceEventHTMLShort", reapableNetworkInterfaceEventHTMLShort)
+	return
+}
+
+type networkInterfaceEventData struct {
+	Config           *Config
+	NetworkInterface *NetworkInterface
+	TerminateLink    string
+	WhitelistLink    string
+	IgnoreLink1      string
+	IgnoreLink3      string
+	IgnoreLink7      string
+}
+
+func (a *NetworkInterface) getTemplateData() (interface{}, error) {
+	ignore1, err := makeIgnoreLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL, time.Duration(1*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	ignore3, err := makeIgnoreLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL, time.Duration(3*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	ignore7, err := makeIgnoreLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL, time.Duration(7*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	terminate, err := makeTerminateLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	whitelist, err := makeWhitelistLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &networkInterfaceEventData{
+		Config:           config,
+		NetworkInterface: a,
+		TerminateLink:    terminate,
+		WhitelistLink:    whitelist,
+		IgnoreLink1:      ignore1,
+		IgnoreLink3:      ignore3,
+		IgnoreLink7:      ignore7,
+	}, nil
+}
+
+const reapableNetworkInterfaceEventHTML = `
+<html>
+<body>
+	<p>NetworkInterface <a href="{{ .NetworkInterface.AWSConsoleURL }}">{{ .NetworkInterface.ID }}{{ if .NetworkInterface.Name }} "{{ .NetworkInterface.Name }}"{{ end }} in {{ .NetworkInterface.Region }}</a> is scheduled to be deleted.</p>
+
+	{{ with .NetworkInterface.ReapReason }}<p>It was {{ . }}.</p>{{ end }}
+
+	<p>
+		You can ignore this message and your NetworkInterface will advance to the next state after <strong>{{ until .NetworkInterface.ReaperState.Until }}</strong> ({{ relativeUntil .NetworkInterface.ReaperState.Until }}). If you do not take action it will be deleted!
+	</p>
+
+	<p>
+		You may also choose to:
+		<ul>
+			<li><a href="{{ .TerminateLink }}">Delete it now</a></li>
+			<li><a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a></li>
+			<li><a href="{{ .IgnoreLink3 }}">Ignore it for 3 more days</a></li>
+			<li><a href="{{ .IgnoreLink7 }}">Ignore it for 7 more days</a></li>
+			<li><a href="{{ .WhitelistLink }}">Whitelist</a> it.</li>
+		</ul>
+	</p>
+
+	<p>
+		If you want the Reaper to ignore this NetworkInterface tag it with {{ .Config.WhitelistTag }} with any value, or click <a href="{{ .WhitelistLink }}">here</a>.
+	</p>
+</body>
+</html>
+`
+
+const reapableNetworkInterfaceEventHTMLShort = `
+<html>
+<body>
+	<p>NetworkInterface <a href="{{ .NetworkInterface.AWSConsoleURL }}">{{ .NetworkInterface.ID }}{{ if .NetworkInterface.Name }} "{{ .NetworkInterface.Name }}"{{ end }}</a> in {{ .NetworkInterface.Region }} is scheduled to be deleted after <strong>{{ until .NetworkInterface.ReaperState.Until }}</strong> ({{ relativeUntil .NetworkInterface.ReaperState.Until }}).
+		<br />
+		<a href="{{ .TerminateLink }}">Delete</a>,
+		<a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a>,
+		<a href="{{ .IgnoreLink3 }}">3 days</a>,
+		<a href="{{ .IgnoreLink7 }}"> 7 days</a>, or
+		<a href="{{ .WhitelistLink }}">Whitelist</a> it.
+		{{ with .NetworkInterface.ReapReason }}<br />It was {{ . }}.{{ end }}
+	</p>
+</body>
+</html>
+`
+
+const reapableNetworkInterfaceEventTextShort = `%%%
+NetworkInterface [{{.NetworkInterface.ID}}]({{.NetworkInterface.AWSConsoleURL}}) in region: [{{.NetworkInterface.Region}}](https://{{.NetworkInterface.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.NetworkInterface.Region}}).{{if .NetworkInterface.Owner}} Owned by {{.NetworkInterface.Owner}}.\n{{end}}
+[Whitelist]({{ .WhitelistLink }}) or [Delete]({{ .TerminateLink }}) this NetworkInterface.
+%%%`
+
+const reapableNetworkInterfaceEventText = `%%%
+Reaper has discovered a NetworkInterface qualified as reapable: [{{.NetworkInterface.ID}}]({{.NetworkInterface.AWSConsoleURL}}) in region: [{{.NetworkInterface.Region}}](https://{{.NetworkInterface.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.NetworkInterface.Region}}).\n
+{{if .NetworkInterface.Owner}}Owned by {{.NetworkInterface.Owner}}.\n{{end}}
+{{ with .NetworkInterface.ReapReason }}It was {{ . }}.\n{{ end }}
+[AWS Console URL]({{.NetworkInterface.AWSConsoleURL}})\n
+[Whitelist]({{ .WhitelistLink }}) this NetworkInterface.
+[Delete]({{ .TerminateLink }}) this NetworkInterface.
+%%%`
+
+// Filter is part of the filter.Filterable interface
+func (a *NetworkInterface) Filter(filter filters.Filter) bool {
+	matched := false
+	// map function names to function calls
+	switch filter.Function {
+	case "Detached":
+		if b, err := filter.BoolValue(0); err == nil && a.Detached() == b {
+			matched = true
+		}
+	case "InVPC":
+		if a.VpcId != nil && anyIn([]string{*a.VpcId}, filter.Arguments) {
+			matched = true
+		}
+	case "NotInVPC":
+		if a.VpcId == nil || !anyIn([]string{*a.VpcId}, filter.Arguments) {
+			matched = true
+		}
+	case "InSubnet":
+		if a.SubnetId != nil && anyIn([]string{*a.SubnetId}, filter.Arguments) {
+			matched = true
+		}
+	case "InCloudformation":
+		if b, err := filter.BoolValue(0); err == nil && a.IsInCloudformation == b {
+			matched = true
+		}
+	case "CloudformationStackNameMatches":
+		if a.cloudformationStackNameMatches(filter) {
+			matched = true
+		}
+	case "Region":
+		for _, region := range filter.Arguments {
+			if a.Region() == reapable.Region(region) {
+				matched = true
+			}
+		}
+	case "NotRegion":
+		// was this resource's region one of those in the NOT list
+		regionSpecified := false
+		for _, region := range filter.Arguments {
+			if a.Region() == reapable.Region(region) {
+				regionSpecified = true
+			}
+		}
+		if !regionSpecified {
+			matched = true
+		}
+	case "Tagged":
+		if a.Tagged(filter.Arguments[0]) {
+			matched = true
+		}
+	case "NotTagged":
+		if !a.Tagged(filter.Arguments[0]) {
+			matched = true
+		}
+	case "MissingAnyTag":
+		if a.missingAnyTag(filter.Arguments) {
+			matched = true
+		}
+	case "MissingAllTags":
+		if a.missingAllTags(filter.Arguments) {
+			matched = true
+		}
+	case "TagNotEqual":
+		if a.Tag(filter.Arguments[0]) != filter.Arguments[1] {
+			matched = true
+		}
+	case "TagsAllEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) == len(pairs) {
+			matched = true
+		}
+	case "TagsAnyEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) > 0 {
+			matched = true
+		}
+	case "TagInSet":
+		if a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "TagNotInSet":
+		if !a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "ReaperState":
+		if a.ReaperState().State.String() == filter.Arguments[0] {
+			matched = true
+		}
+	case "StateOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.stateOlderThan(d, time.Now()) {
+			matched = true
+		}
+	case "UntilWithin":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.untilWithin(d, time.Now()) {
+			matched = true
+		}
+	case "NotReaperState":
+		if a.ReaperState().State.String() != filter.Arguments[0] {
+			matched = true
+		}
+	case "Named":
+		if a.Name == filter.Arguments[0] {
+			matched = true
+		}
+	case "NotNamed":
+		if a.Name != filter.Arguments[0] {
+			matched = true
+		}
+	case "IsDependency":
+		if b, err := filter.BoolValue(0); err == nil && a.Dependency == b {
+			matched = true
+		}
+	case "NameContains":
+		if strings.Contains(a.Name, filter.Arguments[0]) {
+			matched = true
+		}
+	case "NotNameContains":
+		if !strings.Contains(a.Name, filter.Arguments[0]) {
+			matched = true
+		}
+	default:
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering NetworkInterfaces.", filter.Function))
+	}
+	return matched
+}
+
+// AWSConsoleURL returns the url that can be used to access the resource on the AWS Console
+func (a *NetworkInterface) AWSConsoleURL() *url.URL {
+	url, err := url.Parse(fmt.Sprintf("https://%s.console.aws.amazon.com/ec2/v2/home?region=%s#NIC:search=%s",
+		a.Region().String(), a.Region().String(), url.QueryEscape(a.ID().String())))
+	if err != nil {
+		log.Error("Error generating AWSConsoleURL. ", err)
+	}
+	return url
+}
+
+// Terminate is a method of reapable.Terminable, which is embedded in reapable.Reapable
+// Terminate deletes the NetworkInterface
+func (a *NetworkInterface) Terminate() (bool, error) {
+	log.Info("Terminating NetworkInterface %s", a.ReapableDescriptionTiny())
+	api := newEC2API(a.Region().String())
+	input := &ec2.DeleteNetworkInterfaceInput{
+		NetworkInterfaceId: aws.String(a.ID().String()),
+	}
+	err := retry("delete NetworkInterface "+a.ReapableDescriptionTiny(), func() error {
+		_, err := api.DeleteNetworkInterface(input)
+		return err
+	})
+	if err != nil {
+		log.Error("could not delete NetworkInterface %s", a.ReapableDescriptionTiny())
+		return false, err
+	}
+	return true, nil
+}
+
+// Stop is a method of reapable.Stoppable, which is embedded in reapable.Reapable
+// noop
+func (a *NetworkInterface) Stop() (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// testEC2NetworkInterfaces describes and deletes network interfaces
+// other methods of EC2API are not implemented
+type testEC2NetworkInterfaces struct {
+	ec2iface.EC2API
+	interfaces []*ec2.NetworkInterface
+	deleted    []string
+}
+
+func (c *testEC2NetworkInterfaces) DescribeNetworkInterfaces(input *ec2.DescribeNetworkInterfacesInput) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: c.interfaces}, nil
+}
+
+func (c *testEC2NetworkInterfaces) DeleteNetworkInterface(input *ec2.DeleteNetworkInterfaceInput) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	c.deleted = append(c.deleted, *input.NetworkInterfaceId)
+	return &ec2.DeleteNetworkInterfaceOutput{}, nil
+}
+
+func newTestNetworkInterface(id string, attachment *ec2.NetworkInterfaceAttachment) *ec2.NetworkInterface {
+	return &ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String(id),
+		Attachment:         attachment,
+		TagSet:             []*ec2.Tag{&ec2.Tag{Key: aws.String("Owner"), Value: aws.String("owner@example.com")}},
+	}
+}
+
+func TestNetworkInterfaceDetached(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{Regions: []string{"us-west-2"}})
+	api := &testEC2NetworkInterfaces{interfaces: []*ec2.NetworkInterface{
+		newTestNetworkInterface("eni-attached", &ec2.NetworkInterfaceAttachment{
+			InstanceId: aws.String("i-1"),
+			Status:     aws.String("attached"),
+		}),
+		newTestNetworkInterface("eni-detached", nil),
+		newTestNetworkInterface("eni-was-attached", &ec2.NetworkInterfaceAttachment{Status: aws.String("detached")}),
+	}}
+	defer setTestEC2(api)()
+
+	enis := make(map[string]*NetworkInterface)
+	for n := range AllNetworkInterfaces() {
+		enis[n.ID().String()] = n
+	}
+	if len(enis) != 3 {
+		t.Fatalf("expected 3 NetworkInterfaces, got %d", len(enis))
+	}
+
+	detached := *filters.NewFilter("Detached", []string{"true"})
+	tagged := *filters.NewFilter("Tagged", []string{"Owner"})
+	region := *filters.NewFilter("Region", []string{"us-west-2"})
+	tests := []struct {
+		id       string
+		detached bool
+	}{
+		{"eni-attached", false},
+		{"eni-detached", true},
+		{"eni-was-attached", true},
+	}
+	for _, test := range tests {
+		n := enis[test.id]
+		if n.Filter(detached) != test.detached {
+			t.Errorf("%s: expected Detached to be %t", test.id, test.detached)
+		}
+		if n.Dependency == test.detached {
+			t.Errorf("%s: expected only attached NetworkInterfaces to be dependencies", test.id)
+		}
+		if !n.Filter(tagged) || !n.Filter(region) {
+			t.Errorf("%s: expected Tagged and Region to match", test.id)
+		}
+	}
+
+	requesterManaged := newTestNetworkInterface("eni-elb", nil)
+	requesterManaged.RequesterManaged = aws.Bool(true)
+	if !NewNetworkInterface("us-west-2", requesterManaged).Dependency {
+		t.Error("expected a requester managed NetworkInterface to be a dependency")
+	}
+
+	if _, err := enis["eni-detached"].Terminate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.deleted) != 1 || api.deleted[0] != "eni-detached" {
+		t.Errorf("expected eni-detached to be deleted, got %v", api.deleted)
+	}
+}
@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// describeRegions returns the names of all regions available to the account
+// replaceable in tests
+var describeRegions = func() ([]string, error) {
+	api := ec2Client("us-east-1")
+	resp, err := api.DescribeRegions(&ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	var regions []string
+	for _, region := range resp.Regions {
+		if region.RegionName != nil {
+			regions = append(regions, *region.RegionName)
+		}
+	}
+	return regions, nil
+}
+
+// ResolveRegions sets Regions to the effective set of regions
+// every region (from DescribeRegions) if AllRegions is set, less ExcludeRegions
+// if DescribeRegions fails, Regions is the fallback, still less
+// ExcludeRegions, and the error is returned
+func (c *Config) ResolveRegions() error {
+	regions := c.Regions
+	var err error
+	if c.AllRegions {
+		var described []string
+		if described, err = describeRegions(); err == nil {
+			regions = described
+		}
+	}
+
+	var resolved []string
+	for _, region := range regions {
+		if !regionExcluded(region, c.ExcludeRegions) {
+			resolved = append(resolved, region)
+		}
+	}
+	c.Regions = resolved
+	return err
+}
+
+// regionExcluded returns whether region matches one of excluded
+// which are region names, or prefixes ending in "*" such as "cn-*"
+func regionExcluded(region string, excluded []string) bool {
+	for _, e := range excluded {
+		if strings.HasSuffix(e, "*") && strings.HasPrefix(region, strings.TrimSuffix(e, "*")) {
+			return true
+		}
+		if region == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveRegions(t *testing.T) {
+	original := describeRegions
+	defer func() { describeRegions = original }()
+	describeRegions = func() ([]string, error) {
+		return []string{"us-east-1", "us-west-2", "cn-north-1", "us-gov-west-1", "eu-west-1"}, nil
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"explicit", Config{Regions: []string{"us-east-1", "us-west-2"}}, []string{"us-east-1", "us-west-2"}},
+		{"explicit with exclusion", Config{Regions: []string{"us-east-1", "us-west-2"}, ExcludeRegions: []string{"us-west-2"}}, []string{"us-east-1"}},
+		{"all", Config{AllRegions: true}, []string{"us-east-1", "us-west-2", "cn-north-1", "us-gov-west-1", "eu-west-1"}},
+		{"all with exclusions", Config{AllRegions: true, ExcludeRegions: []string{"cn-*", "us-gov-west-1"}}, []string{"us-east-1", "us-west-2", "eu-west-1"}},
+	}
+
+	for _, test := range tests {
+		c := test.config
+		if err := c.ResolveRegions(); err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(c.Regions, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, c.Regions)
+		}
+	}
+}
+
+func TestResolveRegionsError(t *testing.T) {
+	original := describeRegions
+	defer func() { describeRegions = original }()
+	describeRegions = func() ([]string, error) {
+		return nil, errors.New("UnauthorizedOperation")
+	}
+
+	c := Config{AllRegions: true, Regions: []string{"us-east-1", "cn-north-1"}, ExcludeRegions: []string{"cn-*"}}
+	if err := c.ResolveRegions(); err == nil {
+		t.Error("expected an error")
+	}
+	if !reflect.DeepEqual(c.Regions, []string{"us-east-1"}) {
+		t.Errorf("expected the fallback Regions less ExcludeRegions on error, got %v", c.Regions)
+	}
+}
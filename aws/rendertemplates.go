@@ -0,0 +1,169 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// sampleReapable is a fabricated resource that templates are rendered with,
+// and the prefix of its templates' names
+type sampleReapable struct {
+	prefix   string
+	reapable interface {
+		events.Reapable
+		SetReaperState(*state.State)
+	}
+}
+
+// sampleReapables fabricates a resource of each type, owned and
+// in the FirstState, with populated links
+func sampleReapables() []sampleReapable {
+	region := "us-west-2"
+	created := time.Now().Add(-30 * 24 * time.Hour)
+	tags := []*ec2.Tag{&ec2.Tag{Key: aws.String(ownerTags()[0]), Value: aws.String("owner@example.com")}}
+
+	instance := NewInstance(region, &ec2.Instance{
+		InstanceId:   aws.String("i-0123456789abcdef0"),
+		InstanceType: aws.String("m4.large"),
+		LaunchTime:   aws.Time(created),
+		State:        &ec2.InstanceState{Code: aws.Int64(16), Name: aws.String("running")},
+		Tags:         append(tags, &ec2.Tag{Key: aws.String("Name"), Value: aws.String("sample")}),
+	})
+	volume := NewVolume(region, &ec2.Volume{
+		VolumeId:   aws.String("vol-0123456789abcdef0"),
+		Size:       aws.Int64(100),
+		VolumeType: aws.String("gp2"),
+		State:      aws.String("available"),
+		CreateTime: aws.Time(created),
+		Tags:       tags,
+	})
+	securityGroup := NewSecurityGroup(region, &ec2.SecurityGroup{
+		GroupId:   aws.String("sg-0123456789abcdef0"),
+		GroupName: aws.String("sample"),
+		Tags:      tags,
+	})
+	image := NewImage(region, &ec2.Image{
+		ImageId:      aws.String("ami-0123456789abcdef0"),
+		Name:         aws.String("sample"),
+		CreationDate: aws.String(created.UTC().Format(time.RFC3339)),
+		Tags:         tags,
+	})
+	networkInterface := NewNetworkInterface(region, &ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String("eni-0123456789abcdef0"),
+		Description:        aws.String("sample"),
+		Status:             aws.String("available"),
+		TagSet:             tags,
+	})
+	asg := NewAutoScalingGroup(region, &autoscaling.Group{
+		AutoScalingGroupName: aws.String("sample"),
+		CreatedTime:          aws.Time(created),
+		DesiredCapacity:      aws.Int64(2),
+		Tags: []*autoscaling.TagDescription{
+			&autoscaling.TagDescription{Key: aws.String(ownerTags()[0]), Value: aws.String("owner@example.com")},
+		},
+	})
+	stack := NewCloudformation(region, &cloudformation.Stack{
+		StackId:      aws.String("arn:aws:cloudformation:us-west-2:123456789012:stack/sample/0123"),
+		StackName:    aws.String("sample"),
+		StackStatus:  aws.String("CREATE_COMPLETE"),
+		CreationTime: aws.Time(created),
+		Tags: []*cloudformation.Tag{
+			&cloudformation.Tag{Key: aws.String(ownerTags()[0]), Value: aws.String("owner@example.com")},
+		},
+	})
+
+	samples := []sampleReapable{
+		{"ASG", asg},
+		{"Cloudformation", stack},
+		{"Image", image},
+		{"Instance", instance},
+		{"NetworkInterface", networkInterface},
+		{"SecurityGroup", securityGroup},
+		{"Volume", volume},
+	}
+	until := time.Now().Add(3 * 24 * time.Hour)
+	for _, s := range samples {
+		s.reapable.SetReaperState(state.NewStateWithUntilAndState(until, state.FirstState))
+		s.reapable.AddFilterGroup("sample", filters.FilterGroup{
+			"1": *filters.NewFilter("NotTagged", []string{"Project"}),
+		})
+	}
+	return samples
+}
+
+// bufferString returns the contents of b, or an empty string if it is nil
+func bufferString(b *bytes.Buffer) string {
+	if b == nil {
+		return ""
+	}
+	return b.String()
+}
+
+// renderTemplate renders a template, returning a template that
+// fails to parse as an error
+func renderTemplate(render func() (string, error)) (body string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return render()
+}
+
+// RenderTemplates renders every event template, custom or built-in,
+// with a fabricated resource of each type, and writes them to w
+// templates that fail to render are written as errors, and returned
+// together as one error naming each template
+func RenderTemplates(w io.Writer) error {
+	var failed []string
+	for _, s := range sampleReapables() {
+		r := s.reapable
+		renders := []struct {
+			kind   string
+			render func() (string, error)
+		}{
+			{"EventHTML", func() (string, error) {
+				_, _, body, err := r.ReapableEventEmail()
+				return bufferString(body), err
+			}},
+			{"EventHTMLShort", func() (string, error) {
+				_, body, err := r.ReapableEventEmailShort()
+				return bufferString(body), err
+			}},
+			{"EventText", func() (string, error) {
+				body, err := r.ReapableEventText()
+				return bufferString(body), err
+			}},
+			{"EventTextShort", func() (string, error) {
+				body, err := r.ReapableEventTextShort()
+				return bufferString(body), err
+			}},
+		}
+		for _, render := range renders {
+			name := s.prefix + render.kind
+			body, err := renderTemplate(render.render)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", name, err.Error()))
+				fmt.Fprintf(w, "==> %s failed: %s\n\n", name, err.Error())
+				continue
+			}
+			fmt.Fprintf(w, "==> %s\n%s\n\n", name, strings.TrimSpace(body))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d templates failed to render: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
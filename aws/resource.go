@@ -5,12 +5,15 @@ import (
 	"fmt"
 	htmlTemplate "html/template"
 	"net/mail"
+	"strings"
+	"sync"
 	textTemplate "text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
+	"github.com/mozilla-services/reaper/events"
 	"github.com/mozilla-services/reaper/filters"
 	"github.com/mozilla-services/reaper/reapable"
 	log "github.com/mozilla-services/reaper/reaperlog"
@@ -25,11 +28,24 @@ type Resource struct {
 	Name               string
 	Dependency         bool
 	IsInCloudformation bool
-
-	Tags map[string]string
-
-	// reaper state
+	// the name of the Cloudformation the Resource is in, if known
+	CloudformationStackName string
+	// the StackStatus of the Cloudformation the Resource is in, if known
+	CloudformationStackStatus string
+
+	// Tags are the Resource's tags when it was described, and those Reaper
+	// set since, written while tagsMutex is held, as they are read by HTTP
+	// handlers and notifications during a reap
+	Tags      map[string]string
+	tagsMutex sync.RWMutex
+
+	// name of the CloudTrail event that creates the Resource, see CreatedBy
+	createEventName string
+
+	// reaper state, replaced rather than changed in place while
+	// stateMutex is held, as it is read by HTTP handlers during a reap
 	reaperState *state.State
+	stateMutex  sync.RWMutex
 
 	// filters for MatchedFilters
 	matchedFilterGroups map[string]filters.FilterGroup
@@ -47,78 +63,420 @@ func (a *Resource) Region() reapable.Region {
 
 // Tagged returns whether the Resource is tagged with that key
 func (a *Resource) Tagged(tag string) bool {
+	a.tagsMutex.RLock()
+	defer a.tagsMutex.RUnlock()
 	_, ok := a.Tags[tag]
 	return ok
 }
 
+// setTag records a tag Reaper set on the Resource
+func (a *Resource) setTag(key, value string) {
+	a.tagsMutex.Lock()
+	defer a.tagsMutex.Unlock()
+	a.Tags[key] = value
+}
+
+// deleteTag records that Reaper removed a tag from the Resource
+func (a *Resource) deleteTag(key string) {
+	a.tagsMutex.Lock()
+	defer a.tagsMutex.Unlock()
+	delete(a.Tags, key)
+}
+
+// cloudformationStackNameMatches returns whether the Resource is in a
+// Cloudformation whose name matches the filter's regular expression
+func (a *Resource) cloudformationStackNameMatches(filter filters.Filter) bool {
+	re, err := filter.RegexpValue(0)
+	return err == nil && a.CloudformationStackName != "" && re.MatchString(a.CloudformationStackName)
+}
+
+// tagInSet returns whether the Resource has the tag, with one of the values
+// a Resource without the tag is in no set
+func (a *Resource) tagInSet(tag string, values []string) bool {
+	a.tagsMutex.RLock()
+	value, ok := a.Tags[tag]
+	a.tagsMutex.RUnlock()
+	if !ok {
+		return false
+	}
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// tagsEqual returns how many of the pairs the Resource has a tag equal to
+func (a *Resource) tagsEqual(pairs []filters.TagPair) int {
+	a.tagsMutex.RLock()
+	defer a.tagsMutex.RUnlock()
+	equal := 0
+	for _, pair := range pairs {
+		if value, ok := a.Tags[pair.Key]; ok && value == pair.Value {
+			equal++
+		}
+	}
+	return equal
+}
+
+// missingAnyTag returns whether the Resource is missing at least one of the tags
+func (a *Resource) missingAnyTag(tags []string) bool {
+	for _, t := range tags {
+		if !a.Tagged(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingAllTags returns whether the Resource is missing every one of the tags
+func (a *Resource) missingAllTags(tags []string) bool {
+	for _, t := range tags {
+		if a.Tagged(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// HoursPerMonth is the number of hours AWS uses for monthly prices
+const HoursPerMonth = 730
+
+// monthlyCostGreaterThan returns whether the estimated monthly cost of c,
+// in USD, is greater than the filter's first argument
+// it never matches when the cost isn't known, which the reaper package
+// counts once per cycle as reaper.filters.nopriceskipped
+func monthlyCostGreaterThan(c reapable.Costed, filter *filters.Filter) bool {
+	threshold, err := filter.Float64Value(0)
+	if err != nil {
+		return false
+	}
+	hourly, ok := c.EstimatedHourlyCost()
+	if !ok {
+		return false
+	}
+	return hourly*HoursPerMonth > threshold
+}
+
+// anyIn returns whether any of the ids is one of the filter's ids
+func anyIn(ids []string, filterIDs []string) bool {
+	for _, id := range ids {
+		for _, filterID := range filterIDs {
+			if id == filterID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Tag returns the tag's value or an empty string if it does not exist
 func (a *Resource) Tag(t string) string {
+	a.tagsMutex.RLock()
+	defer a.tagsMutex.RUnlock()
 	return a.Tags[t]
 }
 
+// ExpiresAt returns when the Resource's lifetime ends, from an expires-at tag
+// with an RFC3339 time, or else a reaper-ttl tag with a duration such as 4h
+// added to created, and false if it has neither or they can't be parsed
+// a reaper-ttl tag is ignored when created is zero
+func (a *Resource) ExpiresAt(created time.Time) (time.Time, bool) {
+	if value := a.Tag(expiresAtTag); value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+		log.Warning("Ignoring the %s tag of %s, %q is not an RFC3339 time", expiresAtTag, a.ReapableDescriptionTiny(), value)
+	}
+	if value := a.Tag(ttlTag); value != "" && !created.IsZero() {
+		if d, err := time.ParseDuration(value); err == nil {
+			return created.Add(d), true
+		}
+		log.Warning("Ignoring the %s tag of %s, %q is not a duration", ttlTag, a.ReapableDescriptionTiny(), value)
+	}
+	return time.Time{}, false
+}
+
+// ownerTags returns the tag keys that may hold a Resource's owner
+// in order of preference
+func ownerTags() []string {
+	if len(config.OwnerTags) > 0 {
+		return config.OwnerTags
+	}
+	return []string{"Owner"}
+}
+
+// notifyTag returns the tag key of a Resource's NotificationChannel
+func notifyTag() string {
+	if config.NotifyTag != "" {
+		return config.NotifyTag
+	}
+	return "reaper-notify"
+}
+
+// NotificationChannel returns where the owner of the Resource prefers its
+// notifications, from a tag such as reaper-notify=slack:#team (see notifyTag)
+// and false if it is untagged or the tag is invalid
+func (a *Resource) NotificationChannel() (events.NotificationChannel, bool) {
+	if !a.Tagged(notifyTag()) {
+		return events.NotificationChannel{}, false
+	}
+	channel, err := events.ParseNotificationChannel(a.Tag(notifyTag()))
+	if err != nil {
+		log.Warning("Ignoring %s tag of %s: %s", notifyTag(), a.ID(), err.Error())
+		return events.NotificationChannel{}, false
+	}
+	return channel, true
+}
+
 // Owned returns whether the Resource has a clear owner
 // if a DefaultOwner is set, there is always an owner
 func (a *Resource) Owned() bool {
 	// if the resource has an owner tag or a default owner is specified
-	return a.Tagged("Owner") || config.DefaultOwner != ""
+	for _, key := range ownerTags() {
+		if a.Tagged(key) {
+			return true
+		}
+	}
+	return config.DefaultOwner != ""
 }
 
 // ReaperState is a method of reapable.Saveable, which is embedded in reapable.Reapable
 func (a *Resource) ReaperState() *state.State {
+	a.stateMutex.RLock()
+	defer a.stateMutex.RUnlock()
 	return a.reaperState
 }
 
 // SetReaperState sets the ReaperState for a Resource
 func (a *Resource) SetReaperState(newState *state.State) {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
 	a.reaperState = newState
 }
 
+// SetUpdated is a method of reapable.Saveable
+// the ReaperState is copied, so that States already returned by ReaperState
+// aren't changed
 func (a *Resource) SetUpdated(b bool) {
-	a.reaperState.Updated = b
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+	updated := *a.reaperState
+	updated.Updated = b
+	a.reaperState = &updated
 }
 
-// Owner extracts useful information out of the Owner tag which should
-// be parsable by mail.ParseAddress
-func (a *Resource) Owner() *mail.Address {
+// splitOwners splits an owner tag on commas that aren't quoted, so that
+// display names such as "Doe, Jane" <jdoe@example.com> stay whole
+func splitOwners(value string) []string {
+	var entries []string
+	quoted := false
+	start := 0
+	for i, c := range value {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				entries = append(entries, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(entries, value[start:])
+}
+
+// parseOwner parses an owner, which is either an address (with or without
+// a display name) or a username at the DefaultEmailHost
+func parseOwner(owner string) (*mail.Address, error) {
 	// properly formatted email
-	if addr, err := mail.ParseAddress(a.Tag("Owner")); err == nil {
-		return addr
+	addr, err := mail.ParseAddress(owner)
+	if err == nil {
+		return addr, nil
 	}
 
 	// username -> default email host email address
-	if addr, err := mail.ParseAddress(fmt.Sprintf("%s@%s", a.Tag("Owner"), config.DefaultEmailHost)); a.Tagged("Owner") && config.DefaultEmailHost != "" && err == nil {
-		return addr
+	if config.DefaultEmailHost != "" {
+		if addr, hostErr := mail.ParseAddress(fmt.Sprintf("%s@%s", owner, config.DefaultEmailHost)); hostErr == nil {
+			return addr, nil
+		}
+	}
+	return nil, err
+}
+
+// parseOwners parses a comma separated list of owners
+// invalid entries are skipped
+func parseOwners(value string) []mail.Address {
+	var owners []mail.Address
+	for _, entry := range splitOwners(value) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		addr, err := parseOwner(entry)
+		if err != nil {
+			log.Warning("Skipping invalid owner %q: %s", entry, err.Error())
+			continue
+		}
+		owners = append(owners, *addr)
 	}
+	return owners
+}
 
-	// default owner is specified
-	if addr, err := mail.ParseAddress(
-		fmt.Sprintf("%s@%s", config.DefaultOwner, config.DefaultEmailHost)); config.DefaultOwner != "" && config.DefaultEmailHost != "" && err == nil {
-		return addr
+// Owners extracts useful information out of the owner tags, which may hold
+// a comma separated list of addresses parsable by mail.ParseAddress
+// the first owner tag (see ownerTags) with a valid address is used
+// untagged Resources fall back to their creator (see CreatedBy)
+func (a *Resource) Owners() []mail.Address {
+	for _, key := range ownerTags() {
+		if !a.Tagged(key) {
+			continue
+		}
+		if owners := parseOwners(a.Tag(key)); len(owners) > 0 {
+			return owners
+		}
+	}
+
+	// creating principal, if CloudTrailEnrichment is enabled
+	if creator := a.CreatedBy(); creator != "" {
+		if addr, err := parseOwner(creator); err == nil {
+			return []mail.Address{*addr}
+		}
+	}
+
+	if addr := defaultOwner(); addr != nil {
+		return []mail.Address{*addr}
 	}
 	log.Warning("No default owner or email host.")
 	return nil
 }
 
+// defaultOwner returns the owner of unowned Resources: DefaultOwner, which
+// is an address or a username at the DefaultEmailHost, or else
+// Notifications.DefaultOwner, and nil if neither is set
+func defaultOwner() *mail.Address {
+	if config.DefaultOwner != "" {
+		if addr, err := parseOwner(config.DefaultOwner); err == nil {
+			return addr
+		}
+	}
+	if config.Notifications.DefaultOwner != "" {
+		if addr, err := mail.ParseAddress(config.Notifications.DefaultOwner); err == nil {
+			return addr
+		}
+	}
+	return nil
+}
+
+// Owner returns the first of a Resource's Owners
+func (a *Resource) Owner() *mail.Address {
+	if owners := a.Owners(); len(owners) > 0 {
+		return &owners[0]
+	}
+	return nil
+}
+
+// stateDuration returns the configured duration of a state, which
+// IncrementState adds to when the state is entered to set its Until
+// InitialState and FinalState are entered with Until set to when they
+// were entered, so their configured duration is 0
+func stateDuration(s state.StateEnum) time.Duration {
+	switch s {
+	case state.FirstState:
+		return config.Notifications.FirstStateDuration.Duration
+	case state.SecondState:
+		return config.Notifications.SecondStateDuration.Duration
+	case state.ThirdState:
+		return config.Notifications.ThirdStateDuration.Duration
+	}
+	return 0
+}
+
+// stateStart returns when the Resource entered its current ReaperState
+// derived from the Until deadline and the configured duration of the state
+func (a *Resource) stateStart() time.Time {
+	s := a.ReaperState()
+	return s.Until.Add(-stateDuration(s.State))
+}
+
+// stateOlderThan returns whether the Resource has held its
+// current ReaperState for longer than d
+func (a *Resource) stateOlderThan(d time.Duration, now time.Time) bool {
+	return now.Sub(a.stateStart()) > d
+}
+
+// untilWithin returns whether the Resource's ReaperState Until deadline
+// is no more than d from now (including deadlines that have passed)
+func (a *Resource) untilWithin(d time.Duration, now time.Time) bool {
+	return !a.ReaperState().Until.After(now.Add(d))
+}
+
+// emailOwner returns the address a Resource's events are emailed to
+// unowned Resources fall back to the default owner, see defaultOwner
+func (a *Resource) emailOwner() (mail.Address, error) {
+	if owner := a.Owner(); owner != nil {
+		return *owner, nil
+	}
+	return mail.Address{}, reapable.UnownedError{
+		ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort()),
+	}
+}
+
+// ReapableLinks is part of the events.Linker interface
+// it returns the terminate, whitelist and ignore links of the Resource by name
+func (a *Resource) ReapableLinks() (map[string]string, error) {
+	links := make(map[string]string)
+	terminate, err := makeTerminateLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	links["terminate"] = terminate
+	whitelist, err := makeWhitelistLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	links["whitelist"] = whitelist
+	for _, days := range []int{1, 3, 7} {
+		ignore, err := makeIgnoreLink(a.Region(), a.ID(), config.HTTP.TokenSecret, config.HTTP.APIURL,
+			time.Duration(days)*24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		links[fmt.Sprintf("ignore%d", days)] = ignore
+	}
+	return links, nil
+}
+
+// SnoozeOwnerLink is part of the events.OwnerSnoozer interface
+// it returns a link that ignores every resource of the Resource's owner for duration
+func (a *Resource) SnoozeOwnerLink(duration time.Duration) (string, error) {
+	owner, err := a.emailOwner()
+	if err != nil {
+		return "", err
+	}
+	return makeSnoozeOwnerLink(owner.Address, config.HTTP.TokenSecret, config.HTTP.APIURL, duration)
+}
+
 // IncrementState updates the ReaperState of a Resource
 // returns a boolean of whether it was updated
 func (a *Resource) IncrementState() (updated bool) {
+	a.stateMutex.Lock()
+	defer a.stateMutex.Unlock()
+
 	var newState state.StateEnum
-	until := time.Now()
 	switch a.reaperState.State {
 	default:
 		fallthrough
 	case state.InitialState:
 		// set state to the FirstState
 		newState = state.FirstState
-		until = until.Add(config.Notifications.FirstStateDuration.Duration)
 	case state.FirstState:
 		// go to SecondState at the end of FirstState
 		newState = state.SecondState
-		until = until.Add(config.Notifications.SecondStateDuration.Duration)
 	case state.SecondState:
 		// go to ThirdState at the end of SecondState
 		newState = state.ThirdState
-		until = until.Add(config.Notifications.ThirdStateDuration.Duration)
 	case state.ThirdState:
 		// go to FinalState at the end of ThirdState
 		newState = state.FinalState
@@ -132,7 +490,11 @@ func (a *Resource) IncrementState() (updated bool) {
 
 	if newState != a.reaperState.State {
 		updated = true
+		// the same durations stateStart derives when the state was entered from
+		until := time.Now().Add(stateDuration(newState))
+		lastNotified := a.reaperState.LastNotified
 		a.reaperState = state.NewStateWithUntilAndState(until, newState)
+		a.reaperState.LastNotified = lastNotified
 		log.Info("Updating state for %s. New state: %s.", a.ReapableDescriptionTiny(), newState.String())
 	}
 
@@ -152,12 +514,19 @@ func (a *Resource) MatchedFiltersString() string {
 	return filters.FormatFilterGroupsText(a.matchedFilterGroups)
 }
 
+// ReapReason explains to the Resource's owner which FilterGroups it matched,
+// or is empty if it hasn't matched any
+func (a *Resource) ReapReason() string {
+	return filters.FormatReapReason(a.matchedFilterGroups)
+}
+
 type templater interface {
 	getTemplateData() (interface{}, error)
 }
 
-func reapableEventHTML(a templater, text string) (*bytes.Buffer, error) {
-	t := htmlTemplate.Must(htmlTemplate.New("reapable").Parse(text))
+// reapableEventHTML executes the template with that name, see eventTemplate
+func reapableEventHTML(a templater, name, text string) (*bytes.Buffer, error) {
+	t := htmlTemplate.Must(htmlTemplate.New("reapable").Funcs(templateFuncs).Parse(eventTemplate(name, text)))
 	buf := bytes.NewBuffer(nil)
 
 	data, err := a.getTemplateData()
@@ -171,8 +540,9 @@ func reapableEventHTML(a templater, text string) (*bytes.Buffer, error) {
 	return buf, nil
 }
 
-func reapableEventText(a templater, text string) (*bytes.Buffer, error) {
-	t := textTemplate.Must(textTemplate.New("reapable").Parse(text))
+// reapableEventText executes the template with that name, see eventTemplate
+func reapableEventText(a templater, name, text string) (*bytes.Buffer, error) {
+	t := textTemplate.Must(textTemplate.New("reapable").Funcs(templateFuncs).Parse(eventTemplate(name, text)))
 	buf := bytes.NewBuffer(nil)
 
 	data, err := a.getTemplateData()
@@ -188,7 +558,24 @@ func reapableEventText(a templater, text string) (*bytes.Buffer, error) {
 
 // ReapableDescription is a method of reapable.Reapable
 func (a *Resource) ReapableDescription() string {
-	return fmt.Sprintf("%s matched %s", a.ReapableDescriptionShort(), a.MatchedFiltersString())
+	return a.describe(a)
+}
+
+// describe is the ReapableDescription of the Resource embedded in r,
+// including r's age and estimated monthly cost if r knows them
+func (a *Resource) describe(r interface{}) string {
+	description := a.ReapableDescriptionShort()
+	if aged, ok := r.(reapable.Aged); ok {
+		if created, ok := aged.CreatedAt(); ok {
+			description += ", created " + relativeTime(created, time.Now())
+		}
+	}
+	if costed, ok := r.(reapable.Costed); ok {
+		if hourly, ok := costed.EstimatedHourlyCost(); ok {
+			description += fmt.Sprintf(", about $%.2f/month", hourly*HoursPerMonth)
+		}
+	}
+	return fmt.Sprintf("%s matched %s", description, a.MatchedFiltersString())
 }
 
 // ReapableDescriptionShort is a method of reapable.Reapable
@@ -211,25 +598,43 @@ func (a *Resource) ReapableDescriptionTiny() string {
 
 // Whitelist is a method of reapable.Whitelistable, which is embedded in reapable.Reapable
 func (a *Resource) Whitelist() (bool, error) {
-	return tag(a.Region().String(), a.ID().String(), config.WhitelistTag, "true")
+	ok, err := tag(a.Region().String(), a.ID().String(), config.WhitelistTag, "true")
+	if ok {
+		// whitelisted until it is described again
+		a.setTag(config.WhitelistTag, "true")
+	}
+	return ok, err
 }
 
 // Save is a method of reapable.Saveable, which is embedded in reapable.Reapable
-// Save tags a Resource's reaperTag
+// Save tags a Resource's reaperTag, unless it is already tagged with reaperState
 func (a *Resource) Save(reaperState *state.State) (bool, error) {
+	value := reaperState.String()
+	if a.Tag(reaperTag) == value {
+		log.Debug("%s is already saved", a.ReapableDescriptionTiny())
+		return true, nil
+	}
 	log.Info("Saving %s", a.ReapableDescriptionTiny())
-	return tag(a.Region().String(), a.ID().String(), reaperTag, reaperState.String())
+	ok, err := tag(a.Region().String(), a.ID().String(), reaperTag, value)
+	if ok {
+		a.setTag(reaperTag, value)
+	}
+	return ok, err
 }
 
 // Unsave is a method of reapable.Saveable, which is embedded in reapable.Reapable
 // Unsave untags a Resource's reaperTag
 func (a *Resource) Unsave() (bool, error) {
 	log.Info("Unsaving %s", a.ReapableDescriptionTiny())
-	return untag(a.Region().String(), a.ID().String(), reaperTag)
+	ok, err := untag(a.Region().String(), a.ID().String(), reaperTag)
+	if ok {
+		a.deleteTag(reaperTag)
+	}
+	return ok, err
 }
 
 func untag(region, id, key string) (bool, error) {
-	api := ec2.New(sess, aws.NewConfig().WithRegion(string(region)))
+	api := newEC2API(region)
 	delreq := &ec2.DeleteTagsInput{
 		DryRun:    aws.Bool(false),
 		Resources: []*string{aws.String(id)},
@@ -247,7 +652,7 @@ func untag(region, id, key string) (bool, error) {
 }
 
 func tag(region, id, key, value string) (bool, error) {
-	api := ec2.New(sess, aws.NewConfig().WithRegion(string(region)))
+	api := newEC2API(region)
 	createreq := &ec2.CreateTagsInput{
 		DryRun:    aws.Bool(false),
 		Resources: []*string{aws.String(id)},
@@ -0,0 +1,475 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/state"
+)
+
+func newTestInstance(id string, tags map[string]string) *Instance {
+	instance := &ec2.Instance{
+		InstanceId: aws.String(id),
+		State: &ec2.InstanceState{
+			Code: aws.Int64(16),
+			Name: aws.String("running"),
+		},
+	}
+	for k, v := range tags {
+		instance.Tags = append(instance.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return NewInstance("us-west-2", instance)
+}
+
+func TestMissingTagFilters(t *testing.T) {
+	required := []string{"Owner", "CostCenter", "Project"}
+	missingAny := *filters.NewFilter("MissingAnyTag", required)
+	missingAll := *filters.NewFilter("MissingAllTags", required)
+
+	tests := []struct {
+		name       string
+		tags       map[string]string
+		missingAny bool
+		missingAll bool
+	}{
+		{"none missing", map[string]string{"Owner": "a", "CostCenter": "b", "Project": "c"}, false, false},
+		{"one missing", map[string]string{"Owner": "a", "CostCenter": "b"}, true, false},
+		{"some missing", map[string]string{"Owner": "a"}, true, false},
+		{"all missing", map[string]string{"Name": "a"}, true, true},
+	}
+
+	for _, test := range tests {
+		i := newTestInstance("i-"+test.name, test.tags)
+		if i.Filter(missingAny) != test.missingAny {
+			t.Errorf("%s: expected MissingAnyTag to be %t", test.name, test.missingAny)
+		}
+		if i.Filter(missingAll) != test.missingAll {
+			t.Errorf("%s: expected MissingAllTags to be %t", test.name, test.missingAll)
+		}
+	}
+}
+
+func TestStateDurationHelpers(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{})
+	config.Notifications.FirstStateDuration.Duration = 12 * time.Hour
+
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	i := newTestInstance("i-1", nil)
+	// entered FirstState at 00:00
+	i.SetReaperState(state.NewStateWithUntilAndState(now, state.FirstState))
+
+	if i.stateOlderThan(12*time.Hour, now) {
+		t.Error("expected state held for exactly 12h not to be older than 12h")
+	}
+	if !i.stateOlderThan(12*time.Hour-time.Second, now) {
+		t.Error("expected state held for 12h to be older than 12h-1s")
+	}
+
+	i.SetReaperState(state.NewStateWithUntilAndState(now.Add(time.Hour), state.FirstState))
+	if !i.untilWithin(time.Hour, now) {
+		t.Error("expected deadline in exactly 1h to be within 1h")
+	}
+	if i.untilWithin(time.Hour-time.Second, now) {
+		t.Error("expected deadline in 1h not to be within 1h-1s")
+	}
+	if !i.untilWithin(0, now.Add(2*time.Hour)) {
+		t.Error("expected a passed deadline to be within 0s")
+	}
+
+	// each state entered by IncrementState starts when it was entered
+	config.Notifications.SecondStateDuration.Duration = 6 * time.Hour
+	config.Notifications.ThirdStateDuration.Duration = 3 * time.Hour
+	i = newTestInstance("i-2", nil)
+	for _, expected := range []state.StateEnum{state.InitialState, state.FirstState, state.SecondState, state.ThirdState, state.FinalState} {
+		if i.ReaperState().State != expected {
+			t.Fatalf("expected %s, got %s", expected.String(), i.ReaperState().State.String())
+		}
+		if start := i.stateStart(); time.Since(start) < 0 || time.Since(start) > time.Minute {
+			t.Errorf("expected %s to have started when it was entered, got %s", expected.String(), start.String())
+		}
+		i.IncrementState()
+	}
+}
+
+func TestOwnerTagFallback(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{OwnerTags: []string{"owner", "Owner", "team-email"}})
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		expected string
+	}{
+		{"first key", map[string]string{"owner": "a@example.com", "Owner": "b@example.com"}, "a@example.com"},
+		{"second key", map[string]string{"Owner": "b@example.com", "team-email": "c@example.com"}, "b@example.com"},
+		{"invalid first key", map[string]string{"owner": "not an address", "team-email": "c@example.com"}, "c@example.com"},
+		{"unlisted key", map[string]string{"created-by": "d@example.com"}, ""},
+		{"all invalid", map[string]string{"owner": "not an address", "Owner": "@@"}, ""},
+	}
+
+	for _, test := range tests {
+		owner := newTestInstance("i-1", test.tags).Owner()
+		if test.expected == "" {
+			if owner != nil {
+				t.Errorf("%s: expected no owner, got %s", test.name, owner.Address)
+			}
+			continue
+		}
+		if owner == nil || owner.Address != test.expected {
+			t.Errorf("%s: expected owner %s, got %v", test.name, test.expected, owner)
+		}
+	}
+}
+
+func TestOwnerDefaultEmailHost(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{OwnerTags: []string{"owner", "Owner"}, DefaultEmailHost: "example.com"})
+
+	owner := newTestInstance("i-1", map[string]string{"Owner": "jdoe"}).Owner()
+	if owner == nil || owner.Address != "jdoe@example.com" {
+		t.Errorf("expected owner jdoe@example.com, got %v", owner)
+	}
+}
+
+func TestDefaultOwner(t *testing.T) {
+	defer SetConfig(config)
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"username", Config{DefaultOwner: "jdoe", DefaultEmailHost: "example.com"}, "jdoe@example.com"},
+		{"address", Config{DefaultOwner: "jdoe@example.com"}, "jdoe@example.com"},
+		{"escalation", Config{Notifications: events.NotificationsConfig{DefaultOwner: "escalations@example.com"}}, "escalations@example.com"},
+		{"precedence", Config{DefaultOwner: "jdoe@example.com", Notifications: events.NotificationsConfig{DefaultOwner: "escalations@example.com"}}, "jdoe@example.com"},
+		{"neither", Config{}, ""},
+	}
+	for _, test := range tests {
+		c := test.config
+		SetConfig(&c)
+		owner := newTestInstance("i-1", nil).Owner()
+		if test.expected == "" {
+			if owner != nil {
+				t.Errorf("%s: expected no owner, got %s", test.name, owner.Address)
+			}
+			continue
+		}
+		if owner == nil || owner.Address != test.expected {
+			t.Errorf("%s: expected owner %s, got %v", test.name, test.expected, owner)
+		}
+	}
+}
+
+func TestOwners(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{DefaultEmailHost: "example.com"})
+
+	tests := []struct {
+		name     string
+		owner    string
+		expected []string
+	}{
+		{"single", "jdoe@example.com", []string{"jdoe@example.com"}},
+		{"display name", "Jane Doe <jane@example.com>", []string{"jane@example.com"}},
+		{"multiple", "Jane Doe <jane@example.com>, ops@example.com", []string{"jane@example.com", "ops@example.com"}},
+		{"quoted comma", `"Doe, Jane" <jane@example.com>,ops`, []string{"jane@example.com", "ops@example.com"}},
+		{"malformed entries", "jane@example.com, <@@>, , Ops <ops@example.com", []string{"jane@example.com"}},
+		{"all malformed", "<@@>, a b c", nil},
+	}
+
+	for _, test := range tests {
+		i := newTestInstance("i-1", map[string]string{"Owner": test.owner})
+		owners := i.Owners()
+		if len(owners) != len(test.expected) {
+			t.Errorf("%s: expected owners %v, got %v", test.name, test.expected, owners)
+			continue
+		}
+		for j, owner := range owners {
+			if owner.Address != test.expected[j] {
+				t.Errorf("%s: expected owners %v, got %v", test.name, test.expected, owners)
+			}
+		}
+
+		// Owner is the first of Owners
+		owner := i.Owner()
+		if len(test.expected) == 0 {
+			if owner != nil {
+				t.Errorf("%s: expected no owner, got %s", test.name, owner.Address)
+			}
+		} else if owner == nil || owner.Address != test.expected[0] {
+			t.Errorf("%s: expected owner %s, got %v", test.name, test.expected[0], owner)
+		}
+	}
+
+	owners := newTestInstance("i-1", map[string]string{"Owner": "Jane Doe <jane@example.com>"}).Owners()
+	if len(owners) != 1 || owners[0].Name != "Jane Doe" {
+		t.Errorf("expected the display name to be kept, got %v", owners)
+	}
+}
+
+// testEC2Tags records CreateTags calls, and describes the last tag written
+// other methods of EC2API are not implemented
+type testEC2Tags struct {
+	ec2iface.EC2API
+	created []*ec2.Tag
+}
+
+func (c *testEC2Tags) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	c.created = append(c.created, input.Tags...)
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func (c *testEC2Tags) DescribeTags(input *ec2.DescribeTagsInput) (*ec2.DescribeTagsOutput, error) {
+	last := c.created[len(c.created)-1]
+	return &ec2.DescribeTagsOutput{
+		Tags: []*ec2.TagDescription{&ec2.TagDescription{Key: last.Key, Value: last.Value}},
+	}, nil
+}
+
+func TestSaveSkipsUnchangedState(t *testing.T) {
+	api := &testEC2Tags{}
+	defer setTestEC2(api)()
+
+	saved := state.NewStateWithUntilAndState(time.Date(2016, 1, 2, 3, 4, 0, 0, time.UTC), state.SecondState)
+	i := newTestInstance("i-saved", map[string]string{reaperTag: saved.String()})
+
+	if ok, err := i.Save(saved); !ok || err != nil {
+		t.Errorf("expected Save to succeed, got %t, %v", ok, err)
+	}
+	if len(api.created) != 0 {
+		t.Errorf("expected no tag write for an unchanged state, got %d", len(api.created))
+	}
+
+	changed := state.NewStateWithUntilAndState(saved.Until.Add(24*time.Hour), state.ThirdState)
+	if ok, err := i.Save(changed); !ok || err != nil {
+		t.Errorf("expected Save to succeed, got %t, %v", ok, err)
+	}
+	if len(api.created) != 1 || *api.created[0].Value != changed.String() {
+		t.Errorf("expected a tag write for a changed state, got %v", api.created)
+	}
+
+	// the written state is remembered
+	i.Save(changed)
+	if len(api.created) != 1 {
+		t.Errorf("expected no tag write after saving the same state twice, got %d", len(api.created))
+	}
+}
+
+func TestTagSetFilters(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	inSet := *filters.NewFilter("TagInSet", []string{"managed-by", "terraform", "spinnaker"})
+	notInSet := *filters.NewFilter("TagNotInSet", []string{"managed-by", "terraform", "spinnaker"})
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		inSet    bool
+		notInSet bool
+	}{
+		{"in set", map[string]string{"managed-by": "spinnaker"}, true, false},
+		{"not in set", map[string]string{"managed-by": "ansible"}, false, true},
+		{"missing tag", map[string]string{"Owner": "terraform"}, false, true},
+	}
+
+	for _, test := range tests {
+		volume := &ec2.Volume{VolumeId: aws.String("vol-" + test.name)}
+		for k, v := range test.tags {
+			volume.Tags = append(volume.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		for _, r := range []filters.Filterable{newTestInstance("i-"+test.name, test.tags), NewVolume("us-west-2", volume)} {
+			if r.Filter(inSet) != test.inSet {
+				t.Errorf("%s: expected TagInSet to be %t for %T", test.name, test.inSet, r)
+			}
+			if r.Filter(notInSet) != test.notInSet {
+				t.Errorf("%s: expected TagNotInSet to be %t for %T", test.name, test.notInSet, r)
+			}
+		}
+	}
+}
+
+func TestTagsEqualFilters(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	allEqual := *filters.NewFilter("TagsAllEqual", []string{"Owner=jdoe", "env=dev"})
+	anyEqual := *filters.NewFilter("TagsAnyEqual", []string{"Owner=jdoe", "env=dev"})
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		allEqual bool
+		anyEqual bool
+	}{
+		{"all", map[string]string{"Owner": "jdoe", "env": "dev", "team": "web"}, true, true},
+		{"partial", map[string]string{"Owner": "jdoe", "env": "prod"}, false, true},
+		{"none", map[string]string{"Owner": "alice"}, false, false},
+	}
+
+	for _, test := range tests {
+		volume := &ec2.Volume{VolumeId: aws.String("vol-" + test.name)}
+		for k, v := range test.tags {
+			volume.Tags = append(volume.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		for _, r := range []filters.Filterable{newTestInstance("i-"+test.name, test.tags), NewVolume("us-west-2", volume)} {
+			if r.Filter(allEqual) != test.allEqual {
+				t.Errorf("%s: expected TagsAllEqual to be %t for %T", test.name, test.allEqual, r)
+			}
+			if r.Filter(anyEqual) != test.anyEqual {
+				t.Errorf("%s: expected TagsAnyEqual to be %t for %T", test.name, test.anyEqual, r)
+			}
+		}
+	}
+
+	// values may be empty or contain =
+	i := newTestInstance("i-values", map[string]string{"empty": "", "query": "a=b"})
+	if !i.Filter(*filters.NewFilter("TagsAllEqual", []string{"empty=", "query=a=b"})) {
+		t.Error("expected TagsAllEqual to match empty values and values containing =")
+	}
+	if i.Filter(*filters.NewFilter("TagsAnyEqual", []string{"empty"})) {
+		t.Error("expected TagsAnyEqual not to match an argument that isn't a key=value pair")
+	}
+}
+
+func TestCloudformationStackNameMatches(t *testing.T) {
+	temp := newTestInstance("i-temp", map[string]string{"aws:cloudformation:stack-name": "temp-123"})
+	prod := newTestInstance("i-prod", map[string]string{"aws:cloudformation:stack-name": "prod"})
+	standalone := newTestInstance("i-standalone", nil)
+
+	f := *filters.NewFilter("CloudformationStackNameMatches", []string{"^temp-"})
+	if !temp.Filter(f) {
+		t.Error("expected an instance in stack temp-123 to match ^temp-")
+	}
+	if prod.Filter(f) {
+		t.Error("expected an instance in stack prod not to match ^temp-")
+	}
+	if standalone.Filter(f) {
+		t.Error("expected an instance in no stack not to match ^temp-")
+	}
+
+	// the stack name can also come from the Cloudformation's resources
+	// rather than the tag, see reaper.allReapables
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+	volume := NewVolume("us-west-2", &ec2.Volume{VolumeId: aws.String("vol-1")})
+	volume.IsInCloudformation, volume.CloudformationStackName = true, "temp-123"
+	if !volume.Filter(f) {
+		t.Error("expected a volume in stack temp-123 to match ^temp-")
+	}
+
+	if _, err := filters.ApplyFilters(temp, filters.FilterGroup{"1": *filters.NewFilter("CloudformationStackNameMatches", []string{"temp-("})}); err == nil {
+		t.Error("expected an invalid regular expression to be an error")
+	}
+}
+
+func TestNotificationChannel(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		expected events.NotificationChannel
+		ok       bool
+	}{
+		{"slack", map[string]string{"reaper-notify": "slack:#team"}, events.NotificationChannel{Reporter: "slack", Channel: "#team"}, true},
+		{"email", map[string]string{"reaper-notify": "email"}, events.NotificationChannel{Reporter: "email"}, true},
+		{"invalid", map[string]string{"reaper-notify": "pager:ops"}, events.NotificationChannel{}, false},
+		{"untagged", map[string]string{}, events.NotificationChannel{}, false},
+	}
+	for _, test := range tests {
+		c, ok := newTestInstance("i-1", test.tags).NotificationChannel()
+		if c != test.expected || ok != test.ok {
+			t.Errorf("%s: expected %v, %t, got %v, %t", test.name, test.expected, test.ok, c, ok)
+		}
+	}
+
+	config.NotifyTag = "notify"
+	if c, ok := newTestInstance("i-1", map[string]string{"notify": "slack"}).NotificationChannel(); !ok || c.Reporter != "slack" {
+		t.Errorf("expected the NotifyTag to be read, got %v, %t", c, ok)
+	}
+}
+
+func TestCreatedBeforeAfterFilters(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	boundary := "2024-01-01T00:00:00Z"
+	before := *filters.NewFilter("CreatedBefore", []string{boundary})
+	after := *filters.NewFilter("CreatedAfter", []string{boundary})
+
+	tests := []struct {
+		name    string
+		created time.Time
+		before  bool
+		after   bool
+	}{
+		{"a second before", time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC), true, false},
+		{"at the boundary", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), false, false},
+		{"a second after", time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC), false, true},
+		{"another zone", time.Date(2023, 12, 31, 20, 0, 0, 0, time.FixedZone("EST", -5*60*60)), false, true},
+	}
+
+	for _, test := range tests {
+		instance := newTestInstance("i-1", nil)
+		instance.LaunchTime = aws.Time(test.created)
+		volume := NewVolume("us-west-2", &ec2.Volume{VolumeId: aws.String("vol-1"), CreateTime: aws.Time(test.created)})
+		for _, r := range []filters.Filterable{instance, volume} {
+			if r.Filter(before) != test.before {
+				t.Errorf("%s: expected CreatedBefore to be %t for %T", test.name, test.before, r)
+			}
+			if r.Filter(after) != test.after {
+				t.Errorf("%s: expected CreatedAfter to be %t for %T", test.name, test.after, r)
+			}
+		}
+	}
+
+	if newTestInstance("i-1", nil).Filter(before) {
+		t.Error("expected an instance without a LaunchTime not to match CreatedBefore")
+	}
+
+	matched, err := filters.ApplyFilters(newTestInstance("i-1", nil), filters.FilterGroup{
+		"1": *filters.NewFilter("CreatedBefore", []string{"2024-01-01"}),
+	})
+	if _, ok := err.(filters.FilterError); matched || !ok {
+		t.Errorf("expected a FilterError for a time that isn't RFC3339, got %t, %v", matched, err)
+	}
+}
+
+func TestReapableDescriptionAgeAndCost(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	i := newTestInstance("i-described", nil)
+	i.LaunchTime = aws.Time(time.Now().Add(-3*24*time.Hour - time.Hour))
+	i.HourlyCost = 0.1
+
+	description := i.ReapableDescription()
+	if !strings.Contains(description, "created 3 days ago") {
+		t.Errorf("expected the description to include the age, got %q", description)
+	}
+	if !strings.Contains(description, "about $73.00/month") {
+		t.Errorf("expected the description to include the monthly cost, got %q", description)
+	}
+	for _, terse := range []string{i.ReapableDescriptionShort(), i.ReapableDescriptionTiny()} {
+		if strings.Contains(terse, "created") || strings.Contains(terse, "$") {
+			t.Errorf("expected the short descriptions to be terse, got %q", terse)
+		}
+	}
+
+	// without a launch time or price, neither is described
+	unknown := newTestInstance("i-unknown", nil)
+	if description := unknown.ReapableDescription(); strings.Contains(description, "created") || strings.Contains(description, "$") {
+		t.Errorf("expected no age or cost for an instance without them, got %q", description)
+	}
+}
@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBackoff     = time.Second
+)
+
+// sleep waits between attempts
+// replaceable in tests
+var sleep = time.Sleep
+
+// retryableErrorCodes are the AWS error codes of throttling and
+// transient service errors, which are worth retrying
+var retryableErrorCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"RequestLimitExceeded":                   true,
+	"RequestThrottled":                       true,
+	"TooManyRequestsException":               true,
+	"ProvisionedThroughputExceededException": true,
+	"InternalError":                          true,
+	"InternalFailure":                        true,
+	"ServiceUnavailable":                     true,
+	"Unavailable":                            true,
+	"RequestTimeout":                         true,
+	"RequestTimeoutException":                true,
+	// returned by Auto Scaling while a previous scaling activity is in progress
+	"ScalingActivityInProgress": true,
+}
+
+// retryable returns whether err is an AWS error with a retryable code
+func retryable(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return retryableErrorCodes[aerr.Code()]
+	}
+	return false
+}
+
+// retry calls f until it succeeds, it returns an error that isn't retryable,
+// or RetryMaxAttempts attempts have been made
+// the wait between attempts starts at RetryBackoff and doubles each attempt
+func retry(description string, f func() error) error {
+	attempts := defaultRetryMaxAttempts
+	backoff := defaultRetryBackoff
+	if config != nil && config.RetryMaxAttempts > 0 {
+		attempts = config.RetryMaxAttempts
+	}
+	if config != nil && config.RetryBackoff.Duration > 0 {
+		backoff = config.RetryBackoff.Duration
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || !retryable(err) || attempt >= attempts {
+			return err
+		}
+		log.Debug("Attempt %d of %d to %s failed, retrying in %s: %s", attempt, attempts, description, backoff, err.Error())
+		sleep(backoff)
+		backoff *= 2
+	}
+}
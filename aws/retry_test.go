@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// setTestSleep records waits instead of sleeping, returning a func that restores sleep
+func setTestSleep(waits *[]time.Duration) (restore func()) {
+	original := sleep
+	sleep = func(d time.Duration) { *waits = append(*waits, d) }
+	return func() { sleep = original }
+}
+
+func TestRetryFailsTwiceThenSucceeds(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{})
+	var waits []time.Duration
+	defer setTestSleep(&waits)()
+
+	calls := 0
+	err := retry("test", func() error {
+		calls++
+		if calls < 3 {
+			return awserr.New("RequestLimitExceeded", "slow down", nil)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected success, got %s", err.Error())
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if len(waits) != 2 || waits[0] != defaultRetryBackoff || waits[1] != 2*defaultRetryBackoff {
+		t.Errorf("expected waits of %s and %s, got %v", defaultRetryBackoff, 2*defaultRetryBackoff, waits)
+	}
+}
+
+func TestRetryPermanentError(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{})
+	var waits []time.Duration
+	defer setTestSleep(&waits)()
+
+	calls := 0
+	err := retry("test", func() error {
+		calls++
+		return awserr.New("InvalidGroup.NotFound", "no such group", nil)
+	})
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if calls != 1 || len(waits) != 0 {
+		t.Errorf("expected a single call without waiting, got %d calls and waits %v", calls, waits)
+	}
+
+	// errors that aren't from AWS aren't retried either
+	calls = 0
+	retry("test", func() error {
+		calls++
+		return errors.New("not an AWS error")
+	})
+	if calls != 1 {
+		t.Errorf("expected a single call, got %d", calls)
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{RetryMaxAttempts: 5})
+	var waits []time.Duration
+	defer setTestSleep(&waits)()
+
+	calls := 0
+	err := retry("test", func() error {
+		calls++
+		return awserr.New("Throttling", "rate exceeded", nil)
+	})
+	if err == nil {
+		t.Error("expected the last error once attempts are exhausted")
+	}
+	if calls != 5 {
+		t.Errorf("expected 5 calls, got %d", calls)
+	}
+}
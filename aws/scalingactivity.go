@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// recentScalingActivities is how many of an AutoScalingGroup's most recent
+// scaling activities are checked for failures
+const recentScalingActivities = 10
+
+var (
+	// scalingActivityFailures caches whether an AutoScalingGroup's recent
+	// scaling activities failed, by region and name
+	// it is cleared each cycle by AllAutoScalingGroups
+	scalingActivityFailures      = make(map[reapable.Region]map[string]bool)
+	scalingActivityFailuresMutex sync.Mutex
+)
+
+// clearScalingActivityFailures empties the cache of scaling activities,
+// so that they are described again each cycle
+func clearScalingActivityFailures() {
+	scalingActivityFailuresMutex.Lock()
+	defer scalingActivityFailuresMutex.Unlock()
+	scalingActivityFailures = make(map[reapable.Region]map[string]bool)
+}
+
+// scalingActivityFailed returns whether any of the AutoScalingGroup's recent
+// scaling activities failed, and false if the lookup fails
+func (a *AutoScalingGroup) scalingActivityFailed() (failed bool, ok bool) {
+	name := a.ID().String()
+
+	// the lock is only held for the cache, so that a slow lookup doesn't
+	// hold up the other AutoScalingGroups'
+	scalingActivityFailuresMutex.Lock()
+	failed, ok = scalingActivityFailures[a.region][name]
+	scalingActivityFailuresMutex.Unlock()
+	if ok {
+		return failed, true
+	}
+
+	failed, err := lookupScalingActivityFailed(a.region, name)
+	if err != nil {
+		// don't cache failures, the next lookup may succeed
+		log.Error("Scaling activity lookup for %s failed: %s", a.ReapableDescriptionTiny(), err.Error())
+		return false, false
+	}
+
+	scalingActivityFailuresMutex.Lock()
+	defer scalingActivityFailuresMutex.Unlock()
+	if scalingActivityFailures[a.region] == nil {
+		scalingActivityFailures[a.region] = make(map[string]bool)
+	}
+	scalingActivityFailures[a.region][name] = failed
+	return failed, true
+}
+
+// lookupScalingActivityFailed describes the most recent scaling activities
+// of the AutoScalingGroup name, returning whether any of them failed
+func lookupScalingActivityFailed(region reapable.Region, name string) (bool, error) {
+	resp, err := newAutoScalingAPI(region.String()).DescribeScalingActivities(&autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(name),
+		MaxRecords:           aws.Int64(recentScalingActivities),
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, activity := range resp.Activities {
+		if aws.StringValue(activity.StatusCode) == autoscaling.ScalingActivityStatusCodeFailed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -3,12 +3,14 @@ package aws
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"net/mail"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
 	"github.com/mozilla-services/reaper/filters"
@@ -22,27 +24,42 @@ import (
 type SecurityGroup struct {
 	Resource
 	ec2.SecurityGroup
+
+	// NetworkInterfaceIDs are the network interfaces the SecurityGroup is attached to
+	NetworkInterfaceIDs []string
+	// ReferencingGroupIDs are the other SecurityGroups whose rules reference it
+	ReferencingGroupIDs []string
+	// LaunchConfigurationNames are the launch configurations that use it
+	LaunchConfigurationNames []string
+	// usageResolved is whether NetworkInterfaceIDs, ReferencingGroupIDs and
+	// LaunchConfigurationNames were gathered, see resolveSecurityGroupUsage
+	usageResolved bool
 }
 
 // NewSecurityGroup creates an SecurityGroup from the AWS API's ec2.SecurityGroup
 func NewSecurityGroup(region string, sg *ec2.SecurityGroup) *SecurityGroup {
+	if sg.GroupId == nil {
+		log.Warning("Skipping a SecurityGroup without a GroupId in %s", region)
+		return nil
+	}
 	s := SecurityGroup{
 		Resource: Resource{
 			id:     reapable.ID(*sg.GroupId),
 			region: reapable.Region(region),
 
-			Name: *sg.GroupName,
+			Name: aws.StringValue(sg.GroupName),
 			Tags: make(map[string]string),
 		},
 		SecurityGroup: *sg,
 	}
 
 	for _, tag := range sg.Tags {
-		s.Resource.Tags[*tag.Key] = *tag.Value
+		s.Resource.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
 	if s.Tagged("aws:cloudformation:stack-name") {
 		s.Dependency = true
 		s.IsInCloudformation = true
+		s.CloudformationStackName = s.Tag("aws:cloudformation:stack-name")
 	}
 	if s.Tagged(reaperTag) {
 		// restore previously tagged state
@@ -55,39 +72,41 @@ func NewSecurityGroup(region string, sg *ec2.SecurityGroup) *SecurityGroup {
 	return &s
 }
 
+// ReapableType is part of the reapable.Typed interface
+func (a *SecurityGroup) ReapableType() string {
+	return "SecurityGroup"
+}
+
 // ReapableEventText is part of the events.Reapable interface
 func (a *SecurityGroup) ReapableEventText() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableSecurityGroupEventText)
+	return reapableEventText(a, "SecurityGroupEventText", reapableSecurityGroupEventText)
 }
 
 // ReapableEventTextShort is part of the events.Reapable interface
 func (a *SecurityGroup) ReapableEventTextShort() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableSecurityGroupEventTextShort)
+	return reapableEventText(a, "SecurityGroupEventTextShort", reapableSecurityGroupEventTextShort)
 }
 
 // ReapableEventEmail is part of the events.Reapable interface
 func (a *SecurityGroup) ReapableEventEmail() (owner mail.Address, subject string, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-
 	subject = fmt.Sprintf("AWS Resource %s is going to be Reaped!", a.ReapableDescriptionTiny())
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableSecurityGroupEventHTML)
+	body, err = reapableEventHTML(a, "SecurityGroupEventHTML", reapableSecurityGroupEventHTML)
 	return
 }
 
 // ReapableEventEmailShort is part of the events.Reapable interface
 func (a *SecurityGroup) ReapableEventEmailShort() (owner mail.Address, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableSecurityGroupEventHTMLShort)
+	body, err = reapableEventHTML(a, "SecurityGroupEventHTMLShort", reapableSecurityGroupEventHTMLShort)
 	return
 }
 
@@ -130,8 +149,10 @@ const reapableSecurityGroupEventHTML = `
 <body>
 	<p>SecurityGroup <a href="{{ .SecurityGroup.AWSConsoleURL }}">{{ if .SecurityGroup.Name }}"{{.SecurityGroup.Name}}" {{ end }} in {{.SecurityGroup.Region}}</a> is scheduled to be deleted.</p>
 
+	{{ with .SecurityGroup.ReapReason }}<p>It was {{ . }}.</p>{{ end }}
+
 	<p>
-		You can ignore this message and your SecurityGroup will advance to the next state after <strong>{{.SecurityGroup.ReaperState.Until}}</strong>. If you do not take action it will be deleted!
+		You can ignore this message and your SecurityGroup will advance to the next state after <strong>{{ until .SecurityGroup.ReaperState.Until }}</strong> ({{ relativeUntil .SecurityGroup.ReaperState.Until }}). If you do not take action it will be deleted!
 	</p>
 
 	<p>
@@ -155,41 +176,180 @@ const reapableSecurityGroupEventHTML = `
 const reapableSecurityGroupEventHTMLShort = `
 <html>
 <body>
-	<p>SecurityGroup <a href="{{ .SecurityGroup.AWSConsoleURL }}">{{ if .SecurityGroup.Name }}"{{.SecurityGroup.Name}}" {{ end }}</a> in {{.SecurityGroup.Region}}</a> is scheduled to be deleted after <strong>{{.SecurityGroup.ReaperState.Until}}</strong>.
+	<p>SecurityGroup <a href="{{ .SecurityGroup.AWSConsoleURL }}">{{ if .SecurityGroup.Name }}"{{.SecurityGroup.Name}}" {{ end }}</a> in {{.SecurityGroup.Region}}</a> is scheduled to be deleted after <strong>{{ until .SecurityGroup.ReaperState.Until }}</strong> ({{ relativeUntil .SecurityGroup.ReaperState.Until }}).
 		<br />
 		<a href="{{ .TerminateLink }}">Delete</a>,
 		<a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a>,
 		<a href="{{ .IgnoreLink3 }}">3 days</a>,
 		<a href="{{ .IgnoreLink7}}"> 7 days</a>, or
 		<a href="{{ .WhitelistLink }}">Whitelist</a> it.
+		{{ with .SecurityGroup.ReapReason }}<br />It was {{ . }}.{{ end }}
 	</p>
 </body>
 </html>
 `
 
 const reapableSecurityGroupEventTextShort = `%%%
-SecurityGroup [{{.SecurityGroup.ID}}]({{.SecurityGroup.AWSConsoleURL}}) in region: [{{.SecurityGroup.Region}}](https://{{.SecurityGroup.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.SecurityGroup.Region}}).{{if .SecurityGroup.Owned}} Owned by {{.SecurityGroup.Owner}}.\n{{end}}
+SecurityGroup [{{.SecurityGroup.ID}}]({{.SecurityGroup.AWSConsoleURL}}) in region: [{{.SecurityGroup.Region}}](https://{{.SecurityGroup.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.SecurityGroup.Region}}).{{if .SecurityGroup.Owner}} Owned by {{.SecurityGroup.Owner}}.\n{{end}}
 [Whitelist]({{ .WhitelistLink }}) or [Delete]({{ .TerminateLink }}) this SecurityGroup.
 %%%`
 
 const reapableSecurityGroupEventText = `%%%
 Reaper has discovered an SecurityGroup qualified as reapable: [{{.SecurityGroup.ID}}]({{.SecurityGroup.AWSConsoleURL}}) in region: [{{.SecurityGroup.Region}}](https://{{.SecurityGroup.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.SecurityGroup.Region}}).\n
-{{if .SecurityGroup.Owned}}Owned by {{.SecurityGroup.Owner}}.\n{{end}}
+{{if .SecurityGroup.Owner}}Owned by {{.SecurityGroup.Owner}}.\n{{end}}
+{{ with .SecurityGroup.ReapReason }}It was {{ . }}.\n{{ end }}
 {{ if .SecurityGroup.AWSConsoleURL}}{{.SecurityGroup.AWSConsoleURL}}\n{{end}}
 [AWS Console URL]({{.SecurityGroup.AWSConsoleURL}})\n
 [Whitelist]({{ .WhitelistLink }}) this SecurityGroup.
 [Delete]({{ .TerminateLink }}) this SecurityGroup.
 %%%`
 
+// orphaned returns whether the SecurityGroup is attached to no network
+// interfaces, referenced by no other SecurityGroup's rules, used by no
+// launch configuration, and not in a Cloudformation
+// a SecurityGroup whose usage couldn't be resolved is never orphaned, nor
+// is a VPC's default SecurityGroup, which can't be deleted
+func (a *SecurityGroup) orphaned() bool {
+	return a.usageResolved &&
+		len(a.NetworkInterfaceIDs) == 0 &&
+		len(a.ReferencingGroupIDs) == 0 &&
+		len(a.LaunchConfigurationNames) == 0 &&
+		aws.StringValue(a.GroupName) != "default" &&
+		!a.IsInCloudformation
+}
+
+// AllowsPublicIngress returns whether an ingress rule of the SecurityGroup
+// allows traffic on port from anywhere, for TCP, UDP or all protocols
+// the AWS SDK's IpPermission has no Ipv6Ranges yet, so only IPv4 ranges
+// are considered
+func (a *SecurityGroup) AllowsPublicIngress(port int64) bool {
+	for _, permission := range a.IpPermissions {
+		if permission == nil || !permissionAllowsPort(permission, port) {
+			continue
+		}
+		for _, r := range permission.IpRanges {
+			if r != nil && publicCIDR(aws.StringValue(r.CidrIp)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permissionAllowsPort returns whether a rule applies to port
+// the all protocols rule (-1) applies to every port, ICMP rules to none
+func permissionAllowsPort(permission *ec2.IpPermission, port int64) bool {
+	switch aws.StringValue(permission.IpProtocol) {
+	case "-1":
+		return true
+	case "tcp", "udp", "6", "17":
+	default:
+		return false
+	}
+	if permission.FromPort == nil || permission.ToPort == nil {
+		return false
+	}
+	from, to := *permission.FromPort, *permission.ToPort
+	// -1 is every port
+	if from == -1 && to == -1 {
+		return true
+	}
+	return from <= port && port <= to
+}
+
+// publicCIDR returns whether a CIDR is the whole internet, such as 0.0.0.0/0
+func publicCIDR(cidr string) bool {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	ones, _ := network.Mask.Size()
+	return ones == 0
+}
+
+// resolveSecurityGroupUsage sets the network interfaces each of a region's
+// SecurityGroups is attached to, the other SecurityGroups whose rules
+// reference it, and the launch configurations that use it
+func resolveSecurityGroupUsage(sgs []*SecurityGroup, interfaces []*ec2.NetworkInterface, launchConfigs []*autoscaling.LaunchConfiguration) {
+	byID := make(map[string]*SecurityGroup)
+	byName := make(map[string]*SecurityGroup)
+	for _, sg := range sgs {
+		byID[sg.ID().String()] = sg
+		byName[aws.StringValue(sg.GroupName)] = sg
+		sg.NetworkInterfaceIDs = nil
+		sg.ReferencingGroupIDs = nil
+		sg.LaunchConfigurationNames = nil
+		sg.usageResolved = true
+	}
+
+	for _, lc := range launchConfigs {
+		for _, group := range lc.SecurityGroups {
+			// EC2-Classic launch configurations list groups by name
+			sg, ok := byID[aws.StringValue(group)]
+			if !ok {
+				sg, ok = byName[aws.StringValue(group)]
+			}
+			if ok {
+				sg.LaunchConfigurationNames = append(sg.LaunchConfigurationNames, aws.StringValue(lc.LaunchConfigurationName))
+			}
+		}
+	}
+
+	for _, eni := range interfaces {
+		for _, group := range eni.Groups {
+			if sg, ok := byID[aws.StringValue(group.GroupId)]; ok {
+				sg.NetworkInterfaceIDs = append(sg.NetworkInterfaceIDs, aws.StringValue(eni.NetworkInterfaceId))
+			}
+		}
+	}
+
+	for _, sg := range sgs {
+		referenced := make(map[string]bool)
+		for _, permission := range append(sg.IpPermissions, sg.IpPermissionsEgress...) {
+			for _, pair := range permission.UserIdGroupPairs {
+				id := aws.StringValue(pair.GroupId)
+				// rules allowing traffic from the group itself don't count
+				if id == "" || id == sg.ID().String() || referenced[id] {
+					continue
+				}
+				referenced[id] = true
+				if other, ok := byID[id]; ok {
+					other.ReferencingGroupIDs = append(other.ReferencingGroupIDs, sg.ID().String())
+				}
+			}
+		}
+	}
+}
+
 // Filter is part of the filter.Filterable interface
 func (a *SecurityGroup) Filter(filter filters.Filter) bool {
 	matched := false
 	// map function names to function calls
 	switch filter.Function {
+	case "Orphaned":
+		if b, err := filter.BoolValue(0); err == nil && a.orphaned() == b {
+			matched = true
+		}
+	case "AllowsPublicIngress":
+		if port, err := filter.Int64Value(0); err == nil && a.AllowsPublicIngress(port) {
+			matched = true
+		}
+	case "InVPC":
+		if a.VpcId != nil && anyIn([]string{*a.VpcId}, filter.Arguments) {
+			matched = true
+		}
+	case "NotInVPC":
+		if a.VpcId == nil || !anyIn([]string{*a.VpcId}, filter.Arguments) {
+			matched = true
+		}
 	case "InCloudformation":
 		if b, err := filter.BoolValue(0); err == nil && a.IsInCloudformation == b {
 			matched = true
 		}
+	case "CloudformationStackNameMatches":
+		if a.cloudformationStackNameMatches(filter) {
+			matched = true
+		}
 	case "Region":
 		for _, region := range filter.Arguments {
 			if a.Region() == reapable.Region(region) {
@@ -215,16 +375,50 @@ func (a *SecurityGroup) Filter(filter filters.Filter) bool {
 		if !a.Tagged(filter.Arguments[0]) {
 			matched = true
 		}
+	case "MissingAnyTag":
+		if a.missingAnyTag(filter.Arguments) {
+			matched = true
+		}
+	case "MissingAllTags":
+		if a.missingAllTags(filter.Arguments) {
+			matched = true
+		}
 	case "TagNotEqual":
 		if a.Tag(filter.Arguments[0]) != filter.Arguments[1] {
 			matched = true
 		}
+	case "TagsAllEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) == len(pairs) {
+			matched = true
+		}
+	case "TagsAnyEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) > 0 {
+			matched = true
+		}
+	case "TagInSet":
+		if a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "TagNotInSet":
+		if !a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
 	case "ReaperState":
-		if a.reaperState.State.String() == filter.Arguments[0] {
+		if a.ReaperState().State.String() == filter.Arguments[0] {
+			matched = true
+		}
+	case "StateOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.stateOlderThan(d, time.Now()) {
+			matched = true
+		}
+	case "UntilWithin":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.untilWithin(d, time.Now()) {
 			matched = true
 		}
 	case "NotReaperState":
-		if a.reaperState.State.String() != filter.Arguments[0] {
+		if a.ReaperState().State.String() != filter.Arguments[0] {
 			matched = true
 		}
 	case "Named":
@@ -248,7 +442,7 @@ func (a *SecurityGroup) Filter(filter filters.Filter) bool {
 			matched = true
 		}
 	default:
-		log.Error(fmt.Sprintf("No function %s could be found for filtering SecurityGroups.", filter.Function))
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering SecurityGroups.", filter.Function))
 	}
 	return matched
 }
@@ -266,12 +460,15 @@ func (a *SecurityGroup) AWSConsoleURL() *url.URL {
 // Terminate is a method of reapable.Terminable, which is embedded in reapable.Reapable
 func (a *SecurityGroup) Terminate() (bool, error) {
 	log.Info("Terminating SecurityGroup ", a.ReapableDescriptionTiny())
-	api := ec2.New(sess, aws.NewConfig().WithRegion(string(a.Region())))
+	api := ec2Client(string(a.Region()))
 
 	input := &ec2.DeleteSecurityGroupInput{
 		GroupName: aws.String(a.ID().String()),
 	}
-	_, err := api.DeleteSecurityGroup(input)
+	err := retry("delete SecurityGroup "+a.ReapableDescriptionTiny(), func() error {
+		_, err := api.DeleteSecurityGroup(input)
+		return err
+	})
 	if err != nil {
 		log.Error("could not delete SecurityGroup ", a.ReapableDescriptionTiny())
 		return false, err
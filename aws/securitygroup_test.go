@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// testEC2SecurityGroups describes fixed SecurityGroups and network interfaces
+// other methods of EC2API are not implemented
+type testEC2SecurityGroups struct {
+	ec2iface.EC2API
+	securityGroups    []*ec2.SecurityGroup
+	networkInterfaces []*ec2.NetworkInterface
+}
+
+func (c *testEC2SecurityGroups) DescribeSecurityGroups(input *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: c.securityGroups}, nil
+}
+
+func (c *testEC2SecurityGroups) DescribeNetworkInterfaces(input *ec2.DescribeNetworkInterfacesInput) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: c.networkInterfaces}, nil
+}
+
+// testAutoScalingSecurityGroups describes fixed launch configurations in one page
+// other methods of AutoScalingAPI are not implemented
+type testAutoScalingSecurityGroups struct {
+	autoscalingiface.AutoScalingAPI
+	launchConfigs []*autoscaling.LaunchConfiguration
+}
+
+func (c *testAutoScalingSecurityGroups) DescribeLaunchConfigurationsPages(input *autoscaling.DescribeLaunchConfigurationsInput, fn func(*autoscaling.DescribeLaunchConfigurationsOutput, bool) bool) error {
+	fn(&autoscaling.DescribeLaunchConfigurationsOutput{LaunchConfigurations: c.launchConfigs}, true)
+	return nil
+}
+
+// newTestSecurityGroup returns a SecurityGroup with an ingress rule
+// from each of the groups in from
+func newTestSecurityGroup(id string, from ...string) *ec2.SecurityGroup {
+	sg := &ec2.SecurityGroup{GroupId: aws.String(id), GroupName: aws.String(id)}
+	for _, other := range from {
+		sg.IpPermissions = append(sg.IpPermissions, &ec2.IpPermission{
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{&ec2.UserIdGroupPair{GroupId: aws.String(other)}},
+		})
+	}
+	return sg
+}
+
+func TestOrphanedSecurityGroups(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(&Config{Regions: []string{"us-west-2"}})
+	defer setTestEC2(&testEC2SecurityGroups{
+		securityGroups: []*ec2.SecurityGroup{
+			newTestSecurityGroup("sg-referenced"),
+			// references sg-referenced, and itself
+			newTestSecurityGroup("sg-unused", "sg-referenced", "sg-unused"),
+			newTestSecurityGroup("sg-attached"),
+			newTestSecurityGroup("sg-launched"),
+			{GroupId: aws.String("sg-classic"), GroupName: aws.String("classic")},
+			{GroupId: aws.String("sg-default"), GroupName: aws.String("default")},
+		},
+		networkInterfaces: []*ec2.NetworkInterface{
+			&ec2.NetworkInterface{
+				NetworkInterfaceId: aws.String("eni-1"),
+				Groups:             []*ec2.GroupIdentifier{&ec2.GroupIdentifier{GroupId: aws.String("sg-attached")}},
+			},
+		},
+	})()
+	defer setTestAutoScaling(&testAutoScalingSecurityGroups{
+		launchConfigs: []*autoscaling.LaunchConfiguration{
+			{LaunchConfigurationName: aws.String("lc-1"), SecurityGroups: []*string{aws.String("sg-launched")}},
+			// EC2-Classic launch configurations use group names
+			{LaunchConfigurationName: aws.String("lc-2"), SecurityGroups: []*string{aws.String("classic")}},
+		},
+	})()
+
+	sgs := make(map[string]*SecurityGroup)
+	for sg := range AllSecurityGroups() {
+		sgs[sg.ID().String()] = sg
+	}
+
+	orphaned := *filters.NewFilter("Orphaned", []string{"true"})
+	if sgs["sg-referenced"].Filter(orphaned) {
+		t.Error("expected a SecurityGroup referenced by another's rule not to be Orphaned")
+	}
+	if ids := sgs["sg-referenced"].ReferencingGroupIDs; len(ids) != 1 || ids[0] != "sg-unused" {
+		t.Errorf("expected sg-referenced to be referenced by sg-unused, got %v", ids)
+	}
+	if sgs["sg-attached"].Filter(orphaned) {
+		t.Error("expected a SecurityGroup attached to a network interface not to be Orphaned")
+	}
+	if sgs["sg-launched"].Filter(orphaned) || sgs["sg-classic"].Filter(orphaned) {
+		t.Error("expected a SecurityGroup used by a launch configuration not to be Orphaned")
+	}
+	if names := sgs["sg-launched"].LaunchConfigurationNames; len(names) != 1 || names[0] != "lc-1" {
+		t.Errorf("expected sg-launched to be used by lc-1, got %v", names)
+	}
+	if sgs["sg-default"].Filter(orphaned) {
+		t.Error("expected a default SecurityGroup not to be Orphaned")
+	}
+	if !sgs["sg-unused"].Filter(orphaned) {
+		t.Error("expected an unused SecurityGroup that only references itself to be Orphaned")
+	}
+
+	// SecurityGroups in a Cloudformation are never orphaned
+	sgs["sg-unused"].IsInCloudformation = true
+	if sgs["sg-unused"].Filter(orphaned) {
+		t.Error("expected a SecurityGroup in a Cloudformation not to be Orphaned")
+	}
+
+	// nor are SecurityGroups whose usage wasn't resolved
+	if NewSecurityGroup("us-west-2", newTestSecurityGroup("sg-unresolved")).Filter(orphaned) {
+		t.Error("expected a SecurityGroup without resolved usage not to be Orphaned")
+	}
+}
+
+func TestAllowsPublicIngress(t *testing.T) {
+	newGroup := func(protocol string, from, to int64, cidrs ...string) *SecurityGroup {
+		permission := &ec2.IpPermission{
+			IpProtocol: aws.String(protocol),
+			FromPort:   aws.Int64(from),
+			ToPort:     aws.Int64(to),
+		}
+		for _, cidr := range cidrs {
+			permission.IpRanges = append(permission.IpRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+		}
+		sg := newTestSecurityGroup("sg-" + protocol)
+		sg.IpPermissions = append(sg.IpPermissions, permission)
+		return NewSecurityGroup("us-west-2", sg)
+	}
+
+	tests := []struct {
+		name     string
+		sg       *SecurityGroup
+		port     int64
+		expected bool
+	}{
+		{"tcp range spanning the port", newGroup("tcp", 20, 25, "0.0.0.0/0"), 22, true},
+		{"tcp range starting at the port", newGroup("tcp", 22, 22, "0.0.0.0/0"), 22, true},
+		{"tcp range not spanning the port", newGroup("tcp", 80, 443, "0.0.0.0/0"), 22, false},
+		{"tcp range from a private network", newGroup("tcp", 20, 25, "10.0.0.0/8"), 22, false},
+		{"udp", newGroup("udp", 53, 53, "10.0.0.0/8", "0.0.0.0/0"), 53, true},
+		{"all protocols", newGroup("-1", -1, -1, "0.0.0.0/0"), 3389, true},
+		{"icmp", newGroup("icmp", -1, -1, "0.0.0.0/0"), 22, false},
+		// IPv6 ranges aren't described by this version of the AWS SDK,
+		// so a rule without IPv4 ranges is never public
+		{"ipv6 only", newGroup("tcp", 22, 22), 22, false},
+		{"no rules", NewSecurityGroup("us-west-2", newTestSecurityGroup("sg-empty")), 22, false},
+	}
+	for _, test := range tests {
+		if allows := test.sg.AllowsPublicIngress(test.port); allows != test.expected {
+			t.Errorf("%s: expected AllowsPublicIngress(%d) to be %t", test.name, test.port, test.expected)
+		}
+		f := *filters.NewFilter("AllowsPublicIngress", []string{fmt.Sprint(test.port)})
+		if matched := test.sg.Filter(f); matched != test.expected {
+			t.Errorf("%s: expected the AllowsPublicIngress filter to be %t", test.name, test.expected)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/mozilla-services/reaper/filters"
 	"github.com/mozilla-services/reaper/reapable"
@@ -18,26 +19,33 @@ type Snapshot struct {
 	LaunchTime    time.Time
 }
 
+// NewSnapshot creates a Snapshot from the AWS API's ec2.Snapshot
+// returns nil if the ec2.Snapshot has no id
 func NewSnapshot(region string, s *ec2.Snapshot) *Snapshot {
+	if s.SnapshotId == nil {
+		log.Warning("Skipping a Snapshot without a SnapshotId in %s", region)
+		return nil
+	}
 	snap := Snapshot{
 		Resource: Resource{
 			id:     reapable.ID(*s.SnapshotId),
 			region: reapable.Region(region),
 			Tags:   make(map[string]string),
 		},
-		SizeGB:        *s.VolumeSize,
-		SnapshotState: *s.State,
-		VolumeID:      reapable.ID(*s.VolumeId),
-		LaunchTime:    *s.StartTime,
+		SizeGB:        aws.Int64Value(s.VolumeSize),
+		SnapshotState: aws.StringValue(s.State),
+		VolumeID:      reapable.ID(aws.StringValue(s.VolumeId)),
+		LaunchTime:    aws.TimeValue(s.StartTime),
 	}
 
 	for _, tag := range s.Tags {
-		snap.Tags[*tag.Key] = *tag.Value
+		snap.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
 
 	if snap.Tagged("aws:cloudformation:stack-name") {
 		snap.Dependency = true
 		snap.IsInCloudformation = true
+		snap.CloudformationStackName = snap.Tag("aws:cloudformation:stack-name")
 	}
 
 	return &snap
@@ -48,7 +56,7 @@ func (s *Snapshot) Filter(filter filters.Filter) bool {
 	// map function names to function calls
 	switch filter.Function {
 	default:
-		log.Error(fmt.Sprintf("No function %s could be found for filtering Snapshots.", filter.Function))
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering Snapshots.", filter.Function))
 	}
 	return matched
 }
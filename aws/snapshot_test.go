@@ -0,0 +1,23 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestNewSnapshotPartiallyPopulated(t *testing.T) {
+	if s := NewSnapshot("us-west-2", &ec2.Snapshot{}); s != nil {
+		t.Error("expected a Snapshot without an id to be skipped")
+	}
+
+	// pending snapshots may not have a size, state, volume or start time
+	s := NewSnapshot("us-west-2", &ec2.Snapshot{SnapshotId: aws.String("snap-1")})
+	if s == nil {
+		t.Fatal("expected a Snapshot")
+	}
+	if s.SizeGB != 0 || s.SnapshotState != "" || s.VolumeID != "" || !s.LaunchTime.IsZero() {
+		t.Errorf("expected zero values for missing fields, got %+v", s)
+	}
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/prices"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// SpotPrices returns the current Linux spot price of each instance type
+// in each region, averaged across availability zones
+func SpotPrices(regions []string) prices.PricesMap {
+	spotPrices := make(prices.PricesMap)
+	for _, region := range regions {
+		api := ec2Client(region)
+		var history []*ec2.SpotPrice
+		// with a StartTime of now, only current prices are returned
+		err := api.DescribeSpotPriceHistoryPages(&ec2.DescribeSpotPriceHistoryInput{
+			StartTime:           aws.Time(time.Now()),
+			ProductDescriptions: []*string{aws.String("Linux/UNIX")},
+		}, func(resp *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
+			history = append(history, resp.SpotPriceHistory...)
+			return !lastPage
+		})
+		if err != nil {
+			log.Error("Could not get spot prices in %s: %s", region, err.Error())
+			continue
+		}
+		spotPrices[region] = averageSpotPrices(history)
+	}
+	return spotPrices
+}
+
+// averageSpotPrices averages spot prices across availability zones by instance type
+func averageSpotPrices(history []*ec2.SpotPrice) map[string]string {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, price := range history {
+		if price.InstanceType == nil || price.SpotPrice == nil {
+			continue
+		}
+		p, err := strconv.ParseFloat(*price.SpotPrice, 64)
+		if err != nil {
+			continue
+		}
+		sums[*price.InstanceType] += p
+		counts[*price.InstanceType]++
+	}
+
+	averages := make(map[string]string)
+	for instanceType, sum := range sums {
+		averages[instanceType] = strconv.FormatFloat(sum/float64(counts[instanceType]), 'f', -1, 64)
+	}
+	return averages
+}
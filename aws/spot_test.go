@@ -0,0 +1,35 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestAverageSpotPrices(t *testing.T) {
+	newSpotPrice := func(instanceType, zone, price string) *ec2.SpotPrice {
+		return &ec2.SpotPrice{
+			InstanceType:     aws.String(instanceType),
+			AvailabilityZone: aws.String(zone),
+			SpotPrice:        aws.String(price),
+		}
+	}
+
+	averages := averageSpotPrices([]*ec2.SpotPrice{
+		newSpotPrice("m4.large", "us-west-2a", "0.02"),
+		newSpotPrice("m4.large", "us-west-2b", "0.04"),
+		newSpotPrice("c4.large", "us-west-2a", "0.05"),
+		newSpotPrice("c4.xlarge", "us-west-2a", "not a price"),
+	})
+
+	if averages["m4.large"] != "0.03" {
+		t.Errorf("expected m4.large to average 0.03, got %s", averages["m4.large"])
+	}
+	if averages["c4.large"] != "0.05" {
+		t.Errorf("expected c4.large to be 0.05, got %s", averages["c4.large"])
+	}
+	if _, ok := averages["c4.xlarge"]; ok {
+		t.Error("expected unparsable prices to be skipped")
+	}
+}
@@ -0,0 +1,131 @@
+package aws
+
+import (
+	"fmt"
+	htmlTemplate "html/template"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+const defaultTimeLayout = "Jan 2, 2006 at 3:04pm (MST)"
+
+// templateFuncs are the functions available to event templates
+var templateFuncs = map[string]interface{}{
+	"until": formatUntil,
+	"relativeUntil": func(t time.Time) string {
+		return relativeTime(t, time.Now())
+	},
+}
+
+// formatUntil formats a deadline with the configured TimeLayout in TimeZone
+func formatUntil(t time.Time) string {
+	layout := defaultTimeLayout
+	location := time.UTC
+	if config != nil {
+		if config.Notifications.TimeLayout != "" {
+			layout = config.Notifications.TimeLayout
+		}
+		// TimeZone is validated when the config is loaded
+		if l, err := time.LoadLocation(config.Notifications.TimeZone); err == nil {
+			location = l
+		}
+	}
+	return t.In(location).Format(layout)
+}
+
+// relativeTime describes t relative to now, such as "in 3 days" or "2 hours ago"
+func relativeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	past := d < 0
+	if past {
+		d = -d
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	default:
+		n, unit = int(d/(24*time.Hour)), "day"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+
+	if past {
+		return fmt.Sprintf("%d %s ago", n, unit)
+	}
+	return fmt.Sprintf("in %d %s", n, unit)
+}
+
+// templateNames are the names of the event templates that can be customized
+// a custom template file is named after the template, with any extension,
+// such as InstanceEventHTML.html
+var templateNames = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, resource := range []string{"ASG", "Cloudformation", "Image", "Instance", "NetworkInterface", "SecurityGroup", "Volume"} {
+		for _, kind := range []string{"EventHTML", "EventHTMLShort", "EventText", "EventTextShort"} {
+			names[resource+kind] = true
+		}
+	}
+	return names
+}()
+
+// LoadTemplates returns the custom event templates in dir by name
+// templates that fail to parse or aren't named after a known template are errors
+func LoadTemplates(dir string) (map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		if !templateNames[name] {
+			return nil, fmt.Errorf("Template file %s is not named after a known template", file.Name())
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		text := string(b)
+
+		if strings.Contains(name, "HTML") {
+			_, err = htmlTemplate.New(name).Funcs(templateFuncs).Parse(text)
+		} else {
+			_, err = textTemplate.New(name).Funcs(templateFuncs).Parse(text)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Template file %s: %s", file.Name(), err.Error())
+		}
+		log.Info("Using custom template %s", file.Name())
+		templates[name] = text
+	}
+	return templates, nil
+}
+
+// eventTemplate returns the custom template with that name if there is one,
+// or the built-in template text
+func eventTemplate(name, text string) string {
+	if config != nil {
+		if custom, ok := config.CustomTemplates[name]; ok {
+			return custom
+		}
+	}
+	return text
+}
@@ -0,0 +1,244 @@
+package aws
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// writeTestTemplates writes files to a new directory, returning it
+// and a func that removes it
+func writeTestTemplates(t *testing.T, files map[string]string) (dir string, remove func()) {
+	dir, err := ioutil.TempDir("", "reaper-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestCustomTemplateIsUsed(t *testing.T) {
+	defer SetConfig(config)
+	c := newTestConfig()
+	SetConfig(c)
+
+	dir, remove := writeTestTemplates(t, map[string]string{
+		"InstanceEventHTML.html": "<p>Custom notice for {{ .Instance.ReapableDescriptionTiny }}</p>",
+	})
+	defer remove()
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.CustomTemplates = templates
+
+	i := newTestInstance("i-1", map[string]string{"Owner": "owner@example.com"})
+	_, _, body, err := i.ReapableEventEmail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body.String(), "Custom notice for") || !strings.Contains(body.String(), "i-1") {
+		t.Errorf("expected the custom template to be used, got %s", body.String())
+	}
+
+	// templates that aren't provided fall back to the built-in ones
+	_, body, err = i.ReapableEventEmailShort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(body.String(), "Custom notice for") || body.Len() == 0 {
+		t.Errorf("expected the built-in short template to be used, got %s", body.String())
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		file, text string
+	}{
+		{"InstanceEventHTML.html", "{{ .Instance.ID "},
+		{"InstanceEventTextShort.txt", "{{ end }}"},
+		{"InstanceEmail.html", "not a known template name"},
+	}
+	for _, test := range tests {
+		dir, remove := writeTestTemplates(t, map[string]string{test.file: test.text})
+		if _, err := LoadTemplates(dir); err == nil {
+			t.Errorf("expected an error loading %s", test.file)
+		}
+		remove()
+	}
+}
+
+func TestFormatUntil(t *testing.T) {
+	defer SetConfig(config)
+	c := newTestConfig()
+	SetConfig(c)
+
+	until := time.Date(2016, 3, 1, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		timeZone, timeLayout, expected string
+	}{
+		{"", "", "Mar 1, 2016 at 6:30pm (UTC)"},
+		{"America/Los_Angeles", "", "Mar 1, 2016 at 10:30am (PST)"},
+		{"Europe/Berlin", "2006-01-02 15:04 MST", "2016-03-01 19:30 CET"},
+	}
+	for _, test := range tests {
+		c.Notifications.TimeZone = test.timeZone
+		c.Notifications.TimeLayout = test.timeLayout
+		if s := formatUntil(until); s != test.expected {
+			t.Errorf("%q %q: expected %s, got %s", test.timeZone, test.timeLayout, test.expected, s)
+		}
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2016, 3, 1, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{now.Add(3*24*time.Hour + 2*time.Hour), "in 3 days"},
+		{now.Add(24 * time.Hour), "in 1 day"},
+		{now.Add(5 * time.Hour), "in 5 hours"},
+		{now.Add(time.Minute), "in 1 minute"},
+		{now.Add(10 * time.Second), "now"},
+		{now.Add(-2 * time.Hour), "2 hours ago"},
+	}
+	for _, test := range tests {
+		if s := relativeTime(test.t, now); s != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.t.Sub(now), test.expected, s)
+		}
+	}
+}
+
+func TestReapReasonInEvents(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	i := newTestInstance("i-1", map[string]string{"Owner": "owner@example.com"})
+	if i.ReapReason() != "" {
+		t.Errorf("expected no reason before matching, got %s", i.ReapReason())
+	}
+	i.AddFilterGroup("old-untagged", filters.FilterGroup{
+		"1": *filters.NewFilter("NotTagged", []string{"CostCenter"}),
+		"2": *filters.NewFilter("LaunchTimeNotInTheLast", []string{"720h"}),
+	})
+
+	expected := "flagged by group 'old-untagged' because LaunchTimeNotInTheLast 720h and NotTagged CostCenter"
+	if i.ReapReason() != expected {
+		t.Errorf("expected %q, got %q", expected, i.ReapReason())
+	}
+
+	_, _, body, err := i.ReapableEventEmail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// quotes are escaped in HTML
+	if !strings.Contains(body.String(), "flagged by group &#39;old-untagged&#39; because LaunchTimeNotInTheLast 720h and NotTagged CostCenter") {
+		t.Errorf("expected the reason in the email, got %s", body.String())
+	}
+	text, err := i.ReapableEventText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), expected) {
+		t.Errorf("expected the reason in the event text, got %s", text.String())
+	}
+
+	i.AddFilterGroup("large", filters.FilterGroup{"1": *filters.NewFilter("InstanceTypeIs", []string{"m4.large"})})
+	expected = "flagged by group 'large' because InstanceTypeIs m4.large, and by group 'old-untagged' because LaunchTimeNotInTheLast 720h and NotTagged CostCenter"
+	if i.ReapReason() != expected {
+		t.Errorf("expected %q, got %q", expected, i.ReapReason())
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	defer SetConfig(config)
+	c := newTestConfig()
+	SetConfig(c)
+
+	var out bytes.Buffer
+	if err := RenderTemplates(&out); err != nil {
+		t.Fatalf("expected the built-in templates to render, got %s", err.Error())
+	}
+	for name := range templateNames {
+		if !strings.Contains(out.String(), "==> "+name+"\n") {
+			t.Errorf("expected %s to be rendered", name)
+		}
+	}
+
+	// a template that fails to execute is reported with its name
+	c.CustomTemplates = map[string]string{
+		"VolumeEventTextShort": "{{ .Volume.NoSuchField }}",
+		"ASGEventHTML":         "{{ .NoSuchField",
+	}
+	out.Reset()
+	err := RenderTemplates(&out)
+	if err == nil {
+		t.Fatal("expected broken templates to be an error")
+	}
+	for _, name := range []string{"VolumeEventTextShort", "ASGEventHTML"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected the error to name %s, got %s", name, err.Error())
+		}
+	}
+	if strings.Contains(err.Error(), "InstanceEventHTML") {
+		t.Errorf("expected only the broken templates in the error, got %s", err.Error())
+	}
+	if !strings.Contains(out.String(), "==> InstanceEventHTML\n") {
+		t.Error("expected the other templates to still be rendered")
+	}
+}
+
+func TestStopLabelInEvents(t *testing.T) {
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+
+	i := newTestInstance("i-1", map[string]string{"Owner": "owner@example.com"})
+	a := newTestAutoScalingGroup("asg")
+	a.Resource.Tags = map[string]string{"Owner": "owner@example.com"}
+
+	for _, test := range []struct {
+		name     string
+		event    func() (*bytes.Buffer, error)
+		expected string
+	}{
+		{"instance", i.ReapableEventText, "[Stop]("},
+		{"asg", a.ReapableEventText, "[Scale to 0]("},
+	} {
+		text, err := test.event()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(text.String(), test.expected) {
+			t.Errorf("%s: expected the default stop label %q, got %s", test.name, test.expected, text.String())
+		}
+	}
+
+	config.InstanceStopLabel = "Shut down"
+	config.AutoScalingGroupStopLabel = "Drain"
+	_, _, body, err := i.ReapableEventEmail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body.String(), ">Shut down</a>") {
+		t.Errorf("expected the InstanceStopLabel in the email, got %s", body.String())
+	}
+	text, err := a.ReapableEventTextShort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "[Drain](") {
+		t.Errorf("expected the AutoScalingGroupStopLabel in the event, got %s", text.String())
+	}
+}
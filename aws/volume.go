@@ -24,27 +24,44 @@ type Volume struct {
 	ec2.Volume
 
 	AttachedInstanceIDs []string
+	// the VPCs and subnets of the attached instances, set by the caller
+	// since a Volume doesn't have them itself
+	AttachedVpcIDs    []string
+	AttachedSubnetIDs []string
+	// whether any of the attached instances isn't stopped or terminated,
+	// assumed true for any attachment until set by the caller
+	AttachedToRunningInstance bool
+
+	// estimated, in USD, 0 if unknown
+	HourlyCost float64
 }
 
 // NewVolume creates an Volume from the AWS API's ec2.Volume
 func NewVolume(region string, vol *ec2.Volume) *Volume {
+	if vol.VolumeId == nil {
+		log.Warning("Skipping a Volume without a VolumeId in %s", region)
+		return nil
+	}
 	a := Volume{
 		Resource: Resource{
 			region: reapable.Region(region),
 			id:     reapable.ID(*vol.VolumeId),
 			Name:   *vol.VolumeId,
 			Tags:   make(map[string]string),
+
+			createEventName: "CreateVolume",
 		},
 		Volume: *vol,
 	}
 
 	for _, tag := range vol.Tags {
-		a.Resource.Tags[*tag.Key] = *tag.Value
+		a.Resource.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
 
 	if a.Tagged("aws:cloudformation:stack-name") {
 		a.Dependency = true
 		a.IsInCloudformation = true
+		a.CloudformationStackName = a.Tag("aws:cloudformation:stack-name")
 	}
 
 	for _, attachment := range vol.Attachments {
@@ -52,6 +69,7 @@ func NewVolume(region string, vol *ec2.Volume) *Volume {
 			a.AttachedInstanceIDs = append(a.AttachedInstanceIDs, *attachment.InstanceId)
 		}
 	}
+	a.AttachedToRunningInstance = len(a.AttachedInstanceIDs) > 0
 
 	if a.Tagged(reaperTag) {
 		// restore previously tagged state
@@ -66,39 +84,60 @@ func NewVolume(region string, vol *ec2.Volume) *Volume {
 	return &a
 }
 
+// EstimatedHourlyCost is part of the reapable.Costed interface
+func (a *Volume) EstimatedHourlyCost() (float64, bool) {
+	return a.HourlyCost, a.HourlyCost > 0
+}
+
+// ReapableDescription is a method of reapable.Reapable
+// it includes the Volume's age and estimated cost, see describe
+func (a *Volume) ReapableDescription() string {
+	return a.describe(a)
+}
+
+// CreatedAt is part of the reapable.Aged interface
+func (a *Volume) CreatedAt() (time.Time, bool) {
+	if a.CreateTime == nil {
+		return time.Time{}, false
+	}
+	return *a.CreateTime, true
+}
+
+// ReapableType is part of the reapable.Typed interface
+func (a *Volume) ReapableType() string {
+	return "Volume"
+}
+
 // ReapableEventText is part of the events.Reapable interface
 func (a *Volume) ReapableEventText() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableVolumeEventText)
+	return reapableEventText(a, "VolumeEventText", reapableVolumeEventText)
 }
 
 // ReapableEventTextShort is part of the events.Reapable interface
 func (a *Volume) ReapableEventTextShort() (*bytes.Buffer, error) {
-	return reapableEventText(a, reapableVolumeEventText)
+	return reapableEventText(a, "VolumeEventTextShort", reapableVolumeEventText)
 }
 
 // ReapableEventEmail is part of the events.Reapable interface
 func (a *Volume) ReapableEventEmail() (owner mail.Address, subject string, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-
 	subject = fmt.Sprintf("AWS Resource %s is going to be Reaped!", a.ReapableDescriptionTiny())
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableVolumeEventHTMLShort)
+	body, err = reapableEventHTML(a, "VolumeEventHTML", reapableVolumeEventHTMLShort)
 	return
 }
 
 // ReapableEventEmailShort is part of the events.Reapable interface
 func (a *Volume) ReapableEventEmailShort() (owner mail.Address, body *bytes.Buffer, err error) {
-	// if unowned, return unowned error
-	if !a.Owned() {
-		err = reapable.UnownedError{ErrorText: fmt.Sprintf("%s does not have an owner tag", a.ReapableDescriptionShort())}
+	// if unowned and there is no default owner, return unowned error
+	owner, err = a.emailOwner()
+	if err != nil {
 		return
 	}
-	owner = *a.Owner()
-	body, err = reapableEventHTML(a, reapableVolumeEventHTMLShort)
+	body, err = reapableEventHTML(a, "VolumeEventHTMLShort", reapableVolumeEventHTMLShort)
 	return
 }
 
@@ -156,8 +195,10 @@ const reapableVolumeEventHTML = `
 <body>
 	<p>Volume <a href="{{ .Volume.AWSConsoleURL }}">{{ if .Volume.Name }}"{{.Volume.Name}}" {{ end }} in {{.Volume.Region}}</a> is scheduled to be terminated.</p>
 
+	{{ with .Volume.ReapReason }}<p>It was {{ . }}.</p>{{ end }}
+
 	<p>
-		You can ignore this message and your Volume will advance to the next state after <strong>{{.Volume.ReaperState.Until}}</strong>. If you do not take action it will be terminated!
+		You can ignore this message and your Volume will advance to the next state after <strong>{{ until .Volume.ReaperState.Until }}</strong> ({{ relativeUntil .Volume.ReaperState.Until }}). If you do not take action it will be terminated!
 	</p>
 
 	<p>
@@ -180,26 +221,28 @@ const reapableVolumeEventHTML = `
 const reapableVolumeEventHTMLShort = `
 <html>
 <body>
-	<p>Volume <a href="{{ .Volume.AWSConsoleURL }}">{{ if .Volume.Name }}"{{.Volume.Name}}" {{ end }}</a> in {{.Volume.Region}}</a> is scheduled to be terminated after <strong>{{.Volume.ReaperState.Until}}</strong>.
+	<p>Volume <a href="{{ .Volume.AWSConsoleURL }}">{{ if .Volume.Name }}"{{.Volume.Name}}" {{ end }}</a> in {{.Volume.Region}}</a> is scheduled to be terminated after <strong>{{ until .Volume.ReaperState.Until }}</strong> ({{ relativeUntil .Volume.ReaperState.Until }}).
 		<br />
 		<a href="{{ .TerminateLink }}">Terminate</a>,
 		<a href="{{ .IgnoreLink1 }}">Ignore it for 1 more day</a>,
 		<a href="{{ .IgnoreLink3 }}">3 days</a>,
 		<a href="{{ .IgnoreLink7}}"> 7 days</a>,
 		<a href="{{ .WhitelistLink }}">Whitelist</a> it.
+		{{ with .Volume.ReapReason }}<br />It was {{ . }}.{{ end }}
 	</p>
 </body>
 </html>
 `
 
 const reapableVolumeEventTextShort = `%%%
-Volume [{{.Volume.ID}}]({{.Volume.AWSConsoleURL}}) in region: [{{.Volume.Region}}](https://{{.Volume.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Volume.Region}}).{{if .Volume.Owned}} Owned by {{.Volume.Owner}}.\n{{end}}
+Volume [{{.Volume.ID}}]({{.Volume.AWSConsoleURL}}) in region: [{{.Volume.Region}}](https://{{.Volume.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Volume.Region}}).{{if .Volume.Owner}} Owned by {{.Volume.Owner}}.\n{{end}}
 [Whitelist]({{ .WhitelistLink }}) or [Terminate]({{ .TerminateLink }}) this Volume.
 %%%`
 
 const reapableVolumeEventText = `%%%
 Reaper has discovered an Volume qualified as reapable: [{{.Volume.ID}}]({{.Volume.AWSConsoleURL}}) in region: [{{.Volume.Region}}](https://{{.Volume.Region}}.console.aws.amazon.com/ec2/v2/home?region={{.Volume.Region}}).\n
-{{if .Volume.Owned}}Owned by {{.Volume.Owner}}.\n{{end}}
+{{if .Volume.Owner}}Owned by {{.Volume.Owner}}.\n{{end}}
+{{ with .Volume.ReapReason }}It was {{ . }}.\n{{ end }}
 {{ if .Volume.AWSConsoleURL}}{{.Volume.AWSConsoleURL}}\n{{end}}
 [AWS Console URL]({{.Volume.AWSConsoleURL}})\n
 [Whitelist]({{ .WhitelistLink }}) this Volume.
@@ -274,10 +317,34 @@ func (a *Volume) Filter(filter filters.Filter) bool {
 		if !a.Tagged(filter.Arguments[0]) {
 			matched = true
 		}
+	case "MissingAnyTag":
+		if a.missingAnyTag(filter.Arguments) {
+			matched = true
+		}
+	case "MissingAllTags":
+		if a.missingAllTags(filter.Arguments) {
+			matched = true
+		}
 	case "TagNotEqual":
 		if a.Tag(filter.Arguments[0]) != filter.Arguments[1] {
 			matched = true
 		}
+	case "TagsAllEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) == len(pairs) {
+			matched = true
+		}
+	case "TagsAnyEqual":
+		if pairs, err := filter.TagPairsValue(); err == nil && a.tagsEqual(pairs) > 0 {
+			matched = true
+		}
+	case "TagInSet":
+		if a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
+	case "TagNotInSet":
+		if !a.tagInSet(filter.Arguments[0], filter.Arguments[1:]) {
+			matched = true
+		}
 	case "Region":
 		for region := range filter.Arguments {
 			if a.Region() == reapable.Region(region) {
@@ -290,20 +357,80 @@ func (a *Volume) Filter(filter filters.Filter) bool {
 				matched = false
 			}
 		}
+	case "EstimatedMonthlyCostGreaterThan":
+		if monthlyCostGreaterThan(a, &filter) {
+			matched = true
+		}
+	case "AttachedToRunningInstance":
+		if b, err := filter.BoolValue(0); err == nil && a.AttachedToRunningInstance == b {
+			matched = true
+		}
+	case "InVPC":
+		if anyIn(a.AttachedVpcIDs, filter.Arguments) {
+			matched = true
+		}
+	case "NotInVPC":
+		if !anyIn(a.AttachedVpcIDs, filter.Arguments) {
+			matched = true
+		}
+	case "InSubnet":
+		if anyIn(a.AttachedSubnetIDs, filter.Arguments) {
+			matched = true
+		}
+	case "HasRecentSnapshot":
+		d, err := filter.DurationValue(0)
+		if recent, ok := a.hasRecentSnapshot(d); err == nil && ok && recent {
+			matched = true
+		}
+	case "NoRecentSnapshot":
+		d, err := filter.DurationValue(0)
+		if recent, ok := a.hasRecentSnapshot(d); err == nil && ok && !recent {
+			matched = true
+		}
 	case "CreatedInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.CreateTime != nil && time.Since(*a.CreateTime) < d {
 			matched = true
 		}
 	case "CreatedNotInTheLast":
-		d, err := time.ParseDuration(filter.Arguments[0])
+		d, err := filter.DurationValue(0)
 		if err == nil && a.CreateTime != nil && time.Since(*a.CreateTime) > d {
 			matched = true
 		}
+	case "CreatedOutsideBusinessHours":
+		b, err := filter.BoolValue(0)
+		if outside, ok := createdOutsideBusinessHours(a); err == nil && ok && outside == b {
+			matched = true
+		}
+	// uses RFC3339 format
+	case "CreatedBefore":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.Before(t) {
+			matched = true
+		}
+	case "CreatedAfter":
+		t, err := filter.TimeValue(0)
+		if created, ok := a.CreatedAt(); err == nil && ok && created.After(t) {
+			matched = true
+		}
+	case "StateOlderThan":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.stateOlderThan(d, time.Now()) {
+			matched = true
+		}
+	case "UntilWithin":
+		d, err := filter.DurationValue(0)
+		if err == nil && a.untilWithin(d, time.Now()) {
+			matched = true
+		}
 	case "InCloudformation":
 		if b, err := filter.BoolValue(0); err == nil && a.IsInCloudformation == b {
 			matched = true
 		}
+	case "CloudformationStackNameMatches":
+		if a.cloudformationStackNameMatches(filter) {
+			matched = true
+		}
 	case "IsDependency":
 		if b, err := filter.BoolValue(0); err == nil && a.Dependency == b {
 			matched = true
@@ -338,7 +465,7 @@ func (a *Volume) Filter(filter filters.Filter) bool {
 			matched = true
 		}
 	default:
-		log.Error(fmt.Sprintf("No function %s could be found for filtering Volumes.", filter.Function))
+		filter.Fail(fmt.Errorf("No function %s could be found for filtering Volumes.", filter.Function))
 	}
 	return matched
 }
@@ -354,13 +481,35 @@ func (a *Volume) AWSConsoleURL() *url.URL {
 }
 
 // Terminate is a method of reapable.Terminable, which is embedded in reapable.Reapable
+// an attached Volume can't be deleted, so it is detached first, unless it is
+// attached to a running instance
 func (a *Volume) Terminate() (bool, error) {
 	log.Info("Terminating Volume ", a.ReapableDescriptionTiny())
-	api := ec2.New(sess, aws.NewConfig().WithRegion(string(a.Region())))
+	api := ec2Client(string(a.Region()))
+	if len(a.AttachedInstanceIDs) > 0 {
+		if a.AttachedToRunningInstance {
+			return false, fmt.Errorf("%s is attached to a running instance", a.ReapableDescriptionTiny())
+		}
+		// the instances may have been started since they were discovered
+		running, err := a.attachedToRunningInstance(api)
+		if err != nil {
+			return false, err
+		}
+		if running {
+			return false, fmt.Errorf("%s is attached to a running instance", a.ReapableDescriptionTiny())
+		}
+		if err := a.detach(api); err != nil {
+			log.Error("could not detach Volume ", a.ReapableDescriptionTiny())
+			return false, err
+		}
+	}
 	input := &ec2.DeleteVolumeInput{
 		VolumeId: aws.String(a.ID().String()),
 	}
-	_, err := api.DeleteVolume(input)
+	err := retry("delete Volume "+a.ReapableDescriptionTiny(), func() error {
+		_, err := api.DeleteVolume(input)
+		return err
+	})
 	if err != nil {
 		log.Error("could not delete Volume ", a.ReapableDescriptionTiny())
 		return false, err
@@ -368,6 +517,52 @@ func (a *Volume) Terminate() (bool, error) {
 	return true, nil
 }
 
+// attachedToRunningInstance describes the Volume's instances again, and
+// returns whether any of them is pending or running
+func (a *Volume) attachedToRunningInstance(api *ec2.EC2) (bool, error) {
+	input := &ec2.DescribeInstancesInput{}
+	for _, instanceID := range a.AttachedInstanceIDs {
+		input.InstanceIds = append(input.InstanceIds, aws.String(instanceID))
+	}
+	running := false
+	err := api.DescribeInstancesPages(input, func(resp *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, res := range resp.Reservations {
+			for _, instance := range res.Instances {
+				if instance.State == nil {
+					continue
+				}
+				switch aws.StringValue(instance.State.Name) {
+				case ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning:
+					running = true
+				}
+			}
+		}
+		return !lastPage
+	})
+	return running, err
+}
+
+// detach detaches the Volume from each of its instances, and waits until it
+// is available to be deleted
+func (a *Volume) detach(api *ec2.EC2) error {
+	for _, instanceID := range a.AttachedInstanceIDs {
+		input := &ec2.DetachVolumeInput{
+			VolumeId:   aws.String(a.ID().String()),
+			InstanceId: aws.String(instanceID),
+		}
+		err := retry("detach Volume "+a.ReapableDescriptionTiny(), func() error {
+			_, err := api.DetachVolume(input)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return api.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{
+		VolumeIds: []*string{aws.String(a.ID().String())},
+	})
+}
+
 // Stop is a method of reapable.Stoppable, which is embedded in reapable.Reapable
 // noop
 func (a *Volume) Stop() (bool, error) {
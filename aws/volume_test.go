@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestTerminateAttachedVolume(t *testing.T) {
+	var actions []string
+	instanceState := "stopped"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		action := req.Form.Get("Action")
+		actions = append(actions, action)
+		switch action {
+		case "DescribeInstances":
+			fmt.Fprintf(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet><item><instanceId>%s</instanceId><instanceState><name>%s</name></instanceState></item></instancesSet></item></reservationSet></DescribeInstancesResponse>`,
+				req.Form.Get("InstanceId.1"), instanceState)
+		case "DetachVolume":
+			fmt.Fprintf(w, `<DetachVolumeResponse><volumeId>%s</volumeId><instanceId>%s</instanceId><status>detaching</status></DetachVolumeResponse>`,
+				req.Form.Get("VolumeId"), req.Form.Get("InstanceId"))
+		case "DescribeVolumes":
+			fmt.Fprintf(w, `<DescribeVolumesResponse><volumeSet><item><volumeId>%s</volumeId><status>available</status></item></volumeSet></DescribeVolumesResponse>`,
+				req.Form.Get("VolumeId.1"))
+		case "DeleteVolume":
+			fmt.Fprint(w, `<DeleteVolumeResponse><return>true</return></DeleteVolumeResponse>`)
+		default:
+			http.Error(w, "unexpected action", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, "test")
+	}
+	defer SetConfig(config)
+	c := newTestConfig()
+	c.EndpointURL = server.URL
+	SetConfig(c)
+
+	newVolume := func() *Volume {
+		return NewVolume("us-west-2", &ec2.Volume{
+			VolumeId:    aws.String("vol-1"),
+			Attachments: []*ec2.VolumeAttachment{{InstanceId: aws.String("i-1")}},
+		})
+	}
+
+	// until the caller knows the instance is stopped, it's assumed running
+	if ok, err := newVolume().Terminate(); ok || err == nil {
+		t.Error("expected a volume attached to a running instance not to be terminated")
+	}
+	if len(actions) != 0 {
+		t.Errorf("expected no API calls, got %v", actions)
+	}
+
+	// an instance started since it was discovered is running
+	instanceState = "running"
+	v := newVolume()
+	v.AttachedToRunningInstance = false
+	if ok, err := v.Terminate(); ok || err == nil {
+		t.Error("expected a volume attached to an instance started since discovery not to be terminated")
+	}
+	if fmt.Sprint(actions) != fmt.Sprint([]string{"DescribeInstances"}) {
+		t.Errorf("expected only the instance to be described, got %v", actions)
+	}
+
+	instanceState = "stopped"
+	actions = nil
+	if ok, err := v.Terminate(); !ok || err != nil {
+		t.Fatalf("expected a volume attached to a stopped instance to be terminated, got %v", err)
+	}
+	expected := []string{"DescribeInstances", "DetachVolume", "DescribeVolumes", "DeleteVolume"}
+	if fmt.Sprint(actions) != fmt.Sprint(expected) {
+		t.Errorf("expected the volume to be detached before it's deleted, got %v", actions)
+	}
+}
@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+var (
+	// volumeSnapshots caches when each volume's most recent completed
+	// snapshot was started, by region and volume id
+	// a volume that was never snapshotted has a zero time
+	// it is cleared each cycle by AllVolumes
+	volumeSnapshots      = make(map[reapable.Region]map[reapable.ID]time.Time)
+	volumeSnapshotsMutex sync.Mutex
+)
+
+// clearVolumeSnapshots empties the cache of volumes' snapshots,
+// so that they are described again each cycle
+func clearVolumeSnapshots() {
+	volumeSnapshotsMutex.Lock()
+	defer volumeSnapshotsMutex.Unlock()
+	volumeSnapshots = make(map[reapable.Region]map[reapable.ID]time.Time)
+}
+
+// lastSnapshot returns when the Volume's most recent completed snapshot was
+// started, a zero time if it has none, and false if the lookup fails
+func (a *Volume) lastSnapshot() (time.Time, bool) {
+	// the lock is only held for the cache, so that a slow lookup doesn't
+	// hold up the other volumes'
+	volumeSnapshotsMutex.Lock()
+	last, ok := volumeSnapshots[a.region][a.id]
+	volumeSnapshotsMutex.Unlock()
+	if ok {
+		return last, true
+	}
+
+	last, err := lookupLastSnapshot(a.region, a.id)
+	if err != nil {
+		// don't cache failures, the next lookup may succeed
+		log.Error("Snapshot lookup for %s failed: %s", a.ReapableDescriptionTiny(), err.Error())
+		return time.Time{}, false
+	}
+
+	volumeSnapshotsMutex.Lock()
+	defer volumeSnapshotsMutex.Unlock()
+	if volumeSnapshots[a.region] == nil {
+		volumeSnapshots[a.region] = make(map[reapable.ID]time.Time)
+	}
+	volumeSnapshots[a.region][a.id] = last
+	return last, true
+}
+
+// lookupLastSnapshot describes the snapshots of the volume id, returning
+// when the most recent completed one was started
+// pending and failed snapshots aren't backups, so they are ignored
+func lookupLastSnapshot(region reapable.Region, id reapable.ID) (time.Time, error) {
+	var last time.Time
+	err := newEC2API(region.String()).DescribeSnapshotsPages(&ec2.DescribeSnapshotsInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{Name: aws.String("volume-id"), Values: []*string{aws.String(id.String())}},
+		},
+	}, func(resp *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+		for _, snapshot := range resp.Snapshots {
+			if aws.StringValue(snapshot.State) != ec2.SnapshotStateCompleted {
+				continue
+			}
+			if started := aws.TimeValue(snapshot.StartTime); started.After(last) {
+				last = started
+			}
+		}
+		return !lastPage
+	})
+	return last, err
+}
+
+// hasRecentSnapshot returns whether the Volume has a completed snapshot
+// started less than d ago, and false if the lookup fails
+func (a *Volume) hasRecentSnapshot(d time.Duration) (recent bool, ok bool) {
+	last, ok := a.lastSnapshot()
+	return ok && !last.IsZero() && time.Since(last) < d, ok
+}
@@ -0,0 +1,81 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// testEC2Snapshots describes snapshots from a fixed list
+// other methods of EC2API are not implemented
+type testEC2Snapshots struct {
+	ec2iface.EC2API
+	snapshots []*ec2.Snapshot
+	describes int
+}
+
+func (c *testEC2Snapshots) DescribeSnapshotsPages(input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool) error {
+	c.describes++
+	var snapshots []*ec2.Snapshot
+	for _, snapshot := range c.snapshots {
+		for _, filter := range input.Filters {
+			if *filter.Name == "volume-id" && *filter.Values[0] == *snapshot.VolumeId {
+				snapshots = append(snapshots, snapshot)
+			}
+		}
+	}
+	fn(&ec2.DescribeSnapshotsOutput{Snapshots: snapshots}, true)
+	return nil
+}
+
+func TestSnapshotFilters(t *testing.T) {
+	newSnapshot := func(volumeID, state string, age time.Duration) *ec2.Snapshot {
+		return &ec2.Snapshot{
+			VolumeId:  aws.String(volumeID),
+			State:     aws.String(state),
+			StartTime: aws.Time(time.Now().Add(-age)),
+		}
+	}
+	api := &testEC2Snapshots{snapshots: []*ec2.Snapshot{
+		newSnapshot("vol-recent", ec2.SnapshotStateCompleted, 30*24*time.Hour),
+		newSnapshot("vol-recent", ec2.SnapshotStateCompleted, time.Hour),
+		newSnapshot("vol-old", ec2.SnapshotStateCompleted, 30*24*time.Hour),
+		newSnapshot("vol-pending", ec2.SnapshotStatePending, time.Hour),
+	}}
+	defer SetConfig(config)
+	SetConfig(newTestConfig())
+	defer setTestEC2(api)()
+	clearVolumeSnapshots()
+
+	newVolume := func(id string) *Volume {
+		return NewVolume("us-west-2", &ec2.Volume{VolumeId: aws.String(id)})
+	}
+	recent := newVolume("vol-recent")
+	old := newVolume("vol-old")
+	pending := newVolume("vol-pending")
+	never := newVolume("vol-never")
+
+	hasRecent := *filters.NewFilter("HasRecentSnapshot", []string{"168h"})
+	noRecent := *filters.NewFilter("NoRecentSnapshot", []string{"168h"})
+	if !recent.Filter(hasRecent) || recent.Filter(noRecent) {
+		t.Error("expected a recently snapshotted volume to match HasRecentSnapshot(168h) only")
+	}
+	if old.Filter(hasRecent) || !old.Filter(noRecent) {
+		t.Error("expected a volume last snapshotted a month ago to match NoRecentSnapshot(168h) only")
+	}
+	if pending.Filter(hasRecent) || !pending.Filter(noRecent) {
+		t.Error("expected a volume with only a pending snapshot to match NoRecentSnapshot(168h) only")
+	}
+	if never.Filter(hasRecent) || !never.Filter(noRecent) {
+		t.Error("expected a never snapshotted volume to match NoRecentSnapshot(168h) only")
+	}
+
+	if api.describes != 4 {
+		t.Errorf("expected snapshots to be described once per volume, got %d describes", api.describes)
+	}
+}
@@ -6,9 +6,19 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/PagerDuty/godspeed"
+
+	"github.com/mozilla-services/reaper/reapable"
 	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
 )
 
+// sendDatadogEvent sends an event with godspeed
+// replaceable in tests
+var sendDatadogEvent = func(g *godspeed.Godspeed, title, text string, fields map[string]string, tags []string) error {
+	return g.Event(title, text, fields, tags)
+}
+
 // DatadogEvents implements EventReporter encapsulates Datadog, sends events to Datadog
 // uses godspeed, requires dd-agent running
 type DatadogEvents struct {
@@ -35,16 +45,34 @@ func (e *DatadogEvents) newEvent(title string, text string, fields map[string]st
 	if err != nil {
 		return err
 	}
-	err = g.Event(title, text, fields, tags)
+	err = sendDatadogEvent(g, title, text, fields, tags)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// ReapableEventTags returns region, type and owner tags for an event about r
+func ReapableEventTags(r reapable.Reapable) []string {
+	tags := []string{fmt.Sprintf("region:%s", r.Region())}
+	if typed, ok := r.(reapable.Typed); ok {
+		tags = append(tags, fmt.Sprintf("type:%s", typed.ReapableType()))
+	}
+	if owner := r.Owner(); owner != nil {
+		tags = append(tags, fmt.Sprintf("owner:%s", owner.Address))
+	}
+	return tags
+}
+
 // newReapableEvent is a method of EventReporter
 // newReapableEvent is shorthand for a newEvent about a reapable resource
+// resources that just entered the FinalState are also reported to the
+// audit timeline, whatever the Triggers
 func (e *DatadogEvents) newReapableEvent(r Reapable, tags []string) error {
+	if err := e.newFinalStateEvent(r, tags); err != nil {
+		return err
+	}
+
 	if e.Config.shouldTriggerFor(r) {
 		text, err := r.ReapableEventText()
 		if err != nil {
@@ -58,11 +86,32 @@ func (e *DatadogEvents) newReapableEvent(r Reapable, tags []string) error {
 	return nil
 }
 
+// newFinalStateEvent reports r to the audit timeline, with its own tags,
+// if it just entered the FinalState
+func (e *DatadogEvents) newFinalStateEvent(r Reapable, tags []string) error {
+	s := r.ReaperState()
+	if s.State != state.FinalState || !s.Updated {
+		return nil
+	}
+	err := e.newEvent("Reaper: Resource reached its final state",
+		fmt.Sprintf("%s will be terminated after %s", r.ReapableDescriptionShort(), s.Until.String()),
+		nil, append(ReapableEventTags(r), tags...))
+	if err != nil {
+		return fmt.Errorf("Error reporting final state event for %s: %s", r.ReapableDescriptionTiny(), err.Error())
+	}
+	return nil
+}
+
 // newBatchReapableEvent is a method of EventReporter
+// each resource that just entered the FinalState is reported to the audit
+// timeline on its own, as by newReapableEvent
 func (e *DatadogEvents) newBatchReapableEvent(rs []Reapable, tags []string) error {
 	errorStrings := []string{}
 	buffer := new(bytes.Buffer)
 	for _, r := range rs {
+		if err := e.newFinalStateEvent(r, tags); err != nil {
+			errorStrings = append(errorStrings, err.Error())
+		}
 		if !e.Config.shouldTriggerFor(r) {
 			continue
 		}
@@ -110,6 +159,9 @@ func (e *DatadogEvents) newStatistic(string, float64, []string) error {
 	return nil
 }
 
+// notifies is a method of notifier
+func (e *DatadogEvents) notifies() {}
+
 // GetConfig is a method of EventReporter
 func (e *DatadogEvents) GetConfig() EventReporterConfig {
 	return *e.Config.EventReporterConfig
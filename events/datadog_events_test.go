@@ -0,0 +1,125 @@
+package events
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/godspeed"
+
+	"github.com/mozilla-services/reaper/state"
+)
+
+// testDatadogEvent is an event sent to Datadog
+type testDatadogEvent struct {
+	title string
+	text  string
+	tags  []string
+}
+
+// recordDatadogEvents records the events sent to Datadog instead of sending
+// them, returning a func that restores sendDatadogEvent
+func recordDatadogEvents(recorded *[]testDatadogEvent) (restore func()) {
+	original := sendDatadogEvent
+	sendDatadogEvent = func(g *godspeed.Godspeed, title, text string, fields map[string]string, tags []string) error {
+		*recorded = append(*recorded, testDatadogEvent{title: title, text: text, tags: tags})
+		return nil
+	}
+	return func() { sendDatadogEvent = original }
+}
+
+func (r *testReapable) ReapableDescriptionShort() string { return "Instance i-1 in us-west-2" }
+func (r *testReapable) ReapableEventText() (*bytes.Buffer, error) {
+	return bytes.NewBufferString("Instance i-1 in us-west-2 is in the " + r.state.State.String()), nil
+}
+
+func newTestDatadogEvents(dryRun bool) *DatadogEvents {
+	return NewDatadogEvents(&DatadogConfig{
+		EventReporterConfig: &EventReporterConfig{
+			Enabled: true,
+			DryRun:  dryRun,
+		},
+		Host: "127.0.0.1",
+		Port: "8125",
+	})
+}
+
+func TestDatadogFinalStateEvent(t *testing.T) {
+	var recorded []testDatadogEvent
+	defer recordDatadogEvents(&recorded)()
+
+	until := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := state.NewStateWithUntilAndState(until, state.FinalState)
+	s.Updated = true
+	if err := newTestDatadogEvents(false).newReapableEvent(&testReapable{state: s}, []string{"env:test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorded) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(recorded))
+	}
+	e := recorded[0]
+	if e.title != "Reaper: Resource reached its final state" {
+		t.Errorf("unexpected title %q", e.title)
+	}
+	if !strings.HasPrefix(e.text, "Instance i-1 in us-west-2 will be terminated after 2016-01-01") {
+		t.Errorf("unexpected text %q", e.text)
+	}
+	expected := []string{"region:us-west-2", "type:Instance", "owner:jdoe@example.com", "env:test"}
+	if strings.Join(e.tags, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected tags %v, got %v", expected, e.tags)
+	}
+}
+
+func TestDatadogFinalStateEventDryRun(t *testing.T) {
+	var recorded []testDatadogEvent
+	defer recordDatadogEvents(&recorded)()
+
+	s := state.NewStateWithUntilAndState(time.Now(), state.FinalState)
+	s.Updated = true
+	if err := newTestDatadogEvents(true).newReapableEvent(&testReapable{state: s}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 0 {
+		t.Errorf("expected no events in dry run, got %v", recorded)
+	}
+}
+
+func TestDatadogNonFinalStateEvent(t *testing.T) {
+	var recorded []testDatadogEvent
+	defer recordDatadogEvents(&recorded)()
+
+	s := state.NewStateWithUntilAndState(time.Now(), state.SecondState)
+	s.Updated = true
+	if err := newTestDatadogEvents(false).newReapableEvent(&testReapable{state: s}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 0 {
+		t.Errorf("expected no events for a resource that isn't in the final state, got %v", recorded)
+	}
+}
+
+func TestDatadogBatchFinalStateEvents(t *testing.T) {
+	var recorded []testDatadogEvent
+	defer recordDatadogEvents(&recorded)()
+
+	newReapable := func(s state.StateEnum) *testReapable {
+		r := &testReapable{state: state.NewStateWithUntilAndState(time.Now(), s)}
+		r.state.Updated = true
+		return r
+	}
+	rs := []Reapable{newReapable(state.FinalState), newReapable(state.SecondState), newReapable(state.FinalState)}
+	if err := newTestDatadogEvents(false).newBatchReapableEvent(rs, []string{"env:test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorded) != 2 {
+		t.Fatalf("expected a final state event for each resource in the final state, got %v", recorded)
+	}
+	for _, e := range recorded {
+		if e.title != "Reaper: Resource reached its final state" || e.tags[0] != "region:us-west-2" {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+}
@@ -5,27 +5,34 @@ import (
 	"errors"
 	"net/mail"
 	"strings"
+	"time"
 
 	"github.com/mozilla-services/reaper/reapable"
 	log "github.com/mozilla-services/reaper/reaperlog"
 	"github.com/mozilla-services/reaper/state"
 )
 
-var eventReporters *[]EventReporter
-
-func SetEvents(e *[]EventReporter) {
-	eventReporters = e
-}
-
 func SetDryRun(DryRun bool) {
 	// set config values for events
-	for _, er := range *eventReporters {
+	for _, er := range Registered() {
 		er.setDryRun(DryRun)
 	}
 }
 
+// typeDryRun returns the DryRun override of actions on a Reapable's type,
+// and false if its type doesn't override DryRun
+var typeDryRun = func(r reapable.Reapable) (dryRun bool, overridden bool) {
+	return false, false
+}
+
+// SetTypeDryRun sets the per-type DryRun overrides of EventReporters
+// that act on resources, which take precedence over SetDryRun
+func SetTypeDryRun(f func(r reapable.Reapable) (dryRun bool, overridden bool)) {
+	typeDryRun = f
+}
+
 func Cleanup() {
-	for _, er := range *eventReporters {
+	for _, er := range Registered() {
 		c, ok := er.(Cleaner)
 		if ok {
 			if err := c.Cleanup(); err != nil {
@@ -37,7 +44,7 @@ func Cleanup() {
 
 func NewEvent(title string, text string, fields map[string]string, tags []string) error {
 	errorStrings := []string{}
-	for _, er := range *eventReporters {
+	for _, er := range Registered() {
 		err := er.newEvent(title, text, fields, tags)
 		if err != nil {
 			errorStrings = append(errorStrings, err.Error())
@@ -51,7 +58,7 @@ func NewEvent(title string, text string, fields map[string]string, tags []string
 
 func NewStatistic(name string, value float64, tags []string) error {
 	errorStrings := []string{}
-	for _, er := range *eventReporters {
+	for _, er := range Registered() {
 		err := er.newStatistic(name, value, tags)
 		if err != nil {
 			errorStrings = append(errorStrings, err.Error())
@@ -65,7 +72,7 @@ func NewStatistic(name string, value float64, tags []string) error {
 
 func NewCountStatistic(name string, tags []string) error {
 	errorStrings := []string{}
-	for _, er := range *eventReporters {
+	for _, er := range Registered() {
 		err := er.newCountStatistic(name, tags)
 		if err != nil {
 			errorStrings = append(errorStrings, err.Error())
@@ -79,7 +86,7 @@ func NewCountStatistic(name string, tags []string) error {
 
 func NewReapableEvent(r Reapable, tags []string) error {
 	errorStrings := []string{}
-	for _, er := range *eventReporters {
+	for _, er := range Registered() {
 		err := er.newReapableEvent(r, tags)
 		if err != nil {
 			errorStrings = append(errorStrings, err.Error())
@@ -93,7 +100,7 @@ func NewReapableEvent(r Reapable, tags []string) error {
 
 func NewBatchReapableEvent(rs []Reapable, tags []string) error {
 	errorStrings := []string{}
-	for _, er := range *eventReporters {
+	for _, er := range Registered() {
 		err := er.newBatchReapableEvent(rs, tags)
 		if err != nil {
 			errorStrings = append(errorStrings, err.Error())
@@ -105,9 +112,76 @@ func NewBatchReapableEvent(rs []Reapable, tags []string) error {
 	return nil
 }
 
+// RecordReapableEvents sends rs to the EventReporters that only record
+// resources, such as the Tagger, which aren't held back by quiet hours
+func RecordReapableEvents(rs []Reapable, tags []string) error {
+	return newReapableEvents(rs, tags, func(er EventReporter) bool {
+		_, ok := er.(recorder)
+		return ok
+	})
+}
+
+// NotifyReapableEvents sends rs to the EventReporters that notify owners,
+// such as the Mailer
+func NotifyReapableEvents(rs []Reapable, tags []string) error {
+	return newReapableEvents(rs, tags, func(er EventReporter) bool {
+		_, ok := er.(notifier)
+		return ok
+	})
+}
+
+// ActOnReapableEvents sends rs to the rest of the EventReporters, which act
+// on resources, such as the ReaperEvent
+func ActOnReapableEvents(rs []Reapable, tags []string) error {
+	return newReapableEvents(rs, tags, func(er EventReporter) bool {
+		_, records := er.(recorder)
+		_, notifies := er.(notifier)
+		return !records && !notifies
+	})
+}
+
+// newReapableEvents sends rs, as a single or batch reapable event, to the
+// EventReporters that include returns true for
+func newReapableEvents(rs []Reapable, tags []string, include func(EventReporter) bool) error {
+	errorStrings := []string{}
+	for _, er := range Registered() {
+		if !include(er) {
+			continue
+		}
+		var err error
+		if len(rs) == 1 {
+			err = er.newReapableEvent(rs[0], tags)
+		} else {
+			err = er.newBatchReapableEvent(rs, tags)
+		}
+		if err != nil {
+			errorStrings = append(errorStrings, err.Error())
+		}
+	}
+	if len(errorStrings) > 0 {
+		return errors.New(strings.Join(errorStrings, "\n"))
+	}
+	return nil
+}
+
 // NotificationsConfig wraps state.StatesConfig
 type NotificationsConfig struct {
 	state.StatesConfig
+
+	// unowned resources are sent to this address, if set
+	DefaultOwner string
+
+	// reapable events are deferred until QuietHours end
+	QuietHours QuietHoursConfig
+
+	// deadlines in reapable events are formatted with TimeLayout in TimeZone,
+	// the IANA name of a zone, defaults to UTC
+	TimeZone   string
+	TimeLayout string
+
+	// a resource is sent reapable events at most once per
+	// MinPerResourceInterval, however often its state advances
+	MinPerResourceInterval state.Duration
 }
 
 // Reapable expands upon the reapable.Reapable interface
@@ -119,6 +193,16 @@ type Reapable interface {
 	ReapableEventEmailShort() (mail.Address, *bytes.Buffer, error)
 }
 
+// MultiOwner is a Reapable that can have more than one owner
+type MultiOwner interface {
+	Owners() []mail.Address
+}
+
+// OwnerSnoozer is a Reapable that can link to ignoring all of its owner's resources
+type OwnerSnoozer interface {
+	SnoozeOwnerLink(time.Duration) (string, error)
+}
+
 // EventReporterConfig has configuration variables for EventReporters
 type EventReporterConfig struct {
 	Enabled bool
@@ -168,6 +252,18 @@ func (e *EventReporterConfig) shouldTriggerFor(r Reapable) bool {
 	return triggering
 }
 
+// recorder is an EventReporter that records resources, rather than
+// notifying owners or acting on resources
+type recorder interface {
+	records()
+}
+
+// notifier is an EventReporter that notifies owners of resources, whose
+// reapable events are throttled per resource
+type notifier interface {
+	notifies()
+}
+
 // Cleaner needs to be cleaned up
 type Cleaner interface {
 	Cleanup() error
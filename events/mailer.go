@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/mail"
 	"net/smtp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jordan-wright/email"
 
@@ -14,6 +16,12 @@ import (
 	log "github.com/mozilla-services/reaper/reaperlog"
 )
 
+// sendEmail sends an email with the SMTP server at addr
+// replaceable in tests
+var sendEmail = func(m *email.Email, addr string, auth smtp.Auth) error {
+	return m.Send(addr, auth)
+}
+
 // Mailer implements EventReporter, sends email
 // uses godspeed, requires dd-agent running
 type Mailer struct {
@@ -113,7 +121,11 @@ func NewMailer(c *MailerConfig) *Mailer {
 }
 
 // newReapableEvent is a method of EventReporter
+// resources whose owner prefers another NotificationChannel are not emailed
 func (e *Mailer) newReapableEvent(r Reapable, tags []string) error {
+	if len(routedTo("email", []Reapable{r})) == 0 {
+		return nil
+	}
 	if e.Config.shouldTriggerFor(r) {
 		addr, subject, body, err := r.ReapableEventEmail()
 		if err != nil {
@@ -126,27 +138,88 @@ func (e *Mailer) newReapableEvent(r Reapable, tags []string) error {
 			}
 			return err
 		}
-		return e.send(addr, subject, body)
+		return e.send(recipients(r, addr), subject, body)
 	}
 	return nil
 }
 
+// recipients returns all of a MultiOwner's owners,
+// or addr, the owner from ReapableEventEmail
+func recipients(r Reapable, addr mail.Address) []mail.Address {
+	if multi, ok := r.(MultiOwner); ok {
+		if owners := multi.Owners(); len(owners) > 0 {
+			return owners
+		}
+	}
+	return []mail.Address{addr}
+}
+
 // newBatchReapableEvent is a method of EventReporter
+// resources whose owner prefers another NotificationChannel are not emailed
+// resources with more than one owner may not share every recipient, so one
+// email is sent to each set of recipients
 func (e *Mailer) newBatchReapableEvent(rs []Reapable, tags []string) error {
+	rs = routedTo("email", rs)
 	errorStrings := []string{}
-	buffer := new(bytes.Buffer)
 
-	// owner is the same for all of these resources
-	owner, _, err := rs[0].ReapableEventEmailShort()
-	if err != nil {
-		return fmt.Errorf("Error getting resource owner with ReapableEventEmailShort: %s", err)
+	var keys []string
+	batches := make(map[string][]Reapable)
+	owners := make(map[string]mail.Address)
+	to := make(map[string][]mail.Address)
+	for _, r := range rs {
+		owner, _, err := r.ReapableEventEmailShort()
+		if err != nil {
+			errorStrings = append(errorStrings, fmt.Sprintf("Error getting resource owner with ReapableEventEmailShort: %s", err))
+			continue
+		}
+		recipients := recipients(r, owner)
+		key := recipientsKey(recipients)
+		if _, ok := batches[key]; !ok {
+			keys = append(keys, key)
+			owners[key] = owner
+			to[key] = recipients
+		}
+		batches[key] = append(batches[key], r)
+	}
+
+	for _, key := range keys {
+		if err := e.sendBatch(batches[key], owners[key], to[key]); err != nil {
+			errorStrings = append(errorStrings, err.Error())
+		}
+	}
+	if len(errorStrings) > 0 {
+		return errors.New(strings.Join(errorStrings, "\n"))
+	}
+	return nil
+}
+
+// recipientsKey identifies a set of recipients, whatever their order
+func recipientsKey(to []mail.Address) string {
+	var addresses []string
+	for _, addr := range to {
+		addresses = append(addresses, strings.ToLower(addr.Address))
 	}
+	sort.Strings(addresses)
+	return strings.Join(addresses, ",")
+}
+
+// sendBatch emails the resources that should trigger the Mailer to their
+// recipients, to, in one email addressed to owner
+// no email is sent if none of them should trigger it
+func (e *Mailer) sendBatch(rs []Reapable, owner mail.Address, to []mail.Address) error {
+	errorStrings := []string{}
+	buffer := new(bytes.Buffer)
 
 	subject := fmt.Sprintf("AWS Resources you own are going to be reaped!")
 	buffer.WriteString(
 		fmt.Sprintf("You are receiving this message because your email, "+
 			"%s, is associated with AWS resources that matched Reaper's filters.\n"+
 			"If you do not take action they will be stopped and then terminated!\n", owner.Address))
+	if snoozer, ok := rs[0].(OwnerSnoozer); ok {
+		if link, err := snoozer.SnoozeOwnerLink(7 * 24 * time.Hour); err == nil {
+			buffer.WriteString(fmt.Sprintf("<p><a href=\"%s\">Ignore all of your resources for 7 more days</a></p>\n", link))
+		}
+	}
 
 	// if none of these resources should trigger, we shouldn't send an email
 	triggering := false
@@ -154,14 +227,17 @@ func (e *Mailer) newBatchReapableEvent(rs []Reapable, tags []string) error {
 		if !e.Config.shouldTriggerFor(r) {
 			continue
 		}
-		triggering = true
 		_, body, err := r.ReapableEventEmailShort()
-		errorStrings = append(errorStrings, fmt.Sprintf("ReapableEventEmailShort: %s", err))
+		if err != nil {
+			errorStrings = append(errorStrings, fmt.Sprintf("ReapableEventEmailShort: %s", err))
+			continue
+		}
+		triggering = true
 		buffer.ReadFrom(body)
 		buffer.WriteString("\n")
 	}
 	if triggering {
-		return e.send(owner, subject, buffer)
+		return e.send(to, subject, buffer)
 	}
 	if len(errorStrings) > 0 {
 		return errors.New(strings.Join(errorStrings, "\n"))
@@ -170,22 +246,30 @@ func (e *Mailer) newBatchReapableEvent(rs []Reapable, tags []string) error {
 }
 
 // Send an HTML email
-func (e *Mailer) send(to mail.Address, subject string, htmlBody *bytes.Buffer) error {
+func (e *Mailer) send(to []mail.Address, subject string, htmlBody *bytes.Buffer) error {
+	var addresses, names []string
+	for _, addr := range to {
+		addresses = append(addresses, addr.Address)
+		names = append(names, addr.String())
+	}
 	log.Debug("Sending email to: \"%s\", from: \"%s\", subject: \"%s\"",
-		to.String(),
+		strings.Join(names, ", "),
 		e.Config.From.Address,
 		subject)
 
 	m := email.NewEmail()
 	m.From = e.Config.From.Address
-	m.To = []string{to.Address}
+	m.To = addresses
 	m.Bcc = e.Config.CopyEmailAddresses
 	m.Subject = subject
 	m.HTML = htmlBody.Bytes()
 
-	return m.Send(e.Config.Addr(), e.Config.Auth())
+	return sendEmail(m, e.Config.Addr(), e.Config.Auth())
 }
 
+// notifies is a method of notifier
+func (e *Mailer) notifies() {}
+
 // GetConfig is a method of EventReporter
 func (e *Mailer) GetConfig() EventReporterConfig {
 	return *e.Config.EventReporterConfig
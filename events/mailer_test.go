@@ -0,0 +1,61 @@
+package events
+
+import (
+	"bytes"
+	"net/mail"
+	"testing"
+
+	"github.com/jordan-wright/email"
+
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+// multiOwnedReapable is a routedReapable with several owners
+type multiOwnedReapable struct {
+	routedReapable
+	id     reapable.ID
+	owners []mail.Address
+}
+
+func (r *multiOwnedReapable) ID() reapable.ID        { return r.id }
+func (r *multiOwnedReapable) Owners() []mail.Address { return r.owners }
+func (r *multiOwnedReapable) ReapableEventEmailShort() (mail.Address, *bytes.Buffer, error) {
+	return *r.Owner(), bytes.NewBufferString(r.id.String()), nil
+}
+
+func TestBatchEmailRecipients(t *testing.T) {
+	var emails []*email.Email
+	defer recordEmails(&emails)()
+	mailer := NewMailer(&MailerConfig{
+		EventReporterConfig: &EventReporterConfig{Enabled: true, Triggers: []string{"first"}},
+		From:                FromAddress{Address: "reaper@example.com"},
+	})
+	defer setTestReporters(mailer)()
+
+	newReapable := func(id string, owners ...string) *multiOwnedReapable {
+		r := &multiOwnedReapable{routedReapable: *newRoutedReapable(""), id: reapable.ID(id)}
+		for _, owner := range owners {
+			r.owners = append(r.owners, mail.Address{Address: owner})
+		}
+		return r
+	}
+	rs := []Reapable{
+		newReapable("i-1", "jdoe@example.com", "team@example.com"),
+		newReapable("i-2", "jdoe@example.com"),
+		newReapable("i-3", "team@example.com", "jdoe@example.com"),
+	}
+	if err := mailer.newBatchReapableEvent(rs, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(emails) != 2 {
+		t.Fatalf("expected an email to each set of recipients, got %d", len(emails))
+	}
+	if len(emails[0].To) != 2 || !bytes.Contains(emails[0].HTML, []byte("i-1")) || !bytes.Contains(emails[0].HTML, []byte("i-3")) ||
+		bytes.Contains(emails[0].HTML, []byte("i-2")) {
+		t.Errorf("expected i-1 and i-3 to be emailed to both owners, got %v: %s", emails[0].To, emails[0].HTML)
+	}
+	if len(emails[1].To) != 1 || !bytes.Contains(emails[1].HTML, []byte("i-2")) || bytes.Contains(emails[1].HTML, []byte("i-1")) {
+		t.Errorf("expected only i-2 to be emailed to jdoe alone, got %v: %s", emails[1].To, emails[1].HTML)
+	}
+}
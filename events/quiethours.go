@@ -0,0 +1,119 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuietHoursConfig describes when reapable events must not be sent
+type QuietHoursConfig struct {
+	// Ranges are daily "15:04-15:04" ranges, which may wrap past midnight
+	// such as "19:00-07:00"
+	Ranges []string
+	// Days are weekdays that are quiet all day, such as "Saturday"
+	Days []string
+	// Timezone is the IANA name of the zone Ranges and Days are in, defaults to UTC
+	Timezone string
+}
+
+type quietRange struct {
+	start, end time.Duration
+}
+
+// parse returns the location, ranges and days of QuietHoursConfig
+func (q *QuietHoursConfig) parse() (*time.Location, []quietRange, map[time.Weekday]bool, error) {
+	location := time.UTC
+	if q.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(q.Timezone)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var ranges []quietRange
+	for _, r := range q.Ranges {
+		parts := strings.Split(r, "-")
+		if len(parts) != 2 {
+			return nil, nil, nil, fmt.Errorf("QuietHours range %q must be of the form 15:04-15:04", r)
+		}
+		start, err := time.Parse("15:04", strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("QuietHours range %q: %s", r, err.Error())
+		}
+		end, err := time.Parse("15:04", strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("QuietHours range %q: %s", r, err.Error())
+		}
+		ranges = append(ranges, quietRange{
+			start: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+			end:   time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+		})
+	}
+
+	days := make(map[time.Weekday]bool)
+	for _, d := range q.Days {
+		found := false
+		for w := time.Sunday; w <= time.Saturday; w++ {
+			if strings.EqualFold(w.String(), d) {
+				days[w] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, nil, nil, fmt.Errorf("QuietHours day %q is not a weekday", d)
+		}
+	}
+	return location, ranges, days, nil
+}
+
+// Validate returns an error if QuietHoursConfig can't be parsed
+func (q *QuietHoursConfig) Validate() error {
+	_, _, _, err := q.parse()
+	return err
+}
+
+// Quiet returns whether t is within quiet hours
+// an invalid QuietHoursConfig is never quiet, see Validate
+func (q *QuietHoursConfig) Quiet(t time.Time) bool {
+	location, ranges, days, err := q.parse()
+	if err != nil {
+		return false
+	}
+	return quiet(t.In(location), ranges, days)
+}
+
+// End returns the first time at or after t that is not within quiet hours
+// to the minute, or t if quiet hours never end
+func (q *QuietHoursConfig) End(t time.Time) time.Time {
+	location, ranges, days, err := q.parse()
+	if err != nil {
+		return t
+	}
+	// quiet hours repeat weekly
+	for end := t.In(location); end.Before(t.Add(8 * 24 * time.Hour)); end = end.Truncate(time.Minute).Add(time.Minute) {
+		if !quiet(end, ranges, days) {
+			return end
+		}
+	}
+	return t
+}
+
+func quiet(t time.Time, ranges []quietRange, days map[time.Weekday]bool) bool {
+	if days[t.Weekday()] {
+		return true
+	}
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	for _, r := range ranges {
+		if r.start <= r.end {
+			if sinceMidnight >= r.start && sinceMidnight < r.end {
+				return true
+			}
+		} else if sinceMidnight >= r.start || sinceMidnight < r.end {
+			// wraps past midnight
+			return true
+		}
+	}
+	return false
+}
@@ -14,9 +14,14 @@ type ReaperEventConfig struct {
 }
 
 // this is a copy of the method from events.go EXCEPT
-// that it triggers whether or not the state was updated this run
+// that it triggers whether or not the state was updated this run,
+// and the DryRun of the Reapable's type overrides the ReaperEvent's
 func (e *ReaperEventConfig) shouldTriggerFor(r Reapable) bool {
-	if e.DryRun {
+	dryRun := e.DryRun
+	if d, ok := typeDryRun(r); ok {
+		dryRun = d
+	}
+	if dryRun {
 		if log.Extras() {
 			log.Info("DryRun: Not triggering %s for %s", e.Name, r.ReapableDescriptionTiny())
 		}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mozilla-services/reaper/reapable"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// testTerminable is a testReapable that counts its terminations
+type testTerminable struct {
+	*testReapable
+	reapableType string
+	terminated   int
+}
+
+func (r *testTerminable) ReapableType() string { return r.reapableType }
+func (r *testTerminable) ReapableDescriptionShort() string {
+	return r.reapableType
+}
+func (r *testTerminable) Terminate() (bool, error) {
+	r.terminated++
+	return true, nil
+}
+
+// setTestTypeDryRun puts the types in dryRunTypes in DryRun mode,
+// returning a func that restores typeDryRun
+func setTestTypeDryRun(dryRunTypes ...string) (restore func()) {
+	original := typeDryRun
+	SetTypeDryRun(func(r reapable.Reapable) (bool, bool) {
+		for _, t := range dryRunTypes {
+			if r.(reapable.Typed).ReapableType() == t {
+				return true, true
+			}
+		}
+		return false, false
+	})
+	return func() { typeDryRun = original }
+}
+
+func TestReaperEventTypeDryRun(t *testing.T) {
+	defer setTestReporters()()
+	defer setTestTypeDryRun("Volume")()
+
+	e := NewReaperEvent(&ReaperEventConfig{
+		EventReporterConfig: &EventReporterConfig{Enabled: true, Triggers: []string{"final"}},
+		Mode:                "Terminate",
+	})
+	s := state.NewStateWithUntilAndState(time.Now(), state.FinalState)
+	instance := &testTerminable{testReapable: &testReapable{state: s}, reapableType: "Instance"}
+	volume := &testTerminable{testReapable: &testReapable{state: s}, reapableType: "Volume"}
+	for _, r := range []*testTerminable{instance, volume} {
+		if err := e.newReapableEvent(r, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if instance.terminated != 1 {
+		t.Errorf("expected the instance to be terminated, got %d terminations", instance.terminated)
+	}
+	if volume.terminated != 0 {
+		t.Errorf("expected no volume terminations in DryRun mode, got %d", volume.terminated)
+	}
+
+	// the type's DryRun overrides the ReaperEvent's
+	instance.terminated = 0
+	e.setDryRun(true)
+	SetTypeDryRun(func(r reapable.Reapable) (bool, bool) { return false, true })
+	if err := e.newReapableEvent(instance, nil); err != nil {
+		t.Fatal(err)
+	}
+	if instance.terminated != 1 {
+		t.Errorf("expected the instance's type to override DryRun, got %d terminations", instance.terminated)
+	}
+}
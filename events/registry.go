@@ -0,0 +1,40 @@
+package events
+
+import "sync"
+
+// registry holds the EventReporters that events, statistics and reapable
+// events are sent to
+var registry = &reporterRegistry{}
+
+// reporterRegistry is an ordered set of EventReporters
+type reporterRegistry struct {
+	sync.RWMutex
+	reporters []EventReporter
+}
+
+// Register adds an EventReporter, which is sent every event, statistic and
+// reapable event from then on, and is cleaned up by Cleanup
+// a new EventReporter needs no changes to the dispatch, only to be registered
+func Register(er EventReporter) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.reporters = append(registry.reporters, er)
+}
+
+// Registered returns the registered EventReporters, in the order they
+// were registered
+func Registered() []EventReporter {
+	registry.RLock()
+	defer registry.RUnlock()
+	return append([]EventReporter(nil), registry.reporters...)
+}
+
+// SetEvents replaces the registered EventReporters with e
+func SetEvents(e *[]EventReporter) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.reporters = nil
+	if e != nil {
+		registry.reporters = append(registry.reporters, *e...)
+	}
+}
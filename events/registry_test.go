@@ -0,0 +1,176 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+// setTestReporters replaces the registered EventReporters with ers,
+// returning a func that restores them
+func setTestReporters(ers ...EventReporter) (restore func()) {
+	original := Registered()
+	SetEvents(&ers)
+	return func() { SetEvents(&original) }
+}
+
+// fakeReporter is an EventReporter that records what it is sent
+type fakeReporter struct {
+	name       string
+	err        error
+	dryRun     bool
+	events     []string
+	statistics []string
+	counts     []string
+	reapables  int
+	batches    int
+	cleanedUp  int
+}
+
+func (f *fakeReporter) newEvent(title string, text string, fields map[string]string, tags []string) error {
+	f.events = append(f.events, title)
+	return f.err
+}
+func (f *fakeReporter) newStatistic(name string, value float64, tags []string) error {
+	f.statistics = append(f.statistics, name)
+	return f.err
+}
+func (f *fakeReporter) newCountStatistic(name string, tags []string) error {
+	f.counts = append(f.counts, name)
+	return f.err
+}
+func (f *fakeReporter) newReapableEvent(r Reapable, tags []string) error {
+	f.reapables++
+	return f.err
+}
+func (f *fakeReporter) newBatchReapableEvent(rs []Reapable, tags []string) error {
+	f.batches++
+	return f.err
+}
+func (f *fakeReporter) setDryRun(b bool) { f.dryRun = b }
+func (f *fakeReporter) GetConfig() EventReporterConfig {
+	return EventReporterConfig{Name: f.name, DryRun: f.dryRun}
+}
+func (f *fakeReporter) Cleanup() error {
+	f.cleanedUp++
+	return nil
+}
+
+func TestRegisteredReporters(t *testing.T) {
+	defer setTestReporters()()
+	first := &fakeReporter{name: "first"}
+	second := &fakeReporter{name: "second"}
+	Register(first)
+	Register(second)
+
+	if rs := Registered(); len(rs) != 2 || rs[0] != first || rs[1] != second {
+		t.Fatalf("expected both reporters registered in order, got %v", rs)
+	}
+
+	SetDryRun(true)
+	r := &testReapable{}
+	if err := NewEvent("title", "text", nil, nil); err != nil {
+		t.Error(err)
+	}
+	if err := NewStatistic("reaper.test", 1, nil); err != nil {
+		t.Error(err)
+	}
+	if err := NewCountStatistic("reaper.test.count", nil); err != nil {
+		t.Error(err)
+	}
+	if err := NewReapableEvent(r, nil); err != nil {
+		t.Error(err)
+	}
+	if err := NewBatchReapableEvent([]Reapable{r}, nil); err != nil {
+		t.Error(err)
+	}
+	Cleanup()
+
+	for _, f := range []*fakeReporter{first, second} {
+		if !f.dryRun {
+			t.Errorf("expected DryRun to be propagated to %s", f.name)
+		}
+		if len(f.events) != 1 || len(f.statistics) != 1 || len(f.counts) != 1 || f.reapables != 1 || f.batches != 1 {
+			t.Errorf("expected %s to receive each event once, got %d events, %d statistics, %d counts, %d reapable events and %d batches",
+				f.name, len(f.events), len(f.statistics), len(f.counts), f.reapables, f.batches)
+		}
+		if f.cleanedUp != 1 {
+			t.Errorf("expected %s to be cleaned up once, got %d", f.name, f.cleanedUp)
+		}
+	}
+
+	SetDryRun(false)
+	if first.dryRun || second.dryRun {
+		t.Error("expected DryRun to be disabled on both reporters")
+	}
+}
+
+func TestRegisteredReportersErrors(t *testing.T) {
+	defer setTestReporters()()
+	failing := &fakeReporter{name: "failing", err: errors.New("failed")}
+	working := &fakeReporter{name: "working"}
+	Register(failing)
+	Register(working)
+
+	if err := NewStatistic("reaper.test", 1, nil); err == nil {
+		t.Error("expected the failing reporter's error")
+	}
+	if len(working.statistics) != 1 {
+		t.Error("expected a failing reporter not to stop the others")
+	}
+}
+
+func TestNoRegisteredReporters(t *testing.T) {
+	defer setTestReporters()()
+	if err := NewEvent("title", "text", nil, nil); err != nil {
+		t.Errorf("expected no error without reporters, got %s", err.Error())
+	}
+}
+
+// fakeRecorder is a fakeReporter that only records resources
+type fakeRecorder struct {
+	fakeReporter
+}
+
+func (f *fakeRecorder) records() {}
+
+// fakeNotifier is a fakeReporter that notifies owners
+type fakeNotifier struct {
+	fakeReporter
+}
+
+func (f *fakeNotifier) notifies() {}
+
+func TestRecordNotifyAndActOnReapableEvents(t *testing.T) {
+	defer setTestReporters()()
+	recorder := &fakeRecorder{fakeReporter{name: "recorder"}}
+	notifier := &fakeNotifier{fakeReporter{name: "notifier"}}
+	actor := &fakeReporter{name: "actor"}
+	Register(recorder)
+	Register(notifier)
+	Register(actor)
+
+	r := &testReapable{}
+	if err := RecordReapableEvents([]Reapable{r}, nil); err != nil {
+		t.Error(err)
+	}
+	if recorder.reapables != 1 || notifier.reapables != 0 || actor.reapables != 0 {
+		t.Errorf("expected only the recorder to be sent recorded events, got %d, %d and %d",
+			recorder.reapables, notifier.reapables, actor.reapables)
+	}
+
+	if err := NotifyReapableEvents([]Reapable{r, r}, nil); err != nil {
+		t.Error(err)
+	}
+	if recorder.batches != 0 || notifier.batches != 1 || actor.batches != 0 {
+		t.Errorf("expected only the notifier to be sent notified batches, got %d, %d and %d",
+			recorder.batches, notifier.batches, actor.batches)
+	}
+
+	if err := ActOnReapableEvents([]Reapable{r}, nil); err != nil {
+		t.Error(err)
+	}
+	if recorder.reapables != 1 || notifier.reapables != 0 || actor.reapables != 1 {
+		t.Errorf("expected only the actor to be sent events to act on, got %d, %d and %d",
+			recorder.reapables, notifier.reapables, actor.reapables)
+	}
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotificationChannel is where an owner wants a resource's notifications,
+// such as the Slack channel #team, instead of every EventReporter
+type NotificationChannel struct {
+	// Reporter is the kind of EventReporter, email or slack
+	Reporter string
+	// Channel is where the EventReporter sends them, such as a Slack
+	// channel, or empty for its default
+	Channel string
+}
+
+// notificationReporters are the kinds of EventReporter that notify owners,
+// and that notifications can be routed to
+var notificationReporters = map[string]bool{
+	"email": true,
+	"slack": true,
+}
+
+// ParseNotificationChannel parses a preference such as email or slack:#team
+func ParseNotificationChannel(s string) (NotificationChannel, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	c := NotificationChannel{Reporter: strings.ToLower(strings.TrimSpace(parts[0]))}
+	if len(parts) == 2 {
+		c.Channel = strings.TrimSpace(parts[1])
+	}
+	if !notificationReporters[c.Reporter] {
+		return NotificationChannel{}, fmt.Errorf("Unknown notification channel %q, expected email or slack:<channel>", s)
+	}
+	return c, nil
+}
+
+// Routed is a Reapable whose owner may prefer where it is notified
+type Routed interface {
+	NotificationChannel() (NotificationChannel, bool)
+}
+
+// reporterKind returns the kind of NotificationChannel an EventReporter
+// notifies, or an empty string if it isn't one of notificationReporters
+func reporterKind(er EventReporter) string {
+	switch er.(type) {
+	case *Mailer:
+		return "email"
+	case *Slack:
+		return "slack"
+	}
+	return ""
+}
+
+// registeredKinds returns the kinds of NotificationChannel that a
+// registered EventReporter notifies
+func registeredKinds() map[string]bool {
+	kinds := make(map[string]bool)
+	for _, er := range Registered() {
+		if kind := reporterKind(er); kind != "" {
+			kinds[kind] = true
+		}
+	}
+	return kinds
+}
+
+// route returns the resources of one owner that the kind of EventReporter
+// notifies, by channel
+// a resource without a NotificationChannel takes that of another of its
+// owner's resources, so that one tagged resource routes all of them, and
+// otherwise goes to every EventReporter's default channel
+// a NotificationChannel whose kind of EventReporter isn't registered is
+// ignored, so that its resources are still notified
+func route(kind string, rs []Reapable) map[string][]Reapable {
+	kinds := registeredKinds()
+	preferred := func(r Reapable) (NotificationChannel, bool) {
+		if routed, ok := r.(Routed); ok {
+			if c, ok := routed.NotificationChannel(); ok && kinds[c.Reporter] {
+				return c, true
+			}
+		}
+		return NotificationChannel{}, false
+	}
+
+	var ownerChannel *NotificationChannel
+	for _, r := range rs {
+		if c, ok := preferred(r); ok {
+			ownerChannel = &c
+			break
+		}
+	}
+
+	channels := make(map[string][]Reapable)
+	for _, r := range rs {
+		c := NotificationChannel{Reporter: kind}
+		if ownerChannel != nil {
+			c = *ownerChannel
+		}
+		if own, ok := preferred(r); ok {
+			c = own
+		}
+		if c.Reporter == kind {
+			channels[c.Channel] = append(channels[c.Channel], r)
+		}
+	}
+	return channels
+}
+
+// routedTo returns the resources of one owner that the kind of EventReporter
+// notifies, whatever their channel
+func routedTo(kind string, rs []Reapable) []Reapable {
+	routed := make(map[Reapable]bool)
+	for _, channel := range route(kind, rs) {
+		for _, r := range channel {
+			routed[r] = true
+		}
+	}
+	// in their original order
+	var ordered []Reapable
+	for _, r := range rs {
+		if routed[r] {
+			ordered = append(ordered, r)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"bytes"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/jordan-wright/email"
+
+	"github.com/mozilla-services/reaper/state"
+)
+
+// routedReapable is a testReapable tagged with a NotificationChannel
+type routedReapable struct {
+	testReapable
+	channel string
+}
+
+func (r *routedReapable) NotificationChannel() (NotificationChannel, bool) {
+	if r.channel == "" {
+		return NotificationChannel{}, false
+	}
+	c, err := ParseNotificationChannel(r.channel)
+	return c, err == nil
+}
+
+func (r *routedReapable) ReapableEventEmail() (mail.Address, string, *bytes.Buffer, error) {
+	return *r.Owner(), "subject", bytes.NewBufferString("body"), nil
+}
+
+func newRoutedReapable(channel string) *routedReapable {
+	s := state.NewStateWithUntilAndState(state.NewState().Until, state.FirstState)
+	s.Updated = true
+	return &routedReapable{testReapable: testReapable{state: s}, channel: channel}
+}
+
+// recordSlackMessages replaces postSlackMessage with a func that records
+// messages, returning a func that restores it
+func recordSlackMessages(recorded *[]slackMessage) (restore func()) {
+	original := postSlackMessage
+	postSlackMessage = func(url string, m slackMessage) error {
+		*recorded = append(*recorded, m)
+		return nil
+	}
+	return func() { postSlackMessage = original }
+}
+
+// recordEmails replaces sendEmail with a func that records emails,
+// returning a func that restores it
+func recordEmails(recorded *[]*email.Email) (restore func()) {
+	original := sendEmail
+	sendEmail = func(m *email.Email, addr string, auth smtp.Auth) error {
+		*recorded = append(*recorded, m)
+		return nil
+	}
+	return func() { sendEmail = original }
+}
+
+func TestParseNotificationChannel(t *testing.T) {
+	for s, expected := range map[string]NotificationChannel{
+		"email":         {Reporter: "email"},
+		" Slack:#team ": {Reporter: "slack", Channel: "#team"},
+		"slack":         {Reporter: "slack"},
+	} {
+		if c, err := ParseNotificationChannel(s); err != nil || c != expected {
+			t.Errorf("expected %q to parse as %v, got %v, %v", s, expected, c, err)
+		}
+	}
+	for _, s := range []string{"", "pager:ops", ":#team"} {
+		if _, err := ParseNotificationChannel(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	defer setTestReporters(NewMailer(&MailerConfig{EventReporterConfig: &EventReporterConfig{}}),
+		NewSlack(&SlackConfig{EventReporterConfig: &EventReporterConfig{}}))()
+
+	untagged := newRoutedReapable("")
+	if len(routedTo("email", []Reapable{untagged})) != 1 || len(routedTo("slack", []Reapable{untagged})) != 1 {
+		t.Error("expected an untagged resource to be routed to every reporter")
+	}
+
+	slack := newRoutedReapable("slack:#team")
+	email := newRoutedReapable("email")
+	if routed := routedTo("email", []Reapable{slack, email}); len(routed) != 1 || routed[0] != email {
+		t.Errorf("expected only the email tagged resource to be emailed, got %v", routed)
+	}
+	if channels := route("slack", []Reapable{slack, email}); len(channels) != 1 || len(channels["#team"]) != 1 {
+		t.Errorf("expected only the slack tagged resource to be posted to #team, got %v", channels)
+	}
+
+	// untagged resources of the same owner take the tagged resource's channel
+	if routed := routedTo("email", []Reapable{untagged, slack}); len(routed) != 0 {
+		t.Errorf("expected no resources of an owner preferring slack to be emailed, got %v", routed)
+	}
+	if channels := route("slack", []Reapable{untagged, slack}); len(channels["#team"]) != 2 {
+		t.Errorf("expected all of the owner's resources to be posted to #team, got %v", channels)
+	}
+}
+
+func TestRoutedNotifications(t *testing.T) {
+	var messages []slackMessage
+	defer recordSlackMessages(&messages)()
+	var emails []*email.Email
+	defer recordEmails(&emails)()
+
+	config := func() *EventReporterConfig {
+		return &EventReporterConfig{Enabled: true, Triggers: []string{"first"}}
+	}
+	slack := NewSlack(&SlackConfig{EventReporterConfig: config(), WebhookURL: "http://localhost/hook", Channel: "#reaper"})
+	mailer := NewMailer(&MailerConfig{EventReporterConfig: config(), From: FromAddress{Address: "reaper@example.com"}})
+	defer setTestReporters(slack, mailer)()
+
+	for _, r := range []Reapable{newRoutedReapable("slack:#team"), newRoutedReapable("email"), newRoutedReapable("")} {
+		if err := slack.newReapableEvent(r, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := mailer.newReapableEvent(r, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(messages) != 2 || messages[0].Channel != "#team" || messages[1].Channel != "#reaper" {
+		t.Fatalf("expected messages to #team and the default #reaper, got %v", messages)
+	}
+	if !strings.Contains(messages[0].Text, "Instance i-1 in us-west-2") || !strings.Contains(messages[0].Text, "jdoe@example.com") {
+		t.Errorf("expected the message to describe the resource and its owner, got %q", messages[0].Text)
+	}
+	if !strings.Contains(messages[0].Text, "<http://localhost/?t=terminate|Terminate>") {
+		t.Errorf("expected the message to link to terminating the resource, got %q", messages[0].Text)
+	}
+	if len(emails) != 2 {
+		t.Errorf("expected the email tagged and untagged resources to be emailed, got %d emails", len(emails))
+	}
+}
+
+func TestRouteWithoutSlack(t *testing.T) {
+	var emails []*email.Email
+	defer recordEmails(&emails)()
+	mailer := NewMailer(&MailerConfig{
+		EventReporterConfig: &EventReporterConfig{Enabled: true, Triggers: []string{"first"}},
+		From:                FromAddress{Address: "reaper@example.com"},
+	})
+	defer setTestReporters(mailer)()
+
+	slack := newRoutedReapable("slack:#team")
+	if routed := routedTo("email", []Reapable{slack}); len(routed) != 1 {
+		t.Errorf("expected a resource routed to slack to be emailed without a Slack reporter, got %v", routed)
+	}
+	if err := mailer.newReapableEvent(slack, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 {
+		t.Errorf("expected the resource routed to slack to be emailed, got %d emails", len(emails))
+	}
+}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// SlackConfig is the configuration for a Slack EventReporter
+type SlackConfig struct {
+	*EventReporterConfig
+
+	// WebhookURL is a Slack incoming webhook
+	WebhookURL string
+	// Channel is where resources without a NotificationChannel are posted,
+	// the webhook's own channel if empty
+	Channel string
+}
+
+// slackMessage is the body of a message posted to a Slack incoming webhook
+type slackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+// postSlackMessage posts a message to a Slack incoming webhook
+// replaceable in tests
+var postSlackMessage = func(url string, m slackMessage) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Slack implements EventReporter, posts reapable events to Slack
+// resources are posted to the channel of their NotificationChannel,
+// or to Channel
+type Slack struct {
+	Config *SlackConfig
+}
+
+// NewSlack returns a new instance of Slack
+func NewSlack(c *SlackConfig) *Slack {
+	c.Name = "Slack"
+	return &Slack{c}
+}
+
+// setDryRun is a method of EventReporter
+func (e *Slack) setDryRun(b bool) {
+	e.Config.DryRun = b
+}
+
+// notifies is a method of notifier
+func (e *Slack) notifies() {}
+
+// GetConfig is a method of EventReporter
+func (e *Slack) GetConfig() EventReporterConfig {
+	return *e.Config.EventReporterConfig
+}
+
+// newReapableEvent is a method of EventReporter
+func (e *Slack) newReapableEvent(r Reapable, tags []string) error {
+	return e.newBatchReapableEvent([]Reapable{r}, tags)
+}
+
+// newBatchReapableEvent is a method of EventReporter
+// one message is posted to each channel the owner's resources are routed to
+func (e *Slack) newBatchReapableEvent(rs []Reapable, tags []string) error {
+	var errorStrings []string
+	for channel, routed := range route("slack", rs) {
+		var lines []string
+		for _, r := range routed {
+			if e.Config.shouldTriggerFor(r) {
+				lines = append(lines, "• "+r.ReapableDescriptionShort()+slackLinks(r))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if channel == "" {
+			channel = e.Config.Channel
+		}
+
+		owner := "nobody"
+		if o := routed[0].Owner(); o != nil {
+			owner = o.Address
+		}
+		text := fmt.Sprintf("Reaper: resources owned by %s matched Reaper's filters and will be reaped if no action is taken:\n%s",
+			owner, strings.Join(lines, "\n"))
+		if err := postSlackMessage(e.Config.WebhookURL, slackMessage{Channel: channel, Text: text}); err != nil {
+			log.Error("Could not post to Slack channel %q: %s", channel, err.Error())
+			errorStrings = append(errorStrings, err.Error())
+		}
+	}
+	if len(errorStrings) > 0 {
+		return errors.New(strings.Join(errorStrings, "\n"))
+	}
+	return nil
+}
+
+// slackLinks returns the links to the actions that can be taken on a
+// Linker, as Slack formats them, or an empty string if it has none
+func slackLinks(r Reapable) string {
+	linker, ok := r.(Linker)
+	if !ok {
+		return ""
+	}
+	links, err := linker.ReapableLinks()
+	if err != nil {
+		log.Error("Could not make the links of %s: %s", r.ReapableDescriptionTiny(), err.Error())
+		return ""
+	}
+	var formatted []string
+	for _, link := range []struct{ name, text string }{
+		{"ignore1", "Ignore for 1 day"},
+		{"ignore7", "Ignore for 7 days"},
+		{"whitelist", "Whitelist"},
+		{"terminate", "Terminate"},
+	} {
+		if url, ok := links[link.name]; ok {
+			formatted = append(formatted, fmt.Sprintf("<%s|%s>", url, link.text))
+		}
+	}
+	if len(formatted) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(formatted, " | ") + ")"
+}
+
+// newEvent is a method of EventReporter
+func (e *Slack) newEvent(string, string, map[string]string, []string) error {
+	return nil
+}
+
+// newStatistic is a method of EventReporter
+func (e *Slack) newStatistic(string, float64, []string) error {
+	return nil
+}
+
+// newCountStatistic is a method of EventReporter
+func (e *Slack) newCountStatistic(string, []string) error {
+	return nil
+}
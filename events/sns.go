@@ -0,0 +1,219 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+var (
+	// snsSession is shared by the default SNS clients
+	snsSession = session.New()
+
+	// newSNSAPI returns an SNS client for a region
+	// replaceable in tests, and set to the aws package's cached clients
+	// by SetSNSAPI
+	newSNSAPI = func(region string) snsiface.SNSAPI {
+		return sns.New(snsSession, aws.NewConfig().WithRegion(region))
+	}
+)
+
+// SetSNSAPI sets the SNS clients of the SNSEventReporter, so that they
+// share the configured endpoint and client cache of other AWS services
+func SetSNSAPI(f func(region string) snsiface.SNSAPI) {
+	newSNSAPI = f
+}
+
+// Linker is a Reapable that can link to the actions that can be taken on it
+type Linker interface {
+	ReapableLinks() (map[string]string, error)
+}
+
+// SNSConfig is the configuration for an SNSEventReporter
+type SNSConfig struct {
+	*EventReporterConfig
+
+	// reapable events and events are published to TopicARN
+	TopicARN string
+	// statistics are published to MetricsTopicARN, if set
+	MetricsTopicARN string
+}
+
+// SNSEventReporter implements EventReporter, publishes JSON messages to SNS topics
+type SNSEventReporter struct {
+	Config *SNSConfig
+}
+
+// NewSNSEventReporter returns a new instance of SNSEventReporter
+func NewSNSEventReporter(c *SNSConfig) *SNSEventReporter {
+	c.Name = "SNSEventReporter"
+	return &SNSEventReporter{c}
+}
+
+// snsReapableMessage is the body of a reapable event published to SNS
+type snsReapableMessage struct {
+	ID     string            `json:"id"`
+	Region string            `json:"region"`
+	Type   string            `json:"type,omitempty"`
+	Owner  string            `json:"owner,omitempty"`
+	State  string            `json:"state"`
+	Until  time.Time         `json:"until"`
+	Links  map[string]string `json:"links,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+}
+
+// snsStatisticMessage is the body of a statistic published to SNS
+type snsStatisticMessage struct {
+	Name  string   `json:"name"`
+	Value float64  `json:"value"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// regionFromARN returns the region of an ARN such as
+// arn:aws:sns:us-west-2:123456789012:reaper
+func regionFromARN(arn string) (string, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 || parts[0] != "arn" || parts[3] == "" {
+		return "", fmt.Errorf("%s is not a valid ARN", arn)
+	}
+	return parts[3], nil
+}
+
+// publish publishes a JSON message with string attributes to a topic
+func (e *SNSEventReporter) publish(topicARN, subject string, message interface{}, attributes map[string]string) error {
+	region, err := regionFromARN(topicARN)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	input := &sns.PublishInput{
+		TopicArn:          aws.String(topicARN),
+		Subject:           aws.String(subject),
+		Message:           aws.String(string(body)),
+		MessageAttributes: make(map[string]*sns.MessageAttributeValue),
+	}
+	for key, value := range attributes {
+		// SNS rejects attributes with empty values
+		if value == "" {
+			continue
+		}
+		input.MessageAttributes[key] = &sns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	_, err = newSNSAPI(region).Publish(input)
+	return err
+}
+
+// setDryRun is a method of EventReporter
+func (e *SNSEventReporter) setDryRun(b bool) {
+	e.Config.DryRun = b
+}
+
+// notifies is a method of notifier
+func (e *SNSEventReporter) notifies() {}
+
+// GetConfig is a method of EventReporter
+func (e *SNSEventReporter) GetConfig() EventReporterConfig {
+	return *e.Config.EventReporterConfig
+}
+
+// newReapableEvent is a method of EventReporter
+func (e *SNSEventReporter) newReapableEvent(r Reapable, tags []string) error {
+	if !e.Config.shouldTriggerFor(r) {
+		return nil
+	}
+
+	message := snsReapableMessage{
+		ID:     r.ID().String(),
+		Region: r.Region().String(),
+		State:  r.ReaperState().State.String(),
+		Until:  r.ReaperState().Until,
+		Tags:   tags,
+	}
+	if typed, ok := r.(reapable.Typed); ok {
+		message.Type = typed.ReapableType()
+	}
+	if owner := r.Owner(); owner != nil {
+		message.Owner = owner.Address
+	}
+	if linker, ok := r.(Linker); ok {
+		links, err := linker.ReapableLinks()
+		if err != nil {
+			return err
+		}
+		message.Links = links
+	}
+
+	log.Info("SNSEventReporter: publishing %s to %s", r.ReapableDescriptionTiny(), e.Config.TopicARN)
+	return e.publish(e.Config.TopicARN, fmt.Sprintf("Reaper: %s is %s", r.ReapableDescriptionTiny(), message.State),
+		message, map[string]string{
+			"event":  "reapable",
+			"type":   message.Type,
+			"region": message.Region,
+			"state":  message.State,
+		})
+}
+
+// newBatchReapableEvent is a method of EventReporter
+func (e *SNSEventReporter) newBatchReapableEvent(rs []Reapable, tags []string) error {
+	for _, r := range rs {
+		err := e.newReapableEvent(r, tags)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newEvent is a method of EventReporter
+func (e *SNSEventReporter) newEvent(title string, text string, fields map[string]string, tags []string) error {
+	if e.Config.DryRun {
+		if log.Extras() {
+			log.Info("DryRun: Not publishing %s", title)
+		}
+		return nil
+	}
+	return e.publish(e.Config.TopicARN, title, map[string]interface{}{
+		"title":  title,
+		"text":   text,
+		"fields": fields,
+		"tags":   tags,
+	}, map[string]string{"event": "event"})
+}
+
+// newStatistic is a method of EventReporter
+func (e *SNSEventReporter) newStatistic(name string, value float64, tags []string) error {
+	if e.Config.MetricsTopicARN == "" {
+		return nil
+	}
+	if e.Config.DryRun {
+		if log.Extras() {
+			log.Info("DryRun: Not reporting %s", name)
+		}
+		return nil
+	}
+	return e.publish(e.Config.MetricsTopicARN, name,
+		snsStatisticMessage{Name: name, Value: value, Tags: tags},
+		map[string]string{"event": "statistic", "name": name})
+}
+
+// newCountStatistic is a method of EventReporter
+// counts are published as statistics with a value of 1
+func (e *SNSEventReporter) newCountStatistic(name string, tags []string) error {
+	return e.newStatistic(name, 1, tags)
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"encoding/json"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+
+	"github.com/mozilla-services/reaper/reapable"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// testSNS records Publish calls
+// other methods of SNSAPI are not implemented
+type testSNS struct {
+	snsiface.SNSAPI
+	region    string
+	published []*sns.PublishInput
+}
+
+func (c *testSNS) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	c.published = append(c.published, input)
+	return &sns.PublishOutput{MessageId: aws.String("1")}, nil
+}
+
+// setTestSNS replaces the SNS client, returning a func that restores it
+func setTestSNS(api *testSNS) (restore func()) {
+	original := newSNSAPI
+	newSNSAPI = func(region string) snsiface.SNSAPI {
+		api.region = region
+		return api
+	}
+	return func() { newSNSAPI = original }
+}
+
+// testReapable implements the parts of Reapable, reapable.Typed and Linker
+// the SNSEventReporter uses
+type testReapable struct {
+	Reapable
+	state *state.State
+}
+
+func (r *testReapable) ID() reapable.ID                 { return "i-1" }
+func (r *testReapable) Region() reapable.Region         { return "us-west-2" }
+func (r *testReapable) ReaperState() *state.State       { return r.state }
+func (r *testReapable) Owner() *mail.Address            { return &mail.Address{Address: "jdoe@example.com"} }
+func (r *testReapable) ReapableDescriptionTiny() string { return "i-1" }
+func (r *testReapable) ReapableType() string            { return "Instance" }
+func (r *testReapable) ReapableLinks() (map[string]string, error) {
+	return map[string]string{"terminate": "http://localhost/?t=terminate"}, nil
+}
+
+func newTestSNSEventReporter(dryRun bool) *SNSEventReporter {
+	return NewSNSEventReporter(&SNSConfig{
+		EventReporterConfig: &EventReporterConfig{
+			Enabled:  true,
+			DryRun:   dryRun,
+			Triggers: []string{"first"},
+		},
+		TopicARN:        "arn:aws:sns:us-west-2:123456789012:reaper-events",
+		MetricsTopicARN: "arn:aws:sns:us-east-1:123456789012:reaper-metrics",
+	})
+}
+
+func TestSNSReapableEvent(t *testing.T) {
+	api := &testSNS{}
+	defer setTestSNS(api)()
+
+	until := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := state.NewStateWithUntilAndState(until, state.FirstState)
+	s.Updated = true
+	if err := newTestSNSEventReporter(false).newReapableEvent(&testReapable{state: s}, []string{"env:test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(api.published) != 1 {
+		t.Fatalf("expected a single message, got %d", len(api.published))
+	}
+	input := api.published[0]
+	if api.region != "us-west-2" || aws.StringValue(input.TopicArn) != "arn:aws:sns:us-west-2:123456789012:reaper-events" {
+		t.Errorf("expected the events topic in us-west-2, got %s in %s", aws.StringValue(input.TopicArn), api.region)
+	}
+	for key, expected := range map[string]string{"event": "reapable", "type": "Instance", "region": "us-west-2", "state": "FirstState"} {
+		if attribute, ok := input.MessageAttributes[key]; !ok || aws.StringValue(attribute.StringValue) != expected {
+			t.Errorf("expected message attribute %s to be %s, got %v", key, expected, attribute)
+		}
+	}
+
+	var message snsReapableMessage
+	if err := json.Unmarshal([]byte(aws.StringValue(input.Message)), &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.ID != "i-1" || message.Owner != "jdoe@example.com" || message.State != "FirstState" ||
+		!message.Until.Equal(until) || message.Links["terminate"] == "" {
+		t.Errorf("unexpected message %+v", message)
+	}
+}
+
+func TestSNSDryRun(t *testing.T) {
+	api := &testSNS{}
+	defer setTestSNS(api)()
+
+	s := state.NewStateWithUntilAndState(time.Now(), state.FirstState)
+	s.Updated = true
+	e := newTestSNSEventReporter(true)
+	e.newReapableEvent(&testReapable{state: s}, nil)
+	e.newStatistic("reaper.instances.total", 1, nil)
+	if len(api.published) != 0 {
+		t.Errorf("expected nothing to be published in dry run mode, got %d messages", len(api.published))
+	}
+}
+
+func TestSNSStatistic(t *testing.T) {
+	api := &testSNS{}
+	defer setTestSNS(api)()
+
+	if err := newTestSNSEventReporter(false).newStatistic("reaper.instances.total", 3, []string{"region:us-west-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.published) != 1 || aws.StringValue(api.published[0].TopicArn) != "arn:aws:sns:us-east-1:123456789012:reaper-metrics" {
+		t.Fatalf("expected a message to the metrics topic, got %v", api.published)
+	}
+	var message snsStatisticMessage
+	if err := json.Unmarshal([]byte(aws.StringValue(api.published[0].Message)), &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Name != "reaper.instances.total" || message.Value != 3 {
+		t.Errorf("unexpected message %+v", message)
+	}
+}
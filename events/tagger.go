@@ -50,6 +50,9 @@ func (e *Tagger) newBatchReapableEvent(rs []Reapable, tags []string) error {
 	return nil
 }
 
+// records is a method of recorder
+func (e *Tagger) records() {}
+
 // GetConfig is a method of EventReporter
 func (e *Tagger) GetConfig() EventReporterConfig {
 	return *e.Config.EventReporterConfig
@@ -0,0 +1,189 @@
+package filters
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Expression is a boolean expression over FilterGroup names
+// such as "Old AND NOT (Tagged OR Stopped)"
+type Expression interface {
+	// Eval returns the value of the Expression, given which FilterGroups matched
+	Eval(matched map[string]bool) bool
+	// Groups returns the FilterGroup names referenced by the Expression
+	Groups() []string
+}
+
+type groupExpression string
+
+func (e groupExpression) Eval(matched map[string]bool) bool {
+	return matched[string(e)]
+}
+
+func (e groupExpression) Groups() []string {
+	return []string{string(e)}
+}
+
+type notExpression struct {
+	e Expression
+}
+
+func (e notExpression) Eval(matched map[string]bool) bool {
+	return !e.e.Eval(matched)
+}
+
+func (e notExpression) Groups() []string {
+	return e.e.Groups()
+}
+
+type andExpression struct {
+	left, right Expression
+}
+
+func (e andExpression) Eval(matched map[string]bool) bool {
+	return e.left.Eval(matched) && e.right.Eval(matched)
+}
+
+func (e andExpression) Groups() []string {
+	return append(e.left.Groups(), e.right.Groups()...)
+}
+
+type orExpression struct {
+	left, right Expression
+}
+
+func (e orExpression) Eval(matched map[string]bool) bool {
+	return e.left.Eval(matched) || e.right.Eval(matched)
+}
+
+func (e orExpression) Groups() []string {
+	return append(e.left.Groups(), e.right.Groups()...)
+}
+
+// ParseExpression parses a boolean expression of FilterGroup names
+// joined by AND, OR, and NOT (case insensitive), grouped with parentheses
+// NOT binds tightest, then AND, then OR
+func ParseExpression(s string) (Expression, error) {
+	p := &expressionParser{tokens: tokenizeExpression(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter expression %q", p.tokens[p.pos], s)
+	}
+	return e, nil
+}
+
+func tokenizeExpression(s string) []string {
+	var tokens []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = nil
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type expressionParser struct {
+	tokens []string
+	pos    int
+}
+
+// next returns the next token without consuming it
+// returns an empty string at the end of the expression
+func (p *expressionParser) next() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *expressionParser) isKeyword(keyword string) bool {
+	return strings.EqualFold(p.next(), keyword)
+}
+
+func (p *expressionParser) parseOr() (Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpression{left, right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (Expression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpression{left, right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseNot() (Expression, error) {
+	if p.isKeyword("NOT") {
+		p.pos++
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpression{e}, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *expressionParser) parseTerm() (Expression, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of filter expression")
+	case token == "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ) in filter expression")
+		}
+		p.pos++
+		return e, nil
+	case token == ")", p.isKeyword("AND"), p.isKeyword("OR"):
+		return nil, fmt.Errorf("unexpected %q in filter expression", token)
+	}
+	p.pos++
+	return groupExpression(token), nil
+}
@@ -0,0 +1,64 @@
+package filters
+
+import "testing"
+
+func TestParseExpression(t *testing.T) {
+	matched := map[string]bool{"A": true, "B": false, "C": true}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{"A", true},
+		{"B", false},
+		{"A AND B", false},
+		{"A OR B", true},
+		{"NOT B", true},
+		{"A AND NOT B", true},
+		{"a and not C", false},
+		{"B OR A AND C", true},
+		{"(B OR A) AND NOT C", false},
+		{"NOT (A AND B)", true},
+		{"NOT NOT A", true},
+		{"Unknown OR B", false},
+	}
+
+	for _, test := range tests {
+		e, err := ParseExpression(test.expression)
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", test.expression, err.Error())
+			continue
+		}
+		if e.Eval(matched) != test.expected {
+			t.Errorf("%q: expected %t", test.expression, test.expected)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"A AND",
+		"AND A",
+		"A B",
+		"(A OR B",
+		"A OR B)",
+		"NOT",
+		"()",
+	} {
+		if _, err := ParseExpression(expression); err == nil {
+			t.Errorf("%q: expected an error", expression)
+		}
+	}
+}
+
+func TestExpressionGroups(t *testing.T) {
+	e, err := ParseExpression("A AND NOT (B OR C)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := e.Groups()
+	if len(groups) != 3 || groups[0] != "A" || groups[1] != "B" || groups[2] != "C" {
+		t.Errorf("expected groups [A B C], got %v", groups)
+	}
+}
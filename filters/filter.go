@@ -2,11 +2,11 @@ package filters
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-
-	log "github.com/mozilla-services/reaper/reaperlog"
+	"time"
 )
 
 type Filterable interface {
@@ -14,18 +14,103 @@ type Filterable interface {
 	AddFilterGroup(string, FilterGroup)
 }
 
-func ApplyFilters(f Filterable, fs FilterGroup) bool {
+// FilterError describes a Filter that could not be evaluated
+// such as one with a malformed argument or an unknown function
+type FilterError struct {
+	Filter Filter
+	Err    error
+}
+
+func (e FilterError) Error() string {
+	return fmt.Sprintf("%s(%s): %s", e.Filter.Function, strings.Join(e.Filter.Arguments, ", "), e.Err.Error())
+}
+
+// expensiveFunctions are the Functions that make API calls
+// they are applied after every other Filter in a FilterGroup, and only
+// while the FilterGroup can still match
+var expensiveFunctions = map[string]bool{
+	"AmiOlderThan":          true,
+	"AmiMissing":            true,
+	"LaunchConfigOlderThan": true,
+	"ScalingActivityFailed": true,
+	"HasRecentSnapshot":     true,
+	"NoRecentSnapshot":      true,
+}
+
+// orderedFilters returns the filters in fs, cheap ones first, each sorted
+// by name so that they are applied in the same order every time
+func orderedFilters(fs FilterGroup) (cheap []Filter, expensive []Filter) {
+	var names []string
+	for name := range fs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if expensiveFunctions[fs[name].Function] {
+			expensive = append(expensive, fs[name])
+		} else {
+			cheap = append(cheap, fs[name])
+		}
+	}
+	return cheap, expensive
+}
+
+// ApplyFilters returns whether f matches all filters in fs
+// a Filter that could not be evaluated does not match, and the first
+// such Filter is returned as a FilterError
+// expensive filters are skipped once a Filter doesn't match, so their
+// errors are only returned when every cheap Filter matches
+func ApplyFilters(f Filterable, fs FilterGroup) (bool, error) {
 	// defaults to a match
 	matched := true
+	var filterErr error
+
+	cheap, expensive := orderedFilters(fs)
 
 	// if any of the filters return false -> not a match
-	for _, filter := range fs {
-		if !f.Filter(filter) {
+	for _, filter := range cheap {
+		didMatch, err := applyFilter(f, filter)
+		if err != nil && filterErr == nil {
+			filterErr = err
+		}
+		if !didMatch {
+			matched = false
+		}
+	}
+
+	for _, filter := range expensive {
+		if !matched {
+			break
+		}
+		didMatch, err := applyFilter(f, filter)
+		if err != nil && filterErr == nil {
+			filterErr = err
+		}
+		if !didMatch {
 			matched = false
 		}
 	}
 
-	return matched
+	return matched, filterErr
+}
+
+func applyFilter(f Filterable, filter Filter) (matched bool, err error) {
+	var errs []error
+	filter.errs = &errs
+
+	// missing arguments cause index out of range panics
+	defer func() {
+		if r := recover(); r != nil {
+			matched = false
+			err = FilterError{Filter: filter, Err: fmt.Errorf("%v", r)}
+		}
+	}()
+
+	matched = f.Filter(filter)
+	if len(errs) > 0 {
+		return false, FilterError{Filter: filter, Err: errs[0]}
+	}
+	return matched, nil
 }
 
 func FormatFiltersText(filters map[string]Filter) string {
@@ -46,11 +131,50 @@ func FormatFilterGroupsText(filterGroups map[string]FilterGroup) string {
 	return fmt.Sprintf("%s", strings.Join(filterGroupText, ", "))
 }
 
+// FormatReapReason explains which FilterGroups matched and why, such as
+// flagged by group 'old-untagged' because NotTagged Owner and LaunchTimeNotInTheLast 720h
+func FormatReapReason(filterGroups map[string]FilterGroup) string {
+	var names []string
+	for name := range filterGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var reasons []string
+	for _, name := range names {
+		var filterText []string
+		for _, filter := range filterGroups[name] {
+			text := filter.Function
+			if len(filter.Arguments) > 0 {
+				text = fmt.Sprintf("%s %s", filter.Function, strings.Join(filter.Arguments, ", "))
+			}
+			filterText = append(filterText, text)
+		}
+		sort.Strings(filterText)
+		reasons = append(reasons, fmt.Sprintf("group '%s' because %s", name, strings.Join(filterText, " and ")))
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return "flagged by " + strings.Join(reasons, ", and by ")
+}
+
 type FilterGroup map[string]Filter
 
 type Filter struct {
 	Function  string
 	Arguments []string
+
+	// errors recorded by Fail while this Filter is applied
+	errs *[]error
+}
+
+// Fail records that the Filter could not be evaluated
+// the error is returned by ApplyFilters
+func (filter *Filter) Fail(err error) {
+	if filter.errs != nil {
+		*filter.errs = append(*filter.errs, err)
+	}
 }
 
 func NewFilter(f string, args []string) *Filter {
@@ -64,7 +188,8 @@ func (filter *Filter) Int64Value(v int) (int64, error) {
 	// parseint -> base 10, 64 bit int
 	i, err := strconv.ParseInt(filter.Arguments[v], 10, 64)
 	if err != nil {
-		log.Error(fmt.Sprintf("could not parse %s as int64", filter.Arguments[v]))
+		err = fmt.Errorf("could not parse %s as int64", filter.Arguments[v])
+		filter.Fail(err)
 		return 0, err
 	}
 	return i, nil
@@ -73,8 +198,80 @@ func (filter *Filter) Int64Value(v int) (int64, error) {
 func (filter *Filter) BoolValue(v int) (bool, error) {
 	b, err := strconv.ParseBool(filter.Arguments[v])
 	if err != nil {
-		log.Error(fmt.Sprintf("could not parse %s as bool", filter.Arguments[v]))
+		err = fmt.Errorf("could not parse %s as bool", filter.Arguments[v])
+		filter.Fail(err)
 		return false, err
 	}
 	return b, nil
 }
+
+// Float64Value parses an argument as a 64 bit float
+func (filter *Filter) Float64Value(v int) (float64, error) {
+	f, err := strconv.ParseFloat(filter.Arguments[v], 64)
+	if err != nil {
+		err = fmt.Errorf("could not parse %s as float64", filter.Arguments[v])
+		filter.Fail(err)
+		return 0, err
+	}
+	return f, nil
+}
+
+// DurationValue parses an argument with time.ParseDuration
+func (filter *Filter) DurationValue(v int) (time.Duration, error) {
+	d, err := time.ParseDuration(filter.Arguments[v])
+	if err != nil {
+		err = fmt.Errorf("could not parse %s as a duration", filter.Arguments[v])
+		filter.Fail(err)
+		return 0, err
+	}
+	return d, nil
+}
+
+// TimeValue parses an RFC3339 argument
+func (filter *Filter) TimeValue(v int) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, filter.Arguments[v])
+	if err != nil {
+		err = fmt.Errorf("could not parse %s as an RFC3339 time", filter.Arguments[v])
+		filter.Fail(err)
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// TagPair is a tag key and value, parsed from a key=value argument
+type TagPair struct {
+	Key   string
+	Value string
+}
+
+// TagPairsValue parses every argument as a key=value pair
+// the value may be empty or contain =, the key may not be empty
+func (filter *Filter) TagPairsValue() ([]TagPair, error) {
+	if len(filter.Arguments) == 0 {
+		err := fmt.Errorf("expected at least one key=value pair")
+		filter.Fail(err)
+		return nil, err
+	}
+	var pairs []TagPair
+	for _, arg := range filter.Arguments {
+		i := strings.Index(arg, "=")
+		if i < 1 {
+			err := fmt.Errorf("could not parse %s as a key=value pair", arg)
+			filter.Fail(err)
+			return nil, err
+		}
+		pairs = append(pairs, TagPair{Key: arg[:i], Value: arg[i+1:]})
+	}
+	return pairs, nil
+}
+
+// RegexpValue compiles an argument as a regular expression
+func (filter *Filter) RegexpValue(v int) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(filter.Arguments[v])
+	if err != nil {
+		err = fmt.Errorf("could not parse %s as a regular expression", filter.Arguments[v])
+		filter.Fail(err)
+		return nil, err
+	}
+	return re, nil
+}
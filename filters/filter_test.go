@@ -0,0 +1,83 @@
+package filters
+
+import (
+	"testing"
+	"time"
+)
+
+// countingFilterable matches the Functions in matches, and counts
+// how many times each Function is applied
+type countingFilterable struct {
+	matches map[string]bool
+	applied map[string]int
+	// delay is how long expensive Functions take
+	delay time.Duration
+}
+
+func newCountingFilterable(matches ...string) *countingFilterable {
+	f := &countingFilterable{matches: make(map[string]bool), applied: make(map[string]int)}
+	for _, m := range matches {
+		f.matches[m] = true
+	}
+	return f
+}
+
+func (f *countingFilterable) Filter(filter Filter) bool {
+	f.applied[filter.Function]++
+	if expensiveFunctions[filter.Function] {
+		time.Sleep(f.delay)
+	}
+	return f.matches[filter.Function]
+}
+
+func (f *countingFilterable) AddFilterGroup(string, FilterGroup) {}
+
+func TestApplyFiltersSkipsExpensiveFilters(t *testing.T) {
+	group := FilterGroup{
+		"a": *NewFilter("AmiOlderThan", []string{"720h"}),
+		"b": *NewFilter("Tagged", []string{"Owner"}),
+		"c": *NewFilter("NotRegion", []string{"us-east-1"}),
+	}
+
+	// a cheap filter decides the group, the expensive one is skipped
+	f := newCountingFilterable("NotRegion", "AmiOlderThan")
+	if matched, err := ApplyFilters(f, group); matched || err != nil {
+		t.Errorf("expected no match without error, got %t, %v", matched, err)
+	}
+	if f.applied["AmiOlderThan"] != 0 {
+		t.Error("expected AmiOlderThan not to be applied once Tagged didn't match")
+	}
+	if f.applied["Tagged"] != 1 || f.applied["NotRegion"] != 1 {
+		t.Errorf("expected every cheap filter to be applied once, got %v", f.applied)
+	}
+
+	// the expensive filter decides the group when the cheap ones match
+	for _, test := range []struct {
+		matches  []string
+		expected bool
+	}{
+		{[]string{"Tagged", "NotRegion", "AmiOlderThan"}, true},
+		{[]string{"Tagged", "NotRegion"}, false},
+	} {
+		f := newCountingFilterable(test.matches...)
+		if matched, _ := ApplyFilters(f, group); matched != test.expected {
+			t.Errorf("%v: expected %t", test.matches, test.expected)
+		}
+		if f.applied["AmiOlderThan"] != 1 {
+			t.Errorf("%v: expected AmiOlderThan to be applied once", test.matches)
+		}
+	}
+}
+
+func BenchmarkApplyFiltersDecidedByCheapFilter(b *testing.B) {
+	group := FilterGroup{
+		"a": *NewFilter("AmiOlderThan", []string{"720h"}),
+		"b": *NewFilter("LaunchConfigOlderThan", []string{"720h"}),
+		"c": *NewFilter("Tagged", []string{"Owner"}),
+	}
+	f := newCountingFilterable("AmiOlderThan", "LaunchConfigOlderThan")
+	f.delay = time.Millisecond
+	for i := 0; i < b.N; i++ {
+		ApplyFilters(f, group)
+	}
+}
@@ -15,6 +15,13 @@ import (
 var (
 	config         reaper.Config
 	eventReporters []reaperevents.EventReporter
+
+	// command is run on a single resource instead of reaping, if set
+	command *reaper.Command
+	// renderTemplates renders every event template instead of reaping
+	renderTemplates bool
+	// signManifest signs a manifest read from stdin instead of reaping
+	signManifest bool
 )
 
 func init() {
@@ -27,6 +34,22 @@ func init() {
 		log.EnableMozlog()
 	}
 
+	// reaper -config=filename render-templates
+	// reaper -config=filename sign-manifest < manifest.txt
+	// reaper -config=filename terminate -region us-west-2 -id i-0123456789abcdef0
+	if flag.NArg() == 1 && flag.Arg(0) == "render-templates" {
+		renderTemplates = true
+	} else if flag.NArg() == 1 && flag.Arg(0) == "sign-manifest" {
+		signManifest = true
+	} else if flag.NArg() > 0 {
+		c, err := reaper.ParseCommand(flag.Args())
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(2)
+		}
+		command = c
+	}
+
 	// if no config file -> exit with error
 	if *configFile == "" {
 		log.Error("Config file is a required Argument. Specify with -config='filename'")
@@ -55,39 +78,14 @@ func init() {
 		log.AddLogFile(config.LogFile)
 	}
 
-	// if DatadogStatistics EventReporter is enabled
-	if config.Events.DatadogStatistics.Enabled {
-		log.Info("DatadogStatistics EventReporter enabled.")
-		eventReporters = append(eventReporters, reaperevents.NewDatadogStatistics(&config.Events.DatadogStatistics))
-	}
-
-	// if DatadogEvents EventReporter is enabled
-	if config.Events.DatadogEvents.Enabled {
-		log.Info("DatadogEvents EventReporter enabled.")
-		eventReporters = append(eventReporters, reaperevents.NewDatadogEvents(&config.Events.DatadogEvents))
-	}
-
-	// if Email EventReporter is enabled
-	if config.Events.Email.Enabled {
-		log.Info("Email EventReporter enabled.")
-		eventReporters = append(eventReporters, reaperevents.NewMailer(&config.Events.Email))
+	// EventReporters are enabled by their section under [Events], or by Reporters
+	eventReporters = config.EventReporters()
+	if config.Events.Email.EventReporterConfig != nil && config.Events.Email.Enabled {
 		// these methods have pointer receivers
 		log.Debug("SMTP Config: %s", &config.Events.Email)
 		log.Debug("SMTP From: %s", &config.Events.Email.From)
 	}
 
-	// if Tagger EventReporter is enabled
-	if config.Events.Tagger.Enabled {
-		log.Info("Tagger EventReporter enabled.")
-		eventReporters = append(eventReporters, reaperevents.NewTagger(&config.Events.Tagger))
-	}
-
-	// if Reaper EventReporter is enabled
-	if config.Events.Reaper.Enabled {
-		log.Info("Reaper EventReporter enabled.")
-		eventReporters = append(eventReporters, reaperevents.NewReaperEvent(&config.Events.Reaper))
-	}
-
 	// if WhitelistTag is not set
 	if config.WhitelistTag == "" {
 		log.Error("WhitelistTag is empty, exiting")
@@ -105,33 +103,64 @@ func main() {
 	// config and events are vars in the reaper package
 	// they NEED to be set before a reaper.Reaper can be initialized
 	reaper.SetConfig(&config)
-	reaperevents.SetEvents(&eventReporters)
+	for _, er := range eventReporters {
+		reaperevents.Register(er)
+	}
 
 	if config.DryRun {
 		log.Info("Dry run mode enabled, no events will be triggered. Enable Extras in Notifications for per-event DryRun notifications.")
 		reaperevents.SetDryRun(config.DryRun)
 	}
 
-	// Ready() NEEDS to be called after BOTH SetConfig() and SetEvents()
+	// sets the config variable in Reaper's AWS package
+	// this also NEEDS to be set before Ready(), which resolves the regions
+	// with the AWS package's clients
+	reaperaws.SetConfig(&config.AWS)
+
+	// Ready() NEEDS to be called after BOTH SetConfig() and Register()
 	// it uses those values to set individual EventReporter config values
 	// and to init the Reapables map
 	reaper.Ready()
 
-	// sets the config variable in Reaper's AWS package
-	// this also NEEDS to be set before a Reaper can be started
-	reaperaws.SetConfig(&config.AWS)
+	if renderTemplates {
+		if err := reaperaws.RenderTemplates(os.Stdout); err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
-	// single instance of reaper
-	reapRunner := reaper.NewReaper()
-	// Run the reaper process
-	reapRunner.Start()
+	if signManifest {
+		signed, err := reaper.SignManifest(os.Stdin)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(signed)
+		return
+	}
+
+	if command != nil {
+		if err := reaper.RunCommand(command, os.Stdin, os.Stdout); err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
-	// run the HTTP server
+	// run the HTTP server first, so health checks respond while
+	// the reaper starts
 	api := reaper.NewHTTPApi(config.HTTP)
 	if err := api.Serve(); err != nil {
 		log.Error(err.Error())
 	} else {
 		// HTTP server successfully started
+
+		// single instance of reaper
+		reapRunner := reaper.NewReaper()
+		// Run the reaper process
+		reapRunner.Start()
+
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt, os.Kill)
 
@@ -7,6 +7,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
+	"time"
 
 	log "github.com/mozilla-services/reaper/reaperlog"
 )
@@ -15,6 +17,46 @@ const Ec2PricingUrl = "https://pricing.us-east-1.amazonaws.com/offers/v1.0/aws/A
 
 type PricesMap map[string]map[string]string
 
+// DownloadPricesMap tries a download this many times, waiting
+// downloadBackoff after the first failure and doubling the wait after each
+var (
+	downloadAttempts = 4
+	downloadBackoff  = 5 * time.Second
+)
+
+// sleep waits between download attempts
+// replaceable in tests
+var sleep = time.Sleep
+
+// last is the last PricesMap that was downloaded, kept for when a download
+// fails
+var last struct {
+	sync.Mutex
+	pricesMap PricesMap
+	updated   time.Time
+}
+
+// LastUpdated returns when prices were last downloaded, and the zero time
+// if they never were
+func LastUpdated() time.Time {
+	last.Lock()
+	defer last.Unlock()
+	return last.updated
+}
+
+// EBSMonthlyPricesPerGB are the us-east-1 prices in USD per GB-month of
+// EBS storage by volume type, used as an estimate in every region
+// provisioned IOPS and throughput are not included
+var EBSMonthlyPricesPerGB = map[string]float64{
+	"standard": 0.05,
+	"gp2":      0.10,
+	"gp3":      0.08,
+	"io1":      0.125,
+	"io2":      0.125,
+	"st1":      0.045,
+	"sc1":      0.015,
+}
+
 var regions = map[string]string{
 	"US West (N. California)":   "us-west-1",
 	"US West (Oregon)":          "us-west-2",
@@ -98,17 +140,55 @@ func GetPricesMapFromFile(filename string) (PricesMap, error) {
 	return populatePricesMap(bytes.NewReader(bs))
 }
 
+// DownloadPricesMap downloads and parses the prices at url, retrying with
+// backoff on errors
+// if every attempt fails, the last downloaded PricesMap is returned along
+// with the error, or nil if prices were never downloaded
 func DownloadPricesMap(url string) (PricesMap, error) {
 	if url == "" {
 		return PricesMap{}, fmt.Errorf("Invalid price url")
 	}
 
+	var err error
+	backoff := downloadBackoff
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		if attempt > 1 {
+			log.Warning("Downloading prices failed, retrying in %s: %s", backoff, err.Error())
+			sleep(backoff)
+			backoff *= 2
+		}
+		var pricesMap PricesMap
+		pricesMap, err = downloadPricesMap(url)
+		if err == nil {
+			last.Lock()
+			defer last.Unlock()
+			last.pricesMap = pricesMap
+			last.updated = time.Now()
+			return pricesMap, nil
+		}
+	}
+
+	last.Lock()
+	defer last.Unlock()
+	return last.pricesMap, fmt.Errorf("Downloading prices failed after %d attempts: %s", downloadAttempts, err.Error())
+}
+
+// downloadPricesMap makes a single attempt at downloading prices
+func downloadPricesMap(url string) (PricesMap, error) {
 	res, err := http.Get(url)
 	if err != nil {
-		return PricesMap{}, err
+		return nil, err
 	}
 	defer res.Body.Close()
-	return populatePricesMap(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded %s", url, res.Status)
+	}
+	pricesMap, err := populatePricesMap(res.Body)
+	if err == nil && pricesMap == nil {
+		// populatePricesMap recovered from a panic
+		err = fmt.Errorf("Could not parse prices from %s", url)
+	}
+	return pricesMap, err
 }
 
 func populatePricesMap(r io.Reader) (PricesMap, error) {
@@ -0,0 +1,110 @@
+package prices
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testPrices = `{
+	"products": {
+		"SKU1": {"productFamily": "Compute Instance", "attributes": {"location": "US West (Oregon)", "instanceType": "m4.large"}}
+	},
+	"terms": {
+		"OnDemand": {
+			"SKU1": {"SKU1.TERM": {"priceDimensions": {"SKU1.TERM.RATE": {"pricePerUnit": {"USD": "0.1"}}}}}
+		}
+	}
+}`
+
+// setTestDownloads records the waits between download attempts instead of
+// sleeping and forgets the last download, returning a func that restores both
+func setTestDownloads() (waits *[]time.Duration, restore func()) {
+	originalSleep := sleep
+	last.Lock()
+	originalMap, originalUpdated := last.pricesMap, last.updated
+	last.pricesMap, last.updated = nil, time.Time{}
+	last.Unlock()
+
+	waits = &[]time.Duration{}
+	sleep = func(d time.Duration) { *waits = append(*waits, d) }
+	return waits, func() {
+		sleep = originalSleep
+		last.Lock()
+		last.pricesMap, last.updated = originalMap, originalUpdated
+		last.Unlock()
+	}
+}
+
+// newTestPricesServer serves testPrices after failing failures times,
+// and fails every request once fail is set
+func newTestPricesServer(failures int, fail *bool) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures || *fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, testPrices)
+	}))
+}
+
+func TestDownloadPricesMapRetries(t *testing.T) {
+	waits, restore := setTestDownloads()
+	defer restore()
+	fail := false
+	server := newTestPricesServer(2, &fail)
+	defer server.Close()
+
+	pricesMap, err := DownloadPricesMap(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pricesMap["us-west-2"]["m4.large"] != "0.1" {
+		t.Errorf("expected the m4.large price in us-west-2, got %v", pricesMap["us-west-2"])
+	}
+	if len(*waits) != 2 || (*waits)[0] != downloadBackoff || (*waits)[1] != 2*downloadBackoff {
+		t.Errorf("expected 2 doubling waits, got %v", *waits)
+	}
+	if LastUpdated().IsZero() {
+		t.Error("expected LastUpdated to be set")
+	}
+}
+
+func TestDownloadPricesMapKeepsStalePrices(t *testing.T) {
+	_, restore := setTestDownloads()
+	defer restore()
+	fail := true
+	server := newTestPricesServer(0, &fail)
+	defer server.Close()
+
+	// without a previous download, there is nothing to keep
+	pricesMap, err := DownloadPricesMap(server.URL)
+	if err == nil || pricesMap != nil {
+		t.Errorf("expected an error and no prices, got %v, %v", pricesMap, err)
+	}
+	if !LastUpdated().IsZero() {
+		t.Error("expected LastUpdated to be zero before prices are downloaded")
+	}
+
+	fail = false
+	if _, err := DownloadPricesMap(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	updated := LastUpdated()
+
+	fail = true
+	pricesMap, err = DownloadPricesMap(server.URL)
+	if err == nil {
+		t.Error("expected an error once every attempt fails")
+	}
+	if pricesMap["us-west-2"]["m4.large"] != "0.1" {
+		t.Errorf("expected the previous prices to be kept, got %v", pricesMap)
+	}
+	if !LastUpdated().Equal(updated) {
+		t.Errorf("expected LastUpdated to stay %s, got %s", updated, LastUpdated())
+	}
+}
@@ -0,0 +1,123 @@
+package reapable
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Typed is a Reapable that reports its resource type, used by Dump
+type Typed interface {
+	ReapableType() string
+}
+
+// Costed is a Reapable with an estimated hourly cost in USD, used by Dump
+type Costed interface {
+	EstimatedHourlyCost() (float64, bool)
+}
+
+// DumpRecord is the exported description of a Reapable
+type DumpRecord struct {
+	Region     string   `json:"region"`
+	ID         string   `json:"id"`
+	Type       string   `json:"type"`
+	Owner      string   `json:"owner"`
+	State      string   `json:"state"`
+	Until      string   `json:"until"`
+	HourlyCost *float64 `json:"hourly_cost"`
+}
+
+var dumpCSVHeader = []string{"region", "id", "type", "owner", "state", "until", "hourly_cost"}
+
+func newDumpRecord(region Region, id ID, r Reapable) DumpRecord {
+	record := DumpRecord{
+		Region: region.String(),
+		ID:     id.String(),
+	}
+	if t, ok := r.(Typed); ok {
+		record.Type = t.ReapableType()
+	}
+	if owner := r.Owner(); owner != nil {
+		record.Owner = owner.Address
+	}
+	if s := r.ReaperState(); s != nil {
+		record.State = s.State.String()
+		record.Until = s.Until.Format(time.RFC3339)
+	}
+	if c, ok := r.(Costed); ok {
+		if cost, ok := c.EstimatedHourlyCost(); ok {
+			record.HourlyCost = &cost
+		}
+	}
+	return record
+}
+
+// NewDumpRecord returns the exported description of a Reapable
+func NewDumpRecord(r Reapable) DumpRecord {
+	return newDumpRecord(r.Region(), r.ID(), r)
+}
+
+// SortDumpRecords sorts records by region and id, as Dump does
+func SortDumpRecords(records []DumpRecord) {
+	sort.Sort(dumpRecords(records))
+}
+
+func (d DumpRecord) csv() []string {
+	cost := ""
+	if d.HourlyCost != nil {
+		cost = strconv.FormatFloat(*d.HourlyCost, 'f', -1, 64)
+	}
+	return []string{d.Region, d.ID, d.Type, d.Owner, d.State, d.Until, cost}
+}
+
+// Dump writes every Reapable, sorted by region and id, to w
+// format is one of "csv" or "json"
+func (rs *Reapables) Dump(w io.Writer, format string) error {
+	rs.RLock()
+	var records []DumpRecord
+	for region, regionMap := range rs.storage {
+		for id, r := range regionMap {
+			records = append(records, newDumpRecord(region, id, r))
+		}
+	}
+	rs.RUnlock()
+
+	sort.Sort(dumpRecords(records))
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(dumpCSVHeader); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := cw.Write(record.csv()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		if records == nil {
+			records = []DumpRecord{}
+		}
+		return json.NewEncoder(w).Encode(records)
+	default:
+		return fmt.Errorf("unknown dump format %q, must be csv or json", format)
+	}
+}
+
+type dumpRecords []DumpRecord
+
+func (d dumpRecords) Len() int      { return len(d) }
+func (d dumpRecords) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d dumpRecords) Less(i, j int) bool {
+	if d[i].Region != d[j].Region {
+		return d[i].Region < d[j].Region
+	}
+	return d[i].ID < d[j].ID
+}
@@ -0,0 +1,97 @@
+package reapable
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/state"
+)
+
+type testReapable struct {
+	owner *mail.Address
+	state *state.State
+	cost  float64
+}
+
+func (r *testReapable) Filter(filters.Filter) bool                 { return false }
+func (r *testReapable) AddFilterGroup(string, filters.FilterGroup) {}
+func (r *testReapable) Terminate() (bool, error)                   { return true, nil }
+func (r *testReapable) Stop() (bool, error)                        { return true, nil }
+func (r *testReapable) Whitelist() (bool, error)                   { return true, nil }
+func (r *testReapable) Save(*state.State) (bool, error)            { return true, nil }
+func (r *testReapable) Unsave() (bool, error)                      { return true, nil }
+func (r *testReapable) ReaperState() *state.State                  { return r.state }
+func (r *testReapable) IncrementState() bool                       { return false }
+func (r *testReapable) SetUpdated(bool)                            {}
+func (r *testReapable) Owner() *mail.Address                       { return r.owner }
+func (r *testReapable) ID() ID                                     { return "" }
+func (r *testReapable) Region() Region                             { return "" }
+func (r *testReapable) ReapableDescription() string                { return "" }
+func (r *testReapable) ReapableDescriptionShort() string           { return "" }
+func (r *testReapable) ReapableDescriptionTiny() string            { return "" }
+func (r *testReapable) ReapableType() string                       { return "Instance" }
+func (r *testReapable) EstimatedHourlyCost() (float64, bool)       { return r.cost, r.cost > 0 }
+
+func newTestReapables() *Reapables {
+	until := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	rs := NewReapables([]string{"us-west-2", "us-east-1"})
+	rs.Put("us-west-2", "i-2", &testReapable{
+		owner: &mail.Address{Address: "owner@example.com"},
+		state: state.NewStateWithUntilAndState(until, state.FirstState),
+		cost:  0.5,
+	})
+	rs.Put("us-east-1", "i-1", &testReapable{
+		state: state.NewStateWithUntilAndState(until, state.FinalState),
+	})
+	return rs
+}
+
+func TestDumpCSV(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	if err := newTestReapables().Dump(buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"region,id,type,owner,state,until,hourly_cost",
+		"us-east-1,i-1,Instance,,FinalState,2016-01-02T03:04:05Z,",
+		"us-west-2,i-2,Instance,owner@example.com,FirstState,2016-01-02T03:04:05Z,0.5",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	if err := newTestReapables().Dump(buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []DumpRecord
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[0].ID != "i-1" || records[0].HourlyCost != nil {
+		t.Errorf("expected i-1 without a cost first, got %+v", records[0])
+	}
+	if records[1].Owner != "owner@example.com" || records[1].State != "FirstState" ||
+		records[1].HourlyCost == nil || *records[1].HourlyCost != 0.5 {
+		t.Errorf("unexpected record for i-2: %+v", records[1])
+	}
+}
+
+func TestDumpUnknownFormat(t *testing.T) {
+	if err := newTestReapables().Dump(bytes.NewBuffer(nil), "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
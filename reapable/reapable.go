@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/mail"
 	"sync"
+	"time"
 
 	"github.com/mozilla-services/reaper/filters"
 	"github.com/mozilla-services/reaper/state"
@@ -51,6 +52,17 @@ type Reapable interface {
 	ReapableDescriptionTiny() string
 }
 
+// Aged is a Reapable that knows when it was created
+type Aged interface {
+	CreatedAt() (time.Time, bool)
+}
+
+// ForceStopper is a Reapable that can be stopped without waiting
+// for it to shut down cleanly
+type ForceStopper interface {
+	ForceStop() (bool, error)
+}
+
 type Region string
 
 func (r Region) String() string {
@@ -70,20 +82,35 @@ type Reapables struct {
 
 func NewReapables(regions []string) *Reapables {
 	r := Reapables{}
-	r.Lock()
-	defer r.Unlock()
+	r.Reset(regions)
+	return &r
+}
+
+// Reset empties the Reapables, keeping a map for each of the regions
+// Reapables hold a lock, so they must be reset rather than replaced
+// while other goroutines may be using them
+func (rs *Reapables) Reset(regions []string) {
+	rs.Lock()
+	defer rs.Unlock()
 
 	// initialize Reapables map
-	r.storage = make(map[Region]map[ID]Reapable)
+	rs.storage = make(map[Region]map[ID]Reapable)
 	for _, region := range regions {
-		r.storage[Region(region)] = make(map[ID]Reapable)
+		rs.storage[Region(region)] = make(map[ID]Reapable)
 	}
-	return &r
 }
 
 func (rs *Reapables) Put(region Region, id ID, r Reapable) {
 	rs.Lock()
 	defer rs.Unlock()
+	// resources can be found outside the configured regions,
+	// such as from the command line
+	if rs.storage == nil {
+		rs.storage = make(map[Region]map[ID]Reapable)
+	}
+	if rs.storage[region] == nil {
+		rs.storage[region] = make(map[ID]Reapable)
+	}
 	rs.storage[region][id] = r
 }
 
@@ -98,8 +125,8 @@ func (rs *Reapables) Get(region Region, id ID) (Reapable, error) {
 }
 
 func (rs *Reapables) Delete(region Region, id ID) {
-	rs.RLock()
-	defer rs.RUnlock()
+	rs.Lock()
+	defer rs.Unlock()
 	delete(rs.storage[region], id)
 }
 
@@ -120,8 +147,10 @@ func (r *ReapableContainer) ID() ID {
 func (rs *Reapables) Iter() <-chan ReapableContainer {
 	ch := make(chan ReapableContainer)
 	go func(c chan ReapableContainer) {
-		rs.Lock()
-		defer rs.Unlock()
+		// the lock is held until the channel is drained, so the Reapables
+		// must not be changed while iterating
+		rs.RLock()
+		defer rs.RUnlock()
 		for region, regionMap := range rs.storage {
 			for id, r := range regionMap {
 				c <- ReapableContainer{r, region, id}
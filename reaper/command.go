@@ -0,0 +1,103 @@
+package reaper
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+// findReapable describes a single resource by region and id
+// replaceable in tests
+var findReapable = func(region reapable.Region, id reapable.ID) (reaperevents.Reapable, error) {
+	return reaperaws.FindReapable(region, id)
+}
+
+// commandActions are the actions a Command can run, by name
+var commandActions = map[string]func(reapable.Region, reapable.ID) error{
+	"terminate":  Terminate,
+	"stop":       Stop,
+	"force-stop": ForceStop,
+}
+
+// Command is an action on a single resource, run from the command line
+// instead of a full cycle, such as during incident cleanup
+type Command struct {
+	Action string
+	Region reapable.Region
+	ID     reapable.ID
+
+	// Yes skips the confirmation prompt
+	Yes bool
+	// Force skips the confirmation prompt, and acts on whitelisted
+	// resources and those in NeverReapIDs
+	Force bool
+}
+
+// ParseCommand parses the arguments of a command, such as
+// terminate -region us-west-2 -id i-0123456789abcdef0
+func ParseCommand(args []string) (*Command, error) {
+	if len(args) == 0 {
+		return nil, errors.New("No command, expected terminate, stop or force-stop")
+	}
+	c := &Command{Action: args[0]}
+	if _, ok := commandActions[c.Action]; !ok {
+		return nil, fmt.Errorf("Unknown command %q, expected terminate, stop or force-stop", c.Action)
+	}
+
+	flags := flag.NewFlagSet(c.Action, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	region := flags.String("region", "", "region of the resource")
+	id := flags.String("id", "", "id of the resource")
+	flags.BoolVar(&c.Yes, "yes", false, "skip the confirmation prompt")
+	flags.BoolVar(&c.Force, "force", false, "skip the confirmation prompt and act on protected resources")
+	if err := flags.Parse(args[1:]); err != nil {
+		return nil, fmt.Errorf("%s: %s", c.Action, err.Error())
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("%s: unexpected arguments %q", c.Action, flags.Args())
+	}
+	if *region == "" || *id == "" {
+		return nil, fmt.Errorf("%s: -region and -id are required", c.Action)
+	}
+	c.Region = reapable.Region(*region)
+	c.ID = reapable.ID(*id)
+	return c, nil
+}
+
+// RunCommand finds the Command's resource, prints its description,
+// and runs the Command's action once it is confirmed on in
+func RunCommand(c *Command, in io.Reader, out io.Writer) error {
+	action, ok := commandActions[c.Action]
+	if !ok {
+		return fmt.Errorf("Unknown command %q", c.Action)
+	}
+
+	r, err := findReapable(c.Region, c.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, r.ReapableDescription())
+
+	if !c.Force && (isWhitelisted(r) || neverReaped(r)) {
+		return fmt.Errorf("%s is protected by the WhitelistTag or NeverReapIDs, use -force to %s it anyway", r.ReapableDescriptionTiny(), c.Action)
+	}
+
+	if !c.Yes && !c.Force {
+		fmt.Fprintf(out, "Type yes to %s %s: ", c.Action, r.ReapableDescriptionTiny())
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		if strings.TrimSpace(answer) != "yes" {
+			return fmt.Errorf("Not confirmed, %s was not run", c.Action)
+		}
+	}
+
+	reapables.Put(r.Region(), r.ID(), r)
+	return action(c.Region, c.ID)
+}
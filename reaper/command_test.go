@@ -0,0 +1,121 @@
+package reaper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+// setTestFindReapable makes findReapable return r,
+// returning a func that restores it
+func setTestFindReapable(r *testReapable) (restore func()) {
+	original := findReapable
+	findReapable = func(region reapable.Region, id reapable.ID) (reaperevents.Reapable, error) {
+		return r, nil
+	}
+	return func() { findReapable = original }
+}
+
+func TestParseCommand(t *testing.T) {
+	c, err := ParseCommand([]string{"terminate", "-region", "us-west-2", "--id", "i-1", "-yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Action != "terminate" || c.Region != "us-west-2" || c.ID != "i-1" || !c.Yes || c.Force {
+		t.Errorf("unexpected command %+v", c)
+	}
+
+	c, err = ParseCommand([]string{"force-stop", "-region=us-east-1", "-id=i-2", "-force"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Action != "force-stop" || c.Region != "us-east-1" || c.ID != "i-2" || c.Yes || !c.Force {
+		t.Errorf("unexpected command %+v", c)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"delete", "-region", "us-west-2", "-id", "i-1"},
+		{"stop", "-id", "i-1"},
+		{"stop", "-region", "us-west-2"},
+		{"stop", "-region", "us-west-2", "-id", "i-1", "-unknown"},
+		{"stop", "-region", "us-west-2", "-id", "i-1", "extra"},
+	} {
+		if _, err := ParseCommand(args); err == nil {
+			t.Errorf("expected %q to be invalid", args)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	defer setTestConfig(&Config{WhitelistTag: "REAPER_SPARE_ME"})()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	tests := []struct {
+		action                            string
+		terminated, stopped, forceStopped int
+	}{
+		{"terminate", 1, 0, 0},
+		{"stop", 0, 1, 0},
+		{"force-stop", 0, 0, 1},
+	}
+	for _, test := range tests {
+		r := newTestReapable("us-west-2", "i-1", "")
+		defer setTestFindReapable(r)()
+
+		var out bytes.Buffer
+		c := &Command{Action: test.action, Region: "us-west-2", ID: "i-1", Yes: true}
+		if err := RunCommand(c, strings.NewReader(""), &out); err != nil {
+			t.Fatal(err)
+		}
+		if r.terminated != test.terminated || r.stopped != test.stopped || r.forceStopped != test.forceStopped {
+			t.Errorf("%s: expected %d terminated, %d stopped and %d force stopped, got %d, %d and %d", test.action,
+				test.terminated, test.stopped, test.forceStopped, r.terminated, r.stopped, r.forceStopped)
+		}
+		if !strings.HasPrefix(out.String(), "i-1\n") {
+			t.Errorf("%s: expected the resource's description, got %q", test.action, out.String())
+		}
+	}
+}
+
+func TestRunCommandConfirmation(t *testing.T) {
+	defer setTestConfig(&Config{WhitelistTag: "REAPER_SPARE_ME", NeverReapIDs: []string{"us-west-2/i-protected"}})()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "")
+	defer setTestFindReapable(r)()
+
+	c := &Command{Action: "terminate", Region: "us-west-2", ID: "i-1"}
+	if err := RunCommand(c, strings.NewReader("no\n"), &bytes.Buffer{}); err == nil {
+		t.Error("expected an error when the command isn't confirmed")
+	}
+	if r.terminated != 0 {
+		t.Error("expected no termination when the command isn't confirmed")
+	}
+	if err := RunCommand(c, strings.NewReader("yes\n"), &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.terminated != 1 {
+		t.Error("expected a termination once confirmed")
+	}
+
+	// protected resources need -force
+	protected := newTestReapable("us-west-2", "i-protected", "")
+	defer setTestFindReapable(protected)()
+	c = &Command{Action: "terminate", Region: "us-west-2", ID: "i-protected", Yes: true}
+	if err := RunCommand(c, strings.NewReader(""), &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a resource in NeverReapIDs")
+	}
+	c.Force = true
+	if err := RunCommand(c, strings.NewReader(""), &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if protected.terminated != 1 {
+		t.Error("expected -force to terminate a resource in NeverReapIDs")
+	}
+}
@@ -0,0 +1,179 @@
+package reaper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// newTestEC2TagServer answers the EC2 CreateTags, DeleteTags and DescribeTags
+// calls of Save, Unsave and Whitelist, describing the last value written
+func newTestEC2TagServer() *httptest.Server {
+	var mutex sync.Mutex
+	tags := make(map[string]string)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch req.Form.Get("Action") {
+		case "CreateTags":
+			tags[req.Form.Get("ResourceId.1")+"/"+req.Form.Get("Tag.1.Key")] = req.Form.Get("Tag.1.Value")
+			fmt.Fprint(w, `<CreateTagsResponse><return>true</return></CreateTagsResponse>`)
+		case "DeleteTags":
+			delete(tags, req.Form.Get("ResourceId.1")+"/"+req.Form.Get("Tag.1.Key"))
+			fmt.Fprint(w, `<DeleteTagsResponse><return>true</return></DeleteTagsResponse>`)
+		case "DescribeTags":
+			id, key := req.Form.Get("Filter.1.Value.1"), req.Form.Get("Filter.2.Value.1")
+			fmt.Fprintf(w, `<DescribeTagsResponse><tagSet><item><resourceId>%s</resourceId><key>%s</key><value>%s</value></item></tagSet></DescribeTagsResponse>`,
+				id, key, tags[id+"/"+key])
+		default:
+			http.Error(w, "unexpected action", http.StatusBadRequest)
+		}
+	}))
+}
+
+// TestConcurrentDiscoveryAndReaping registers and prunes resources while
+// the HTTP handlers read and change them, run it with -race
+func TestConcurrentDiscoveryAndReaping(t *testing.T) {
+	_, restore := recordStatistics()
+	defer restore()
+	_, restoreCount := recordCountStatistics()
+	defer restoreCount()
+	defer setTestNeverReap()()
+	defer setTestConfig(&Config{WhitelistTag: "REAPER_SPARE_ME"})()
+	server := newTestEC2TagServer()
+	defer server.Close()
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, "test")
+	}
+	reaperaws.SetConfig(&reaperaws.Config{
+		Notifications: config.Notifications,
+		WhitelistTag:  config.WhitelistTag,
+		EndpointURL:   server.URL,
+	})
+	defer reaperaws.SetConfig(nil)
+	reapables.Reset([]string{"us-west-2"})
+
+	const cycles, resources = 20, 20
+	newCycle := func(cycle int) []reaperevents.Reapable {
+		var discovered []reaperevents.Reapable
+		// half of the resources are replaced each cycle, so that some are pruned
+		for n := 0; n < resources; n++ {
+			id := fmt.Sprintf("i-%d", n)
+			if n%2 == 1 {
+				id = fmt.Sprintf("i-%d-%d", cycle, n)
+			}
+			discovered = append(discovered, reaperaws.NewInstance("us-west-2", &ec2.Instance{
+				InstanceId: aws.String(id),
+				LaunchTime: aws.Time(time.Now().Add(-time.Hour)),
+			}))
+		}
+		return discovered
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// reaping
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for cycle := 0; cycle < cycles; cycle++ {
+			discovered := newCycle(cycle)
+			var found sync.WaitGroup
+			for _, r := range discovered {
+				found.Add(1)
+				go func(r reaperevents.Reapable) {
+					defer found.Done()
+					registerReapable(r)
+				}(r)
+			}
+			found.Wait()
+			pruneReapables(discovered)
+		}
+	}()
+
+	// the Tagger and whitelist links, which tag the resources
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; ; n = (n + 2) % resources {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			r, err := reapables.Get("us-west-2", reapable.ID(fmt.Sprintf("i-%d", n)))
+			if err != nil {
+				continue
+			}
+			if _, err := r.Save(state.NewStateWithUntilAndState(time.Now().Add(time.Hour), state.FirstState)); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := r.Whitelist(); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := r.Unsave(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// the HTTP handlers
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := reapables.Dump(ioutil.Discard, "json"); err != nil {
+					t.Error(err)
+				}
+				for r := range reapables.Iter() {
+					r.ReaperState()
+					isWhitelisted(r.Reapable)
+				}
+				// a delay link
+				if r, err := reapables.Get("us-west-2", reapable.ID(fmt.Sprintf("i-%d", 2*n))); err == nil {
+					if s, ok := r.(interface {
+						SetReaperState(*state.State)
+					}); ok {
+						s.SetReaperState(state.NewStateWithUntilAndState(time.Now().Add(time.Hour), r.ReaperState().State))
+					}
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	// only the resources of the last cycle are left
+	count := 0
+	for range reapables.Iter() {
+		count++
+	}
+	if count != resources {
+		t.Errorf("expected %d reapables after the last cycle, got %d", resources, count)
+	}
+}
@@ -1,8 +1,12 @@
 package reaper
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -10,6 +14,7 @@ import (
 	reaperaws "github.com/mozilla-services/reaper/aws"
 	reaperevents "github.com/mozilla-services/reaper/events"
 	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/reapable"
 	log "github.com/mozilla-services/reaper/reaperlog"
 	"github.com/mozilla-services/reaper/state"
 )
@@ -45,29 +50,116 @@ func LoadConfig(path string) (*Config, error) {
 		},
 		HTTP:          httpconfig,
 		Notifications: notifications,
-		DryRun:        true,
+		Events: EventTypes{
+			// so that configs without [Events.SNS] still load
+			SNS: reaperevents.SNSConfig{EventReporterConfig: &reaperevents.EventReporterConfig{}},
+		},
+		DryRun:     true,
+		DeadLetter: DeadLetterConfig{MaxRetries: 3},
 		Logging: log.LogConfig{
 			Extras: true,
 		},
 	}
-	md, err := toml.DecodeFile(path, &conf)
-	if err != nil {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := decodeJSONFile(path, &conf); err != nil {
+			return nil, err
+		}
+	} else {
+		md, err := toml.DecodeFile(path, &conf)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(md.Undecoded()) > 0 {
+			log.Error(fmt.Sprintf("Undecoded configuration keys: %q\nExiting!", md.Undecoded()))
+			os.Exit(1)
+		}
+	}
+
+	// secrets such as HTTP.TokenSecret can come from the environment
+	if err := interpolateEnv(reflect.ValueOf(&conf)); err != nil {
 		return nil, err
 	}
 
-	if len(md.Undecoded()) > 0 {
-		log.Error(fmt.Sprintf("Undecoded configuration keys: %q\nExiting!", md.Undecoded()))
-		os.Exit(1)
+	for _, c := range []*ResourceConfig{
+		&conf.AutoScalingGroups,
+		&conf.Instances,
+		&conf.Snapshots,
+		&conf.Cloudformations,
+		&conf.SecurityGroups,
+		&conf.Volumes,
+		&conf.Images.ResourceConfig,
+		&conf.NetworkInterfaces,
+	} {
+		if err := c.parseFilterExpression(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := conf.Notifications.QuietHours.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := time.LoadLocation(conf.Notifications.TimeZone); err != nil {
+		return nil, err
+	}
+	if err := conf.Safety.Validate(); err != nil {
+		return nil, err
+	}
+	if err := conf.AWS.BusinessHours.Validate(); err != nil {
+		return nil, err
+	}
+	if err := conf.applyReporters(); err != nil {
+		return nil, err
+	}
+	for i, s := range conf.NeverReapIDs {
+		id, err := parseNeverReapID(s)
+		if err != nil {
+			return nil, err
+		}
+		conf.NeverReapIDs[i] = id
+	}
+	if err := neverReap.reload(conf.NeverReapIDsFile); err != nil {
+		return nil, err
+	}
+	if conf.WhitelistPolicyFile != "" {
+		rules, err := readWhitelistPolicy(conf.WhitelistPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.whitelistRules = rules
+	}
+	if err := deadLetters.load(conf.DeadLetter.File); err != nil {
+		return nil, err
+	}
+
+	if conf.AWS.DiscoveryCursors != "" {
+		if err := os.MkdirAll(conf.AWS.DiscoveryCursors, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Templates != "" {
+		templates, err := reaperaws.LoadTemplates(conf.Templates)
+		if err != nil {
+			return nil, err
+		}
+		conf.AWS.CustomTemplates = templates
 	}
 
 	// set dependent values
 	conf.Notifications.StatesConfig = conf.States
 	conf.AWS.DryRun = conf.DryRun
 	conf.AWS.WhitelistTag = conf.WhitelistTag
+	conf.AWS.OwnerTags = conf.OwnerTags
+	conf.AWS.NotifyTag = conf.NotifyTag
 	conf.AWS.DefaultOwner = conf.DefaultOwner
 	conf.AWS.DefaultEmailHost = conf.DefaultEmailHost
 	conf.AWS.Notifications = conf.Notifications
 	conf.AWS.HTTP = conf.HTTP
+	conf.AWS.DeleteImageBackingSnapshots = conf.Images.DeleteBackingSnapshots
+	conf.AWS.InstanceStopLabel = conf.Instances.StopLabel
+	conf.AWS.AutoScalingGroupStopLabel = conf.AutoScalingGroups.StopLabel
+	conf.AWS.PropagateAutoScalingGroupWhitelist = conf.AutoScalingGroups.PropagateWhitelist
 	conf.SMTP.HTTPConfig = conf.HTTP
 
 	log.SetConfig(&conf.Logging)
@@ -77,6 +169,24 @@ func LoadConfig(path string) (*Config, error) {
 	return &conf, nil
 }
 
+// decodeJSONFile decodes a JSON config file, which has the same shape
+// as a TOML one, into conf
+// unknown keys and values of the wrong type are errors
+func decodeJSONFile(path string, conf *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(conf); err != nil {
+		return fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return nil
+}
+
 // Global reaper config
 type Config struct {
 	HTTP reaperevents.HTTPConfig
@@ -87,21 +197,67 @@ type Config struct {
 	Logging       log.LogConfig
 	States        state.StatesConfig
 
-	Events           EventTypes
+	Events EventTypes
+	// Reporters are the names of the sections under [Events] to enable,
+	// overriding their Enabled, if set
+	Reporters        []string
 	EventTag         string
 	LogFile          string
 	WhitelistTag     string
+	OwnerTags        []string
 	DefaultOwner     string
 	DefaultEmailHost string
 
+	// ExportDependencyGraph is a file the relationships between resources
+	// found each cycle are written to, as DOT if it ends in .dot and
+	// otherwise as json
+	ExportDependencyGraph string
+
+	// NotifyTag is the tag key of a resource's NotificationChannel,
+	// see aws.Resource.NotificationChannel
+	NotifyTag string
+
+	// Templates is a directory of custom event templates, see aws.LoadTemplates
+	Templates string
+
 	AutoScalingGroups ResourceConfig
 	Instances         ResourceConfig
 	Snapshots         ResourceConfig
 	Cloudformations   ResourceConfig
 	SecurityGroups    ResourceConfig
 	Volumes           ResourceConfig
+	Images            ImageConfig
+	NetworkInterfaces ResourceConfig
 
 	DryRun bool
+
+	// AutoTerminate terminates resources that reach the final state
+	AutoTerminate bool
+
+	// Safety halts reaping types with an abnormal number of filtered resources
+	Safety SafetyConfig
+
+	// OwnerStatistics emits resource counts and costs per owner
+	OwnerStatistics OwnerStatisticsConfig
+
+	// DeadLetter records resources that failed to be terminated or stopped
+	DeadLetter DeadLetterConfig
+
+	// MinimumResourceAge keeps resources created more recently than this
+	// from ever matching filters
+	MinimumResourceAge state.Duration
+
+	// NeverReapIDs are region/id pairs that never match filters,
+	// whatever their tags
+	NeverReapIDs []string
+
+	// WhitelistPolicyFile is an AWS Organizations tag policy whose tag keys,
+	// and their values, whitelist resources like the WhitelistTag
+	WhitelistPolicyFile string
+	whitelistRules      []whitelistRule
+	// NeverReapIDsFile is a file of region/id pairs, one per line, that
+	// never match filters, reloaded each cycle when it changes
+	NeverReapIDsFile string
 }
 
 type EventTypes struct {
@@ -110,9 +266,111 @@ type EventTypes struct {
 	Email             reaperevents.MailerConfig
 	Tagger            reaperevents.TaggerConfig
 	Reaper            reaperevents.ReaperEventConfig
+	SNS               reaperevents.SNSConfig
+	Slack             reaperevents.SlackConfig
 }
 
 type ResourceConfig struct {
 	Enabled      bool
 	FilterGroups map[string]filters.FilterGroup
+
+	// RegionOverrides override Enabled in a region, by region name
+	RegionOverrides map[string]RegionOverride
+
+	// FilterExpression optionally combines FilterGroups by name with AND, OR and NOT
+	// if empty, a resource matching any FilterGroup is a match
+	FilterExpression string
+	filterExpression filters.Expression
+
+	// MaxConcurrentActions limits how many resources of this type
+	// are terminated or stopped at once, see defaultMaxConcurrentActions
+	MaxConcurrentActions int
+
+	// DryRun overrides the global DryRun for actions on this type, if set
+	DryRun *bool
+
+	// StopLabel is the text of the stop link in events, for the types that
+	// can be stopped, see aws.Config.InstanceStopLabel
+	StopLabel string
+
+	// PropagateWhitelist is whether whitelisting an AutoScalingGroup also
+	// whitelists its instances, for AutoScalingGroups only
+	PropagateWhitelist bool
+}
+
+// resourceConfig returns the ResourceConfig of a resource type (see reapableType)
+// unknown types get an empty ResourceConfig
+func resourceConfig(resourceType string) ResourceConfig {
+	switch resourceType {
+	case "instances":
+		return config.Instances
+	case "asgs":
+		return config.AutoScalingGroups
+	case "cloudformations":
+		return config.Cloudformations
+	case "securitygroups":
+		return config.SecurityGroups
+	case "volumes":
+		return config.Volumes
+	case "images":
+		return config.Images.ResourceConfig
+	case "networkinterfaces":
+		return config.NetworkInterfaces
+	}
+	return ResourceConfig{}
+}
+
+// RegionOverride overrides a ResourceConfig in a region
+type RegionOverride struct {
+	// Enabled overrides ResourceConfig.Enabled, if set
+	Enabled *bool
+}
+
+// enabledIn returns whether the resource type is enabled in a region
+// defaults to Enabled, unless the region overrides it
+func (c *ResourceConfig) enabledIn(region reapable.Region) bool {
+	if override, ok := c.RegionOverrides[string(region)]; ok && override.Enabled != nil {
+		return *override.Enabled
+	}
+	return c.Enabled
+}
+
+// enabledAnywhere returns whether the resource type is enabled in any region
+func (c *ResourceConfig) enabledAnywhere() bool {
+	if c.Enabled {
+		return true
+	}
+	for _, override := range c.RegionOverrides {
+		if override.Enabled != nil && *override.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageConfig is the ResourceConfig for AMIs
+type ImageConfig struct {
+	ResourceConfig
+
+	// DeleteBackingSnapshots deletes the snapshots backing an AMI when it is deregistered
+	DeleteBackingSnapshots bool
+}
+
+// parseFilterExpression parses FilterExpression, checking that
+// every FilterGroup it references exists
+func (c *ResourceConfig) parseFilterExpression() error {
+	if c.FilterExpression == "" {
+		return nil
+	}
+	e, err := filters.ParseExpression(c.FilterExpression)
+	if err != nil {
+		return err
+	}
+	for _, name := range e.Groups() {
+		if _, ok := c.FilterGroups[name]; !ok {
+			return fmt.Errorf("FilterExpression %q references unknown FilterGroup %q", c.FilterExpression, name)
+		}
+	}
+	c.filterExpression = e
+	return nil
 }
@@ -0,0 +1,177 @@
+package reaper
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+func TestRegionOverrides(t *testing.T) {
+	enabled, disabled := true, false
+	overrides := map[string]RegionOverride{
+		"us-west-2": RegionOverride{Enabled: &enabled},
+		"us-east-1": RegionOverride{Enabled: &disabled},
+		"eu-west-1": RegionOverride{},
+	}
+
+	tests := []struct {
+		globallyEnabled bool
+		region          reapable.Region
+		expected        bool
+	}{
+		{false, "us-west-2", true},
+		{true, "us-west-2", true},
+		{false, "us-east-1", false},
+		{true, "us-east-1", false},
+		// an override without Enabled, or no override, uses Enabled
+		{true, "eu-west-1", true},
+		{false, "eu-west-1", false},
+		{true, "ap-southeast-2", true},
+		{false, "ap-southeast-2", false},
+	}
+	for _, test := range tests {
+		c := ResourceConfig{Enabled: test.globallyEnabled, RegionOverrides: overrides}
+		if c.enabledIn(test.region) != test.expected {
+			t.Errorf("Enabled %t in %s: expected %t", test.globallyEnabled, test.region, test.expected)
+		}
+	}
+
+	if !(&ResourceConfig{RegionOverrides: overrides}).enabledAnywhere() {
+		t.Error("expected a type enabled only in us-west-2 to be enabled somewhere")
+	}
+	if (&ResourceConfig{RegionOverrides: map[string]RegionOverride{"us-east-1": RegionOverride{Enabled: &disabled}}}).enabledAnywhere() {
+		t.Error("expected a type that is disabled everywhere not to be enabled anywhere")
+	}
+}
+
+// setTestEnv replaces lookupEnv with env, returning a func that restores it
+func setTestEnv(env map[string]string) (restore func()) {
+	original := lookupEnv
+	lookupEnv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	return func() { lookupEnv = original }
+}
+
+func TestInterpolate(t *testing.T) {
+	defer setTestEnv(map[string]string{"TOKEN_SECRET": "s3cret", "HOST": "smtp.example.com", "EMPTY": ""})()
+
+	tests := []struct {
+		s, expected string
+	}{
+		{"${TOKEN_SECRET}", "s3cret"},
+		{"smtp://${HOST}:587", "smtp://smtp.example.com:587"},
+		{"${HOST}/${TOKEN_SECRET}", "smtp.example.com/s3cret"},
+		{"${EMPTY}", ""},
+		// literals are left alone
+		{"a literal secret", "a literal secret"},
+		{"pa$$word", "pa$$word"},
+		{"$HOST", "$HOST"},
+		{"${not a reference}", "${not a reference}"},
+		{"$${HOST}", "${HOST}"},
+	}
+	for _, test := range tests {
+		s, err := interpolate(test.s)
+		if err != nil {
+			t.Errorf("%q: %s", test.s, err.Error())
+		} else if s != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.s, test.expected, s)
+		}
+	}
+
+	if _, err := interpolate("${UNSET}"); err == nil {
+		t.Error("expected an error for an unset environment variable")
+	}
+}
+
+func TestLoadConfigInterpolatesEnv(t *testing.T) {
+	defer setTestEnv(map[string]string{"REAPER_TOKEN_SECRET": "s3cret", "REAPER_SMTP_PASSWORD": "hunter2"})()
+
+	f, err := ioutil.TempFile("", "reaper-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	path := f.Name() + ".json"
+	defer os.Remove(path)
+	err = ioutil.WriteFile(path, []byte(`{
+		"HTTP": {"TokenSecret": "${REAPER_TOKEN_SECRET}", "Listen": "localhost:9000"},
+		"Events": {"Email": {"Password": "${REAPER_SMTP_PASSWORD}", "Username": "reaper"}},
+		"OwnerTags": ["Owner", "${REAPER_TOKEN_SECRET}"],
+		"States": {"Interval": "1h"}
+	}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.HTTP.TokenSecret != "s3cret" || c.AWS.HTTP.TokenSecret != "s3cret" {
+		t.Errorf("expected TokenSecret to be interpolated, got %q", c.HTTP.TokenSecret)
+	}
+	if c.Events.Email.Password != "hunter2" || c.Events.Email.Username != "reaper" {
+		t.Errorf("expected the Email password to be interpolated, got %q", c.Events.Email.Password)
+	}
+	if len(c.OwnerTags) != 2 || c.OwnerTags[0] != "Owner" || c.OwnerTags[1] != "s3cret" {
+		t.Errorf("expected OwnerTags to be interpolated, got %v", c.OwnerTags)
+	}
+	if c.States.Interval.Duration != time.Hour {
+		t.Errorf("expected an Interval of 1h, got %s", c.States.Interval.Duration)
+	}
+
+	// values of the wrong type and unknown keys are errors
+	for _, invalid := range []string{`{"DryRun": "yes"}`, `{"NotAnOption": true}`} {
+		if err := ioutil.WriteFile(path, []byte(invalid), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("expected an error loading %s", invalid)
+		}
+	}
+}
+
+func TestApplyReporters(t *testing.T) {
+	newConfig := func(reporters ...string) *Config {
+		return &Config{
+			Reporters: reporters,
+			Events: EventTypes{
+				DatadogStatistics: reaperevents.DatadogConfig{EventReporterConfig: &reaperevents.EventReporterConfig{Enabled: true}},
+				Tagger:            reaperevents.TaggerConfig{EventReporterConfig: &reaperevents.EventReporterConfig{}},
+				SNS:               reaperevents.SNSConfig{EventReporterConfig: &reaperevents.EventReporterConfig{}},
+			},
+		}
+	}
+
+	// without Reporters, each section's Enabled is kept
+	c := newConfig()
+	if err := c.applyReporters(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Events.DatadogStatistics.Enabled || c.Events.Tagger.Enabled {
+		t.Error("expected Enabled to be kept without Reporters")
+	}
+
+	c = newConfig("Tagger", "SNS")
+	if err := c.applyReporters(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Events.DatadogStatistics.Enabled || !c.Events.Tagger.Enabled || !c.Events.SNS.Enabled {
+		t.Error("expected exactly the EventReporters in Reporters to be enabled")
+	}
+	if ers := c.EventReporters(); len(ers) != 2 {
+		t.Errorf("expected 2 EventReporters, got %d", len(ers))
+	}
+
+	for _, reporters := range [][]string{{"PagerDuty"}, {"Reaper"}} {
+		if err := newConfig(reporters...).applyReporters(); err == nil {
+			t.Errorf("expected an error enabling %v", reporters)
+		}
+	}
+}
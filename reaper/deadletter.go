@@ -0,0 +1,223 @@
+package reaper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// DeadLetterConfig configures the record of resources that failed to be
+// terminated or stopped
+type DeadLetterConfig struct {
+	// File is a json file the records are kept in across restarts
+	// if empty, they are only kept in memory
+	File string
+
+	// MaxRetries is how many cycles a failed action is retried in
+	MaxRetries int
+}
+
+// deadLetter is a resource whose action failed
+type deadLetter struct {
+	Region  reapable.Region `json:"region"`
+	ID      reapable.ID     `json:"id"`
+	Action  string          `json:"action"`
+	Error   string          `json:"error"`
+	Time    time.Time       `json:"timestamp"`
+	Retries int             `json:"retries"`
+}
+
+// deadLetterActions are the actions that are retried, by name
+var deadLetterActions = map[string]func(reapable.Reapable) (bool, error){
+	"terminate":  terminate,
+	"stop":       stop,
+	"force-stop": forceStop,
+}
+
+// deadLetters holds the failed actions, by region/id
+var deadLetters = &deadLetterList{letters: make(map[string]deadLetter)}
+
+type deadLetterList struct {
+	sync.Mutex
+	letters map[string]deadLetter
+}
+
+func deadLetterKey(region reapable.Region, id reapable.ID) string {
+	return fmt.Sprintf("%s/%s", region, id)
+}
+
+// failed records that action failed on r with err
+// a resource that already failed the same action has its retries counted
+func (l *deadLetterList) failed(r reapable.Reapable, action string, err error) {
+	l.Lock()
+	defer l.Unlock()
+	key := deadLetterKey(r.Region(), r.ID())
+	letter, ok := l.letters[key]
+	if ok && letter.Action == action {
+		letter.Retries++
+	} else {
+		letter = deadLetter{Region: r.Region(), ID: r.ID(), Action: action}
+	}
+	letter.Error = err.Error()
+	letter.Time = now()
+	if ok && letter.Retries == config.DeadLetter.MaxRetries {
+		log.Error("Giving up on %s of %s after %d retries: %s", action, key, letter.Retries, letter.Error)
+	}
+	l.letters[key] = letter
+	l.save()
+}
+
+// succeeded forgets the failed action of a resource, if any
+func (l *deadLetterList) succeeded(region reapable.Region, id reapable.ID) {
+	l.Lock()
+	defer l.Unlock()
+	key := deadLetterKey(region, id)
+	if _, ok := l.letters[key]; !ok {
+		return
+	}
+	delete(l.letters, key)
+	l.save()
+}
+
+// list returns the failed actions, sorted by region and id
+func (l *deadLetterList) list() []deadLetter {
+	l.Lock()
+	defer l.Unlock()
+	letters := []deadLetter{}
+	for _, letter := range l.letters {
+		letters = append(letters, letter)
+	}
+	sort.Sort(deadLettersByKey(letters))
+	return letters
+}
+
+// save writes the failed actions to DeadLetter.File, if set
+// the caller must hold the lock
+func (l *deadLetterList) save() {
+	path := config.DeadLetter.File
+	if path == "" {
+		return
+	}
+	letters := []deadLetter{}
+	for _, letter := range l.letters {
+		letters = append(letters, letter)
+	}
+	sort.Sort(deadLettersByKey(letters))
+	b, err := json.MarshalIndent(letters, "", "  ")
+	if err != nil {
+		log.Error("Could not encode dead letters: %s", err.Error())
+		return
+	}
+	// write then rename, so that a crash never leaves a partial file
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		log.Error("Could not save dead letters: %s", err.Error())
+		return
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		log.Error("Could not save dead letters: %s", err.Error())
+	}
+}
+
+// load reads the failed actions from path, if it exists
+func (l *deadLetterList) load(path string) error {
+	if path == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var letters []deadLetter
+	if err := json.Unmarshal(b, &letters); err != nil {
+		return fmt.Errorf("%s: %s", path, err.Error())
+	}
+	l.Lock()
+	defer l.Unlock()
+	l.letters = make(map[string]deadLetter)
+	for _, letter := range letters {
+		l.letters[deadLetterKey(letter.Region, letter.ID)] = letter
+	}
+	return nil
+}
+
+type deadLettersByKey []deadLetter
+
+func (s deadLettersByKey) Len() int      { return len(s) }
+func (s deadLettersByKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s deadLettersByKey) Less(i, j int) bool {
+	return deadLetterKey(s[i].Region, s[i].ID) < deadLetterKey(s[j].Region, s[j].ID)
+}
+
+// recordAction records the outcome of action on r in deadLetters
+func recordAction(r reapable.Reapable, action string, err error) {
+	if err != nil {
+		deadLetters.failed(r, action, err)
+		return
+	}
+	deadLetters.succeeded(r.Region(), r.ID())
+}
+
+// retryDeadLetters retries each failed action that has been retried fewer
+// than DeadLetter.MaxRetries times
+// resources that no longer exist, or have been protected since, are
+// forgotten, as are all of them in DryRun mode
+func retryDeadLetters() {
+	for _, letter := range deadLetters.list() {
+		if letter.Retries >= config.DeadLetter.MaxRetries {
+			continue
+		}
+		action, ok := deadLetterActions[letter.Action]
+		if !ok {
+			log.Error("Unknown dead letter action %q for %s", letter.Action, deadLetterKey(letter.Region, letter.ID))
+			deadLetters.succeeded(letter.Region, letter.ID)
+			continue
+		}
+		r, err := findReapable(letter.Region, letter.ID)
+		if _, notFound := err.(reapable.ReapableNotFoundError); notFound {
+			log.Info("Forgetting dead letter for %s, it no longer exists", deadLetterKey(letter.Region, letter.ID))
+			deadLetters.succeeded(letter.Region, letter.ID)
+			continue
+		} else if err != nil {
+			log.Error("Could not find %s to retry %s: %s", deadLetterKey(letter.Region, letter.ID), letter.Action, err.Error())
+			continue
+		}
+
+		// resources protected since the action failed are left alone
+		if isWhitelisted(r) || neverReaped(r) {
+			log.Info("Forgetting dead letter for %s, it is protected by the WhitelistTag or NeverReapIDs", r.ReapableDescriptionTiny())
+			deadLetters.succeeded(letter.Region, letter.ID)
+			continue
+		}
+		// in DryRun mode, actions aren't recorded, so the letter would be
+		// retried every cycle
+		if dryRun(r) {
+			log.Info("DryRun: Forgetting dead letter for %s", r.ReapableDescriptionTiny())
+			deadLetters.succeeded(letter.Region, letter.ID)
+			continue
+		}
+
+		log.Info("Retrying %s of %s, attempt %d of %d", letter.Action, r.ReapableDescriptionTiny(), letter.Retries+1, config.DeadLetter.MaxRetries)
+		if _, err := action(r); err != nil {
+			log.Error("Retrying %s of %s failed: %s", letter.Action, r.ReapableDescriptionTiny(), err.Error())
+		}
+	}
+}
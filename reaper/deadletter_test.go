@@ -0,0 +1,147 @@
+package reaper
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setTestDeadLetters empties deadLetters, returning a func that restores them
+func setTestDeadLetters() (restore func()) {
+	original := deadLetters
+	deadLetters = &deadLetterList{letters: make(map[string]deadLetter)}
+	return func() { deadLetters = original }
+}
+
+func TestDeadLetterRecorded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reaper-deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "deadletters.json")
+
+	defer setTestConfig(&Config{DeadLetter: DeadLetterConfig{File: path, MaxRetries: 3}})()
+	defer setTestDeadLetters()()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "owner@example.com")
+	r.terminateErr = errors.New("UnauthorizedOperation")
+	if _, err := terminate(r); err == nil {
+		t.Fatal("expected the termination to fail")
+	}
+
+	letters := deadLetters.list()
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.Region != "us-west-2" || letter.ID != "i-1" || letter.Action != "terminate" ||
+		letter.Error != "UnauthorizedOperation" || letter.Time.IsZero() || letter.Retries != 0 {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+
+	// the dead letters are kept across restarts
+	loaded := &deadLetterList{}
+	if err := loaded.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.list()) != 1 || loaded.list()[0].ID != "i-1" {
+		t.Errorf("expected the dead letter to be loaded from %s, got %+v", path, loaded.list())
+	}
+
+	// a successful action forgets it
+	r.terminateErr = nil
+	if _, err := terminate(r); err != nil {
+		t.Fatal(err)
+	}
+	if len(deadLetters.list()) != 0 {
+		t.Error("expected a successful termination to forget the dead letter")
+	}
+	if err := loaded.load(path); err != nil || len(loaded.list()) != 0 {
+		t.Errorf("expected the saved dead letters to be empty, got %+v, %v", loaded.list(), err)
+	}
+}
+
+func TestDeadLetterRetries(t *testing.T) {
+	defer setTestConfig(&Config{DeadLetter: DeadLetterConfig{MaxRetries: 2}})()
+	defer setTestDeadLetters()()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "owner@example.com")
+	r.terminateErr = errors.New("UnauthorizedOperation")
+	defer setTestFindReapable(r)()
+	terminate(r)
+
+	for cycle := 0; cycle < 4; cycle++ {
+		retryDeadLetters()
+	}
+	letters := deadLetters.list()
+	if len(letters) != 1 || letters[0].Retries != 2 {
+		t.Fatalf("expected retries to stop at 2, got %+v", letters)
+	}
+
+	// a retry that succeeds forgets the dead letter
+	deadLetters.letters[deadLetterKey(r.region, r.id)] = deadLetter{Region: r.region, ID: r.id, Action: "terminate", Retries: 1}
+	r.terminateErr = nil
+	retryDeadLetters()
+	if r.terminated != 1 || len(deadLetters.list()) != 0 {
+		t.Errorf("expected a successful retry to terminate and forget the resource, got %d terminations and %+v", r.terminated, deadLetters.list())
+	}
+}
+
+func TestDeadLetterRetryProtected(t *testing.T) {
+	defer setTestConfig(&Config{
+		WhitelistTag: "REAPER_SPARE_ME",
+		NeverReapIDs: []string{"us-west-2/i-listed"},
+		DeadLetter:   DeadLetterConfig{MaxRetries: 2},
+	})()
+	defer setTestNeverReap()()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	whitelisted := newTestReapable("us-west-2", "i-whitelisted", "owner@example.com")
+	listed := newTestReapable("us-west-2", "i-listed", "owner@example.com")
+	for _, r := range []*testReapable{whitelisted, listed} {
+		defer setTestDeadLetters()()
+		r.terminateErr = errors.New("UnauthorizedOperation")
+		terminate(r)
+		r.terminateErr = nil
+
+		// protected after the termination failed
+		if r == whitelisted {
+			r.filters = map[string]bool{"Tagged": true}
+		}
+		restoreFind := setTestFindReapable(r)
+		retryDeadLetters()
+		restoreFind()
+		if r.terminated != 0 {
+			t.Errorf("expected protected %s not to be terminated by a retry", r.id)
+		}
+		if len(deadLetters.list()) != 0 {
+			t.Errorf("expected the dead letter of protected %s to be forgotten, got %+v", r.id, deadLetters.list())
+		}
+	}
+}
+
+func TestDeadLetterRetryDryRun(t *testing.T) {
+	defer setTestConfig(&Config{DeadLetter: DeadLetterConfig{MaxRetries: 2}})()
+	defer setTestDeadLetters()()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "owner@example.com")
+	r.terminateErr = errors.New("UnauthorizedOperation")
+	defer setTestFindReapable(r)()
+	terminate(r)
+
+	config.DryRun = true
+	retryDeadLetters()
+	if len(deadLetters.list()) != 0 {
+		t.Errorf("expected a DryRun retry to forget the dead letter, got %+v", deadLetters.list())
+	}
+}
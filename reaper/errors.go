@@ -0,0 +1,95 @@
+package reaper
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+// ErrReapableNotFound is returned when a resource isn't known to Reaper,
+// such as when it wasn't discovered in the last cycle
+type ErrReapableNotFound struct {
+	Region reapable.Region
+	ID     reapable.ID
+}
+
+func (e ErrReapableNotFound) Error() string {
+	return fmt.Sprintf("Could not find resource %s in %s", e.ID, e.Region)
+}
+
+// ErrActionDenied is returned when AWS refuses an action, such as for
+// missing IAM permissions or termination protection
+type ErrActionDenied struct {
+	Action string
+	Region reapable.Region
+	ID     reapable.ID
+	Err    error
+}
+
+func (e ErrActionDenied) Error() string {
+	return fmt.Sprintf("%s of %s in %s was denied: %s", e.Action, e.ID, e.Region, e.Err.Error())
+}
+
+// ErrAlreadyTerminated is returned when a resource no longer exists in AWS,
+// such as when it was deleted since it was discovered
+type ErrAlreadyTerminated struct {
+	Action string
+	Region reapable.Region
+	ID     reapable.ID
+	Err    error
+}
+
+func (e ErrAlreadyTerminated) Error() string {
+	return fmt.Sprintf("Could not %s %s in %s, it no longer exists: %s", e.Action, e.ID, e.Region, e.Err.Error())
+}
+
+// deniedErrorCodes are the AWS error codes of refused actions
+var deniedErrorCodes = map[string]bool{
+	"AccessDenied":          true,
+	"AccessDeniedException": true,
+	"UnauthorizedOperation": true,
+	"OperationNotPermitted": true,
+}
+
+// actionError returns err as an ErrActionDenied or ErrAlreadyTerminated
+// if its AWS error code is one of theirs, and otherwise err
+func actionError(r reapable.Reapable, action string, err error) error {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+	switch code := aerr.Code(); {
+	case deniedErrorCodes[code]:
+		return ErrActionDenied{Action: action, Region: r.Region(), ID: r.ID(), Err: err}
+	case strings.HasSuffix(code, ".NotFound") || code == "InvalidAMIID.Unavailable" || autoScalingGroupNotFound(aerr):
+		return ErrAlreadyTerminated{Action: action, Region: r.Region(), ID: r.ID(), Err: err}
+	}
+	return err
+}
+
+// autoScalingGroupNotFound returns whether aerr is the AutoScaling API's
+// error for a group that no longer exists, which has no code of its own,
+// such as "AutoScalingGroup name not found - AutoScalingGroup 'asg' not found"
+func autoScalingGroupNotFound(aerr awserr.Error) bool {
+	return aerr.Code() == "ValidationError" &&
+		strings.Contains(aerr.Message(), "AutoScalingGroup") &&
+		strings.Contains(aerr.Message(), "not found")
+}
+
+// errorStatus returns the HTTP status code of an error returned by Reaper's
+// actions
+func errorStatus(err error) int {
+	switch err.(type) {
+	case ErrReapableNotFound, reapable.ReapableNotFoundError:
+		return http.StatusNotFound
+	case ErrActionDenied:
+		return http.StatusForbidden
+	case ErrAlreadyTerminated:
+		return http.StatusGone
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,68 @@
+package reaper
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+func TestActionErrors(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	defer setTestDeadLetters()()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "owner@example.com")
+	reapables.Reset([]string{"us-west-2"})
+	reapables.Put(r.Region(), r.ID(), r)
+
+	err := Terminate("us-west-2", "i-unknown")
+	if _, ok := err.(ErrReapableNotFound); !ok {
+		t.Errorf("expected an ErrReapableNotFound for an unknown resource, got %T: %v", err, err)
+	}
+
+	r.terminateErr = awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+	err = Terminate("us-west-2", "i-1")
+	if _, ok := err.(ErrActionDenied); !ok {
+		t.Errorf("expected an ErrActionDenied when AWS refuses, got %T: %v", err, err)
+	}
+
+	r.terminateErr = awserr.New("InvalidInstanceID.NotFound", "The instance ID 'i-1' does not exist", nil)
+	err = Terminate("us-west-2", "i-1")
+	if _, ok := err.(ErrAlreadyTerminated); !ok {
+		t.Errorf("expected an ErrAlreadyTerminated for a resource that no longer exists, got %T: %v", err, err)
+	}
+
+	// other validation errors aren't a missing AutoScalingGroup
+	r.terminateErr = awserr.New("ValidationError", "MinSize must be less than or equal to MaxSize", nil)
+	if err = Terminate("us-west-2", "i-1"); err != r.terminateErr {
+		t.Errorf("expected other ValidationErrors to be returned as they are, got %T: %v", err, err)
+	}
+
+	r.terminateErr = errors.New("connection reset")
+	if err = Terminate("us-west-2", "i-1"); err != r.terminateErr {
+		t.Errorf("expected other errors to be returned as they are, got %T: %v", err, err)
+	}
+	r.terminateErr = nil
+	if err := Stop("us-west-2", "i-unknown"); err == nil || errorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected a 404 for stopping an unknown resource, got %v", err)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	for err, expected := range map[error]int{
+		ErrReapableNotFound{Region: "us-west-2", ID: "i-1"}:                http.StatusNotFound,
+		reapable.ReapableNotFoundError{ErrorText: "not found"}:             http.StatusNotFound,
+		ErrActionDenied{Action: "terminate", Err: errors.New("denied")}:    http.StatusForbidden,
+		ErrAlreadyTerminated{Action: "terminate", Err: errors.New("gone")}: http.StatusGone,
+		errors.New("connection reset"):                                     http.StatusInternalServerError,
+	} {
+		if status := errorStatus(err); status != expected {
+			t.Errorf("expected %d for %T, got %d", expected, err, status)
+		}
+	}
+}
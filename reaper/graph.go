@@ -0,0 +1,213 @@
+package reaper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// dependencyNode is a discovered resource in a dependencyGraph
+type dependencyNode struct {
+	Region             string `json:"region"`
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	Dependency         bool   `json:"dependency"`
+	InCloudformation   bool   `json:"inCloudformation"`
+	CloudformationName string `json:"cloudformation,omitempty"`
+}
+
+// dependencyEdge is a relationship from one resource to another,
+// which makes To a dependency
+type dependencyEdge struct {
+	Region   string `json:"region"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// dependencyGraph records the relationships allReapables finds between
+// resources, so that why a resource is or isn't a dependency can be inspected
+// a nil dependencyGraph records nothing
+type dependencyGraph struct {
+	Nodes []dependencyNode `json:"nodes"`
+	Edges []dependencyEdge `json:"edges"`
+}
+
+// newDependencyGraph returns a dependencyGraph if ExportDependencyGraph is
+// set, and otherwise nil
+func newDependencyGraph() *dependencyGraph {
+	if config.ExportDependencyGraph == "" {
+		return nil
+	}
+	return &dependencyGraph{}
+}
+
+func (g *dependencyGraph) addEdge(region reapable.Region, from, to reapable.ID, relation string) {
+	if g == nil {
+		return
+	}
+	g.Edges = append(g.Edges, dependencyEdge{Region: region.String(), From: from.String(), To: to.String(), Relation: relation})
+}
+
+// addCloudformation records the resources of a Cloudformation,
+// including those of nested stacks
+func (g *dependencyGraph) addCloudformation(c *reaperaws.Cloudformation) {
+	if g == nil {
+		return
+	}
+	for _, id := range c.PhysicalResourceIDs() {
+		g.addEdge(c.Region(), c.ID(), id, "contains")
+	}
+}
+
+// addAutoScalingGroup records the instances of an AutoScalingGroup
+func (g *dependencyGraph) addAutoScalingGroup(a *reaperaws.AutoScalingGroup, instanceIDs map[reapable.Region]map[reapable.ID]bool) {
+	if g == nil {
+		return
+	}
+	for region := range instanceIDs {
+		for id := range instanceIDs[region] {
+			g.addEdge(region, a.ID(), id, "launches")
+		}
+	}
+}
+
+// addInstance records the security groups and AMI an instance uses
+func (g *dependencyGraph) addInstance(i *reaperaws.Instance) {
+	if g == nil {
+		return
+	}
+	for id := range i.SecurityGroups {
+		g.addEdge(i.Region(), i.ID(), id, "uses")
+	}
+	if i.ImageId != nil {
+		g.addEdge(i.Region(), i.ID(), reapable.ID(*i.ImageId), "launched-from")
+	}
+}
+
+// addVolume records the instances a volume is attached to
+func (g *dependencyGraph) addVolume(v *reaperaws.Volume) {
+	if g == nil {
+		return
+	}
+	for _, id := range v.AttachedInstanceIDs {
+		g.addEdge(v.Region(), reapable.ID(id), v.ID(), "attaches")
+	}
+}
+
+// addNodes records the discovered resources, once their Dependency and
+// IsInCloudformation are resolved
+func (g *dependencyGraph) addNodes(rs []reaperevents.Reapable) {
+	if g == nil {
+		return
+	}
+	for _, r := range rs {
+		node := dependencyNode{Region: r.Region().String(), ID: r.ID().String(), Type: reapableType(r)}
+		if resource, ok := awsResource(r); ok {
+			node.Dependency = resource.Dependency
+			node.InCloudformation = resource.IsInCloudformation
+			node.CloudformationName = resource.CloudformationStackName
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+}
+
+// sort orders the nodes and edges, so that exports of the same resources
+// are identical
+func (g *dependencyGraph) sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Region != g.Nodes[j].Region {
+			return g.Nodes[i].Region < g.Nodes[j].Region
+		}
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Relation < b.Relation
+	})
+}
+
+// writeDOT writes the graph in Graphviz's DOT format
+// nodes are named region/id, and dependencies are drawn bold
+func (g *dependencyGraph) writeDOT(w io.Writer) error {
+	var b bytes.Buffer
+	b.WriteString("digraph dependencies {\n")
+	for _, n := range g.Nodes {
+		var attrs []string
+		attrs = append(attrs, fmt.Sprintf("label=%q", fmt.Sprintf("%s %s", n.Type, n.ID)))
+		if n.Dependency {
+			attrs = append(attrs, "style=bold")
+		}
+		fmt.Fprintf(&b, "\t%q [%s];\n", n.Region+"/"+n.ID, strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", e.Region+"/"+e.From, e.Region+"/"+e.To, e.Relation)
+	}
+	b.WriteString("}\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// export writes the graph to ExportDependencyGraph, as DOT if it ends
+// in .dot and otherwise as json
+func (g *dependencyGraph) export() {
+	if g == nil {
+		return
+	}
+	g.sort()
+	path := config.ExportDependencyGraph
+
+	var b bytes.Buffer
+	var err error
+	if strings.HasSuffix(path, ".dot") {
+		err = g.writeDOT(&b)
+	} else {
+		enc := json.NewEncoder(&b)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(g)
+	}
+	if err != nil {
+		log.Error("Could not encode the dependency graph: %s", err.Error())
+		return
+	}
+
+	// write then rename, so that readers never see a partial file
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		log.Error("Could not export the dependency graph: %s", err.Error())
+		return
+	}
+	_, err = f.Write(b.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		log.Error("Could not export the dependency graph: %s", err.Error())
+		return
+	}
+	log.Info("Exported the dependency graph of %d resources to %s", len(g.Nodes), path)
+}
@@ -0,0 +1,84 @@
+package reaper
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reaper-graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setTestConfig(&Config{ExportDependencyGraph: filepath.Join(dir, "graph.json")})()
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+
+	group := reaperaws.NewAutoScalingGroup("us-west-2", &autoscaling.Group{
+		AutoScalingGroupName: aws.String("web"),
+	})
+	instance := reaperaws.NewInstance("us-west-2", &ec2.Instance{
+		InstanceId:     aws.String("i-1"),
+		ImageId:        aws.String("ami-1"),
+		SecurityGroups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-1"), GroupName: aws.String("web")}},
+	})
+	volume := reaperaws.NewVolume("us-west-2", &ec2.Volume{VolumeId: aws.String("vol-1")})
+	volume.AttachedInstanceIDs = []string{"i-1"}
+
+	graph := newDependencyGraph()
+	graph.addVolume(volume)
+	graph.addInstance(instance)
+	graph.addAutoScalingGroup(group, map[reapable.Region]map[reapable.ID]bool{"us-west-2": {"i-1": true}})
+	graph.addNodes([]reaperevents.Reapable{instance, volume})
+	graph.export()
+
+	b, err := ioutil.ReadFile(config.ExportDependencyGraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported dependencyGraph
+	if err := json.Unmarshal(b, &exported); err != nil {
+		t.Fatal(err)
+	}
+	expected := []dependencyEdge{
+		{Region: "us-west-2", From: "i-1", To: "ami-1", Relation: "launched-from"},
+		{Region: "us-west-2", From: "i-1", To: "sg-1", Relation: "uses"},
+		{Region: "us-west-2", From: "i-1", To: "vol-1", Relation: "attaches"},
+		{Region: "us-west-2", From: "web", To: "i-1", Relation: "launches"},
+	}
+	if !reflect.DeepEqual(exported.Edges, expected) {
+		t.Errorf("expected edges %v, got %v", expected, exported.Edges)
+	}
+	if len(exported.Nodes) != 2 || exported.Nodes[0].ID != "i-1" || exported.Nodes[0].Type != "instances" {
+		t.Errorf("expected the instance and volume nodes, got %v", exported.Nodes)
+	}
+
+	var dot bytes.Buffer
+	if err := graph.writeDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dot.String(), `"us-west-2/i-1" -> "us-west-2/sg-1" [label="uses"];`) {
+		t.Errorf("expected an edge from the instance to its security group, got %s", dot.String())
+	}
+
+	// nothing is recorded when ExportDependencyGraph isn't set
+	config.ExportDependencyGraph = ""
+	if g := newDependencyGraph(); g != nil {
+		t.Error("expected no dependency graph")
+	}
+}
@@ -1,11 +1,15 @@
 package reaper
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	reaperaws "github.com/mozilla-services/reaper/aws"
 	reaperevents "github.com/mozilla-services/reaper/events"
@@ -33,6 +37,13 @@ func (h *HTTPApi) Serve() (e error) {
 	mux.HandleFunc("/", processToken(h))
 	mux.HandleFunc("/__heartbeat__", heartbeat(h))
 	mux.HandleFunc("/__lbheartbeat__", heartbeat(h))
+	mux.HandleFunc("/healthz", heartbeat(h))
+	mux.HandleFunc("/readyz", readyz(h))
+	mux.HandleFunc("/reapables", dumpReapables(h))
+	mux.HandleFunc("/whatif", whatif(h))
+	mux.HandleFunc("/deadletters", listDeadLetters(h))
+	mux.HandleFunc("/terminate-batch", terminateBatch(h))
+	mux.HandleFunc("/whitelist", whitelist(h))
 	h.server = &http.Server{Handler: mux}
 
 	log.Debug("Starting HTTP server: %s", h.conf.Listen)
@@ -51,6 +62,7 @@ func NewHTTPApi(c reaperevents.HTTPConfig) *HTTPApi {
 
 func writeResponse(w http.ResponseWriter, code int, body string) {
 	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(code)
 	io.WriteString(w, fmt.Sprintf(`<DOCTYPE html>
 		<html>
 			<head>
@@ -72,6 +84,266 @@ func heartbeat(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+// readyz responds OK once Ready has been called, prices have been downloaded
+// and the first reap has started, and otherwise with what it is waiting for
+// either way, it includes the time of the last completed reap
+func readyz(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		health.Lock()
+		var waiting []string
+		if !health.ready {
+			waiting = append(waiting, "initialization")
+		}
+		if !health.pricesDownloaded {
+			waiting = append(waiting, "prices")
+		}
+		if !health.reapStarted {
+			waiting = append(waiting, "the first reap")
+		}
+		lastReap := "never"
+		if !health.lastReap.IsZero() {
+			lastReap = health.lastReap.UTC().Format(time.RFC3339)
+		}
+		health.Unlock()
+
+		w.Header().Set("X-Reaper-Last-Reap", lastReap)
+		if len(waiting) > 0 {
+			writeResponse(w, http.StatusServiceUnavailable, fmt.Sprintf("Waiting for %s. Last successful reap: %s",
+				strings.Join(waiting, ", "), lastReap))
+			return
+		}
+		writeResponse(w, http.StatusOK, fmt.Sprintf("Ready. Last successful reap: %s", lastReap))
+	}
+}
+
+// whatifRequest is the body of a /whatif request
+type whatifRequest struct {
+	// Type is the resource type the filters apply to, one of
+	// instances, asgs, cloudformations, securitygroups, volumes, images
+	// or networkinterfaces
+	Type string
+	ResourceConfig
+}
+
+// whatifResponse is the body of a /whatif response
+type whatifResponse struct {
+	Matched []reapable.DumpRecord `json:"matched"`
+	// errors of FilterGroups that could not be evaluated, by FilterGroup name
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// whatif applies the FilterGroups of a POSTed whatifRequest to every tracked
+// Reapable of its Type, and writes the matches as json
+// it changes no state and sends no events
+func whatif(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" {
+			writeResponse(w, http.StatusMethodNotAllowed, "POST a FilterGroups definition as json")
+			return
+		}
+		if !h.authorized(req) {
+			unauthorized(w)
+			return
+		}
+		var request whatifRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			writeResponse(w, http.StatusBadRequest, fmt.Sprintf("Could not parse the request: %s", err.Error()))
+			return
+		}
+		if err := request.parseFilterExpression(); err != nil {
+			writeResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		response := whatifResponse{
+			Matched: []reapable.DumpRecord{},
+			Errors:  make(map[string]string),
+		}
+		for r := range reapables.Iter() {
+			if reapableType(r.Reapable) != request.Type {
+				continue
+			}
+			matched, _, errs := evaluateFilters(r.Reapable, request.ResourceConfig)
+			for name, err := range errs {
+				response.Errors[name] = err.Error()
+			}
+			if matched {
+				response.Matched = append(response.Matched, reapable.NewDumpRecord(&r))
+			}
+		}
+		reapable.SortDumpRecords(response.Matched)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("Writing whatif response: %s", err.Error())
+		}
+	}
+}
+
+// dumpReapables writes every tracked Reapable as csv or json (the default)
+// selected by the format query parameter
+func dumpReapables(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !h.authorized(req) {
+			unauthorized(w)
+			return
+		}
+		format := req.URL.Query().Get("format")
+		switch format {
+		case "":
+			format = "json"
+			fallthrough
+		case "json":
+			w.Header().Set("Content-Type", "application/json")
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv")
+		default:
+			writeResponse(w, http.StatusBadRequest, fmt.Sprintf("Unknown format %s", format))
+			return
+		}
+		if err := reapables.Dump(w, format); err != nil {
+			log.Error("Dumping reapables: %s", err.Error())
+		}
+	}
+}
+
+// listDeadLetters writes the resources that failed to be terminated or
+// stopped as json
+func listDeadLetters(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !h.authorized(req) {
+			unauthorized(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(deadLetters.list()); err != nil {
+			log.Error("Writing dead letters: %s", err.Error())
+		}
+	}
+}
+
+// terminateBatch terminates every resource of a manifest signed with
+// SignManifest, POSTed as the token parameter, and writes the outcome of
+// each as json
+// a manifest that was changed after it was signed is rejected
+func terminateBatch(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" {
+			writeResponse(w, http.StatusMethodNotAllowed, "POST a signed manifest")
+			return
+		}
+		if err := req.ParseForm(); err != nil {
+			writeResponse(w, http.StatusBadRequest, "Bad request body")
+			return
+		}
+		userToken := req.Form.Get(h.conf.Token)
+		if userToken == "" {
+			writeResponse(w, http.StatusBadRequest, "Token Missing")
+			return
+		}
+
+		job, err := token.Untokenize(h.conf.TokenSecret, userToken)
+		if err != nil {
+			writeResponse(w, http.StatusForbidden, fmt.Sprintf("Invalid manifest: %s", err.Error()))
+			return
+		}
+		if job.Action != token.J_TERMINATE_BATCH {
+			writeResponse(w, http.StatusBadRequest, "Token is not a manifest")
+			return
+		}
+		if job.Expired() {
+			writeResponse(w, http.StatusBadRequest, "Token expired")
+			return
+		}
+
+		log.Info("Batch terminate request received for %d resources", len(job.Manifest))
+		results := terminateManifest(job.Manifest)
+		newCountStatistic("reaper.reapables.requests", []string{"type:terminatebatch", config.EventTag})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			log.Error("Writing batch terminate results: %s", err.Error())
+		}
+	}
+}
+
+// authorized returns whether a request carries the TokenSecret as a bearer
+// token, as operators' requests do
+// no request is authorized if the TokenSecret is not set
+func (h *HTTPApi) authorized(req *http.Request) bool {
+	const prefix = "Bearer "
+	header := req.Header.Get("Authorization")
+	if h.conf.TokenSecret == "" || !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, prefix)), []byte(h.conf.TokenSecret)) == 1
+}
+
+// unauthorized responds to a request that isn't authorized
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeResponse(w, http.StatusUnauthorized, "Unauthorized")
+}
+
+// whitelistResponse is the body of a /whitelist response
+type whitelistResponse struct {
+	reapable.DumpRecord
+	Whitelisted bool `json:"whitelisted"`
+}
+
+// whitelist whitelists the tracked Reapable of the region and id query
+// parameters, for operators, who authenticate with the TokenSecret instead of
+// a token link, and writes its state as json
+// a Reapable that is already tagged with the whitelist tag isn't tagged again
+func whitelist(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" {
+			writeResponse(w, http.StatusMethodNotAllowed, "POST the region and id to whitelist")
+			return
+		}
+		if !h.authorized(req) {
+			unauthorized(w)
+			return
+		}
+		region, id := req.URL.Query().Get("region"), req.URL.Query().Get("id")
+		if region == "" || id == "" {
+			writeResponse(w, http.StatusBadRequest, "region and id are required")
+			return
+		}
+
+		r, err := reapables.Get(reapable.Region(region), reapable.ID(id))
+		if err != nil {
+			writeResponse(w, errorStatus(err), err.Error())
+			return
+		}
+		log.Info("Operator whitelist request received for %s in region %s", id, region)
+		if !taggedWhitelist(r) {
+			ok, err := r.Whitelist()
+			if err != nil {
+				writeResponse(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if !ok {
+				writeResponse(w, http.StatusInternalServerError,
+					fmt.Sprintf("Whitelist failed for %s.", r.ReapableDescriptionTiny()))
+				return
+			}
+			reaperevents.NewEvent("Reaper: Whitelist Request Received",
+				r.ReapableDescriptionShort(), nil, actionEventTags(r, "whitelist"))
+			newCountStatistic("reaper.reapables.requests",
+				[]string{"type:whitelist", config.EventTag})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(whitelistResponse{
+			DumpRecord:  reapable.NewDumpRecord(r),
+			Whitelisted: true,
+		}); err != nil {
+			log.Error("Writing whitelist response: %s", err.Error())
+		}
+	}
+}
+
 func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
 		if err := req.ParseForm(); err != nil {
@@ -105,10 +377,35 @@ func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		// snoozing applies to every reapable of the owner, not one reapable
+		if job.Action == token.J_SNOOZE_OWNER {
+			log.Debug("Snooze request received for %s until %s", job.Owner, job.IgnoreUntil.String())
+			snoozed, err := snoozeOwner(job.Owner, job.IgnoreUntil)
+			if err != nil {
+				writeResponse(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			reaperevents.NewEvent("Reaper: Snooze Request Received",
+				fmt.Sprintf("Delay for %d resources of %s by %s", snoozed, job.Owner, job.IgnoreUntil.String()),
+				nil,
+				[]string{fmt.Sprintf("owner:%s", job.Owner), "action:snooze", config.EventTag},
+			)
+			newCountStatistic("reaper.reapables.requests", []string{"type:snooze", config.EventTag})
+			writeResponse(w, http.StatusOK, fmt.Sprintf("Delayed %d resources by %s.", snoozed, job.IgnoreUntil.String()))
+			return
+		}
+
 		// find reapable associated with the job
 		r, err := reapables.Get(reapable.Region(job.Region), reapable.ID(job.ID))
 		if err != nil {
-			writeResponse(w, http.StatusInternalServerError, err.Error())
+			writeResponse(w, errorStatus(err), err.Error())
+			return
+		}
+
+		// listed resources are protected from links sent before they were listed
+		if (job.Action == token.J_TERMINATE || job.Action == token.J_STOP) && neverReaped(r) {
+			writeResponse(w, http.StatusForbidden,
+				fmt.Sprintf("%s is in NeverReapIDs and can't be terminated or stopped.", r.ReapableDescriptionTiny()))
 			return
 		}
 
@@ -118,8 +415,7 @@ func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 				job.ID,
 				job.Region,
 				job.IgnoreUntil.String())
-			s := r.ReaperState()
-			ok, err := r.Save(state.NewStateWithUntilAndState(s.Until.Add(job.IgnoreUntil), s.State))
+			ok, err := delay(r, job.IgnoreUntil)
 			if err != nil {
 				writeResponse(w, http.StatusInternalServerError, err.Error())
 				return
@@ -135,14 +431,19 @@ func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 					job.Region,
 					job.IgnoreUntil.String()),
 				nil,
-				[]string{},
+				actionEventTags(r, "delay"),
 			)
-			reaperevents.NewCountStatistic("reaper.reapables.requests", []string{"type:delay"})
+			newCountStatistic("reaper.reapables.requests", []string{"type:delay", config.EventTag})
 		case token.J_TERMINATE:
 			log.Debug("Terminate request received for %s in region %s.", job.ID, job.Region)
-			ok, err := r.Terminate()
+			ok, err := terminate(r)
+			if _, gone := err.(ErrAlreadyTerminated); gone {
+				// such as when a link is clicked twice
+				writeResponse(w, http.StatusOK, fmt.Sprintf("%s was already terminated.", r.ReapableDescriptionTiny()))
+				return
+			}
 			if err != nil {
-				writeResponse(w, http.StatusInternalServerError, err.Error())
+				writeResponse(w, errorStatus(err), err.Error())
 				return
 			}
 			if !ok {
@@ -151,11 +452,15 @@ func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 				return
 			}
 			reaperevents.NewEvent("Reaper: Terminate Request Received",
-				r.ReapableDescriptionShort(), nil, []string{})
-			reaperevents.NewCountStatistic("reaper.reapables.requests",
-				[]string{"type:terminate"})
+				r.ReapableDescriptionShort(), nil, actionEventTags(r, "terminate"))
+			newCountStatistic("reaper.reapables.requests",
+				[]string{"type:terminate", config.EventTag})
 		case token.J_WHITELIST:
 			log.Debug("Whitelist request received for %s in region %s", job.ID, job.Region)
+			if taggedWhitelist(r) {
+				writeResponse(w, http.StatusOK, fmt.Sprintf("%s is already whitelisted.", r.ReapableDescriptionTiny()))
+				return
+			}
 			ok, err := r.Whitelist()
 			if err != nil {
 				writeResponse(w, http.StatusInternalServerError, err.Error())
@@ -167,14 +472,14 @@ func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 				return
 			}
 			reaperevents.NewEvent("Reaper: Whitelist Request Received",
-				r.ReapableDescriptionShort(), nil, []string{})
-			reaperevents.NewCountStatistic("reaper.reapables.requests",
-				[]string{"type:whitelist"})
+				r.ReapableDescriptionShort(), nil, actionEventTags(r, "whitelist"))
+			newCountStatistic("reaper.reapables.requests",
+				[]string{"type:whitelist", config.EventTag})
 		case token.J_STOP:
 			log.Debug("Stop request received for %s in region %s", job.ID, job.Region)
-			ok, err := r.Stop()
+			ok, err := stop(r)
 			if err != nil {
-				writeResponse(w, http.StatusInternalServerError, err.Error())
+				writeResponse(w, errorStatus(err), err.Error())
 				return
 			}
 			if !ok {
@@ -183,8 +488,8 @@ func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 				return
 			}
 			reaperevents.NewEvent("Reaper: Stop Request Received",
-				r.ReapableDescriptionShort(), nil, []string{})
-			reaperevents.NewCountStatistic("reaper.reapables.requests", []string{"type:stop"})
+				r.ReapableDescriptionShort(), nil, actionEventTags(r, "stop"))
+			newCountStatistic("reaper.reapables.requests", []string{"type:stop", config.EventTag})
 		default:
 			log.Error("Unrecognized job token received.")
 			writeResponse(w, http.StatusInternalServerError, "Unrecognized job token.")
@@ -205,3 +510,44 @@ func processToken(h *HTTPApi) func(http.ResponseWriter, *http.Request) {
 				r.ReapableDescriptionTiny(), consoleURL))
 	}
 }
+
+// actionEventTags returns the tags of an event about an action taken on r
+func actionEventTags(r reapable.Reapable, action string) []string {
+	return append(reaperevents.ReapableEventTags(r), fmt.Sprintf("action:%s", action), config.EventTag)
+}
+
+// delay moves a Reapable's ReaperState Until later by d
+func delay(r reapable.Reapable, d time.Duration) (bool, error) {
+	s := r.ReaperState()
+	delayed := state.NewStateWithUntilAndState(s.Until.Add(d), s.State)
+	delayed.LastNotified = s.LastNotified
+	return r.Save(delayed)
+}
+
+// snoozeOwner delays every tracked Reapable owned by owner by d
+// returns the number of Reapables that were delayed
+func snoozeOwner(owner string, d time.Duration) (int, error) {
+	var owned []reapable.Reapable
+	for r := range reapables.Iter() {
+		if o := r.Owner(); o != nil && strings.EqualFold(o.Address, owner) {
+			owned = append(owned, r.Reapable)
+		}
+	}
+
+	snoozed := 0
+	var errs []string
+	for _, r := range owned {
+		ok, err := delay(r, d)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if ok {
+			snoozed++
+		}
+	}
+	if len(errs) > 0 {
+		return snoozed, fmt.Errorf("Delay failed for %d resources: %s", len(errs), strings.Join(errs, ", "))
+	}
+	return snoozed, nil
+}
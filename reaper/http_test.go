@@ -0,0 +1,424 @@
+package reaper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/state"
+	"github.com/mozilla-services/reaper/token"
+)
+
+func TestSnoozeOwnerHandler(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	reaperevents.SetEvents(&[]reaperevents.EventReporter{})
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	until := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	newReapable := func(id, owner string) *testReapable {
+		r := newTestReapable("us-west-2", id, owner)
+		r.state = state.NewStateWithUntilAndState(until, state.FirstState)
+		return r
+	}
+	mine1 := newReapable("i-1", "jdoe@example.com")
+	mine2 := newReapable("i-2", "jdoe@example.com")
+	theirs := newReapable("i-3", "someone@example.com")
+
+	reapables.Reset([]string{"us-west-2"})
+	for _, r := range []*testReapable{mine1, mine2, theirs} {
+		reapables.Put(r.Region(), r.ID(), r)
+	}
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{TokenSecret: "secret", Token: "t", Action: "a"})
+	tok, err := token.Tokenize("secret", token.NewSnoozeOwnerJob("jdoe@example.com", 72*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/?t="+url.QueryEscape(tok), nil)
+	processToken(h)(httptest.NewRecorder(), req)
+
+	expected := until.Add(72 * time.Hour)
+	for _, r := range []*testReapable{mine1, mine2} {
+		if !r.state.Until.Equal(expected) {
+			t.Errorf("expected %s to be delayed until %s, got %s", r.id, expected, r.state.Until)
+		}
+	}
+	if !theirs.state.Until.Equal(until) {
+		t.Errorf("expected another owner's resource not to be delayed, got %s", theirs.state.Until)
+	}
+	if len(recorded["reaper.reapables.requests"]) != 1 {
+		t.Errorf("expected a snooze request statistic, got %v", recorded)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	setHealth := func(ready, pricesDownloaded, reapStarted bool, lastReap time.Time) {
+		health.Lock()
+		defer health.Unlock()
+		health.ready = ready
+		health.pricesDownloaded = pricesDownloaded
+		health.reapStarted = reapStarted
+		health.lastReap = lastReap
+	}
+	defer setHealth(false, false, false, time.Time{})
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{})
+
+	// not ready until prices are downloaded
+	setHealth(true, false, false, time.Time{})
+	w := httptest.NewRecorder()
+	readyz(h)(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d before prices are downloaded, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if w.Header().Get("X-Reaper-Last-Reap") != "never" {
+		t.Errorf("expected no last reap, got %s", w.Header().Get("X-Reaper-Last-Reap"))
+	}
+
+	lastReap := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	setHealth(true, true, true, lastReap)
+	w = httptest.NewRecorder()
+	readyz(h)(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d once ready, got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("X-Reaper-Last-Reap") != "2016-01-01T12:00:00Z" {
+		t.Errorf("expected the last reap time, got %s", w.Header().Get("X-Reaper-Last-Reap"))
+	}
+
+	// liveness doesn't depend on readiness
+	setHealth(false, false, false, time.Time{})
+	w = httptest.NewRecorder()
+	heartbeat(h)(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected /healthz to be %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestWhatif(t *testing.T) {
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+	groups := map[string]filters.FilterGroup{
+		"Large": filters.FilterGroup{"1": *filters.NewFilter("InstanceTypeIs", []string{"m4.large"})},
+	}
+	defer setTestConfig(&Config{
+		WhitelistTag: "REAPER_SPARE_ME",
+		Instances:    ResourceConfig{FilterGroups: groups},
+	})()
+
+	newInstance := func(id, instanceType string, tags ...string) *reaperaws.Instance {
+		instance := &ec2.Instance{
+			InstanceId:   aws.String(id),
+			InstanceType: aws.String(instanceType),
+		}
+		for _, tag := range tags {
+			instance.Tags = append(instance.Tags, &ec2.Tag{Key: aws.String(tag), Value: aws.String("true")})
+		}
+		return reaperaws.NewInstance("us-west-2", instance)
+	}
+	instances := []*reaperaws.Instance{
+		newInstance("i-1", "m4.large"),
+		newInstance("i-2", "t2.micro"),
+		newInstance("i-3", "m4.large", "REAPER_SPARE_ME"),
+		newInstance("i-4", "m4.large"),
+	}
+	reapables.Reset([]string{"us-west-2"})
+	for _, i := range instances {
+		reapables.Put(i.Region(), i.ID(), i)
+	}
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{TokenSecret: "secret"})
+	post := func(groups map[string]filters.FilterGroup, secret string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(whatifRequest{Type: "instances", ResourceConfig: ResourceConfig{FilterGroups: groups}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("POST", "/whatif", bytes.NewReader(body))
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		whatif(h)(w, req)
+		return w
+	}
+
+	for _, secret := range []string{"", "wrong"} {
+		if w := post(groups, secret); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected %d without the TokenSecret, got %d", http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	// a filter missing its arguments is reported, rather than failing the request
+	w := post(map[string]filters.FilterGroup{
+		"Broken": filters.FilterGroup{"1": *filters.NewFilter("Tagged", nil)},
+	}, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d for a filter missing its arguments, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var broken whatifResponse
+	if err := json.NewDecoder(w.Body).Decode(&broken); err != nil {
+		t.Fatal(err)
+	}
+	if len(broken.Matched) != 0 || broken.Errors["Broken"] == "" {
+		t.Errorf("expected no matches and the missing arguments reported, got %+v", broken)
+	}
+
+	w = post(groups, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response whatifResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	simulated := make(map[string]bool)
+	for _, record := range response.Matched {
+		simulated[record.ID] = true
+	}
+
+	// simulating doesn't record matched FilterGroups
+	for _, i := range instances {
+		if i.MatchedFiltersString() != "" {
+			t.Errorf("%s: expected no matched FilterGroups to be recorded, got %s", i.ID(), i.MatchedFiltersString())
+		}
+	}
+
+	// the same resources a real cycle would match
+	for _, i := range instances {
+		if matchesFilters(i) != simulated[i.ID().String()] {
+			t.Errorf("%s: expected whatif to agree with matchesFilters", i.ID())
+		}
+	}
+	if len(response.Matched) != 2 {
+		t.Errorf("expected i-1 and i-4 to match, got %v", response.Matched)
+	}
+}
+
+func TestWhitelistHandler(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	reaperevents.SetEvents(&[]reaperevents.EventReporter{})
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	reapables.Reset([]string{"us-west-2"})
+	reapables.Put(r.Region(), r.ID(), r)
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{TokenSecret: "secret"})
+	post := func(query, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/whitelist?"+query, nil)
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		whitelist(h)(w, req)
+		return w
+	}
+
+	for _, secret := range []string{"", "wrong"} {
+		if w := post("region=us-west-2&id=i-1", secret); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected %d without the TokenSecret, got %d", http.StatusUnauthorized, w.Code)
+		}
+	}
+	if r.whitelisted != 0 {
+		t.Fatal("expected an unauthenticated request not to whitelist")
+	}
+
+	if w := post("region=us-west-2&id=i-unknown", "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("expected %d for an unknown id, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w := post("region=us-west-2&id=i-1", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if r.whitelisted != 1 {
+		t.Errorf("expected i-1 to be whitelisted once, got %d", r.whitelisted)
+	}
+	var response whitelistResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ID != "i-1" || response.Region != "us-west-2" || !response.Whitelisted || response.State == "" {
+		t.Errorf("expected the state of whitelisted i-1, got %+v", response)
+	}
+	if len(recorded["reaper.reapables.requests"]) != 1 {
+		t.Errorf("expected a whitelist request statistic, got %v", recorded)
+	}
+
+	// no request is authorized without a TokenSecret
+	h = NewHTTPApi(reaperevents.HTTPConfig{})
+	req := httptest.NewRequest("POST", "/whitelist?region=us-west-2&id=i-1", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w = httptest.NewRecorder()
+	whitelist(h)(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %d without a TokenSecret, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestDumpReapablesAuthorization(t *testing.T) {
+	r := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	reapables.Reset([]string{"us-west-2"})
+	reapables.Put(r.Region(), r.ID(), r)
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{TokenSecret: "secret"})
+	get := func(secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/reapables", nil)
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		dumpReapables(h)(w, req)
+		return w
+	}
+
+	for _, secret := range []string{"", "wrong"} {
+		if w := get(secret); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected %d without the TokenSecret, got %d", http.StatusUnauthorized, w.Code)
+		}
+	}
+	w := get("secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "i-1") {
+		t.Errorf("expected i-1 to be dumped, got %s", w.Body.String())
+	}
+}
+
+func TestListDeadLettersAuthorization(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	defer setTestDeadLetters()()
+	deadLetters.failed(newTestReapable("us-west-2", "i-1", "jdoe@example.com"), "terminate", errors.New("UnauthorizedOperation"))
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{TokenSecret: "secret"})
+	get := func(secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/deadletters", nil)
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		listDeadLetters(h)(w, req)
+		return w
+	}
+
+	for _, secret := range []string{"", "wrong"} {
+		if w := get(secret); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected %d without the TokenSecret, got %d", http.StatusUnauthorized, w.Code)
+		}
+	}
+	w := get("secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var letters []deadLetter
+	if err := json.NewDecoder(w.Body).Decode(&letters); err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].ID != "i-1" {
+		t.Errorf("expected the dead letter of i-1, got %+v", letters)
+	}
+}
+
+func TestNeverReapedTokenActions(t *testing.T) {
+	defer setTestConfig(&Config{NeverReapIDs: []string{"us-west-2/i-1"}})()
+	defer setTestNeverReap()()
+	reaperevents.SetEvents(&[]reaperevents.EventReporter{})
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	reapables.Reset([]string{"us-west-2"})
+	reapables.Put(r.Region(), r.ID(), r)
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{TokenSecret: "secret", Token: "t", Action: "a"})
+	for _, job := range []*token.JobToken{token.NewTerminateJob("us-west-2", "i-1"), token.NewStopJob("us-west-2", "i-1")} {
+		tok, err := token.Tokenize("secret", job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		processToken(h)(w, httptest.NewRequest("GET", "/?t="+url.QueryEscape(tok), nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected %d for a resource in NeverReapIDs, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+		}
+	}
+	if r.terminated != 0 || r.stopped != 0 {
+		t.Errorf("expected a resource in NeverReapIDs not to be terminated or stopped, got %d and %d", r.terminated, r.stopped)
+	}
+}
+
+func TestRepeatedTokenActions(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	defer setTestDeadLetters()()
+	reaperevents.SetEvents(&[]reaperevents.EventReporter{})
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "vol-1", "jdoe@example.com")
+	reapables.Reset([]string{"us-west-2"})
+	reapables.Put(r.Region(), r.ID(), r)
+
+	h := NewHTTPApi(reaperevents.HTTPConfig{TokenSecret: "secret", Token: "t", Action: "a"})
+	click := func(job *token.JobToken) *httptest.ResponseRecorder {
+		tok, err := token.Tokenize("secret", job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		processToken(h)(w, httptest.NewRequest("GET", "/?t="+url.QueryEscape(tok), nil))
+		return w
+	}
+
+	if w := click(token.NewTerminateJob("us-west-2", "vol-1")); w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	// the volume is gone by the second click
+	r.terminateErr = awserr.New("InvalidVolume.NotFound", "The volume 'vol-1' does not exist.", nil)
+	w := click(token.NewTerminateJob("us-west-2", "vol-1"))
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("already terminated")) {
+		t.Errorf("expected a repeated terminate to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if r.terminated != 1 {
+		t.Errorf("expected vol-1 to be terminated once, got %d", r.terminated)
+	}
+
+	// an AutoScalingGroup that is gone has no NotFound code
+	asg := newTestReapable("us-west-2", "asg-1", "jdoe@example.com")
+	asg.terminateErr = awserr.New("ValidationError", "AutoScalingGroup name not found - AutoScalingGroup 'asg-1' not found", nil)
+	reapables.Put(asg.Region(), asg.ID(), asg)
+	w = click(token.NewTerminateJob("us-west-2", "asg-1"))
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("already terminated")) {
+		t.Errorf("expected a repeated AutoScalingGroup terminate to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// once the whitelist tag is seen, whitelisting again is a no-op
+	r.filters = map[string]bool{"Tagged": true}
+	w = click(token.NewWhitelistJob("us-west-2", "vol-1"))
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("already whitelisted")) {
+		t.Errorf("expected a repeated whitelist to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if r.whitelisted != 0 {
+		t.Errorf("expected an already whitelisted resource not to be whitelisted again, got %d", r.whitelisted)
+	}
+
+	// only the first terminate is counted as a request
+	if len(recorded["reaper.reapables.requests"]) != 1 {
+		t.Errorf("expected a single request statistic, got %v", recorded["reaper.reapables.requests"])
+	}
+}
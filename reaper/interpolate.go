@@ -0,0 +1,86 @@
+package reaper
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+)
+
+// envReference matches ${NAME} references to environment variables,
+// and $${, which escapes a literal ${
+var envReference = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// lookupEnv looks up an environment variable
+// replaceable in tests
+var lookupEnv = os.LookupEnv
+
+// interpolate replaces ${NAME} references in s with the value of
+// the environment variable NAME
+// a reference to an unset variable is an error, other text is left alone
+func interpolate(s string) (string, error) {
+	var err error
+	result := envReference.ReplaceAllStringFunc(s, func(reference string) string {
+		if reference == "$${" {
+			return "${"
+		}
+		name := reference[2 : len(reference)-1]
+		value, ok := lookupEnv(name)
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("Configuration references unset environment variable %s", name)
+			}
+			return reference
+		}
+		return value
+	})
+	return result, err
+}
+
+// interpolateEnv interpolates every exported string field of v,
+// including strings in slices, maps and nested structs
+// fields decoded with UnmarshalText, such as durations, are not strings
+// once decoded, so they are not interpolated
+func interpolateEnv(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return interpolateEnv(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				if err := interpolateEnv(field); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := interpolateEnv(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// map values aren't addressable, so each is copied and replaced
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			if err := interpolateEnv(value); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		s, err := interpolate(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	}
+	return nil
+}
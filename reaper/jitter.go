@@ -0,0 +1,54 @@
+package reaper
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// jitterSchedule is a cron.Schedule that activates once every interval,
+// offset by a random amount within [-jitter, +jitter]
+// so that many Reapers don't all hit the AWS API on the same boundary
+type jitterSchedule struct {
+	interval cron.ConstantDelaySchedule
+	jitter   time.Duration
+
+	// *rand.Rand is not safe for concurrent use
+	sync.Mutex
+	rand *rand.Rand
+}
+
+// newJitterSchedule returns a jitterSchedule using the provided rand.Source
+// a seeded source makes the schedule deterministic
+func newJitterSchedule(interval, jitter time.Duration, src rand.Source) *jitterSchedule {
+	if jitter < 0 {
+		jitter = -jitter
+	}
+	return &jitterSchedule{
+		interval: cron.Every(interval),
+		jitter:   jitter,
+		rand:     rand.New(src),
+	}
+}
+
+// Next is a method of cron.Schedule
+func (s *jitterSchedule) Next(t time.Time) time.Time {
+	next := s.interval.Next(t)
+	if s.jitter == 0 {
+		return next
+	}
+
+	s.Lock()
+	// uniformly distributed in [-jitter, +jitter]
+	offset := time.Duration(s.rand.Int63n(2*int64(s.jitter)+1)) - s.jitter
+	s.Unlock()
+
+	next = next.Add(offset)
+	// never schedule a run at or before the previous one
+	if !next.After(t) {
+		next = t.Add(time.Second)
+	}
+	return next
+}
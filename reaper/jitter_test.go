@@ -0,0 +1,40 @@
+package reaper
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitterScheduleWithinWindow(t *testing.T) {
+	interval := 20 * time.Minute
+	jitter := 5 * time.Minute
+	s := newJitterSchedule(interval, jitter, rand.NewSource(1))
+
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		next := s.Next(now)
+		if next.Before(now.Add(interval-jitter)) || next.After(now.Add(interval+jitter)) {
+			t.Errorf("%s is outside of the jitter window", next.Sub(now))
+		}
+	}
+}
+
+func TestJitterScheduleDeterministic(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := newJitterSchedule(time.Hour, 10*time.Minute, rand.NewSource(42))
+	b := newJitterSchedule(time.Hour, 10*time.Minute, rand.NewSource(42))
+	for i := 0; i < 10; i++ {
+		if !a.Next(now).Equal(b.Next(now)) {
+			t.Error("expected identical schedules with identical seeds")
+		}
+	}
+}
+
+func TestJitterScheduleWithoutJitter(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newJitterSchedule(time.Hour, 0, rand.NewSource(1))
+	if next := s.Next(now); !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected %s, got %s", now.Add(time.Hour), next)
+	}
+}
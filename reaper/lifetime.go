@@ -0,0 +1,58 @@
+package reaper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// expired returns whether a Reapable is past the end of its lifetime,
+// from its expires-at or reaper-ttl tag (see aws.Resource.ExpiresAt)
+// only resources that match their type's filters expire, so that a tag
+// alone can't make a resource reapable, and so whitelisted resources and
+// those in NeverReapIDs never do
+func expired(r reapable.Reapable, now time.Time) bool {
+	expiring, ok := r.(interface {
+		ExpiresAt(created time.Time) (time.Time, bool)
+	})
+	if !ok {
+		return false
+	}
+	// a reaper-ttl is counted from when the resource was created
+	var created time.Time
+	if aged, ok := r.(reapable.Aged); ok {
+		created, _ = aged.CreatedAt()
+	}
+	expiresAt, ok := expiring.ExpiresAt(created)
+	if !ok || !now.After(expiresAt) {
+		return false
+	}
+	return matchesFilters(r)
+}
+
+// expire moves an expired Reapable straight to the FinalState,
+// skipping the notification states it would otherwise ramp through
+func expire(r reapable.Reapable) {
+	if r.ReaperState().State == state.FinalState {
+		return
+	}
+	settable, ok := r.(interface {
+		SetReaperState(*state.State)
+	})
+	if !ok {
+		return
+	}
+	log.Info("%s is past its lifetime, moving it to %s", r.ReapableDescriptionTiny(), state.FinalState.String())
+	final := state.NewStateWithUntilAndState(now(), state.FinalState)
+	final.LastNotified = r.ReaperState().LastNotified
+	settable.SetReaperState(final)
+	r.SetUpdated(true)
+
+	err := newCountStatistic(fmt.Sprintf("reaper.%s.expired", reapableType(r)), reapableStatisticTags(r))
+	if err != nil {
+		log.Error(err.Error())
+	}
+}
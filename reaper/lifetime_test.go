@@ -0,0 +1,79 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/state"
+)
+
+func TestExpiredResourcesSkipToFinalState(t *testing.T) {
+	recorded, restore := recordCountStatistics()
+	defer restore()
+	defer setTestNeverReap()()
+	defer setTestConfig(&Config{
+		WhitelistTag: "REAPER_SPARE_ME",
+		Instances: ResourceConfig{
+			FilterGroups: map[string]filters.FilterGroup{
+				"Owned": filters.FilterGroup{"1": *filters.NewFilter("Tagged", []string{"Owner"})},
+			},
+		},
+	})()
+	reaperaws.SetConfig(&reaperaws.Config{Notifications: config.Notifications})
+	defer reaperaws.SetConfig(nil)
+	reapables.Reset([]string{"us-west-2"})
+
+	newInstance := func(id string, launched time.Duration, tags map[string]string) *reaperaws.Instance {
+		instance := &ec2.Instance{
+			InstanceId: aws.String(id),
+			LaunchTime: aws.Time(time.Now().Add(-launched)),
+		}
+		if _, ok := tags["Owner"]; !ok && id != "i-unfiltered" {
+			instance.Tags = append(instance.Tags, &ec2.Tag{Key: aws.String("Owner"), Value: aws.String("jdoe")})
+		}
+		for k, v := range tags {
+			instance.Tags = append(instance.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		return reaperaws.NewInstance("us-west-2", instance)
+	}
+
+	tests := []struct {
+		name    string
+		i       *reaperaws.Instance
+		expired bool
+	}{
+		{"past ttl", newInstance("i-past-ttl", 5*time.Hour, map[string]string{"reaper-ttl": "4h"}), true},
+		{"within ttl", newInstance("i-within-ttl", time.Hour, map[string]string{"reaper-ttl": "4h"}), false},
+		{"past expires-at", newInstance("i-past-expires-at", time.Hour, map[string]string{"expires-at": time.Now().Add(-time.Minute).Format(time.RFC3339)}), true},
+		{"before expires-at", newInstance("i-before-expires-at", time.Hour, map[string]string{"expires-at": time.Now().Add(time.Hour).Format(time.RFC3339)}), false},
+		{"invalid ttl", newInstance("i-invalid-ttl", 5*time.Hour, map[string]string{"reaper-ttl": "soon"}), false},
+		{"no tags", newInstance("i-no-tags", 5*time.Hour, nil), false},
+		{"whitelisted", newInstance("i-whitelisted", 5*time.Hour, map[string]string{"reaper-ttl": "4h", "REAPER_SPARE_ME": "true"}), false},
+		// a lifetime tag doesn't make a resource that doesn't match its filters reapable
+		{"unfiltered", newInstance("i-unfiltered", 5*time.Hour, map[string]string{"reaper-ttl": "4h"}), false},
+	}
+	for _, test := range tests {
+		if expired(test.i, now()) != test.expired {
+			t.Errorf("%s: expected expired to be %t", test.name, test.expired)
+		}
+
+		registerReapable(test.i)
+		if test.expired {
+			if s := test.i.ReaperState(); s.State != state.FinalState || !s.Updated {
+				t.Errorf("%s: expected the instance to skip to the FinalState, got %s", test.name, s.State.String())
+			}
+		} else if s := test.i.ReaperState(); s.State != state.FirstState {
+			// the normal ramp starts with the FirstState
+			t.Errorf("%s: expected the instance to move to the FirstState, got %s", test.name, s.State.String())
+		}
+	}
+
+	if n := len(recorded["reaper.instances.expired"]); n != 2 {
+		t.Errorf("expected 2 reaper.instances.expired statistics, got %d", n)
+	}
+}
@@ -0,0 +1,45 @@
+package reaper
+
+import "sync"
+
+// defaultMaxConcurrentActions is used for resource types that
+// don't set MaxConcurrentActions
+const defaultMaxConcurrentActions = 10
+
+// actions bounds concurrent Terminate and Stop calls per resource type
+var actions = newLimiter()
+
+// limiter bounds the number of functions running at once per key
+type limiter struct {
+	sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newLimiter() *limiter {
+	return &limiter{slots: make(map[string]chan struct{})}
+}
+
+// run calls f once fewer than limit functions are running for key
+// the limit for a key is fixed by its first call
+func (l *limiter) run(key string, limit int, f func()) {
+	l.Lock()
+	slots, ok := l.slots[key]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		l.slots[key] = slots
+	}
+	l.Unlock()
+
+	slots <- struct{}{}
+	defer func() { <-slots }()
+	f()
+}
+
+// maxConcurrentActions returns the configured limit on concurrent
+// Terminate and Stop calls for a resource type (see reapableType)
+func maxConcurrentActions(resourceType string) int {
+	if c := resourceConfig(resourceType); c.MaxConcurrentActions > 0 {
+		return c.MaxConcurrentActions
+	}
+	return defaultMaxConcurrentActions
+}
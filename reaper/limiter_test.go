@@ -0,0 +1,107 @@
+package reaper
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	const limit = 3
+	l := newLimiter()
+
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	terminate := func() {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.run("instances", limit, terminate)
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > limit {
+		t.Errorf("expected at most %d concurrent terminations, got %d", limit, maxRunning)
+	}
+	if maxRunning < 2 {
+		t.Errorf("expected terminations to run concurrently, got %d at once", maxRunning)
+	}
+}
+
+// slowReapable records how many Terminate calls run at once
+type slowReapable struct {
+	*testReapable
+	mutex               *sync.Mutex
+	running, maxRunning *int
+}
+
+func (r slowReapable) Terminate() (bool, error) {
+	r.mutex.Lock()
+	*r.running++
+	if *r.running > *r.maxRunning {
+		*r.maxRunning = *r.running
+	}
+	r.mutex.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	r.mutex.Lock()
+	*r.running--
+	r.mutex.Unlock()
+	return true, nil
+}
+
+func TestTerminateConcurrency(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	_, restore := recordCountStatistics()
+	defer restore()
+	defer func(original *limiter) { actions = original }(actions)
+	actions = newLimiter()
+
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3*defaultMaxConcurrentActions; i++ {
+		r := slowReapable{newTestReapable("us-west-2", "i-slow", ""), &mutex, &running, &maxRunning}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := terminate(r); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > defaultMaxConcurrentActions {
+		t.Errorf("expected at most %d concurrent terminations, got %d", defaultMaxConcurrentActions, maxRunning)
+	}
+}
+
+func TestMaxConcurrentActions(t *testing.T) {
+	defer setTestConfig(&Config{Instances: ResourceConfig{MaxConcurrentActions: 2}})()
+
+	if n := maxConcurrentActions("instances"); n != 2 {
+		t.Errorf("expected 2 concurrent actions for instances, got %d", n)
+	}
+	if n := maxConcurrentActions("volumes"); n != defaultMaxConcurrentActions {
+		t.Errorf("expected the default for volumes, got %d", n)
+	}
+}
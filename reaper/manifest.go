@@ -0,0 +1,94 @@
+package reaper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/token"
+)
+
+// manifestResult is the outcome of terminating one resource of a manifest
+type manifestResult struct {
+	Region     string `json:"region"`
+	ID         string `json:"id"`
+	Terminated bool   `json:"terminated"`
+	Error      string `json:"error,omitempty"`
+}
+
+// readManifest reads region/id pairs, one per line, such as
+// us-west-2/i-0123456789abcdef0
+// blank lines and lines starting with # are ignored
+func readManifest(r io.Reader) ([]string, error) {
+	var manifest []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// the same format as NeverReapIDs
+		id, err := parseNeverReapID(line)
+		if err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(manifest) == 0 {
+		return nil, errors.New("The manifest is empty")
+	}
+	return manifest, nil
+}
+
+// SignManifest reads a manifest of region/id pairs and returns a token
+// that terminates all of them when posted to /terminate-batch
+// the whole manifest is signed with TokenSecret, so it can't be changed
+// once it was reviewed
+func SignManifest(r io.Reader) (string, error) {
+	manifest, err := readManifest(r)
+	if err != nil {
+		return "", err
+	}
+	return token.Tokenize(config.HTTP.TokenSecret, token.NewTerminateBatchJob(manifest))
+}
+
+// terminateManifest terminates each resource of a manifest with Terminate,
+// returning the outcome of each
+// resources that aren't tracked are found first, and resources protected
+// by the WhitelistTag or NeverReapIDs are not terminated
+func terminateManifest(manifest []string) []manifestResult {
+	results := make([]manifestResult, 0, len(manifest))
+	for _, entry := range manifest {
+		parts := strings.SplitN(entry, "/", 2)
+		result := manifestResult{Region: parts[0], ID: parts[len(parts)-1], Terminated: true}
+		if err := terminateManifestEntry(reapable.Region(result.Region), reapable.ID(result.ID)); err != nil {
+			log.Error("Batch terminate of %s failed: %s", entry, err.Error())
+			result.Terminated, result.Error = false, err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func terminateManifestEntry(region reapable.Region, id reapable.ID) error {
+	r, err := reapables.Get(region, id)
+	if err != nil {
+		found, err := findReapable(region, id)
+		if err != nil {
+			return err
+		}
+		reapables.Put(region, id, found)
+		r = found
+	}
+	if isWhitelisted(r) || neverReaped(r) {
+		return fmt.Errorf("%s is protected by the WhitelistTag or NeverReapIDs", r.ReapableDescriptionTiny())
+	}
+	return Terminate(region, id)
+}
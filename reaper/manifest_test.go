@@ -0,0 +1,107 @@
+package reaper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+	"github.com/mozilla-services/reaper/token"
+)
+
+func TestReadManifest(t *testing.T) {
+	manifest, err := readManifest(strings.NewReader("# cleanup\nus-west-2/i-1\n\n us-east-1/vol-1 \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != 2 || manifest[0] != "us-west-2/i-1" || manifest[1] != "us-east-1/vol-1" {
+		t.Errorf("unexpected manifest %v", manifest)
+	}
+	for _, s := range []string{"i-1\n", "# nothing\n"} {
+		if _, err := readManifest(strings.NewReader(s)); err == nil {
+			t.Errorf("expected %q to be an invalid manifest", s)
+		}
+	}
+}
+
+func TestTerminateBatch(t *testing.T) {
+	defer setTestConfig(&Config{
+		WhitelistTag: "REAPER_SPARE_ME",
+		HTTP:         reaperevents.HTTPConfig{TokenSecret: "secret", Token: "t"},
+	})()
+	defer setTestDeadLetters()()
+	_, restore := recordCountStatistics()
+	defer restore()
+
+	ok := newTestReapable("us-west-2", "i-ok", "owner@example.com")
+	failing := newTestReapable("us-west-2", "i-failing", "owner@example.com")
+	failing.terminateErr = errors.New("denied")
+	reapables.Reset([]string{"us-west-2"})
+	for _, r := range []*testReapable{ok, failing} {
+		reapables.Put(r.Region(), r.ID(), r)
+	}
+	originalFind := findReapable
+	defer func() { findReapable = originalFind }()
+	findReapable = func(region reapable.Region, id reapable.ID) (reaperevents.Reapable, error) {
+		return nil, reapable.ReapableNotFoundError{ErrorText: "Could not find resource " + id.String()}
+	}
+
+	signed, err := SignManifest(strings.NewReader("us-west-2/i-ok\nus-west-2/i-failing\nus-west-2/i-gone\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHTTPApi(config.HTTP)
+	post := func(tok string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/terminate-batch", strings.NewReader("t="+url.QueryEscape(tok)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		terminateBatch(h)(w, req)
+		return w
+	}
+
+	w := post(signed)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var results []manifestResult
+	if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected a result per resource, got %+v", results)
+	}
+	if !results[0].Terminated || results[0].Error != "" || ok.terminated != 1 {
+		t.Errorf("expected i-ok to be terminated, got %+v", results[0])
+	}
+	if results[1].Terminated || results[1].Error == "" {
+		t.Errorf("expected i-failing to fail, got %+v", results[1])
+	}
+	if results[2].Terminated || results[2].Region != "us-west-2" || results[2].ID != "i-gone" {
+		t.Errorf("expected i-gone not to be found, got %+v", results[2])
+	}
+
+	// changing a byte of the signed manifest is rejected
+	tampered := []byte(signed)
+	tampered[2]++
+	if w := post(string(tampered)); w.Code != http.StatusForbidden {
+		t.Errorf("expected a tampered manifest to be rejected with 403, got %d", w.Code)
+	}
+	if ok.terminated != 1 {
+		t.Error("expected nothing to be terminated by a tampered manifest")
+	}
+
+	// other tokens aren't manifests
+	single, err := token.Tokenize("secret", token.NewTerminateJob("us-west-2", "i-ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := post(single); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a terminate token to be rejected with 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package reaper
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// neverReap holds the ids read from NeverReapIDsFile
+var neverReap = &neverReapList{}
+
+// neverReapList is a set of region/id pairs that are never reaped,
+// whatever their tags
+type neverReapList struct {
+	sync.RWMutex
+	path    string
+	modTime time.Time
+	ids     map[string]bool
+}
+
+// parseNeverReapID validates a region/id pair
+func parseNeverReapID(s string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("NeverReapIDs entry %q must be a region/id pair, such as us-west-2/i-0123456789abcdef0", s)
+	}
+	return parts[0] + "/" + parts[1], nil
+}
+
+// readNeverReapIDs reads a file of region/id pairs, one per line
+// blank lines and lines starting with # are ignored
+func readNeverReapIDs(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := parseNeverReapID(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err.Error())
+		}
+		ids[id] = true
+	}
+	return ids, scanner.Err()
+}
+
+// reload reads path if it is a different file, or it changed since it was
+// last read
+// if the file can't be read, the last ids are kept, so that a bad edit
+// never unprotects resources
+func (l *neverReapList) reload(path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	l.RLock()
+	unchanged := l.path == path && l.modTime.Equal(info.ModTime())
+	l.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	ids, err := readNeverReapIDs(path)
+	if err != nil {
+		return err
+	}
+	l.Lock()
+	defer l.Unlock()
+	if l.path == path {
+		log.Info("Reloaded %d NeverReapIDs from %s", len(ids), path)
+	}
+	l.path = path
+	l.modTime = info.ModTime()
+	l.ids = ids
+	return nil
+}
+
+// contains returns whether the region/id pair was read from the file
+func (l *neverReapList) contains(id string) bool {
+	l.RLock()
+	defer l.RUnlock()
+	return l.ids[id]
+}
+
+// reloadNeverReapIDs reloads NeverReapIDsFile if it changed, logging errors
+func reloadNeverReapIDs() {
+	if err := neverReap.reload(config.NeverReapIDsFile); err != nil {
+		log.Error("Could not reload NeverReapIDsFile, keeping the previous ids: %s", err.Error())
+	}
+}
+
+// neverReaped returns whether a resource is listed in NeverReapIDs
+// or NeverReapIDsFile
+func neverReaped(r interface{}) bool {
+	identified, ok := r.(interface {
+		Region() reapable.Region
+		ID() reapable.ID
+	})
+	if !ok {
+		return false
+	}
+	id := fmt.Sprintf("%s/%s", identified.Region(), identified.ID())
+	for _, listed := range config.NeverReapIDs {
+		if listed == id {
+			return true
+		}
+	}
+	return neverReap.contains(id)
+}
@@ -0,0 +1,108 @@
+package reaper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// setTestNeverReap replaces the ids read from NeverReapIDsFile,
+// returning a func that restores them
+func setTestNeverReap() (restore func()) {
+	original := neverReap
+	neverReap = &neverReapList{}
+	return func() { neverReap = original }
+}
+
+func TestNeverReapIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reaper-neverreap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "neverreap.txt")
+	if err := ioutil.WriteFile(path, []byte("# production databases\nus-west-2/i-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer setTestNeverReap()()
+	defer setTestConfig(&Config{
+		WhitelistTag:     "REAPER_SPARE_ME",
+		NeverReapIDs:     []string{"us-west-2/i-listed"},
+		NeverReapIDsFile: path,
+		Instances: ResourceConfig{
+			FilterGroups: map[string]filters.FilterGroup{
+				"Owned": filters.FilterGroup{"1": *filters.NewFilter("Tagged", []string{"Owner"})},
+			},
+		},
+	})()
+	reloadNeverReapIDs()
+
+	newInstance := func(region, id string) *reaperaws.Instance {
+		return reaperaws.NewInstance(region, &ec2.Instance{
+			InstanceId: aws.String(id),
+			Tags:       []*ec2.Tag{&ec2.Tag{Key: aws.String("Owner"), Value: aws.String("jdoe")}},
+		})
+	}
+
+	if matchesFilters(newInstance("us-west-2", "i-listed")) {
+		t.Error("expected an instance in NeverReapIDs not to match")
+	}
+	if matchesFilters(newInstance("us-west-2", "i-file")) {
+		t.Error("expected an instance in NeverReapIDsFile not to match")
+	}
+	if !matchesFilters(newInstance("us-west-2", "i-unlisted")) {
+		t.Error("expected an unlisted instance to match")
+	}
+	if !matchesFilters(newInstance("us-east-1", "i-listed")) {
+		t.Error("expected an instance with a listed id in another region to match")
+	}
+
+	// the file is reloaded when it changes
+	if err := ioutil.WriteFile(path, []byte("us-west-2/i-unlisted\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	reloadNeverReapIDs()
+	if matchesFilters(newInstance("us-west-2", "i-unlisted")) {
+		t.Error("expected an instance added to NeverReapIDsFile not to match after a reload")
+	}
+	if !matchesFilters(newInstance("us-west-2", "i-file")) {
+		t.Error("expected an instance removed from NeverReapIDsFile to match after a reload")
+	}
+
+	// a bad edit keeps the previous ids
+	if err := ioutil.WriteFile(path, []byte("i-no-region\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	reloadNeverReapIDs()
+	if matchesFilters(newInstance("us-west-2", "i-unlisted")) {
+		t.Error("expected the previous NeverReapIDsFile to be kept after a bad edit")
+	}
+}
+
+func TestParseNeverReapID(t *testing.T) {
+	for _, s := range []string{"i-1", "us-west-2/", "/i-1", "us-west-2/i-1/i-2"} {
+		if _, err := parseNeverReapID(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+	if id, err := parseNeverReapID(" us-west-2/i-1 "); err != nil || id != "us-west-2/i-1" {
+		t.Errorf("expected us-west-2/i-1, got %q, %v", id, err)
+	}
+}
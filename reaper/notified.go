@@ -0,0 +1,144 @@
+package reaper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// notification is when a resource was last sent a reapable event,
+// and the state it was in
+type notification struct {
+	at    time.Time
+	state state.StateEnum
+}
+
+// notified holds the last notification of each filtered resource,
+// by region/id
+var notified = struct {
+	sync.Mutex
+	last map[string]notification
+}{last: make(map[string]notification)}
+
+// notifiable drops the resources whose state was updated, and so would be
+// notified, but that were already notified of their current state or
+// within Notifications.MinPerResourceInterval
+// a resource whose state was saved before its owner was notified of it,
+// such as because sending failed, is updated again, so that it is notified
+// states are rebuilt from tags each cycle, so without the Tagger a
+// resource's state is updated every cycle, and whether and when it was
+// notified is only known in memory
+// only the EventReporters that notify owners are sent the kept resources,
+// see markNotified
+// owners left without resources are dropped
+func notifiable(filteredOwnerMap map[string][]reaperevents.Reapable, current time.Time) map[string][]reaperevents.Reapable {
+	interval := config.Notifications.MinPerResourceInterval.Duration
+
+	notified.Lock()
+	defer notified.Unlock()
+
+	// forget resources that are no longer filtered
+	filtered := make(map[string]bool)
+	for _, rs := range filteredOwnerMap {
+		for _, r := range rs {
+			filtered[fmt.Sprintf("%s/%s", r.Region(), r.ID())] = true
+		}
+	}
+	for id := range notified.last {
+		if !filtered[id] {
+			delete(notified.last, id)
+		}
+	}
+
+	kept := make(map[string][]reaperevents.Reapable)
+	for owner, rs := range filteredOwnerMap {
+		for _, r := range rs {
+			s := r.ReaperState()
+			if s == nil {
+				kept[owner] = append(kept[owner], r)
+				continue
+			}
+			id := fmt.Sprintf("%s/%s", r.Region(), r.ID())
+			last, ok := notified.last[id]
+			if ok && last.state == s.State && !s.NotifiedForCurrentState {
+				setNotified(r, last.at)
+				s = r.ReaperState()
+			}
+			if !s.Updated {
+				if s.NotifiedForCurrentState || s.State == state.InitialState {
+					kept[owner] = append(kept[owner], r)
+					continue
+				}
+				log.Info("%s was not notified of %s, notifying it again", r.ReapableDescriptionTiny(), s.State.String())
+				r.SetUpdated(true)
+				s = r.ReaperState()
+			}
+			if s.NotifiedForCurrentState {
+				log.Info("Not notifying %s again of %s", r.ReapableDescriptionTiny(), s.State.String())
+				continue
+			}
+			// the later of when this process and any other notified it
+			lastAt := s.LastNotified
+			if ok && last.at.After(lastAt) {
+				lastAt = last.at
+			}
+			if !lastAt.IsZero() && interval > 0 && current.Sub(lastAt) < interval {
+				log.Info("Not notifying %s again until %s", r.ReapableDescriptionTiny(), lastAt.Add(interval).String())
+				continue
+			}
+			kept[owner] = append(kept[owner], r)
+		}
+	}
+	return kept
+}
+
+// markNotified records that the owners of the resources whose state was
+// updated were notified of it at current, in memory and in their states,
+// once the notifications were sent
+func markNotified(notifiedOwnerMap map[string][]reaperevents.Reapable, current time.Time) {
+	notified.Lock()
+	defer notified.Unlock()
+	for _, rs := range notifiedOwnerMap {
+		for _, r := range rs {
+			s := r.ReaperState()
+			if s == nil || !s.Updated {
+				continue
+			}
+			setNotified(r, current)
+			notified.last[fmt.Sprintf("%s/%s", r.Region(), r.ID())] = notification{at: current, state: s.State}
+		}
+	}
+}
+
+// setNotified sets NotifiedForCurrentState and LastNotified on a copy of r's
+// state, so that States already returned by ReaperState aren't changed
+// a Reapable whose state can't be set is only recorded in memory
+func setNotified(r reapable.Reapable, at time.Time) {
+	settable, ok := r.(interface {
+		SetReaperState(*state.State)
+	})
+	if !ok {
+		return
+	}
+	s := *r.ReaperState()
+	s.NotifiedForCurrentState = true
+	s.LastNotified = at
+	settable.SetReaperState(&s)
+}
+
+// notifiedOf returns whether r's owner was notified of its current state
+func notifiedOf(r reapable.Reapable) bool {
+	s := r.ReaperState()
+	if s.NotifiedForCurrentState {
+		return true
+	}
+	notified.Lock()
+	defer notified.Unlock()
+	last, ok := notified.last[fmt.Sprintf("%s/%s", r.Region(), r.ID())]
+	return ok && last.state == s.State
+}
@@ -0,0 +1,195 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// setTestNotified forgets when resources were notified,
+// returning a func that restores it
+func setTestNotified() (restore func()) {
+	notified.Lock()
+	original := notified.last
+	notified.last = make(map[string]notification)
+	notified.Unlock()
+	return func() {
+		notified.Lock()
+		notified.last = original
+		notified.Unlock()
+	}
+}
+
+// setTestState gives r a new, updated state, as IncrementState does
+func setTestState(r *testReapable, s state.StateEnum) {
+	r.state = state.NewStateWithUntilAndState(time.Now(), s)
+	r.SetUpdated(true)
+}
+
+// notifyCycle runs notify at a time and returns the resources sent to jdoe
+func notifyCycle(t *testing.T, at time.Time, rs ...reaperevents.Reapable) []reaperevents.Reapable {
+	sent, restore := recordNotifications(at)
+	defer restore()
+	notify(map[string][]reaperevents.Reapable{"jdoe@example.com": rs})
+	select {
+	case m := <-sent:
+		return m["jdoe@example.com"]
+	case <-time.After(time.Second):
+		t.Fatal("expected notifications to be sent")
+	}
+	return nil
+}
+
+func TestNotifyOncePerState(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	defer setTestNotified()()
+	start := time.Date(2016, 6, 1, 14, 0, 0, 0, time.UTC)
+
+	r := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	setTestState(r, state.FirstState)
+	if sent := notifyCycle(t, start, r); len(sent) != 1 {
+		t.Fatalf("expected the first state to be notified, got %d resources", len(sent))
+	}
+	if !r.ReaperState().NotifiedForCurrentState {
+		t.Error("expected NotifiedForCurrentState to be set once notified")
+	}
+
+	// without the Tagger, the state is rebuilt and updated every cycle
+	setTestState(r, state.FirstState)
+	if sent := notifyCycle(t, start.Add(time.Minute), r); len(sent) != 0 {
+		t.Errorf("expected the same state not to be notified twice, got %d resources", len(sent))
+	}
+	if !r.ReaperState().NotifiedForCurrentState {
+		t.Error("expected NotifiedForCurrentState to be restored for a notified state")
+	}
+
+	setTestState(r, state.SecondState)
+	if r.ReaperState().NotifiedForCurrentState {
+		t.Error("expected a new state not to be notified")
+	}
+	if sent := notifyCycle(t, start.Add(2*time.Minute), r); len(sent) != 1 {
+		t.Errorf("expected the next state to be notified, got %d resources", len(sent))
+	}
+
+	// a resource whose state didn't change isn't notified, and passes through
+	r.SetUpdated(false)
+	if kept := notifiable(map[string][]reaperevents.Reapable{"jdoe@example.com": {r}}, start.Add(3*time.Minute)); len(kept["jdoe@example.com"]) != 1 {
+		t.Error("expected a resource whose state didn't change to be kept")
+	}
+
+	// a resource that is no longer filtered is forgotten
+	notifiable(map[string][]reaperevents.Reapable{}, start.Add(4*time.Minute))
+	setTestState(r, state.SecondState)
+	if sent := notifyCycle(t, start.Add(5*time.Minute), r); len(sent) != 1 {
+		t.Errorf("expected a resource filtered again to be notified, got %d resources", len(sent))
+	}
+}
+
+func TestMinPerResourceInterval(t *testing.T) {
+	c := &Config{}
+	c.Notifications.MinPerResourceInterval.Duration = time.Hour
+	defer setTestConfig(c)()
+	defer setTestNotified()()
+	start := time.Date(2016, 6, 1, 14, 0, 0, 0, time.UTC)
+
+	first := newTestReapable("us-west-2", "i-first", "jdoe@example.com")
+	setTestState(first, state.FirstState)
+	if sent := notifyCycle(t, start, first); len(sent) != 1 {
+		t.Fatalf("expected the first cycle to notify, got %d resources", len(sent))
+	}
+
+	// a resource notified within the interval is skipped, even in a new
+	// state, and others aren't
+	second := newTestReapable("us-west-2", "i-second", "jdoe@example.com")
+	setTestState(first, state.SecondState)
+	setTestState(second, state.FirstState)
+	sent := notifyCycle(t, start.Add(10*time.Minute), first, second)
+	if len(sent) != 1 || sent[0] != second {
+		t.Errorf("expected only i-second to be notified within the interval, got %v", sent)
+	}
+
+	setTestState(first, state.ThirdState)
+	if sent := notifyCycle(t, start.Add(time.Hour), first); len(sent) != 1 {
+		t.Errorf("expected i-first to be notified once the interval passed, got %d resources", len(sent))
+	}
+}
+
+func TestLastNotifiedAcrossRestarts(t *testing.T) {
+	c := &Config{}
+	c.Notifications.MinPerResourceInterval.Duration = time.Hour
+	defer setTestConfig(c)()
+	defer setTestNotified()()
+	start := time.Date(2016, 6, 1, 14, 0, 0, 0, time.UTC)
+
+	r := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	setTestState(r, state.FirstState)
+	if sent := notifyCycle(t, start, r); len(sent) != 1 {
+		t.Fatalf("expected the first state to be notified, got %d resources", len(sent))
+	}
+	if !r.ReaperState().LastNotified.Equal(start) {
+		t.Errorf("expected LastNotified to be %s, got %s", start, r.ReaperState().LastNotified)
+	}
+
+	// after a restart, the state restored from its tag still throttles
+	restored := state.NewStateWithTag(r.ReaperState().String())
+	if !restored.LastNotified.Equal(start) {
+		t.Fatalf("expected LastNotified to be restored from %q, got %s", r.ReaperState().String(), restored.LastNotified)
+	}
+	setTestNotified()
+	r.state = state.NewStateWithUntilAndState(start, state.SecondState)
+	r.state.LastNotified = restored.LastNotified
+	r.SetUpdated(true)
+	if sent := notifyCycle(t, start.Add(10*time.Minute), r); len(sent) != 0 {
+		t.Errorf("expected a resource notified before a restart to be throttled, got %d resources", len(sent))
+	}
+}
+
+func TestNotifiedOnceSent(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	defer setTestNotified()()
+	start := time.Date(2016, 6, 1, 14, 0, 0, 0, time.UTC)
+
+	// sending fails
+	done := make(chan bool, 1)
+	originalSend, originalRecord, originalNow := sendNotifications, recordReapableEvents, now
+	sendNotifications = func(m map[string][]reaperevents.Reapable) map[string][]reaperevents.Reapable {
+		return nil
+	}
+	recordReapableEvents = func(m map[string][]reaperevents.Reapable) {
+		originalRecord(m)
+		done <- true
+	}
+	now = func() time.Time { return start }
+	r := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	setTestState(r, state.FirstState)
+	notify(map[string][]reaperevents.Reapable{"jdoe@example.com": {r}})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected notifications to be sent")
+	}
+	sendNotifications, recordReapableEvents, now = originalSend, originalRecord, originalNow
+	if r.ReaperState().NotifiedForCurrentState || notifiedOf(r) {
+		t.Error("expected a resource whose notification failed not to be marked notified")
+	}
+
+	// next cycle, the state was saved, so isn't updated, but is notified again
+	r.SetUpdated(false)
+	if sent := notifyCycle(t, start.Add(time.Minute), r); len(sent) != 1 || !sent[0].ReaperState().Updated {
+		t.Fatalf("expected a resource that wasn't notified to be notified again, got %v", sent)
+	}
+	s := r.ReaperState()
+	if !s.NotifiedForCurrentState || !s.LastNotified.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected the resource to be marked notified once sent, got %+v", s)
+	}
+
+	// the Tagger saves that it was notified
+	if restored := state.NewStateWithTag(s.String()); !restored.NotifiedForCurrentState || !restored.LastNotified.Equal(s.LastNotified) {
+		t.Errorf("expected NotifiedForCurrentState and LastNotified to be restored from %q", s.String())
+	}
+	if restored := state.NewStateWithTag(state.NewStateWithUntilAndState(start, state.FirstState).String()); restored.NotifiedForCurrentState {
+		t.Error("expected a saved state that wasn't notified not to be restored as notified")
+	}
+}
@@ -0,0 +1,94 @@
+package reaper
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// OwnerStatisticsConfig configures the reaper.owner statistics
+// every owner is a tag value, so Owners and Hash bound their cardinality
+type OwnerStatisticsConfig struct {
+	Enabled bool
+
+	// Owners are the addresses that are tagged, the rest are tagged
+	// owner:other
+	// if empty, every owner is tagged
+	Owners []string
+
+	// Hash tags owners with a hash of their address instead
+	Hash bool
+}
+
+// ownerTotals are the resources of an owner, and their estimated hourly cost
+type ownerTotals struct {
+	resources int
+	cost      float64
+}
+
+// ownerStatisticTag returns the owner tag of an owner's statistics
+// owners are compared and tagged in lower case
+func ownerStatisticTag(owner string) string {
+	c := config.OwnerStatistics
+	owner = strings.ToLower(owner)
+	if owner == "" {
+		return "owner:unowned"
+	}
+	if len(c.Owners) > 0 {
+		allowed := false
+		for _, o := range c.Owners {
+			if strings.EqualFold(o, owner) {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return "owner:other"
+		}
+	}
+	if c.Hash {
+		return fmt.Sprintf("owner:%x", sha256.Sum256([]byte(owner)))[:len("owner:")+12]
+	}
+	return "owner:" + owner
+}
+
+// sumOwnerTotals counts the resources of each owner tag and sums their
+// estimated hourly costs
+func sumOwnerTotals(rs []reaperevents.Reapable) map[string]ownerTotals {
+	totals := make(map[string]ownerTotals)
+	for _, r := range rs {
+		owner := ""
+		if address := r.Owner(); address != nil {
+			owner = address.Address
+		}
+		tag := ownerStatisticTag(owner)
+		t := totals[tag]
+		t.resources++
+		if costed, ok := r.(reapable.Costed); ok {
+			if cost, ok := costed.EstimatedHourlyCost(); ok {
+				t.cost += cost
+			}
+		}
+		totals[tag] = t
+	}
+	return totals
+}
+
+// emitOwnerStatistics emits reaper.owner.resourcecount and
+// reaper.owner.estimatedcost, the hourly cost, once per owner tag
+func emitOwnerStatistics(rs []reaperevents.Reapable) {
+	if !config.OwnerStatistics.Enabled {
+		return
+	}
+	for tag, t := range sumOwnerTotals(rs) {
+		if err := newStatistic("reaper.owner.resourcecount", float64(t.resources), []string{tag, config.EventTag}); err != nil {
+			log.Error(err.Error())
+		}
+		if err := newStatistic("reaper.owner.estimatedcost", t.cost, []string{tag, config.EventTag}); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package reaper
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+)
+
+// costedTestReapable is a testReapable with an estimated hourly cost
+type costedTestReapable struct {
+	*testReapable
+	cost float64
+}
+
+func (r *costedTestReapable) EstimatedHourlyCost() (float64, bool) { return r.cost, true }
+
+func TestOwnerStatistics(t *testing.T) {
+	defer setTestConfig(&Config{
+		EventTag:        "env:test",
+		OwnerStatistics: OwnerStatisticsConfig{Enabled: true, Owners: []string{"a@example.com", "b@example.com"}},
+	})()
+	values := make(map[string]map[string]float64)
+	original := newStatistic
+	defer func() { newStatistic = original }()
+	newStatistic = func(name string, value float64, tags []string) error {
+		if values[name] == nil {
+			values[name] = make(map[string]float64)
+		}
+		values[name][tags[0]] += value
+		return nil
+	}
+
+	rs := []reaperevents.Reapable{
+		&costedTestReapable{newTestReapable("us-west-2", "i-1", "a@example.com"), 1.5},
+		&costedTestReapable{newTestReapable("us-west-2", "i-2", "A@example.com"), 0.25},
+		&costedTestReapable{newTestReapable("us-west-2", "i-3", "b@example.com"), 2},
+		&costedTestReapable{newTestReapable("us-west-2", "i-4", "c@example.com"), 4},
+		newTestReapable("us-west-2", "sg-1", "d@example.com"),
+		newTestReapable("us-west-2", "sg-2", ""),
+	}
+	emitOwnerStatistics(rs)
+
+	counts, costs := values["reaper.owner.resourcecount"], values["reaper.owner.estimatedcost"]
+	if counts["owner:a@example.com"] != 2 || counts["owner:b@example.com"] != 1 || counts["owner:other"] != 2 || counts["owner:unowned"] != 1 {
+		t.Errorf("unexpected resource counts %v", counts)
+	}
+	if costs["owner:a@example.com"] != 1.75 || costs["owner:other"] != 4 {
+		t.Errorf("unexpected estimated costs %v", costs)
+	}
+
+	// per owner statistics sum to the totals
+	var count, cost float64
+	for _, v := range counts {
+		count += v
+	}
+	for _, v := range costs {
+		cost += v
+	}
+	if int(count) != len(rs) {
+		t.Errorf("expected resource counts to sum to %d, got %v", len(rs), count)
+	}
+	if math.Abs(cost-7.75) > 1e-9 {
+		t.Errorf("expected estimated costs to sum to 7.75, got %v", cost)
+	}
+}
+
+func TestOwnerStatisticTag(t *testing.T) {
+	defer setTestConfig(&Config{OwnerStatistics: OwnerStatisticsConfig{Hash: true}})()
+	tag := ownerStatisticTag("a@example.com")
+	if strings.Contains(tag, "example") || len(tag) != len("owner:")+12 {
+		t.Errorf("expected a hashed owner tag, got %s", tag)
+	}
+	if tag != ownerStatisticTag("A@example.com") {
+		t.Error("expected owners to be hashed case insensitively")
+	}
+	if ownerStatisticTag("") != "owner:unowned" {
+		t.Error("expected resources without an owner to be tagged owner:unowned")
+	}
+}
@@ -2,15 +2,23 @@ package reaper
 
 import (
 	"fmt"
+	"math/rand"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+
 	reaperaws "github.com/mozilla-services/reaper/aws"
 	reaperevents "github.com/mozilla-services/reaper/events"
 	"github.com/mozilla-services/reaper/filters"
 	"github.com/mozilla-services/reaper/prices"
 	"github.com/mozilla-services/reaper/reapable"
 	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
 	"github.com/robfig/cron"
 )
 
@@ -19,6 +27,35 @@ var (
 	config    *Config
 	schedule  *cron.Cron
 	pricesMap prices.PricesMap
+	// spot prices, same format as pricesMap
+	spotPricesMap prices.PricesMap
+
+	// replaceable in tests
+	newStatistic      = reaperevents.NewStatistic
+	newCountStatistic = reaperevents.NewCountStatistic
+	now               = time.Now
+	downloadPrices    = prices.DownloadPricesMap
+	spotPrices        = reaperaws.SpotPrices
+
+	// events deferred during quiet hours
+	deferred struct {
+		sync.Mutex
+		byOwner map[string][]reaperevents.Reapable
+		timer   *time.Timer
+	}
+
+	// what readiness depends on, see readyz
+	health struct {
+		sync.Mutex
+		ready            bool
+		pricesDownloaded bool
+		reapStarted      bool
+		lastReap         time.Time
+	}
+
+	// filter errors that have been logged, so each is only logged once
+	reportedFilterErrors      = make(map[string]bool)
+	reportedFilterErrorsMutex sync.Mutex
 )
 
 func SetConfig(c *Config) {
@@ -29,12 +66,22 @@ func SetConfig(c *Config) {
 // which means events AND config need to be set BEFORE Ready
 func Ready() {
 	reaperevents.SetDryRun(config.DryRun)
-
-	if r := reapable.NewReapables(config.AWS.Regions); r != nil {
-		reapables = *r
-	} else {
-		log.Error("reapables improperly initialized")
+	reaperevents.SetTypeDryRun(typeDryRun)
+	reaperevents.SetSNSAPI(func(region string) snsiface.SNSAPI {
+		return reaperaws.SNSClient(region)
+	})
+
+	// resolve AllRegions and ExcludeRegions into the regions used everywhere
+	if err := config.AWS.ResolveRegions(); err != nil {
+		log.Error("Could not resolve regions: %s", err.Error())
 	}
+	log.Info("Using regions %s", strings.Join(config.AWS.Regions, ", "))
+
+	reapables.Reset(config.AWS.Regions)
+
+	health.Lock()
+	health.ready = true
+	health.Unlock()
 }
 
 // Reaper finds resources and deals with them
@@ -49,22 +96,48 @@ func NewReaper() *Reaper {
 	}
 }
 
+// GetPrices downloads on-demand prices and refreshes spot prices
+// spot prices come from EC2 rather than the price list, so they are
+// refreshed even when the download fails
 func GetPrices() {
-	// prevent shadowing
-	var err error
 	log.Info("Downloading prices")
-	pricesMap, err = prices.DownloadPricesMap(prices.Ec2PricingUrl)
+	downloaded, err := downloadPrices(prices.Ec2PricingUrl)
 	if err != nil {
 		log.Error(fmt.Sprintf("Error getting prices: %s", err.Error()))
-		return
+		if downloaded != nil {
+			// keep costs with stale prices rather than none
+			pricesMap = downloaded
+			log.Warning("Using prices last downloaded %s", prices.LastUpdated().Format(time.RFC3339))
+		}
+	} else {
+		pricesMap = downloaded
+		log.Info("Successfully downloaded prices")
+		health.Lock()
+		health.pricesDownloaded = true
+		health.Unlock()
+	}
+
+	spotPricesMap = spotPrices(config.AWS.Regions)
+}
+
+// retryPrices calls GetPrices if prices have never been downloaded,
+// so that a failed initial download is retried every reap, not weekly
+func retryPrices() {
+	health.Lock()
+	downloaded := health.pricesDownloaded
+	health.Unlock()
+	if !downloaded {
+		GetPrices()
 	}
-	log.Info("Successfully downloaded prices")
 }
 
 // Start begins Reaper's schedule
 func (r *Reaper) Start() {
 	// adding as a job runs r.Run() every interval
-	r.Cron.Schedule(cron.Every(config.Notifications.Interval.Duration), r)
+	// optionally offset by a random jitter
+	r.Cron.Schedule(newJitterSchedule(config.Notifications.Interval.Duration,
+		config.Notifications.IntervalJitter.Duration,
+		rand.NewSource(time.Now().UnixNano())), r)
 	r.Cron.AddFunc("@weekly", GetPrices)
 	r.Cron.Start()
 
@@ -80,24 +153,71 @@ func (r *Reaper) Stop() {
 	log.Debug("Stopping Reaper")
 	reaperevents.Cleanup()
 	r.Cron.Stop()
+	scheduleMutex.Lock()
+	if schedule != nil {
+		schedule.Stop()
+	}
+	scheduleMutex.Unlock()
 }
 
 // Run handles all reaping logic
 // conforms to the cron.Job interface
 func (r *Reaper) Run() {
-	r.reap()
+	measureCycle(r.reap)
 
 	// this is no longer true, but is roughly accurate
 	log.Info("Sleeping for %s", config.Notifications.Interval.Duration.String())
 }
 
+// measureCycle calls reap, then emits reaper.cycle.duration in seconds and
+// reaper.cycle.errors, the number of errors logged while it ran
+func measureCycle(reap func()) {
+	start := now()
+	errorsBefore := log.ErrorCount()
+
+	reap()
+
+	duration := now().Sub(start)
+	errorCount := log.ErrorCount() - errorsBefore
+	log.Info("Reap cycle took %s with %d errors", duration.String(), errorCount)
+	if err := newStatistic("reaper.cycle.duration", duration.Seconds(), []string{config.EventTag}); err != nil {
+		log.Error(err.Error())
+	}
+	if err := newStatistic("reaper.cycle.errors", float64(errorCount), []string{config.EventTag}); err != nil {
+		log.Error(err.Error())
+	}
+}
+
 func (r *Reaper) reap() {
+	health.Lock()
+	health.reapStarted = true
+	health.Unlock()
+
+	retryPrices()
+	reloadNeverReapIDs()
+	retryDeadLetters()
+	reaperaws.ResetDiscoveryFailures()
 	reapables := allReapables()
-
-	filteredOwnerMap := make(map[string][]reaperevents.Reapable)
+	pruneReapables(reapables)
+	emitOwnerStatistics(reapables)
+
+	// count the resources of each type, and those matching filters,
+	// for the safety circuit breaker
+	totals := make(map[string]int)
+	filteredTotals := make(map[string]int)
+	var filtered []reaperevents.Reapable
 	for _, reapable := range reapables {
+		resourceType := reapableType(reapable)
+		totals[resourceType]++
+
+		// instances that are already going away, or still starting,
+		// are neither notified about nor acted on
+		if !settled(reapable) {
+			continue
+		}
+
 		// default owner should ensure this does not happen
-		if reapable.Owner() == nil {
+		if notificationOwner(reapable) == "" {
 			log.Error("Resource %s has no owner", reapable.ReapableDescriptionTiny())
 			continue
 		}
@@ -105,31 +225,233 @@ func (r *Reaper) reap() {
 		// TODO naively re-call matchesFilters here
 		// after previously calling it for statistics
 		if matchesFilters(reapable) {
-			// group resources by owner
-			owner := reapable.Owner().Address
-			filteredOwnerMap[owner] = append(filteredOwnerMap[owner], reapable)
-			registerReapable(reapable)
+			filteredTotals[resourceType]++
+			filtered = append(filtered, reapable)
 		}
+		reportMissingPrice(reapable)
 	}
 
-	// trigger batch events for each filtered owned resource in a goroutine
-	// for each owner in the owner map
-	go func() {
-		// trigger a per owner batch event
-		for _, filteredOwnedReapables := range filteredOwnerMap {
-			// if there's only one resource for the owner, do a single event
-			if len(filteredOwnedReapables) == 1 {
-				if err := reaperevents.NewReapableEvent(filteredOwnedReapables[0], []string{config.EventTag}); err != nil {
-					log.Error(err.Error())
-				}
-			} else {
-				// batch event
-				if err := reaperevents.NewBatchReapableEvent(filteredOwnedReapables, []string{config.EventTag}); err != nil {
-					log.Error(err.Error())
+	tripped := make(map[string]bool)
+	for resourceType, n := range filteredTotals {
+		tripped[resourceType] = safetyTripped(resourceType, n, totals[resourceType])
+	}
+
+	filteredOwnerMap := make(map[string][]reaperevents.Reapable)
+	for _, reapable := range filtered {
+		if tripped[reapableType(reapable)] {
+			continue
+		}
+		// group resources by owner
+		owner := notificationOwner(reapable)
+		filteredOwnerMap[owner] = append(filteredOwnerMap[owner], reapable)
+		registerReapable(reapable)
+	}
+
+	// states are recorded straight away, even during quiet hours,
+	// so that they advance from the recorded state next cycle
+	recordReapableEvents(filteredOwnerMap)
+	notify(filteredOwnerMap)
+
+	for _, rs := range filteredOwnerMap {
+		for _, reapable := range rs {
+			autoTerminate(reapable)
+		}
+	}
+
+	health.Lock()
+	health.lastReap = now()
+	health.Unlock()
+}
+
+// notify triggers events for each owner's filtered resources in a goroutine
+// during quiet hours, the events are deferred until quiet hours end
+func notify(filteredOwnerMap map[string][]reaperevents.Reapable) {
+	deferred.Lock()
+	defer deferred.Unlock()
+
+	// the latest resources supersede any that were deferred,
+	// except that deferred notices of updated states are kept
+	filteredOwnerMap = mergeDeferred(deferred.byOwner, filteredOwnerMap)
+	deferred.byOwner = nil
+	if deferred.timer != nil {
+		deferred.timer.Stop()
+		deferred.timer = nil
+	}
+
+	current := now()
+	if config.Notifications.QuietHours.Quiet(current) {
+		end := config.Notifications.QuietHours.End(current)
+		log.Info("Quiet hours, deferring events for %d owners until %s", len(filteredOwnerMap), end.String())
+		deferred.byOwner = filteredOwnerMap
+		deferred.timer = time.AfterFunc(end.Sub(current), sendDeferredNotifications)
+		return
+	}
+	go sendReapableEvents(filteredOwnerMap, notifiable(filteredOwnerMap, current), current)
+}
+
+// sendDeferredNotifications sends the events deferred by notify
+func sendDeferredNotifications() {
+	deferred.Lock()
+	filteredOwnerMap := deferred.byOwner
+	deferred.byOwner = nil
+	deferred.timer = nil
+	deferred.Unlock()
+
+	if filteredOwnerMap != nil {
+		current := now()
+		sendReapableEvents(filteredOwnerMap, notifiable(filteredOwnerMap, current), current)
+	}
+}
+
+// sendReapableEvents sends each owner's filtered resources to the
+// EventReporters that act on them, and those that notifiable kept to the
+// EventReporters that notify owners
+// the resources of owners that were notified are marked notified and
+// recorded again, so that the Tagger saves that they were
+func sendReapableEvents(filteredOwnerMap, notifiableOwnerMap map[string][]reaperevents.Reapable, current time.Time) {
+	actOnReapableEvents(filteredOwnerMap)
+	sent := sendNotifications(notifiableOwnerMap)
+	markNotified(sent, current)
+	recordReapableEvents(sent)
+}
+
+// mergeDeferred returns the latest resources, except that a resource whose
+// state wasn't updated since its notice was deferred is replaced by the
+// deferred resource, so that its owner is still notified of the update
+// deferred resources that are no longer filtered are dropped
+func mergeDeferred(earlier, latest map[string][]reaperevents.Reapable) map[string][]reaperevents.Reapable {
+	if earlier == nil {
+		return latest
+	}
+	updated := make(map[string]reaperevents.Reapable)
+	for _, rs := range earlier {
+		for _, r := range rs {
+			if s := r.ReaperState(); s != nil && s.Updated {
+				updated[fmt.Sprintf("%s/%s", r.Region(), r.ID())] = r
+			}
+		}
+	}
+
+	merged := make(map[string][]reaperevents.Reapable)
+	for owner, rs := range latest {
+		merged[owner] = nil
+		for _, r := range rs {
+			if s := r.ReaperState(); s == nil || !s.Updated {
+				if d, ok := updated[fmt.Sprintf("%s/%s", r.Region(), r.ID())]; ok {
+					r = d
 				}
 			}
+			merged[owner] = append(merged[owner], r)
 		}
-	}()
+	}
+	return merged
+}
+
+// deferredNotice returns whether r's owner is yet to be notified of an
+// update to its state, because the notice was deferred during quiet hours
+func deferredNotice(r reapable.Reapable) bool {
+	deferred.Lock()
+	defer deferred.Unlock()
+	for _, rs := range deferred.byOwner {
+		for _, d := range rs {
+			if d.Region() == r.Region() && d.ID() == r.ID() {
+				s := d.ReaperState()
+				return s != nil && s.Updated
+			}
+		}
+	}
+	return false
+}
+
+// recordReapableEvents sends each owner's filtered resources to the
+// EventReporters that record them, such as the Tagger
+// replaceable in tests
+var recordReapableEvents = func(filteredOwnerMap map[string][]reaperevents.Reapable) {
+	for _, filteredOwnedReapables := range filteredOwnerMap {
+		if len(filteredOwnedReapables) == 0 {
+			continue
+		}
+		if err := reaperevents.RecordReapableEvents(filteredOwnedReapables, []string{config.EventTag}); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
+// actOnReapableEvents sends each owner's filtered resources to the
+// EventReporters that act on them, such as the ReaperEvent
+// replaceable in tests
+var actOnReapableEvents = func(filteredOwnerMap map[string][]reaperevents.Reapable) {
+	for _, filteredOwnedReapables := range filteredOwnerMap {
+		if len(filteredOwnedReapables) == 0 {
+			continue
+		}
+		if err := reaperevents.ActOnReapableEvents(filteredOwnedReapables, []string{config.EventTag}); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
+// sendNotifications triggers a per owner event for each owner in the owner map,
+// a single event if the owner has one resource, or else a batch event,
+// with the EventReporters that notify owners
+// it returns the resources of the owners whose events were sent
+// replaceable in tests
+var sendNotifications = func(filteredOwnerMap map[string][]reaperevents.Reapable) map[string][]reaperevents.Reapable {
+	sent := make(map[string][]reaperevents.Reapable)
+	for owner, filteredOwnedReapables := range filteredOwnerMap {
+		if len(filteredOwnedReapables) == 0 {
+			continue
+		}
+		if err := reaperevents.NotifyReapableEvents(filteredOwnedReapables, []string{config.EventTag}); err != nil {
+			log.Error(err.Error())
+			continue
+		}
+		sent[owner] = filteredOwnedReapables
+	}
+	return sent
+}
+
+// notificationOwner returns the address a reapable's events are grouped by
+// unowned reapables fall back to the default owner, see Resource.Owners
+func notificationOwner(r reaperevents.Reapable) string {
+	if owner := r.Owner(); owner != nil {
+		return owner.Address
+	}
+	return ""
+}
+
+// instanceHourlyCost returns the price of an instance from pricesMap
+// or from spotPricesMap for spot instances
+func instanceHourlyCost(instance *reaperaws.Instance) (float64, bool) {
+	if instance.InstanceType == nil {
+		return 0, false
+	}
+	m := pricesMap
+	if instance.IsSpot() {
+		m = spotPricesMap
+	}
+	if m == nil {
+		return 0, false
+	}
+	price, ok := m[instance.Region().String()][aws.StringValue(instance.InstanceType)]
+	if !ok {
+		return 0, false
+	}
+	cost, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return 0, false
+	}
+	return cost, true
+}
+
+// volumeHourlyCost estimates the price of a volume's storage
+// from prices.EBSMonthlyPricesPerGB
+func volumeHourlyCost(volume *reaperaws.Volume) (float64, bool) {
+	perGB, ok := prices.EBSMonthlyPricesPerGB[aws.StringValue(volume.VolumeType)]
+	if !ok || volume.Size == nil {
+		return 0, false
+	}
+	return perGB * float64(*volume.Size) / reaperaws.HoursPerMonth, true
 }
 
 func getSecurityGroups() chan *reaperaws.SecurityGroup {
@@ -157,19 +479,19 @@ func getSecurityGroups() chan *reaperaws.SecurityGroup {
 		}
 		go func() {
 			for region, regionSum := range regionSums {
-				err := reaperevents.NewStatistic("reaper.securitygroups.total",
+				err := newStatistic("reaper.securitygroups.total",
 					float64(regionSum),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
 					log.Error(err.Error())
 				}
-				err = reaperevents.NewStatistic("reaper.securitygroups.whitelistedCount",
+				err = newStatistic("reaper.securitygroups.whitelistedCount",
 					float64(whitelistedCount[region]),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
 					log.Error(err.Error())
 				}
-				err = reaperevents.NewStatistic("reaper.securitygroups.filtered",
+				err = newStatistic("reaper.securitygroups.filtered",
 					float64(filteredCount[region]),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
@@ -187,13 +509,16 @@ func getVolumes() chan *reaperaws.Volume {
 	go func() {
 		volumeCh := reaperaws.AllVolumes()
 		regionSums := make(map[reapable.Region]int)
-		volumeSizeSums := make(map[reapable.Region]map[int64]int)
+		volumeSizeSums := make(map[reapable.Region]map[volumeSize]int)
+		volumeTypeCosts := make(map[reapable.Region]map[string]float64)
+		unpricedVolumeTypes := make(map[string]bool)
 		filteredCount := make(map[reapable.Region]int)
 		whitelistedCount := make(map[reapable.Region]int)
 		for volume := range volumeCh {
 			// make the map if it is not initialized
 			if volumeSizeSums[volume.Region()] == nil {
-				volumeSizeSums[volume.Region()] = make(map[int64]int)
+				volumeSizeSums[volume.Region()] = make(map[volumeSize]int)
+				volumeTypeCosts[volume.Region()] = make(map[string]float64)
 			}
 			regionSums[volume.Region()]++
 
@@ -201,7 +526,17 @@ func getVolumes() chan *reaperaws.Volume {
 				whitelistedCount[volume.Region()]++
 			}
 
-			volumeSizeSums[volume.Region()][*volume.Size]++
+			volumeType := aws.StringValue(volume.VolumeType)
+			volumeSizeSums[volume.Region()][volumeSize{size: aws.Int64Value(volume.Size), volumeType: volumeType}]++
+
+			if cost, ok := volumeHourlyCost(volume); ok {
+				volume.HourlyCost = cost
+				volumeTypeCosts[volume.Region()][volumeType] += cost
+			} else if !unpricedVolumeTypes[volumeType] {
+				// the volume's cost is left out of reaper.volumes.totalcost
+				unpricedVolumeTypes[volumeType] = true
+				log.Error(fmt.Sprintf("No price for volume type %s", volumeType))
+			}
 
 			if matchesFilters(volume) {
 				filteredCount[volume.Region()]++
@@ -213,30 +548,7 @@ func getVolumes() chan *reaperaws.Volume {
 			log.Info("Found %d total volumes in %s", sum, region)
 		}
 
-		go func() {
-			for region, regionMap := range volumeSizeSums {
-				for volumeType, volumeSizeSum := range regionMap {
-					err := reaperevents.NewStatistic("reaper.volumes.total",
-						float64(volumeSizeSum),
-						[]string{fmt.Sprintf("region:%s,volumesize:%d", region, volumeType)})
-					if err != nil {
-						log.Error(err.Error())
-					}
-					err = reaperevents.NewStatistic("reaper.volumes.filtered",
-						float64(filteredCount[region]),
-						[]string{fmt.Sprintf("region:%s,volumesize:%d", region, volumeType)})
-					if err != nil {
-						log.Error(err.Error())
-					}
-				}
-				err := reaperevents.NewStatistic("reaper.volumes.whitelistedCount",
-					float64(whitelistedCount[region]),
-					[]string{fmt.Sprintf("region:%s", region)})
-				if err != nil {
-					log.Error(err.Error())
-				}
-			}
-		}()
+		go emitVolumeStatistics(regionSums, volumeSizeSums, volumeTypeCosts, filteredCount, whitelistedCount)
 		close(ch)
 	}()
 	return ch
@@ -248,18 +560,25 @@ func getInstances() chan *reaperaws.Instance {
 		instanceCh := reaperaws.AllInstances()
 		regionSums := make(map[reapable.Region]int)
 		instanceTypeSums := make(map[reapable.Region]map[string]int)
+		instanceTypeCosts := make(map[reapable.Region]map[string]float64)
 		filteredCount := make(map[reapable.Region]int)
 		whitelistedCount := make(map[reapable.Region]int)
 		for instance := range instanceCh {
 			// make the map if it is not initialized
 			if instanceTypeSums[instance.Region()] == nil {
 				instanceTypeSums[instance.Region()] = make(map[string]int)
+				instanceTypeCosts[instance.Region()] = make(map[string]float64)
 			}
 
 			// don't count terminated or stopped instances
 			if !instance.Terminated() && !instance.Stopped() {
 				// increment InstanceType counter
-				instanceTypeSums[instance.Region()][*instance.InstanceType]++
+				instanceTypeSums[instance.Region()][aws.StringValue(instance.InstanceType)]++
+
+				if cost, ok := instanceHourlyCost(instance); ok {
+					instance.HourlyCost = cost
+					instanceTypeCosts[instance.Region()][aws.StringValue(instance.InstanceType)] += cost
+				}
 
 				if isWhitelisted(instance) {
 					whitelistedCount[instance.Region()]++
@@ -282,37 +601,33 @@ func getInstances() chan *reaperaws.Instance {
 			for region, regionMap := range instanceTypeSums {
 				for instanceType, instanceTypeSum := range regionMap {
 					if pricesMap != nil {
-						price, ok := pricesMap[string(region)][instanceType]
-						if ok {
-							priceFloat, err := strconv.ParseFloat(price, 64)
-							if err != nil {
-								log.Error(err.Error())
-							}
-							err = reaperevents.NewStatistic("reaper.instances.totalcost",
-								float64(instanceTypeSum)*priceFloat,
+						// spot instances are priced with spotPricesMap
+						if cost, ok := instanceTypeCosts[region][instanceType]; ok {
+							err := newStatistic("reaper.instances.totalcost",
+								cost,
 								[]string{fmt.Sprintf("region:%s,instancetype:%s", region, instanceType), config.EventTag})
 							if err != nil {
 								log.Error(err.Error())
 							}
-						} else {
+						} else if instanceTypeSum > 0 {
 							// some instance types are priceless
 							log.Error(fmt.Sprintf("No price for %s", instanceType))
 						}
 					}
-					err := reaperevents.NewStatistic("reaper.instances.total",
+					err := newStatistic("reaper.instances.total",
 						float64(instanceTypeSum),
 						[]string{fmt.Sprintf("region:%s,instancetype:%s", region, instanceType), config.EventTag})
 					if err != nil {
 						log.Error(err.Error())
 					}
-					err = reaperevents.NewStatistic("reaper.instances.filtered",
+					err = newStatistic("reaper.instances.filtered",
 						float64(filteredCount[region]),
 						[]string{fmt.Sprintf("region:%s,instancetype:%s", region, instanceType), config.EventTag})
 					if err != nil {
 						log.Error(err.Error())
 					}
 				}
-				err := reaperevents.NewStatistic("reaper.instances.whitelistedCount",
+				err := newStatistic("reaper.instances.whitelistedCount",
 					float64(whitelistedCount[region]),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
@@ -348,19 +663,19 @@ func getCloudformations() chan *reaperaws.Cloudformation {
 		}
 		go func() {
 			for region, regionSum := range regionSums {
-				err := reaperevents.NewStatistic("reaper.cloudformations.total",
+				err := newStatistic("reaper.cloudformations.total",
 					float64(regionSum),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
 					log.Error(err.Error())
 				}
-				err = reaperevents.NewStatistic("reaper.cloudformations.filtered",
+				err = newStatistic("reaper.cloudformations.filtered",
 					float64(filteredCount[region]),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
 					log.Error(err.Error())
 				}
-				err = reaperevents.NewStatistic("reaper.cloudformations.whitelistedCount",
+				err = newStatistic("reaper.cloudformations.whitelistedCount",
 					float64(whitelistedCount[region]),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
@@ -404,58 +719,214 @@ func getAutoScalingGroups() chan *reaperaws.AutoScalingGroup {
 		for region, sum := range regionSums {
 			log.Info("Found %d total AutoScalingGroups in %s", sum, region)
 		}
+		go emitAutoScalingGroupStatistics(regionSums, asgSizeSums, filteredCount, whitelistedCount)
+		close(ch)
+	}()
+	return ch
+}
+
+func getImages() chan *reaperaws.Image {
+	ch := make(chan *reaperaws.Image)
+	go func() {
+		imageCh := reaperaws.AllImages()
+		regionSums := make(map[reapable.Region]int)
+		filteredCount := make(map[reapable.Region]int)
+		whitelistedCount := make(map[reapable.Region]int)
+		for image := range imageCh {
+			regionSums[image.Region()]++
+
+			if isWhitelisted(image) {
+				whitelistedCount[image.Region()]++
+			}
+
+			if matchesFilters(image) {
+				filteredCount[image.Region()]++
+			}
+			ch <- image
+		}
+
+		for region, sum := range regionSums {
+			log.Info("Found %d total images in %s", sum, region)
+		}
+
 		go func() {
-			for region, regionMap := range asgSizeSums {
-				for asgSize, asgSizeSum := range regionMap {
-					err := reaperevents.NewStatistic("reaper.asgs.asgsizes",
-						float64(asgSizeSum),
-						[]string{fmt.Sprintf("region:%s,asgsize:%d", region, asgSize), config.EventTag})
-					if err != nil {
-						log.Error(err.Error())
-					}
+			for region, regionSum := range regionSums {
+				err := newStatistic("reaper.images.total",
+					float64(regionSum),
+					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+				if err != nil {
+					log.Error(err.Error())
+				}
+				err = newStatistic("reaper.images.filtered",
+					float64(filteredCount[region]),
+					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+				if err != nil {
+					log.Error(err.Error())
+				}
+				err = newStatistic("reaper.images.whitelistedCount",
+					float64(whitelistedCount[region]),
+					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+				if err != nil {
+					log.Error(err.Error())
 				}
 			}
+		}()
+		close(ch)
+	}()
+	return ch
+}
+
+func getNetworkInterfaces() chan *reaperaws.NetworkInterface {
+	ch := make(chan *reaperaws.NetworkInterface)
+	go func() {
+		networkInterfaceCh := reaperaws.AllNetworkInterfaces()
+		regionSums := make(map[reapable.Region]int)
+		filteredCount := make(map[reapable.Region]int)
+		whitelistedCount := make(map[reapable.Region]int)
+		for eni := range networkInterfaceCh {
+			regionSums[eni.Region()]++
+
+			if isWhitelisted(eni) {
+				whitelistedCount[eni.Region()]++
+			}
+
+			if matchesFilters(eni) {
+				filteredCount[eni.Region()]++
+			}
+			ch <- eni
+		}
+
+		for region, sum := range regionSums {
+			log.Info("Found %d total NetworkInterfaces in %s", sum, region)
+		}
+
+		go func() {
 			for region, regionSum := range regionSums {
-				err := reaperevents.NewStatistic("reaper.asgs.total",
+				err := newStatistic("reaper.networkinterfaces.total",
 					float64(regionSum),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
 					log.Error(err.Error())
 				}
-				err = reaperevents.NewStatistic("reaper.asgs.filtered",
+				err = newStatistic("reaper.networkinterfaces.filtered",
 					float64(filteredCount[region]),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
 					log.Error(err.Error())
 				}
-				err = reaperevents.NewStatistic("reaper.asgs.whitelistedCount",
+				err = newStatistic("reaper.networkinterfaces.whitelistedCount",
 					float64(whitelistedCount[region]),
 					[]string{fmt.Sprintf("region:%s", region), config.EventTag})
 				if err != nil {
 					log.Error(err.Error())
 				}
 			}
-
 		}()
 		close(ch)
 	}()
 	return ch
 }
 
+// volumeSize is the size and type that volumes are counted by,
+// since the type matters as much as the size to their cost
+type volumeSize struct {
+	size       int64
+	volumeType string
+}
+
+// emitVolumeStatistics emits reaper.volumes.total once per region, size and
+// volume type, reaper.volumes.totalcost, the hourly cost, once per region
+// and volume type, and reaper.volumes.filtered and whitelistedCount once
+// per region
+func emitVolumeStatistics(regionSums map[reapable.Region]int, volumeSizeSums map[reapable.Region]map[volumeSize]int,
+	volumeTypeCosts map[reapable.Region]map[string]float64, filteredCount, whitelistedCount map[reapable.Region]int) {
+	for region, regionMap := range volumeSizeSums {
+		for volumeSize, volumeSizeSum := range regionMap {
+			err := newStatistic("reaper.volumes.total",
+				float64(volumeSizeSum),
+				[]string{fmt.Sprintf("region:%s,volumesize:%d,volumetype:%s", region, volumeSize.size, volumeSize.volumeType), config.EventTag})
+			if err != nil {
+				log.Error(err.Error())
+			}
+		}
+	}
+	for region, regionMap := range volumeTypeCosts {
+		for volumeType, cost := range regionMap {
+			err := newStatistic("reaper.volumes.totalcost",
+				cost,
+				[]string{fmt.Sprintf("region:%s,volumetype:%s", region, volumeType), config.EventTag})
+			if err != nil {
+				log.Error(err.Error())
+			}
+		}
+	}
+	for region := range regionSums {
+		err := newStatistic("reaper.volumes.filtered",
+			float64(filteredCount[region]),
+			[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+		if err != nil {
+			log.Error(err.Error())
+		}
+		err = newStatistic("reaper.volumes.whitelistedCount",
+			float64(whitelistedCount[region]),
+			[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
+// emitAutoScalingGroupStatistics emits reaper.asgs.asgsizes once per region and size,
+// and reaper.asgs.total, filtered and whitelistedCount once per region
+func emitAutoScalingGroupStatistics(regionSums map[reapable.Region]int, asgSizeSums map[reapable.Region]map[int64]int,
+	filteredCount, whitelistedCount map[reapable.Region]int) {
+	for region, regionMap := range asgSizeSums {
+		for asgSize, asgSizeSum := range regionMap {
+			err := newStatistic("reaper.asgs.asgsizes",
+				float64(asgSizeSum),
+				[]string{fmt.Sprintf("region:%s,asgsize:%d", region, asgSize), config.EventTag})
+			if err != nil {
+				log.Error(err.Error())
+			}
+		}
+	}
+	for region, regionSum := range regionSums {
+		err := newStatistic("reaper.asgs.total",
+			float64(regionSum),
+			[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+		if err != nil {
+			log.Error(err.Error())
+		}
+		err = newStatistic("reaper.asgs.filtered",
+			float64(filteredCount[region]),
+			[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+		if err != nil {
+			log.Error(err.Error())
+		}
+		err = newStatistic("reaper.asgs.whitelistedCount",
+			float64(whitelistedCount[region]),
+			[]string{fmt.Sprintf("region:%s", region), config.EventTag})
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
 // makes a slice of all filterables by appending
 // output of each filterable types aggregator function
 func allReapables() []reaperevents.Reapable {
 	var resources []reaperevents.Reapable
 
-	// initialize dependency and isInCloudformation
+	// initialize dependency and cloudformationStacks
 	dependency := make(map[reapable.Region]map[reapable.ID]bool)
 	for _, region := range config.AWS.Regions {
 		dependency[reapable.Region(region)] = make(map[reapable.ID]bool)
 	}
 
-	isInCloudformation := make(map[reapable.Region]map[reapable.ID]bool)
+	// the names of the Cloudformations resources are in
+	cloudformationStacks := make(map[reapable.Region]map[reapable.ID]cloudformationStack)
 	for _, region := range config.AWS.Regions {
-		isInCloudformation[reapable.Region(region)] = make(map[reapable.ID]bool)
+		cloudformationStacks[reapable.Region(region)] = make(map[reapable.ID]cloudformationStack)
 	}
 
 	// initialize the map of instances in ASGs
@@ -464,28 +935,40 @@ func allReapables() []reaperevents.Reapable {
 		instancesInASGs[reapable.Region(region)] = make(map[reapable.ID]bool)
 	}
 
+	// the instances of whitelisted ASGs, if their whitelist is propagated
+	instancesInWhitelistedASGs := make(map[reapable.Region]map[reapable.ID]bool)
+
+	// the relationships found, if ExportDependencyGraph is set
+	graph := newDependencyGraph()
+
 	// without getCloudformations cannot populate basic dependency logic
 	for c := range getCloudformations() {
-		// because getting resources is rate limited...
-		c.RLock()
-		for _, resource := range c.Resources {
-			if resource.PhysicalResourceId != nil {
-				dependency[c.Region()][reapable.ID(*resource.PhysicalResourceId)] = true
-				isInCloudformation[c.Region()][reapable.ID(*resource.PhysicalResourceId)] = true
+		graph.addCloudformation(c)
+		status := aws.StringValue(c.StackStatus)
+		// includes the resources of nested stacks
+		for _, id := range c.PhysicalResourceIDs() {
+			// a stack that rolled back is an orphan that will never use
+			// its remaining resources
+			if status != cloudformation.StackStatusRollbackComplete {
+				dependency[c.Region()][id] = true
+			}
+			// a resource of a stack being deleted stays marked as such,
+			// even if it is also in one of its nested stacks
+			if cloudformationStacks[c.Region()][id].Status != cloudformation.StackStatusDeleteInProgress {
+				cloudformationStacks[c.Region()][id] = cloudformationStack{Name: c.Name, Status: status}
 			}
 		}
-		c.RUnlock()
-		if config.Cloudformations.Enabled {
+		if config.Cloudformations.enabledIn(c.Region()) {
 			resources = append(resources, c)
 		}
 	}
 
+	// the AutoScalingGroups whose tags may schedule scaling
+	var scaled []*reaperaws.AutoScalingGroup
+
 	for a := range getAutoScalingGroups() {
 		// ASGs can be identified by name...
-		if isInCloudformation[a.Region()][a.ID()] ||
-			isInCloudformation[a.Region()][reapable.ID(a.Name)] {
-			a.IsInCloudformation = true
-		}
+		setCloudformationStack(&a.Resource, cloudformationStacks[a.Region()], a.ID(), reapable.ID(a.Name))
 
 		if dependency[a.Region()][a.ID()] ||
 			dependency[a.Region()][reapable.ID(a.Name)] {
@@ -494,6 +977,7 @@ func allReapables() []reaperevents.Reapable {
 
 		// identify instances in an ASG
 		instanceIDsInASGs := reaperaws.AutoScalingGroupInstanceIDs(a)
+		graph.addAutoScalingGroup(a, instanceIDsInASGs)
 		for region := range instanceIDsInASGs {
 			for instanceID := range instanceIDsInASGs[region] {
 				instancesInASGs[region][instanceID] = true
@@ -501,13 +985,46 @@ func allReapables() []reaperevents.Reapable {
 			}
 		}
 
-		if config.AutoScalingGroups.Enabled {
+		// instances launched before the ASG was whitelisted aren't tagged,
+		// so they are protected by the ASG's tag
+		if config.AutoScalingGroups.PropagateWhitelist && isWhitelisted(a) {
+			if instancesInWhitelistedASGs[a.Region()] == nil {
+				instancesInWhitelistedASGs[a.Region()] = make(map[reapable.ID]bool)
+			}
+			for _, instanceID := range a.Instances {
+				instancesInWhitelistedASGs[a.Region()][instanceID] = true
+			}
+		}
+
+		if config.AutoScalingGroups.enabledIn(a.Region()) {
 			resources = append(resources, a)
+			scaled = append(scaled, a)
 		}
 	}
+	scheduleScaling(scaled)
+
+	// AMIs used by launch configurations can't be deregistered
+	imagesInUse := make(map[reapable.Region]map[reapable.ID]bool)
+	if config.Images.enabledAnywhere() {
+		imagesInUse = reaperaws.LaunchConfigurationImageIDs()
+	}
+
+	// the instances, for the volumes attached to them
+	instances := make(map[reapable.Region]map[reapable.ID]*reaperaws.Instance)
 
 	// get all instances
 	for i := range getInstances() {
+		if instances[i.Region()] == nil {
+			instances[i.Region()] = make(map[reapable.ID]*reaperaws.Instance)
+		}
+		instances[i.Region()][i.ID()] = i
+		graph.addInstance(i)
+
+		// add the instance's AMI to the map of in use
+		if i.ImageId != nil && imagesInUse[i.Region()] != nil {
+			imagesInUse[i.Region()][reapable.ID(*i.ImageId)] = true
+		}
+
 		// add security groups to map of in use
 		for id, name := range i.SecurityGroups {
 			dependency[i.Region()][reapable.ID(name)] = true
@@ -517,14 +1034,15 @@ func allReapables() []reaperevents.Reapable {
 		if dependency[i.Region()][i.ID()] {
 			i.Dependency = true
 		}
-		if isInCloudformation[i.Region()][i.ID()] {
-			i.IsInCloudformation = true
-		}
+		setCloudformationStack(&i.Resource, cloudformationStacks[i.Region()], i.ID())
 		if instancesInASGs[i.Region()][i.ID()] {
 			i.AutoScaled = true
 		}
+		if instancesInWhitelistedASGs[i.Region()][i.ID()] {
+			i.InWhitelistedAutoScalingGroup = true
+		}
 
-		if config.Instances.Enabled {
+		if config.Instances.enabledIn(i.Region()) {
 			resources = append(resources, i)
 		}
 	}
@@ -533,14 +1051,12 @@ func allReapables() []reaperevents.Reapable {
 	for s := range getSecurityGroups() {
 		// if the security group is in use, it isn't reapable
 		// names and IDs are used interchangeably by different parts of the API
-		if isInCloudformation[s.Region()][s.ID()] {
-			s.IsInCloudformation = true
-		}
+		setCloudformationStack(&s.Resource, cloudformationStacks[s.Region()], s.ID())
 		if dependency[s.Region()][s.ID()] ||
 			dependency[s.Region()][reapable.ID(*s.GroupName)] {
 			s.Dependency = true
 		}
-		if config.SecurityGroups.Enabled {
+		if config.SecurityGroups.enabledIn(s.Region()) {
 			resources = append(resources, s)
 		}
 	}
@@ -551,27 +1067,156 @@ func allReapables() []reaperevents.Reapable {
 		// names and IDs are used interchangeably by different parts of the API
 
 		// sort of doesn't make sense for volume
-		if isInCloudformation[v.Region()][v.ID()] {
-			v.IsInCloudformation = true
-		}
+		setCloudformationStack(&v.Resource, cloudformationStacks[v.Region()], v.ID())
 
-		// if it is a dependency or is attached to an instance
-		if dependency[v.Region()][v.ID()] || len(v.AttachedInstanceIDs) > 0 {
+		resolveVolumeAttachments(v, instances[v.Region()])
+		graph.addVolume(v)
+
+		// if it is a dependency or is attached to a running instance
+		if dependency[v.Region()][v.ID()] || v.AttachedToRunningInstance {
 			v.Dependency = true
 		}
-		if config.Volumes.Enabled {
+		if config.Volumes.enabledIn(v.Region()) {
 			resources = append(resources, v)
 		}
 	}
+
+	if config.Images.enabledAnywhere() {
+		skippedImageRegions := make(map[reapable.Region]bool)
+		for i := range getImages() {
+			setCloudformationStack(&i.Resource, cloudformationStacks[i.Region()], i.ID())
+			// if it is a dependency or is used by an instance or launch configuration
+			if dependency[i.Region()][i.ID()] || imagesInUse[i.Region()][i.ID()] {
+				i.Dependency = true
+			}
+			if !config.Images.enabledIn(i.Region()) {
+				continue
+			}
+			// an image may be used by an instance or launch configuration
+			// that wasn't discovered, so none are reaped in the region
+			if discoveryFailedIn(i.Region().String()) {
+				if !skippedImageRegions[i.Region()] {
+					log.Warning("Discovery failed in %s, not reaping its images this cycle", i.Region())
+					skippedImageRegions[i.Region()] = true
+				}
+				continue
+			}
+			resources = append(resources, i)
+		}
+	}
+
+	if config.NetworkInterfaces.enabledAnywhere() {
+		for n := range getNetworkInterfaces() {
+			setCloudformationStack(&n.Resource, cloudformationStacks[n.Region()], n.ID())
+			// attached interfaces are already dependencies
+			if dependency[n.Region()][n.ID()] {
+				n.Dependency = true
+			}
+			if config.NetworkInterfaces.enabledIn(n.Region()) {
+				resources = append(resources, n)
+			}
+		}
+	}
+
+	graph.addNodes(resources)
+	graph.export()
 	return resources
 }
 
-// isWhitelisted returns whether the filterable is tagged
-// with the whitelist tag
+// cloudformationStack is the Cloudformation a resource is in
+type cloudformationStack struct {
+	Name   string
+	Status string
+}
+
+// setCloudformationStack marks a resource found by any of its ids in stacks
+// as in that Cloudformation
+// the stack name from its aws:cloudformation:stack-name tag is kept, as it
+// names the nested stack a resource is directly in
+func setCloudformationStack(r *reaperaws.Resource, stacks map[reapable.ID]cloudformationStack, ids ...reapable.ID) {
+	for _, id := range ids {
+		if stack, ok := stacks[id]; ok {
+			r.IsInCloudformation = true
+			if r.CloudformationStackName == "" {
+				r.CloudformationStackName = stack.Name
+			}
+			r.CloudformationStackStatus = stack.Status
+			return
+		}
+	}
+}
+
+// settled returns whether a Reapable is in a state it can be reaped from
+// only running and stopped instances are, and nothing in a Cloudformation
+// that is being deleted, as reaping it would race the deletion
+func settled(r reapable.Reapable) bool {
+	if i, ok := r.(*reaperaws.Instance); ok && i.State != nil && (i.Terminated() || i.Transitioning()) {
+		return false
+	}
+	if c, ok := r.(*reaperaws.Cloudformation); ok && aws.StringValue(c.StackStatus) == cloudformation.StackStatusDeleteInProgress {
+		return false
+	}
+	if resource, ok := awsResource(r); ok && resource.CloudformationStackStatus == cloudformation.StackStatusDeleteInProgress {
+		return false
+	}
+	return true
+}
+
+// orphanedByCloudformation returns whether a Reapable is in a Cloudformation
+// that rolled back, which leaves its remaining resources unused
+func orphanedByCloudformation(r reapable.Reapable) bool {
+	resource, ok := awsResource(r)
+	return ok && resource.CloudformationStackStatus == cloudformation.StackStatusRollbackComplete
+}
+
+// isWhitelisted returns whether the filterable is tagged with the whitelist
+// tag or a tag of WhitelistPolicyFile, or is an instance in a whitelisted ASG
 func isWhitelisted(filterable filters.Filterable) bool {
+	if i, ok := filterable.(*reaperaws.Instance); ok && i.InWhitelistedAutoScalingGroup {
+		return true
+	}
+	for _, rule := range config.whitelistRules {
+		if rule.matches(filterable) {
+			return true
+		}
+	}
+	return taggedWhitelist(filterable)
+}
+
+// taggedWhitelist returns whether the filterable itself is tagged with the
+// whitelist tag, which is what Whitelist sets
+func taggedWhitelist(filterable filters.Filterable) bool {
 	return filterable.Filter(*filters.NewFilter("Tagged", []string{config.WhitelistTag}))
 }
 
+// resolveVolumeAttachments sets the VPCs, subnets and whether any are running
+// of the instances a Volume is attached to
+// instances that weren't found are assumed to be running
+func resolveVolumeAttachments(v *reaperaws.Volume, instances map[reapable.ID]*reaperaws.Instance) {
+	v.AttachedToRunningInstance = false
+	for _, instanceID := range v.AttachedInstanceIDs {
+		i, ok := instances[reapable.ID(instanceID)]
+		if !ok {
+			v.AttachedToRunningInstance = true
+			continue
+		}
+		if i.VpcId != nil {
+			v.AttachedVpcIDs = append(v.AttachedVpcIDs, *i.VpcId)
+		}
+		if i.SubnetId != nil {
+			v.AttachedSubnetIDs = append(v.AttachedSubnetIDs, *i.SubnetId)
+		}
+		// as are instances without a state
+		var instanceState string
+		if i.State != nil {
+			instanceState = aws.StringValue(i.State.Name)
+		}
+		if instanceState != "stopped" && instanceState != "terminated" {
+			v.AttachedToRunningInstance = true
+		}
+	}
+}
+
 // matchesFilters applies the relevant filter groups to a filterable
 func matchesFilters(filterable filters.Filterable) bool {
 	// recover from potential panics caused by malformed filters
@@ -581,28 +1226,63 @@ func matchesFilters(filterable filters.Filterable) bool {
 		}
 	}()
 
-	var groups map[string]filters.FilterGroup
+	resourceConfig, resourceType, ok := resourceConfigFor(filterable)
+	if !ok {
+		log.Warning("You probably screwed up and need to make sure matchesFilters works!")
+		return false
+	}
+
+	matched, matchedGroups, errs := evaluateFilters(filterable, resourceConfig)
+	for name, err := range errs {
+		reportFilterError(resourceType, name, err)
+	}
+	for name, group := range matchedGroups {
+		filterable.AddFilterGroup(name, group)
+	}
+	return matched
+}
+
+// resourceConfigFor returns the ResourceConfig and statistics name of a filterable's type
+func resourceConfigFor(filterable filters.Filterable) (ResourceConfig, string, bool) {
 	switch filterable.(type) {
 	case *reaperaws.Instance:
-		groups = config.Instances.FilterGroups
+		return config.Instances, "instances", true
 	case *reaperaws.AutoScalingGroup:
-		groups = config.AutoScalingGroups.FilterGroups
+		return config.AutoScalingGroups, "asgs", true
 	case *reaperaws.Cloudformation:
-		groups = config.Cloudformations.FilterGroups
+		return config.Cloudformations, "cloudformations", true
 	case *reaperaws.SecurityGroup:
-		groups = config.SecurityGroups.FilterGroups
+		return config.SecurityGroups, "securitygroups", true
 	case *reaperaws.Volume:
-		groups = config.Volumes.FilterGroups
+		return config.Volumes, "volumes", true
+	case *reaperaws.Image:
+		return config.Images.ResourceConfig, "images", true
+	case *reaperaws.NetworkInterface:
+		return config.NetworkInterfaces, "networkinterfaces", true
 	default:
-		log.Warning("You probably screwed up and need to make sure matchesFilters works!")
-		return false
+		return ResourceConfig{}, "", false
 	}
+}
 
-	matched := false
+// evaluateFilters returns whether filterable matches resourceConfig's filters,
+// the FilterGroups it matched by name, and the errors of FilterGroups
+// that could not be evaluated by name, including filters missing their
+// arguments, which ApplyFilters recovers from
+// it has no side effects, see matchesFilters
+func evaluateFilters(filterable filters.Filterable, resourceConfig ResourceConfig) (
+	matched bool, matchedGroups map[string]filters.FilterGroup, errs map[string]error) {
+	matchedGroups = make(map[string]filters.FilterGroup)
+	errs = make(map[string]error)
+	groups := resourceConfig.FilterGroups
+
+	// regardless of filters, young resources are never matched
+	if tooYoung(filterable, time.Now()) {
+		return false, matchedGroups, errs
+	}
 
 	// if there are no filters groups defined default to not match
 	if len(groups) == 0 {
-		return false
+		return false, matchedGroups, errs
 	}
 
 	shouldFilter := false
@@ -614,28 +1294,115 @@ func matchesFilters(filterable filters.Filterable) bool {
 	}
 	// no filters, default to not match
 	if !shouldFilter {
-		return false
+		return false, matchedGroups, errs
 	}
 
+	matchedNames := make(map[string]bool)
 	for name, group := range groups {
-		didMatch := filters.ApplyFilters(filterable, group)
+		didMatch, err := filters.ApplyFilters(filterable, group)
+		if err != nil {
+			errs[name] = err
+		}
 		if didMatch {
 			matched = true
-			filterable.AddFilterGroup(name, group)
+			matchedNames[name] = true
+			matchedGroups[name] = group
 		}
 	}
 
+	// the FilterExpression, if any, replaces matching any FilterGroup
+	// one that didn't parse matches nothing, rather than any FilterGroup
+	if resourceConfig.filterExpression != nil {
+		matched = resourceConfig.filterExpression.Eval(matchedNames)
+	} else if resourceConfig.FilterExpression != "" {
+		matched = false
+	}
+
 	// convenient
 	if isWhitelisted(filterable) {
 		matched = false
 	}
 
-	return matched
+	// listed resources are excluded even if their tags are changed
+	if neverReaped(filterable) {
+		matched = false
+	}
+
+	return matched, matchedGroups, errs
+}
+
+// tooYoung returns whether filterable was created less than MinimumResourceAge before now
+// resources that don't know when they were created are never too young
+func tooYoung(filterable filters.Filterable, now time.Time) bool {
+	if config.MinimumResourceAge.Duration <= 0 {
+		return false
+	}
+	aged, ok := filterable.(reapable.Aged)
+	if !ok {
+		return false
+	}
+	created, ok := aged.CreatedAt()
+	if !ok {
+		return false
+	}
+	return now.Sub(created) < config.MinimumResourceAge.Duration
+}
+
+// reportMissingPrice emits reaper.filters.nopriceskipped for a resource
+// whose cost isn't known, if its FilterGroups filter on its cost
+// reap calls it once per resource each cycle, unlike matchesFilters
+func reportMissingPrice(filterable filters.Filterable) {
+	costed, ok := filterable.(reapable.Costed)
+	if !ok {
+		return
+	}
+	resourceConfig, resourceType, ok := resourceConfigFor(filterable)
+	if !ok || !filtersOnCost(resourceConfig) {
+		return
+	}
+	if _, ok := costed.EstimatedHourlyCost(); ok {
+		return
+	}
+	if err := newCountStatistic("reaper.filters.nopriceskipped", []string{"type:" + resourceType, config.EventTag}); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+// filtersOnCost returns whether any of resourceConfig's FilterGroups
+// uses EstimatedMonthlyCostGreaterThan
+func filtersOnCost(resourceConfig ResourceConfig) bool {
+	for _, group := range resourceConfig.FilterGroups {
+		for _, filter := range group {
+			if filter.Function == "EstimatedMonthlyCostGreaterThan" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportFilterError logs a filter that could not be evaluated, once per
+// resource type, FilterGroup and error, and emits reaper.filters.errors
+func reportFilterError(resourceType, group string, err error) {
+	if err := newCountStatistic("reaper.filters.errors", []string{"type:" + resourceType, "group:" + group, config.EventTag}); err != nil {
+		log.Error(err.Error())
+	}
+
+	key := fmt.Sprintf("%s|%s|%s", resourceType, group, err.Error())
+	reportedFilterErrorsMutex.Lock()
+	defer reportedFilterErrorsMutex.Unlock()
+	if reportedFilterErrors[key] {
+		return
+	}
+	reportedFilterErrors[key] = true
+	log.Error("FilterGroup %s for %s could not be evaluated: %s", group, resourceType, err.Error())
 }
 
 func registerReapable(a reaperevents.Reapable) {
 	// update the internal state
-	if time.Now().After(a.ReaperState().Until) {
+	if expired(a, now()) {
+		expire(a)
+	} else if time.Now().After(a.ReaperState().Until) {
 		// if we updated the state, mark it as having been updated
 		a.SetUpdated(a.IncrementState())
 	}
@@ -643,13 +1410,189 @@ func registerReapable(a reaperevents.Reapable) {
 	reapables.Put(a.Region(), a.ID(), a)
 }
 
+// awsResource returns the aws.Resource shared by every AWS Reapable
+func awsResource(r reapable.Reapable) (*reaperaws.Resource, bool) {
+	switch t := r.(type) {
+	case *reaperaws.AutoScalingGroup:
+		return &t.Resource, true
+	case *reaperaws.Cloudformation:
+		return &t.Resource, true
+	case *reaperaws.Image:
+		return &t.Resource, true
+	case *reaperaws.Instance:
+		return &t.Resource, true
+	case *reaperaws.NetworkInterface:
+		return &t.Resource, true
+	case *reaperaws.SecurityGroup:
+		return &t.Resource, true
+	case *reaperaws.Volume:
+		return &t.Resource, true
+	}
+	return nil, false
+}
+
+// reapableType returns the name used for a Reapable in statistics
+func reapableType(r reapable.Reapable) string {
+	switch r.(type) {
+	case *reaperaws.Instance:
+		return "instances"
+	case *reaperaws.AutoScalingGroup:
+		return "asgs"
+	case *reaperaws.Cloudformation:
+		return "cloudformations"
+	case *reaperaws.SecurityGroup:
+		return "securitygroups"
+	case *reaperaws.Volume:
+		return "volumes"
+	case *reaperaws.Image:
+		return "images"
+	case *reaperaws.NetworkInterface:
+		return "networkinterfaces"
+	default:
+		return "reapables"
+	}
+}
+
+// reapableStatisticTags returns region and owner tags for a Reapable's statistics
+func reapableStatisticTags(r reapable.Reapable) []string {
+	tags := []string{fmt.Sprintf("region:%s", r.Region())}
+	if owner := r.Owner(); owner != nil {
+		tags = append(tags, fmt.Sprintf("owner:%s", owner.Address))
+	}
+	return append(tags, config.EventTag)
+}
+
+// typeDryRun returns the DryRun override of a Reapable's type,
+// and false if its type doesn't override DryRun
+func typeDryRun(r reapable.Reapable) (dryRun bool, overridden bool) {
+	if c := resourceConfig(reapableType(r)); c.DryRun != nil {
+		return *c.DryRun, true
+	}
+	return false, false
+}
+
+// dryRun returns whether actions on a Reapable are in DryRun mode,
+// either because its type is or because the global DryRun is
+func dryRun(r reapable.Reapable) bool {
+	if d, ok := typeDryRun(r); ok {
+		return d
+	}
+	return config.DryRun
+}
+
+// terminate calls a Reapable's own Terminate method
+// and reports a statistic for the termination, limited by MaxConcurrentActions
+// failures are recorded in deadLetters
+// in DryRun mode, the Reapable is not terminated
+func terminate(r reapable.Reapable) (bool, error) {
+	if dryRun(r) {
+		log.Info("DryRun: Not terminating %s", r.ReapableDescriptionTiny())
+		err := newCountStatistic(fmt.Sprintf("reaper.%s.wouldterminate", reapableType(r)), reapableStatisticTags(r))
+		if err != nil {
+			log.Error(err.Error())
+		}
+		return true, nil
+	}
+
+	var ok bool
+	var err error
+	resourceType := reapableType(r)
+	actions.run(resourceType, maxConcurrentActions(resourceType), func() {
+		ok, err = r.Terminate()
+	})
+	err = actionError(r, "terminate", err)
+	recordAction(r, "terminate", err)
+	if err != nil {
+		return ok, err
+	}
+	err = newCountStatistic(fmt.Sprintf("reaper.%s.terminated", reapableType(r)), reapableStatisticTags(r))
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return ok, nil
+}
+
+// stop calls a Reapable's own Stop method, limited by MaxConcurrentActions
+// in DryRun mode, the Reapable is not stopped
+func stop(r reapable.Reapable) (ok bool, err error) {
+	if dryRun(r) {
+		log.Info("DryRun: Not stopping %s", r.ReapableDescriptionTiny())
+		return true, nil
+	}
+	resourceType := reapableType(r)
+	actions.run(resourceType, maxConcurrentActions(resourceType), func() {
+		ok, err = r.Stop()
+	})
+	err = actionError(r, "stop", err)
+	recordAction(r, "stop", err)
+	return ok, err
+}
+
+// forceStop calls a Reapable's own ForceStop method, limited by MaxConcurrentActions
+// in DryRun mode, the Reapable is not stopped
+func forceStop(r reapable.Reapable) (ok bool, err error) {
+	forceStopper, isForceStopper := r.(reapable.ForceStopper)
+	if !isForceStopper {
+		return false, fmt.Errorf("%s cannot be force stopped", r.ReapableDescriptionTiny())
+	}
+	if dryRun(r) {
+		log.Info("DryRun: Not force stopping %s", r.ReapableDescriptionTiny())
+		return true, nil
+	}
+	resourceType := reapableType(r)
+	actions.run(resourceType, maxConcurrentActions(resourceType), func() {
+		ok, err = forceStopper.ForceStop()
+	})
+	err = actionError(r, "force-stop", err)
+	recordAction(r, "force-stop", err)
+	return ok, err
+}
+
+// autoTerminate terminates a Reapable that has reached the FinalState, if
+// AutoTerminate is enabled. Whitelisted resources, those in NeverReapIDs,
+// dependencies, and resources in Cloudformation stacks are never
+// auto-terminated, except for those left by a stack that rolled back
+// a Reapable that only just reached the FinalState, or whose owner wasn't
+// notified of it yet, such as because the notice was deferred during quiet
+// hours or failed to send, is left until a later cycle, so that its owner
+// is notified of the FinalState first
+func autoTerminate(r reapable.Reapable) bool {
+	s := r.ReaperState()
+	if !config.AutoTerminate || s.State != state.FinalState {
+		return false
+	}
+	if s.Updated || deferredNotice(r) || !notifiedOf(r) {
+		log.Info("AutoTerminate: not terminating %s until its owner is notified of %s", r.ReapableDescriptionTiny(), s.State.String())
+		return false
+	}
+
+	if isWhitelisted(r) || neverReaped(r) ||
+		r.Filter(*filters.NewFilter("IsDependency", []string{"true"})) ||
+		(r.Filter(*filters.NewFilter("InCloudformation", []string{"true"})) && !orphanedByCloudformation(r)) {
+		log.Info("AutoTerminate: not terminating protected resource %s", r.ReapableDescriptionTiny())
+		return false
+	}
+
+	log.Warning("AutoTerminate: terminating %s", r.ReapableDescription())
+	err := newCountStatistic(fmt.Sprintf("reaper.%s.autoterminate", reapableType(r)), reapableStatisticTags(r))
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	if _, err := terminate(r); err != nil {
+		log.Error("AutoTerminate: could not terminate %s: %s", r.ReapableDescriptionTiny(), err.Error())
+		return false
+	}
+	return true
+}
+
 // Terminate by region, id, calls a Reapable's own Terminate method
 func Terminate(region reapable.Region, id reapable.ID) error {
 	reapable, err := reapables.Get(region, id)
 	if err != nil {
-		return err
+		return ErrReapableNotFound{Region: region, ID: id}
 	}
-	_, err = reapable.Terminate()
+	_, err = terminate(reapable)
 	if err != nil {
 		log.Error(fmt.Sprintf("Could not terminate resource with region: %s and id: %s. Error: %s",
 			region, id, err.Error()))
@@ -664,9 +1607,9 @@ func Terminate(region reapable.Region, id reapable.ID) error {
 func Stop(region reapable.Region, id reapable.ID) error {
 	reapable, err := reapables.Get(region, id)
 	if err != nil {
-		return err
+		return ErrReapableNotFound{Region: region, ID: id}
 	}
-	_, err = reapable.Stop()
+	_, err = stop(reapable)
 	if err != nil {
 		log.Error(fmt.Sprintf("Could not stop resource with region: %s and id: %s. Error: %s",
 			region, id, err.Error()))
@@ -676,3 +1619,20 @@ func Stop(region reapable.Region, id reapable.ID) error {
 
 	return nil
 }
+
+// ForceStop by region, id, calls a Reapable's own ForceStop method
+func ForceStop(region reapable.Region, id reapable.ID) error {
+	reapable, err := reapables.Get(region, id)
+	if err != nil {
+		return ErrReapableNotFound{Region: region, ID: id}
+	}
+	_, err = forceStop(reapable)
+	if err != nil {
+		log.Error(fmt.Sprintf("Could not force stop resource with region: %s and id: %s. Error: %s",
+			region, id, err.Error()))
+		return err
+	}
+	log.Debug("ForceStop %s", reapable.ReapableDescriptionShort())
+
+	return nil
+}
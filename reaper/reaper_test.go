@@ -0,0 +1,1075 @@
+package reaper
+
+import (
+	"bytes"
+	"errors"
+	"net/mail"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/filters"
+	"github.com/mozilla-services/reaper/prices"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+	"github.com/mozilla-services/reaper/state"
+)
+
+// testReapable is a reaperevents.Reapable that doesn't talk to AWS
+type testReapable struct {
+	region reapable.Region
+	id     reapable.ID
+	owner  *mail.Address
+	state  *state.State
+
+	terminateErr error
+	terminated   int
+	stopped      int
+	forceStopped int
+	whitelisted  int
+
+	// filter functions that match
+	filters map[string]bool
+}
+
+func newTestReapable(region, id, owner string) *testReapable {
+	r := &testReapable{
+		region: reapable.Region(region),
+		id:     reapable.ID(id),
+		state:  state.NewState(),
+	}
+	if owner != "" {
+		r.owner = &mail.Address{Address: owner}
+	}
+	return r
+}
+
+func (r *testReapable) Filter(f filters.Filter) bool               { return r.filters[f.Function] }
+func (r *testReapable) AddFilterGroup(string, filters.FilterGroup) {}
+func (r *testReapable) Whitelist() (bool, error)                   { r.whitelisted++; return true, nil }
+func (r *testReapable) Save(s *state.State) (bool, error)          { r.state = s; return true, nil }
+func (r *testReapable) Unsave() (bool, error)                      { return true, nil }
+func (r *testReapable) ReaperState() *state.State                  { return r.state }
+func (r *testReapable) IncrementState() bool                       { return false }
+func (r *testReapable) SetUpdated(b bool)                          { r.state.Updated = b }
+func (r *testReapable) SetReaperState(s *state.State)              { r.state = s }
+func (r *testReapable) Owner() *mail.Address                       { return r.owner }
+func (r *testReapable) ID() reapable.ID                            { return r.id }
+func (r *testReapable) Region() reapable.Region                    { return r.region }
+func (r *testReapable) ReapableDescription() string                { return r.id.String() }
+func (r *testReapable) ReapableDescriptionShort() string           { return r.id.String() }
+func (r *testReapable) ReapableDescriptionTiny() string            { return r.id.String() }
+
+func (r *testReapable) ReapableEventText() (*bytes.Buffer, error) {
+	return bytes.NewBufferString(r.id.String()), nil
+}
+func (r *testReapable) ReapableEventTextShort() (*bytes.Buffer, error) {
+	return bytes.NewBufferString(r.id.String()), nil
+}
+func (r *testReapable) ReapableEventEmail() (mail.Address, string, *bytes.Buffer, error) {
+	return *r.owner, r.id.String(), bytes.NewBufferString(r.id.String()), nil
+}
+func (r *testReapable) ReapableEventEmailShort() (mail.Address, *bytes.Buffer, error) {
+	return *r.owner, bytes.NewBufferString(r.id.String()), nil
+}
+
+func (r *testReapable) Terminate() (bool, error) {
+	if r.terminateErr != nil {
+		return false, r.terminateErr
+	}
+	r.terminated++
+	return true, nil
+}
+
+func (r *testReapable) Stop() (bool, error) {
+	r.stopped++
+	return true, nil
+}
+
+func (r *testReapable) ForceStop() (bool, error) {
+	r.forceStopped++
+	return true, nil
+}
+
+// recordCountStatistics replaces newCountStatistic until restore is called
+func recordCountStatistics() (recorded map[string][][]string, restore func()) {
+	recorded = make(map[string][][]string)
+	var mutex sync.Mutex
+	original := newCountStatistic
+	newCountStatistic = func(name string, tags []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		recorded[name] = append(recorded[name], tags)
+		return nil
+	}
+	return recorded, func() { newCountStatistic = original }
+}
+
+// recordStatistics replaces newStatistic until restore is called
+func recordStatistics() (recorded map[string][][]string, restore func()) {
+	recorded = make(map[string][][]string)
+	var mutex sync.Mutex
+	original := newStatistic
+	newStatistic = func(name string, value float64, tags []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		recorded[name] = append(recorded[name], tags)
+		return nil
+	}
+	return recorded, func() { newStatistic = original }
+}
+
+// setTestConfig replaces config until restore is called
+func setTestConfig(c *Config) (restore func()) {
+	original := config
+	config = c
+	return func() { config = original }
+}
+
+func TestTerminateStatistics(t *testing.T) {
+	defer setTestConfig(&Config{EventTag: "env:test"})()
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "owner@example.com")
+	for i := 0; i < 2; i++ {
+		if _, err := terminate(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if r.terminated != 2 {
+		t.Errorf("expected 2 terminations, got %d", r.terminated)
+	}
+	stats := recorded["reaper.reapables.terminated"]
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 terminated statistics, got %d", len(stats))
+	}
+	expected := []string{"region:us-west-2", "owner:owner@example.com", "env:test"}
+	for i, tag := range expected {
+		if stats[0][i] != tag {
+			t.Errorf("expected tag %s, got %s", tag, stats[0][i])
+		}
+	}
+
+	failing := newTestReapable("us-west-2", "i-2", "")
+	failing.terminateErr = errors.New("denied")
+	if _, err := terminate(failing); err == nil {
+		t.Error("expected an error from a failed termination")
+	}
+	if len(recorded["reaper.reapables.terminated"]) != 2 {
+		t.Error("expected no statistic for a failed termination")
+	}
+}
+
+func TestTerminateDryRunStatistics(t *testing.T) {
+	defer setTestConfig(&Config{DryRun: true})()
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	r := newTestReapable("us-west-2", "i-1", "")
+	if _, err := terminate(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.terminated != 0 {
+		t.Error("expected no termination in DryRun mode")
+	}
+	if len(recorded["reaper.reapables.terminated"]) != 0 {
+		t.Error("expected no terminated statistic in DryRun mode")
+	}
+	if len(recorded["reaper.reapables.wouldterminate"]) != 1 {
+		t.Error("expected a wouldterminate statistic in DryRun mode")
+	}
+}
+
+func TestTypeDryRun(t *testing.T) {
+	// new volumes' states depend on the aws package's config
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+	c := &Config{}
+	c.Volumes.DryRun = aws.Bool(true)
+	defer setTestConfig(c)()
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	instance := reaperaws.NewInstance("us-west-2", &ec2.Instance{InstanceId: aws.String("i-1")})
+	volume := reaperaws.NewVolume("us-west-2", &ec2.Volume{VolumeId: aws.String("vol-1")})
+	if dryRun(instance) {
+		t.Error("expected instances to follow the global DryRun")
+	}
+	if !dryRun(volume) {
+		t.Error("expected volumes to be in DryRun mode")
+	}
+	// a dry run volume is not terminated, so this doesn't talk to AWS
+	if _, err := terminate(volume); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded["reaper.volumes.wouldterminate"]) != 1 {
+		t.Error("expected a wouldterminate statistic for the volume")
+	}
+
+	// a type can also opt out of the global DryRun
+	c.DryRun = true
+	c.Volumes.DryRun = nil
+	c.Instances.DryRun = aws.Bool(false)
+	if dryRun(instance) {
+		t.Error("expected instances to override the global DryRun")
+	}
+	if !dryRun(volume) {
+		t.Error("expected volumes to follow the global DryRun")
+	}
+}
+
+func TestTerminateByRegionAndID(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	recorded, restore := recordCountStatistics()
+	defer restore()
+	reapables.Reset([]string{"us-west-2"})
+
+	r := newTestReapable("us-west-2", "i-1", "")
+	reapables.Put(r.Region(), r.ID(), r)
+
+	if err := Terminate("us-west-2", "i-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded["reaper.reapables.terminated"]) != 1 {
+		t.Error("expected a terminated statistic")
+	}
+	if err := Terminate("us-west-2", "i-404"); err == nil {
+		t.Error("expected an error for an unknown reapable")
+	}
+}
+
+func TestParseFilterExpression(t *testing.T) {
+	c := ResourceConfig{
+		FilterGroups: map[string]filters.FilterGroup{
+			"Old":     filters.FilterGroup{},
+			"Spared":  filters.FilterGroup{},
+			"Stopped": filters.FilterGroup{},
+		},
+	}
+
+	c.FilterExpression = "Old AND NOT Spared"
+	if err := c.parseFilterExpression(); err != nil {
+		t.Errorf("unexpected error %s", err.Error())
+	}
+	if c.filterExpression == nil {
+		t.Error("expected filterExpression to be set")
+	}
+
+	c.FilterExpression = "Old AND NOT Missing"
+	if err := c.parseFilterExpression(); err == nil {
+		t.Error("expected an error for an unknown FilterGroup")
+	}
+}
+
+func TestMatchesFilterExpression(t *testing.T) {
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+
+	newConfig := func(expression string) *Config {
+		return &Config{Instances: ResourceConfig{
+			FilterGroups: map[string]filters.FilterGroup{
+				"Owned":  filters.FilterGroup{"1": *filters.NewFilter("Tagged", []string{"Owner"})},
+				"Spared": filters.FilterGroup{"1": *filters.NewFilter("Tagged", []string{"Spare"})},
+			},
+			FilterExpression: expression,
+		}}
+	}
+	newInstance := func(id string, tags ...string) *reaperaws.Instance {
+		i := &ec2.Instance{InstanceId: aws.String(id)}
+		for _, tag := range tags {
+			i.Tags = append(i.Tags, &ec2.Tag{Key: aws.String(tag), Value: aws.String("true")})
+		}
+		return reaperaws.NewInstance("us-west-2", i)
+	}
+
+	c := newConfig("Owned AND NOT Spared")
+	if err := c.Instances.parseFilterExpression(); err != nil {
+		t.Fatal(err)
+	}
+	defer setTestConfig(c)()
+	if !matchesFilters(newInstance("i-match", "Owner")) {
+		t.Error("expected an instance matching Owned AND NOT Spared to match")
+	}
+	if matchesFilters(newInstance("i-spared", "Owner", "Spare")) {
+		t.Error("expected an instance matching Spared not to match Owned AND NOT Spared")
+	}
+
+	// an expression that doesn't parse matches nothing, not any FilterGroup
+	c = newConfig("Owned AND")
+	if err := c.Instances.parseFilterExpression(); err == nil {
+		t.Error("expected an error for an incomplete FilterExpression")
+	}
+	defer setTestConfig(c)()
+	if matchesFilters(newInstance("i-match", "Owner")) {
+		t.Error("expected an unparsed FilterExpression not to match")
+	}
+}
+
+func TestReportFilterError(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	err := errors.New("could not parse three as int64")
+	reportFilterError("asgs", "Large", err)
+	reportFilterError("asgs", "Large", err)
+
+	if len(recorded["reaper.filters.errors"]) != 2 {
+		t.Errorf("expected 2 reaper.filters.errors statistics, got %d", len(recorded["reaper.filters.errors"]))
+	}
+	if !reportedFilterErrors["asgs|Large|"+err.Error()] {
+		t.Error("expected the filter error to be recorded as reported")
+	}
+}
+
+func TestAutoTerminate(t *testing.T) {
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	final := func(matching ...string) *testReapable {
+		r := newTestReapable("us-west-2", "i-final", "")
+		r.state = state.NewStateWithUntilAndState(time.Now(), state.FinalState)
+		r.state.NotifiedForCurrentState = true
+		r.filters = make(map[string]bool)
+		for _, f := range matching {
+			r.filters[f] = true
+		}
+		return r
+	}
+	// as registerReapable leaves a resource whose state advanced this cycle
+	justFinal := func() *testReapable {
+		r := final()
+		r.SetUpdated(true)
+		return r
+	}
+	// as a resource whose owner wasn't notified of the final state
+	unnotified := func() *testReapable {
+		r := final()
+		r.state.NotifiedForCurrentState = false
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		config     Config
+		reapable   *testReapable
+		terminated int
+	}{
+		{"disabled", Config{}, final(), 0},
+		{"final state", Config{AutoTerminate: true}, final(), 1},
+		{"first state", Config{AutoTerminate: true}, newTestReapable("us-west-2", "i-first", ""), 0},
+		{"just reached the final state", Config{AutoTerminate: true}, justFinal(), 0},
+		{"owner not notified", Config{AutoTerminate: true}, unnotified(), 0},
+		{"whitelisted", Config{AutoTerminate: true}, final("Tagged"), 0},
+		{"never reaped", Config{AutoTerminate: true, NeverReapIDs: []string{"us-west-2/i-final"}}, final(), 0},
+		{"dependency", Config{AutoTerminate: true}, final("IsDependency"), 0},
+		{"in cloudformation", Config{AutoTerminate: true}, final("InCloudformation"), 0},
+		{"dry run", Config{AutoTerminate: true, DryRun: true}, final(), 0},
+	}
+
+	for _, test := range tests {
+		c := test.config
+		restoreConfig := setTestConfig(&c)
+		autoTerminate(test.reapable)
+		restoreConfig()
+
+		if test.reapable.terminated != test.terminated {
+			t.Errorf("%s: expected %d terminations, got %d", test.name, test.terminated, test.reapable.terminated)
+		}
+	}
+
+	if len(recorded["reaper.reapables.autoterminate"]) != 2 {
+		t.Errorf("expected 2 reaper.reapables.autoterminate statistics, got %d", len(recorded["reaper.reapables.autoterminate"]))
+	}
+	if len(recorded["reaper.reapables.wouldterminate"]) != 1 {
+		t.Errorf("expected a dry run reaper.reapables.wouldterminate statistic, got %d", len(recorded["reaper.reapables.wouldterminate"]))
+	}
+}
+
+func TestCloudformationStackStatus(t *testing.T) {
+	recorded, restore := recordCountStatistics()
+	defer restore()
+	defer setTestConfig(&Config{AutoTerminate: true, DryRun: true})()
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+
+	stacks := map[reapable.ID]cloudformationStack{
+		"i-deleting": {Name: "deleting", Status: cloudformation.StackStatusDeleteInProgress},
+		"i-orphan":   {Name: "orphan", Status: cloudformation.StackStatusRollbackComplete},
+		"i-managed":  {Name: "managed", Status: cloudformation.StackStatusCreateComplete},
+	}
+	newInstance := func(id string) *reaperaws.Instance {
+		i := reaperaws.NewInstance("us-west-2", &ec2.Instance{
+			InstanceId: aws.String(id),
+			State:      &ec2.InstanceState{Code: aws.Int64(16), Name: aws.String("running")},
+		})
+		setCloudformationStack(&i.Resource, stacks, i.ID())
+		s := state.NewStateWithUntilAndState(time.Now(), state.FinalState)
+		s.NotifiedForCurrentState = true
+		i.SetReaperState(s)
+		return i
+	}
+	deleting := newInstance("i-deleting")
+	orphan := newInstance("i-orphan")
+	managed := newInstance("i-managed")
+	// allReapables makes the resources of stacks dependencies,
+	// unless the stack rolled back
+	managed.Dependency = true
+
+	if settled(deleting) {
+		t.Error("expected an instance in a stack being deleted not to be settled")
+	}
+	if !settled(orphan) || !settled(managed) {
+		t.Error("expected instances in other stacks to be settled")
+	}
+	stack := &reaperaws.Cloudformation{}
+	stack.StackStatus = aws.String(cloudformation.StackStatusDeleteInProgress)
+	if settled(stack) {
+		t.Error("expected a stack being deleted not to be settled")
+	}
+
+	if !autoTerminate(orphan) {
+		t.Error("expected an instance left by a stack that rolled back to be auto-terminated")
+	}
+	if autoTerminate(managed) {
+		t.Error("expected an instance in a stack not to be auto-terminated")
+	}
+	if len(recorded["reaper.instances.wouldterminate"]) != 1 {
+		t.Errorf("expected a dry run reaper.instances.wouldterminate statistic, got %v", recorded)
+	}
+}
+
+func TestInstanceHourlyCost(t *testing.T) {
+	defer func(p, s prices.PricesMap) { pricesMap, spotPricesMap = p, s }(pricesMap, spotPricesMap)
+	pricesMap = prices.PricesMap{"us-west-2": {"m4.large": "0.1"}}
+	spotPricesMap = prices.PricesMap{"us-west-2": {"m4.large": "0.03"}}
+
+	newInstance := func(id string, spot bool) *reaperaws.Instance {
+		instance := &ec2.Instance{
+			InstanceId:   aws.String(id),
+			InstanceType: aws.String("m4.large"),
+			State:        &ec2.InstanceState{Code: aws.Int64(16), Name: aws.String("running")},
+		}
+		if spot {
+			instance.SpotInstanceRequestId = aws.String("sir-" + id)
+		}
+		return reaperaws.NewInstance("us-west-2", instance)
+	}
+
+	if cost, ok := instanceHourlyCost(newInstance("i-ondemand", false)); !ok || cost != 0.1 {
+		t.Errorf("expected the on-demand price 0.1, got %f", cost)
+	}
+	if cost, ok := instanceHourlyCost(newInstance("i-spot", true)); !ok || cost != 0.03 {
+		t.Errorf("expected the spot price 0.03, got %f", cost)
+	}
+
+	spotPricesMap = nil
+	if _, ok := instanceHourlyCost(newInstance("i-spot", true)); ok {
+		t.Error("expected no price for a spot instance without spot prices")
+	}
+}
+
+func TestGetPricesRetriedUntilDownloaded(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	defer func(p, s prices.PricesMap) { pricesMap, spotPricesMap = p, s }(pricesMap, spotPricesMap)
+	defer func(d func(string) (prices.PricesMap, error), s func([]string) prices.PricesMap) {
+		downloadPrices, spotPrices = d, s
+	}(downloadPrices, spotPrices)
+	setDownloaded := func(downloaded bool) {
+		health.Lock()
+		defer health.Unlock()
+		health.pricesDownloaded = downloaded
+	}
+	defer setDownloaded(false)
+	setDownloaded(false)
+	pricesMap, spotPricesMap = nil, nil
+
+	downloads := 0
+	var downloadErr error
+	downloadPrices = func(url string) (prices.PricesMap, error) {
+		downloads++
+		if downloadErr != nil {
+			return nil, downloadErr
+		}
+		return prices.PricesMap{"us-west-2": {"m4.large": "0.1"}}, nil
+	}
+	spotPrices = func(regions []string) prices.PricesMap {
+		return prices.PricesMap{"us-west-2": {"m4.large": "0.03"}}
+	}
+
+	// spot prices don't depend on the download
+	downloadErr = errors.New("download failed")
+	GetPrices()
+	if spotPricesMap == nil {
+		t.Error("expected spot prices even though the download failed")
+	}
+	if health.pricesDownloaded {
+		t.Error("expected prices not to be downloaded")
+	}
+
+	// a failed download is retried on the next reap
+	downloadErr = nil
+	retryPrices()
+	if downloads != 2 {
+		t.Errorf("expected the download to be retried, got %d downloads", downloads)
+	}
+	if pricesMap == nil || !health.pricesDownloaded {
+		t.Error("expected prices to be downloaded on retry")
+	}
+
+	// and not once it has succeeded
+	retryPrices()
+	if downloads != 2 {
+		t.Errorf("expected no more downloads once prices were downloaded, got %d", downloads)
+	}
+}
+
+func TestEstimatedMonthlyCostGreaterThan(t *testing.T) {
+	// new volumes' states depend on the aws package's config
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+	defer func(p prices.PricesMap) { pricesMap = p }(pricesMap)
+	// 0.1 an hour is 73 a month
+	pricesMap = prices.PricesMap{"us-west-2": {"m4.large": "0.1"}}
+
+	instance := reaperaws.NewInstance("us-west-2", &ec2.Instance{
+		InstanceId:   aws.String("i-1"),
+		InstanceType: aws.String("m4.large"),
+	})
+	cost, ok := instanceHourlyCost(instance)
+	if !ok {
+		t.Fatal("expected a price for m4.large")
+	}
+	instance.HourlyCost = cost
+
+	tests := []struct {
+		threshold string
+		expected  bool
+	}{
+		{"72.5", true},
+		{"73.5", false},
+		{"100", false},
+		{"0", true},
+	}
+	for _, test := range tests {
+		f := filters.NewFilter("EstimatedMonthlyCostGreaterThan", []string{test.threshold})
+		if instance.Filter(*f) != test.expected {
+			t.Errorf("EstimatedMonthlyCostGreaterThan(%s): expected %t for a monthly cost of 73", test.threshold, test.expected)
+		}
+	}
+
+	// a 100GB gp2 volume is 10 a month
+	volume := reaperaws.NewVolume("us-west-2", &ec2.Volume{
+		VolumeId:   aws.String("vol-1"),
+		VolumeType: aws.String("gp2"),
+		Size:       aws.Int64(100),
+	})
+	if cost, ok := volumeHourlyCost(volume); ok {
+		volume.HourlyCost = cost
+	}
+	if !volume.Filter(*filters.NewFilter("EstimatedMonthlyCostGreaterThan", []string{"9.5"})) {
+		t.Error("expected a 100GB gp2 volume to cost more than 9.5 a month")
+	}
+	if volume.Filter(*filters.NewFilter("EstimatedMonthlyCostGreaterThan", []string{"10.5"})) {
+		t.Error("expected a 100GB gp2 volume to cost less than 10.5 a month")
+	}
+}
+
+func TestReportMissingPrice(t *testing.T) {
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+	recorded, restore := recordCountStatistics()
+	defer restore()
+
+	newInstance := func(id string, hourly float64) *reaperaws.Instance {
+		i := reaperaws.NewInstance("us-west-2", &ec2.Instance{InstanceId: aws.String(id)})
+		i.HourlyCost = hourly
+		return i
+	}
+
+	// without a cost filter, missing prices don't matter
+	defer setTestConfig(&Config{EventTag: "env:test"})()
+	reportMissingPrice(newInstance("i-unpriced", 0))
+	if len(recorded["reaper.filters.nopriceskipped"]) != 0 {
+		t.Errorf("expected no statistic without a cost filter, got %v", recorded["reaper.filters.nopriceskipped"])
+	}
+
+	defer setTestConfig(&Config{
+		EventTag: "env:test",
+		Instances: ResourceConfig{FilterGroups: map[string]filters.FilterGroup{
+			"expensive": {"cost": *filters.NewFilter("EstimatedMonthlyCostGreaterThan", []string{"100"})},
+		}},
+	})()
+	reportMissingPrice(newInstance("i-priced", 0.1))
+	reportMissingPrice(newInstance("i-unpriced", 0))
+	tags := recorded["reaper.filters.nopriceskipped"]
+	if len(tags) != 1 || len(tags[0]) != 2 || tags[0][0] != "type:instances" || tags[0][1] != "env:test" {
+		t.Errorf("expected one statistic for the unpriced instance, got %v", tags)
+	}
+}
+
+func TestAutoScalingGroupStatistics(t *testing.T) {
+	defer setTestConfig(&Config{EventTag: "env:test"})()
+	recorded, restore := recordStatistics()
+	defer restore()
+
+	regionSums := map[reapable.Region]int{"us-west-2": 4, "us-east-1": 1}
+	asgSizeSums := map[reapable.Region]map[int64]int{
+		"us-west-2": {0: 1, 1: 2, 3: 1},
+		"us-east-1": {2: 1},
+	}
+	emitAutoScalingGroupStatistics(regionSums, asgSizeSums,
+		map[reapable.Region]int{"us-west-2": 1}, map[reapable.Region]int{})
+
+	if len(recorded["reaper.asgs.total"]) != 2 {
+		t.Errorf("expected reaper.asgs.total once per region, got %d", len(recorded["reaper.asgs.total"]))
+	}
+	if len(recorded["reaper.asgs.asgsizes"]) != 4 {
+		t.Errorf("expected reaper.asgs.asgsizes once per region and size, got %d", len(recorded["reaper.asgs.asgsizes"]))
+	}
+	if len(recorded["reaper.asgs.filtered"]) != 2 || len(recorded["reaper.asgs.whitelistedCount"]) != 2 {
+		t.Errorf("expected filtered and whitelistedCount once per region, got %v", recorded)
+	}
+}
+
+func TestVolumeStatisticsEventTag(t *testing.T) {
+	defer setTestConfig(&Config{EventTag: "env:test"})()
+	recorded, restore := recordStatistics()
+	defer restore()
+
+	regionSums := map[reapable.Region]int{"us-west-2": 3}
+	volumeSizeSums := map[reapable.Region]map[volumeSize]int{"us-west-2": {{8, "gp2"}: 2, {100, "gp2"}: 1}}
+	emitVolumeStatistics(regionSums, volumeSizeSums, map[reapable.Region]map[string]float64{},
+		map[reapable.Region]int{"us-west-2": 1}, map[reapable.Region]int{})
+
+	if len(recorded["reaper.volumes.total"]) != 2 {
+		t.Fatalf("expected reaper.volumes.total once per size and type, got %d", len(recorded["reaper.volumes.total"]))
+	}
+	if len(recorded["reaper.volumes.filtered"]) != 1 {
+		t.Errorf("expected reaper.volumes.filtered once per region, got %d", len(recorded["reaper.volumes.filtered"]))
+	}
+	for name, calls := range recorded {
+		for _, tags := range calls {
+			if tags[len(tags)-1] != "env:test" {
+				t.Errorf("expected %s to be tagged with the EventTag, got %v", name, tags)
+			}
+		}
+	}
+}
+
+func TestVolumeTotalsByType(t *testing.T) {
+	defer setTestConfig(&Config{EventTag: "env:test"})()
+	var mutex sync.Mutex
+	recorded := make(map[string]float64)
+	defer func(f func(string, float64, []string) error) { newStatistic = f }(newStatistic)
+	newStatistic = func(name string, value float64, tags []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if name == "reaper.volumes.total" {
+			recorded[tags[0]] = value
+		}
+		return nil
+	}
+
+	volumeSizeSums := map[reapable.Region]map[volumeSize]int{
+		"us-west-2": {{100, "gp2"}: 2, {100, "gp3"}: 1, {500, "io1"}: 1},
+		"us-east-1": {{100, "gp2"}: 3},
+	}
+	emitVolumeStatistics(map[reapable.Region]int{}, volumeSizeSums, map[reapable.Region]map[string]float64{},
+		map[reapable.Region]int{}, map[reapable.Region]int{})
+
+	expected := map[string]float64{
+		"region:us-west-2,volumesize:100,volumetype:gp2": 2,
+		"region:us-west-2,volumesize:100,volumetype:gp3": 1,
+		"region:us-west-2,volumesize:500,volumetype:io1": 1,
+		"region:us-east-1,volumesize:100,volumetype:gp2": 3,
+	}
+	if len(recorded) != len(expected) {
+		t.Errorf("expected reaper.volumes.total for %v, got %v", expected, recorded)
+	}
+	for tags, count := range expected {
+		if got, ok := recorded[tags]; !ok || got != count {
+			t.Errorf("expected a total of %.0f for %s, got %.0f", count, tags, got)
+		}
+	}
+}
+
+func TestVolumeTotalCost(t *testing.T) {
+	defer setTestConfig(&Config{EventTag: "env:test"})()
+	defer func(p map[string]float64) { prices.EBSMonthlyPricesPerGB = p }(prices.EBSMonthlyPricesPerGB)
+	prices.EBSMonthlyPricesPerGB = map[string]float64{"gp2": 0.73, "sc1": 0.073}
+
+	// new volumes' states depend on the aws package's config
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+	newVolume := func(id, volumeType string, size int64) *reaperaws.Volume {
+		return reaperaws.NewVolume("us-west-2", &ec2.Volume{
+			VolumeId:   aws.String(id),
+			VolumeType: aws.String(volumeType),
+			Size:       aws.Int64(size),
+		})
+	}
+
+	// 100GB at 0.73 a GB-month is 73 a month, 0.1 an hour
+	costs := map[reapable.Region]map[string]float64{"us-west-2": {}}
+	for _, v := range []*reaperaws.Volume{
+		newVolume("vol-1", "gp2", 100),
+		newVolume("vol-2", "gp2", 100),
+		newVolume("vol-3", "sc1", 1000),
+		newVolume("vol-4", "unknown", 100),
+	} {
+		if cost, ok := volumeHourlyCost(v); ok {
+			costs[v.Region()][*v.VolumeType] += cost
+		} else if *v.VolumeType != "unknown" {
+			t.Errorf("expected a price for %s", *v.VolumeType)
+		}
+	}
+
+	var mutex sync.Mutex
+	recorded := make(map[string]float64)
+	defer func(f func(string, float64, []string) error) { newStatistic = f }(newStatistic)
+	newStatistic = func(name string, value float64, tags []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if name == "reaper.volumes.totalcost" {
+			recorded[tags[0]] = value
+		}
+		return nil
+	}
+	emitVolumeStatistics(map[reapable.Region]int{}, map[reapable.Region]map[volumeSize]int{}, costs,
+		map[reapable.Region]int{}, map[reapable.Region]int{})
+
+	expected := map[string]float64{
+		"region:us-west-2,volumetype:gp2": 0.2,
+		"region:us-west-2,volumetype:sc1": 0.1,
+	}
+	if len(recorded) != len(expected) {
+		t.Errorf("expected reaper.volumes.totalcost for %v, got %v", expected, recorded)
+	}
+	for tags, cost := range expected {
+		if got, ok := recorded[tags]; !ok || got < cost-0.0001 || got > cost+0.0001 {
+			t.Errorf("expected a totalcost of %f for %s, got %f", cost, tags, got)
+		}
+	}
+}
+
+func TestMinimumResourceAge(t *testing.T) {
+	defer setTestConfig(&Config{
+		WhitelistTag:       "REAPER_SPARE_ME",
+		MinimumResourceAge: state.Duration{Duration: 24 * time.Hour},
+		Instances: ResourceConfig{
+			FilterGroups: map[string]filters.FilterGroup{
+				"Owned": filters.FilterGroup{"1": *filters.NewFilter("Tagged", []string{"Owner"})},
+			},
+		},
+	})()
+
+	newInstance := func(id string, launched time.Time) *reaperaws.Instance {
+		return reaperaws.NewInstance("us-west-2", &ec2.Instance{
+			InstanceId: aws.String(id),
+			LaunchTime: aws.Time(launched),
+			Tags:       []*ec2.Tag{&ec2.Tag{Key: aws.String("Owner"), Value: aws.String("jdoe")}},
+		})
+	}
+
+	if matchesFilters(newInstance("i-young", time.Now().Add(-time.Hour))) {
+		t.Error("expected an instance launched an hour ago not to match")
+	}
+	if !matchesFilters(newInstance("i-old", time.Now().Add(-48*time.Hour))) {
+		t.Error("expected an instance launched two days ago to match")
+	}
+}
+
+// recordNotifications replaces sendNotifications and now until restore is called
+// what was sent is passed to sent once the sent resources are marked
+// notified and recorded, so that tests can check their states
+func recordNotifications(at time.Time) (sent chan map[string][]reaperevents.Reapable, restore func()) {
+	sent = make(chan map[string][]reaperevents.Reapable, 1)
+	var mutex sync.Mutex
+	var pending map[string][]reaperevents.Reapable
+	originalSend, originalRecord, originalNow := sendNotifications, recordReapableEvents, now
+	sendNotifications = func(m map[string][]reaperevents.Reapable) map[string][]reaperevents.Reapable {
+		mutex.Lock()
+		defer mutex.Unlock()
+		pending = m
+		return m
+	}
+	recordReapableEvents = func(m map[string][]reaperevents.Reapable) {
+		originalRecord(m)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if pending != nil {
+			sent <- pending
+			pending = nil
+		}
+	}
+	now = func() time.Time { return at }
+	return sent, func() {
+		sendNotifications, recordReapableEvents, now = originalSend, originalRecord, originalNow
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	c := &Config{}
+	c.Notifications.QuietHours = reaperevents.QuietHoursConfig{
+		Ranges:   []string{"19:00-07:00"},
+		Days:     []string{"Saturday"},
+		Timezone: "America/Los_Angeles",
+	}
+	if err := c.Notifications.QuietHours.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	defer setTestConfig(c)()
+
+	pacific, _ := time.LoadLocation("America/Los_Angeles")
+	owners := map[string][]reaperevents.Reapable{"jdoe@example.com": nil}
+
+	// Wednesday afternoon, outside quiet hours
+	sent, restore := recordNotifications(time.Date(2016, 6, 1, 14, 0, 0, 0, pacific))
+	notify(owners)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Error("expected events outside quiet hours to be sent")
+	}
+	restore()
+
+	// Wednesday night, inside quiet hours
+	sent, restore = recordNotifications(time.Date(2016, 6, 1, 23, 0, 0, 0, pacific))
+	defer restore()
+	notify(owners)
+	select {
+	case <-sent:
+		t.Error("expected events inside quiet hours to be deferred")
+	default:
+	}
+	deferred.Lock()
+	if deferred.byOwner == nil || deferred.timer == nil {
+		t.Error("expected events to be deferred until quiet hours end")
+	}
+	deferred.timer.Stop()
+	deferred.Unlock()
+
+	sendDeferredNotifications()
+	select {
+	case <-sent:
+	default:
+		t.Error("expected deferred events to be sent once quiet hours end")
+	}
+
+	end := c.Notifications.QuietHours.End(time.Date(2016, 6, 3, 23, 0, 0, 0, pacific))
+	if expected := time.Date(2016, 6, 5, 7, 0, 0, 0, pacific); !end.Equal(expected) {
+		t.Errorf("expected Friday night quiet hours to end Sunday at %s, got %s", expected, end)
+	}
+}
+
+func TestQuietHoursKeepDeferredUpdates(t *testing.T) {
+	c := &Config{AutoTerminate: true}
+	c.Notifications.QuietHours = reaperevents.QuietHoursConfig{Ranges: []string{"19:00-07:00"}}
+	if err := c.Notifications.QuietHours.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	defer setTestConfig(c)()
+	night := time.Date(2016, 6, 1, 23, 0, 0, 0, time.UTC)
+	_, restore := recordNotifications(night)
+	defer restore()
+
+	// a resource reaches the final state during quiet hours
+	updated := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	setTestState(updated, state.FinalState)
+	notify(map[string][]reaperevents.Reapable{"jdoe@example.com": {updated}})
+
+	// next cycle, its state was recorded, so is no longer updated
+	recorded := newTestReapable("us-west-2", "i-1", "jdoe@example.com")
+	recorded.state = state.NewStateWithUntilAndState(night, state.FinalState)
+	notify(map[string][]reaperevents.Reapable{"jdoe@example.com": {recorded}})
+
+	deferred.Lock()
+	rs := deferred.byOwner["jdoe@example.com"]
+	deferred.timer.Stop()
+	deferred.Unlock()
+	if len(rs) != 1 || rs[0] != updated {
+		t.Errorf("expected the deferred notice of the updated state to be kept, got %v", rs)
+	}
+	if autoTerminate(recorded) || recorded.terminated != 0 {
+		t.Error("expected a resource not to be auto-terminated while its notice is deferred")
+	}
+
+	// resources that are no longer filtered are dropped
+	notify(map[string][]reaperevents.Reapable{})
+	deferred.Lock()
+	if len(deferred.byOwner) != 0 {
+		t.Errorf("expected no deferred resources, got %v", deferred.byOwner)
+	}
+	deferred.timer.Stop()
+	deferred.Unlock()
+}
+
+func TestMeasureCycle(t *testing.T) {
+	defer setTestConfig(&Config{EventTag: "env:test"})()
+	values := make(map[string][]float64)
+	original := newStatistic
+	newStatistic = func(name string, value float64, tags []string) error {
+		values[name] = append(values[name], value)
+		return nil
+	}
+	defer func() { newStatistic = original }()
+
+	measureCycle(func() {
+		time.Sleep(20 * time.Millisecond)
+		log.Error("an error during the cycle")
+		log.Error("another error during the cycle")
+	})
+
+	if len(values["reaper.cycle.duration"]) != 1 {
+		t.Fatalf("expected a single reaper.cycle.duration, got %v", values)
+	}
+	if d := values["reaper.cycle.duration"][0]; d < 0.02 || d > 10 {
+		t.Errorf("expected a duration of about 0.02 seconds, got %f", d)
+	}
+	if len(values["reaper.cycle.errors"]) != 1 || values["reaper.cycle.errors"][0] != 2 {
+		t.Errorf("expected reaper.cycle.errors of 2, got %v", values["reaper.cycle.errors"])
+	}
+}
+
+func TestResolveVolumeAttachments(t *testing.T) {
+	// new volumes' states depend on the aws package's config
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+
+	newInstance := func(id, state string) *reaperaws.Instance {
+		return reaperaws.NewInstance("us-west-2", &ec2.Instance{
+			InstanceId: aws.String(id),
+			State:      &ec2.InstanceState{Name: aws.String(state)},
+			VpcId:      aws.String("vpc-1"),
+		})
+	}
+	newVolume := func(id string, instanceIDs ...string) *reaperaws.Volume {
+		volume := &ec2.Volume{VolumeId: aws.String(id)}
+		for _, instanceID := range instanceIDs {
+			volume.Attachments = append(volume.Attachments, &ec2.VolumeAttachment{InstanceId: aws.String(instanceID)})
+		}
+		return reaperaws.NewVolume("us-west-2", volume)
+	}
+	instances := map[reapable.ID]*reaperaws.Instance{
+		"i-running":    newInstance("i-running", "running"),
+		"i-stopped":    newInstance("i-stopped", "stopped"),
+		"i-terminated": newInstance("i-terminated", "terminated"),
+	}
+
+	tests := []struct {
+		volume   *reaperaws.Volume
+		expected bool
+	}{
+		{newVolume("vol-running", "i-running"), true},
+		{newVolume("vol-stopped", "i-stopped"), false},
+		{newVolume("vol-stopped-and-terminated", "i-stopped", "i-terminated"), false},
+		{newVolume("vol-stopped-and-running", "i-stopped", "i-running"), true},
+		{newVolume("vol-unattached"), false},
+		// unknown instances are assumed to be running
+		{newVolume("vol-unknown", "i-unknown"), true},
+	}
+	for _, test := range tests {
+		resolveVolumeAttachments(test.volume, instances)
+		if test.volume.AttachedToRunningInstance != test.expected {
+			t.Errorf("%s: expected AttachedToRunningInstance %t", test.volume.ID(), test.expected)
+		}
+		matched := test.volume.Filter(*filters.NewFilter("AttachedToRunningInstance", []string{"true"}))
+		if matched != test.expected {
+			t.Errorf("%s: expected the AttachedToRunningInstance filter to be %t", test.volume.ID(), test.expected)
+		}
+	}
+}
+
+func TestSetCloudformationStack(t *testing.T) {
+	stacks := map[reapable.ID]cloudformationStack{"i-temp": {Name: "temp-123"}, "i-prod": {Name: "prod"}, "i-nested": {Name: "parent"}}
+	newInstance := func(id string, tags ...*ec2.Tag) *reaperaws.Instance {
+		i := reaperaws.NewInstance("us-west-2", &ec2.Instance{InstanceId: aws.String(id), Tags: tags})
+		setCloudformationStack(&i.Resource, stacks, i.ID())
+		return i
+	}
+	temp := newInstance("i-temp")
+	prod := newInstance("i-prod")
+	standalone := newInstance("i-standalone")
+	nested := newInstance("i-nested", &ec2.Tag{Key: aws.String("aws:cloudformation:stack-name"), Value: aws.String("temp-child")})
+
+	f := *filters.NewFilter("CloudformationStackNameMatches", []string{"^temp-"})
+	if !temp.IsInCloudformation || !temp.Filter(f) {
+		t.Error("expected an instance of the temp-123 stack to match ^temp-")
+	}
+	if !prod.IsInCloudformation || prod.Filter(f) {
+		t.Error("expected an instance of the prod stack not to match ^temp-")
+	}
+	if standalone.IsInCloudformation || standalone.Filter(f) {
+		t.Error("expected an instance in no stack not to match")
+	}
+	if nested.CloudformationStackName != "temp-child" {
+		t.Errorf("expected the tagged nested stack name to be kept, got %q", nested.CloudformationStackName)
+	}
+}
+
+func TestSettled(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int64
+		settled bool
+	}{
+		{"pending", 0, false},
+		{"running", 16, true},
+		{"shutting-down", 32, false},
+		{"terminated", 48, false},
+		{"stopping", 64, false},
+		{"stopped", 80, true},
+	}
+	for _, test := range tests {
+		i := reaperaws.NewInstance("us-west-2", &ec2.Instance{
+			InstanceId: aws.String("i-" + test.name),
+			State:      &ec2.InstanceState{Code: aws.Int64(test.code), Name: aws.String(test.name)},
+		})
+		if settled(i) != test.settled {
+			t.Errorf("expected settled to be %t for a %s instance", test.settled, test.name)
+		}
+	}
+	if !settled(newTestReapable("us-west-2", "vol-1", "owner@example.com")) {
+		t.Error("expected resources other than instances to be settled")
+	}
+}
+
+func TestInstancesInWhitelistedAutoScalingGroup(t *testing.T) {
+	defer setTestNeverReap()()
+	defer setTestConfig(&Config{
+		WhitelistTag: "REAPER_SPARE_ME",
+		Instances: ResourceConfig{
+			FilterGroups: map[string]filters.FilterGroup{
+				"Owned": filters.FilterGroup{"1": *filters.NewFilter("Tagged", []string{"Owner"})},
+			},
+		},
+	})()
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+
+	i := reaperaws.NewInstance("us-west-2", &ec2.Instance{
+		InstanceId: aws.String("i-1"),
+		Tags:       []*ec2.Tag{&ec2.Tag{Key: aws.String("Owner"), Value: aws.String("jdoe")}},
+	})
+	if isWhitelisted(i) || !matchesFilters(i) {
+		t.Error("expected an instance in an ASG that isn't whitelisted to match")
+	}
+	i.InWhitelistedAutoScalingGroup = true
+	if !isWhitelisted(i) || matchesFilters(i) {
+		t.Error("expected an instance in a whitelisted ASG to be whitelisted and not match")
+	}
+	// whitelist links still tag it, so it stays whitelisted outside the ASG
+	if taggedWhitelist(i) {
+		t.Error("expected an instance in a whitelisted ASG not to count as tagged with the whitelist tag")
+	}
+}
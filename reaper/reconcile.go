@@ -0,0 +1,43 @@
+package reaper
+
+import (
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	reaperevents "github.com/mozilla-services/reaper/events"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// discoveryFailedIn returns whether discovery failed in a region this cycle
+// replaceable in tests
+var discoveryFailedIn = reaperaws.DiscoveryFailedIn
+
+// pruneReapables removes the tracked reapables that weren't discovered this
+// cycle, such as those deleted outside of Reaper, and emits
+// reaper.reapables.pruned
+// regions where discovery failed are skipped, their resources may still exist
+// a resource's state is saved in its own tags, so nothing else is left behind
+func pruneReapables(discovered []reaperevents.Reapable) {
+	seen := make(map[reapable.Region]map[reapable.ID]bool)
+	for _, r := range discovered {
+		if seen[r.Region()] == nil {
+			seen[r.Region()] = make(map[reapable.ID]bool)
+		}
+		seen[r.Region()][r.ID()] = true
+	}
+
+	// collected first, Iter holds the lock until it is drained
+	var pruned []reapable.ReapableContainer
+	for r := range reapables.Iter() {
+		if !seen[r.Region()][r.ID()] && !discoveryFailedIn(r.Region().String()) {
+			pruned = append(pruned, r)
+		}
+	}
+	for _, r := range pruned {
+		log.Info("Pruning %s, it was not discovered this cycle", r.ReapableDescriptionTiny())
+		reapables.Delete(r.Region(), r.ID())
+	}
+
+	if err := newStatistic("reaper.reapables.pruned", float64(len(pruned)), []string{config.EventTag}); err != nil {
+		log.Error(err.Error())
+	}
+}
@@ -0,0 +1,39 @@
+package reaper
+
+import (
+	"testing"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+)
+
+func TestPruneReapables(t *testing.T) {
+	defer setTestConfig(&Config{})()
+	recorded, restore := recordStatistics()
+	defer restore()
+	originalFailedIn := discoveryFailedIn
+	defer func() { discoveryFailedIn = originalFailedIn }()
+	discoveryFailedIn = func(region string) bool { return region == "us-east-1" }
+
+	present := newTestReapable("us-west-2", "i-present", "owner@example.com")
+	deleted := newTestReapable("us-west-2", "i-deleted", "owner@example.com")
+	unknown := newTestReapable("us-east-1", "i-unknown", "owner@example.com")
+	reapables.Reset([]string{"us-west-2", "us-east-1"})
+	for _, r := range []*testReapable{present, deleted, unknown} {
+		reapables.Put(r.Region(), r.ID(), r)
+	}
+
+	pruneReapables([]reaperevents.Reapable{present})
+
+	if _, err := reapables.Get("us-west-2", "i-present"); err != nil {
+		t.Error("expected a discovered resource to be kept")
+	}
+	if _, err := reapables.Get("us-west-2", "i-deleted"); err == nil {
+		t.Error("expected a resource that wasn't discovered to be pruned")
+	}
+	if _, err := reapables.Get("us-east-1", "i-unknown"); err != nil {
+		t.Error("expected resources of a region discovery failed in to be kept")
+	}
+	if len(recorded["reaper.reapables.pruned"]) != 1 {
+		t.Errorf("expected a reaper.reapables.pruned statistic, got %v", recorded)
+	}
+}
@@ -0,0 +1,86 @@
+package reaper
+
+import (
+	"fmt"
+
+	reaperevents "github.com/mozilla-services/reaper/events"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// reporter is an EventReporter that is enabled by its section under [Events]
+type reporter struct {
+	name   string
+	config *reaperevents.EventReporterConfig
+	new    func() reaperevents.EventReporter
+}
+
+// reporters lists every EventReporter by the name of its section
+// adding an EventReporter only needs a section in EventTypes and an entry here
+func (c *Config) reporters() []reporter {
+	return []reporter{
+		{"DatadogStatistics", c.Events.DatadogStatistics.EventReporterConfig, func() reaperevents.EventReporter {
+			return reaperevents.NewDatadogStatistics(&c.Events.DatadogStatistics)
+		}},
+		{"DatadogEvents", c.Events.DatadogEvents.EventReporterConfig, func() reaperevents.EventReporter {
+			return reaperevents.NewDatadogEvents(&c.Events.DatadogEvents)
+		}},
+		{"Email", c.Events.Email.EventReporterConfig, func() reaperevents.EventReporter {
+			return reaperevents.NewMailer(&c.Events.Email)
+		}},
+		{"Tagger", c.Events.Tagger.EventReporterConfig, func() reaperevents.EventReporter {
+			return reaperevents.NewTagger(&c.Events.Tagger)
+		}},
+		{"Reaper", c.Events.Reaper.EventReporterConfig, func() reaperevents.EventReporter {
+			return reaperevents.NewReaperEvent(&c.Events.Reaper)
+		}},
+		{"SNS", c.Events.SNS.EventReporterConfig, func() reaperevents.EventReporter {
+			return reaperevents.NewSNSEventReporter(&c.Events.SNS)
+		}},
+		{"Slack", c.Events.Slack.EventReporterConfig, func() reaperevents.EventReporter {
+			return reaperevents.NewSlack(&c.Events.Slack)
+		}},
+	}
+}
+
+// applyReporters enables exactly the EventReporters named in Reporters,
+// overriding their Enabled, if Reporters is set
+func (c *Config) applyReporters() error {
+	if len(c.Reporters) == 0 {
+		return nil
+	}
+	named := make(map[string]bool)
+	for _, name := range c.Reporters {
+		named[name] = true
+	}
+	for _, r := range c.reporters() {
+		if !named[r.name] {
+			if r.config != nil {
+				r.config.Enabled = false
+			}
+			continue
+		}
+		delete(named, r.name)
+		if r.config == nil {
+			return fmt.Errorf("Reporters enables %s, which has no [Events.%s] section", r.name, r.name)
+		}
+		r.config.Enabled = true
+	}
+	for name := range named {
+		return fmt.Errorf("Reporters enables unknown EventReporter %q", name)
+	}
+	return nil
+}
+
+// EventReporters returns a new EventReporter for each enabled section
+// under [Events]
+func (c *Config) EventReporters() []reaperevents.EventReporter {
+	var ers []reaperevents.EventReporter
+	for _, r := range c.reporters() {
+		if r.config == nil || !r.config.Enabled {
+			continue
+		}
+		log.Info("%s EventReporter enabled.", r.name)
+		ers = append(ers, r.new())
+	}
+	return ers
+}
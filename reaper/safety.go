@@ -0,0 +1,80 @@
+package reaper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// SafetyConfig is a circuit breaker for cycles where an abnormal number
+// of resources match filters, such as after a bad filter change
+type SafetyConfig struct {
+	// MaxFilteredPerType is the most resources of a type that may match
+	// filters in a cycle, either a number such as "50" or a percentage of the
+	// resources of that type such as "10%". If unset, there is no limit
+	MaxFilteredPerType string
+
+	// Override notifies and terminates even when the circuit is tripped
+	Override bool
+}
+
+// limit returns the most resources of a type with total resources that
+// may match filters, and whether there is a limit
+func (s *SafetyConfig) limit(total int) (float64, bool, error) {
+	max := strings.TrimSpace(s.MaxFilteredPerType)
+	if max == "" {
+		return 0, false, nil
+	}
+
+	if strings.HasSuffix(max, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(max, "%")), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return 0, false, fmt.Errorf("Safety MaxFilteredPerType %q must be a percentage between 0%% and 100%%", s.MaxFilteredPerType)
+		}
+		return float64(total) * percent / 100, true, nil
+	}
+
+	n, err := strconv.Atoi(max)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("Safety MaxFilteredPerType %q must be a number or a percentage, such as 50 or 10%%", s.MaxFilteredPerType)
+	}
+	return float64(n), true, nil
+}
+
+// Validate returns an error if MaxFilteredPerType can't be parsed
+func (s *SafetyConfig) Validate() error {
+	_, _, err := s.limit(0)
+	return err
+}
+
+// safetyTripped returns whether filtered of the total resources of a type
+// matching filters exceeds Safety.MaxFilteredPerType, in which case
+// the type must not be notified or terminated this cycle
+func safetyTripped(resourceType string, filtered, total int) bool {
+	limit, ok, err := config.Safety.limit(total)
+	if err != nil {
+		// MaxFilteredPerType is validated when the config is loaded
+		log.Error(err.Error())
+		return false
+	}
+	if !ok || float64(filtered) <= limit {
+		return false
+	}
+
+	if config.Safety.Override {
+		log.Warning("Safety: %d of %d %s matched filters, more than MaxFilteredPerType (%s), proceeding because of Safety.Override",
+			filtered, total, resourceType, config.Safety.MaxFilteredPerType)
+		return false
+	}
+
+	log.Error("SAFETY CIRCUIT TRIPPED: %d of %d %s matched filters, more than MaxFilteredPerType (%s). "+
+		"Not notifying owners of or terminating any %s this cycle. Check the FilterGroups for %s, "+
+		"or set Safety.Override to proceed.",
+		filtered, total, resourceType, config.Safety.MaxFilteredPerType, resourceType, resourceType)
+	if err := newCountStatistic("reaper.safety.tripped", []string{fmt.Sprintf("type:%s", resourceType), config.EventTag}); err != nil {
+		log.Error(err.Error())
+	}
+	return true
+}
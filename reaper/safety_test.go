@@ -0,0 +1,60 @@
+package reaper
+
+import (
+	"testing"
+)
+
+func TestSafetyTripped(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxFilteredPerType string
+		override           bool
+		filtered, total    int
+		tripped            bool
+	}{
+		{"unset", "", false, 100, 100, false},
+		{"below absolute", "10", false, 9, 100, false},
+		{"at absolute", "10", false, 10, 100, false},
+		{"above absolute", "10", false, 11, 100, true},
+		{"below percentage", "10%", false, 19, 200, false},
+		{"at percentage", "10%", false, 20, 200, false},
+		{"above percentage", "10%", false, 21, 200, true},
+		{"above with override", "10", true, 11, 100, false},
+	}
+
+	for _, test := range tests {
+		restoreConfig := setTestConfig(&Config{
+			EventTag: "env:test",
+			Safety:   SafetyConfig{MaxFilteredPerType: test.maxFilteredPerType, Override: test.override},
+		})
+		recorded, restore := recordCountStatistics()
+
+		if tripped := safetyTripped("instances", test.filtered, test.total); tripped != test.tripped {
+			t.Errorf("%s: expected tripped to be %t", test.name, test.tripped)
+		}
+		statistics := recorded["reaper.safety.tripped"]
+		if test.tripped {
+			if len(statistics) != 1 || statistics[0][0] != "type:instances" {
+				t.Errorf("%s: expected a reaper.safety.tripped statistic for instances, got %v", test.name, statistics)
+			}
+		} else if len(statistics) != 0 {
+			t.Errorf("%s: expected no reaper.safety.tripped statistic, got %v", test.name, statistics)
+		}
+
+		restore()
+		restoreConfig()
+	}
+}
+
+func TestSafetyValidate(t *testing.T) {
+	for _, valid := range []string{"", "0", "50", "10%", "2.5%", "100%"} {
+		if err := (&SafetyConfig{MaxFilteredPerType: valid}).Validate(); err != nil {
+			t.Errorf("expected %q to be valid, got %s", valid, err.Error())
+		}
+	}
+	for _, invalid := range []string{"-1", "ten", "101%", "%", "10 percent"} {
+		if err := (&SafetyConfig{MaxFilteredPerType: invalid}).Validate(); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package reaper
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/robfig/cron"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	"github.com/mozilla-services/reaper/reapable"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+// scheduleMutex guards schedule, which is replaced each cycle
+var scheduleMutex sync.Mutex
+
+// scaleJob scales an AutoScalingGroup down or up, on the schedule of its
+// schedule-down or schedule-up tag
+// conforms to the cron.Job interface
+type scaleJob struct {
+	region reapable.Region
+	id     reapable.ID
+	up     bool
+}
+
+func (j scaleJob) action() string {
+	if j.up {
+		return "up"
+	}
+	return "down"
+}
+
+// Run describes the AutoScalingGroup again, since it may have changed since
+// the job was scheduled, then scales it
+// in DryRun mode, the AutoScalingGroup is not scaled
+func (j scaleJob) Run() {
+	r, err := findReapable(j.region, j.id)
+	if err != nil {
+		log.Error("Could not find AutoScalingGroup %s in %s to scale it %s: %s", j.id, j.region, j.action(), err.Error())
+		return
+	}
+	a, ok := r.(*reaperaws.AutoScalingGroup)
+	if !ok {
+		log.Error("%s is not an AutoScalingGroup, not scaling it %s", r.ReapableDescriptionTiny(), j.action())
+		return
+	}
+	if dryRun(a) {
+		log.Info("DryRun: Not scaling %s %s", a.ReapableDescriptionTiny(), j.action())
+		return
+	}
+
+	scale := a.ScaleDown
+	if j.up {
+		scale = a.ScaleUp
+	}
+	if _, err := scale(); err != nil {
+		log.Error("Could not scale %s %s: %s", a.ReapableDescriptionTiny(), j.action(), err.Error())
+		return
+	}
+	if err := newCountStatistic(fmt.Sprintf("reaper.autoscalinggroups.scaled%s", j.action()), reapableStatisticTags(a)); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+// parseScaleSchedule parses a standard five field cron spec, such as
+// 0 19 * * 1-5, or a descriptor, such as @daily
+// the cron package's specs start with seconds, which tags don't have
+func parseScaleSchedule(spec string) (cron.Schedule, error) {
+	if strings.HasPrefix(spec, "@") {
+		return cron.Parse(spec)
+	}
+	if fields := strings.Fields(spec); len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d", len(fields))
+	}
+	return cron.Parse("0 " + spec)
+}
+
+// newScaleSchedule returns a schedule of the scale jobs of the
+// AutoScalingGroups' schedule-down and schedule-up tags
+// tags that aren't valid cron specs are logged and skipped
+func newScaleSchedule(asgs []*reaperaws.AutoScalingGroup) *cron.Cron {
+	c := cron.New()
+	for _, a := range asgs {
+		down, up := a.ScaleSchedule()
+		for _, job := range []struct {
+			spec string
+			up   bool
+		}{{down, false}, {up, true}} {
+			if job.spec == "" {
+				continue
+			}
+			s, err := parseScaleSchedule(job.spec)
+			if err != nil {
+				log.Error("Skipping the scale %s schedule %q of %s: %s", scaleJob{up: job.up}.action(), job.spec, a.ReapableDescriptionTiny(), err.Error())
+				continue
+			}
+			c.Schedule(s, scaleJob{region: a.Region(), id: a.ID(), up: job.up})
+		}
+	}
+	return c
+}
+
+// scheduleScaling replaces the scale jobs of the previous cycle with those
+// of the AutoScalingGroups found this cycle
+// if discovery failed in any region, the previous jobs are kept, so that
+// AutoScalingGroups aren't left scaled down
+func scheduleScaling(asgs []*reaperaws.AutoScalingGroup) {
+	for _, region := range config.AWS.Regions {
+		if discoveryFailedIn(region) {
+			log.Warning("Discovery failed in %s, keeping the previous scale schedules", region)
+			return
+		}
+	}
+	setScaleSchedule(newScaleSchedule(asgs))
+}
+
+// setScaleSchedule stops the previous cycle's scale jobs and starts c's
+func setScaleSchedule(c *cron.Cron) {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+	if schedule != nil {
+		schedule.Stop()
+	}
+	schedule = c
+	schedule.Start()
+}
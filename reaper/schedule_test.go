@@ -0,0 +1,85 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	log "github.com/mozilla-services/reaper/reaperlog"
+)
+
+func newTestScheduledAutoScalingGroup(name string, tags map[string]string) *reaperaws.AutoScalingGroup {
+	group := &autoscaling.Group{AutoScalingGroupName: aws.String(name)}
+	for k, v := range tags {
+		group.Tags = append(group.Tags, &autoscaling.TagDescription{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return reaperaws.NewAutoScalingGroup("us-west-2", group)
+}
+
+func TestScaleScheduleFromTags(t *testing.T) {
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+
+	asgs := []*reaperaws.AutoScalingGroup{
+		newTestScheduledAutoScalingGroup("office-hours", map[string]string{
+			"schedule-down": "0 19 * * 1-5",
+			"schedule-up":   "0 7 * * 1-5",
+		}),
+		newTestScheduledAutoScalingGroup("nightly", map[string]string{
+			"schedule-down": "@daily",
+		}),
+		newTestScheduledAutoScalingGroup("invalid", map[string]string{
+			"schedule-down": "0 19 * *",
+			"schedule-up":   "0 25 * * *",
+		}),
+		newTestScheduledAutoScalingGroup("unscheduled", nil),
+	}
+
+	errorsBefore := log.ErrorCount()
+	entries := newScaleSchedule(asgs).Entries()
+	if errors := log.ErrorCount() - errorsBefore; errors != 2 {
+		t.Errorf("expected 2 invalid schedules to be logged, got %d", errors)
+	}
+
+	expected := map[scaleJob]bool{
+		scaleJob{region: "us-west-2", id: "office-hours", up: false}: true,
+		scaleJob{region: "us-west-2", id: "office-hours", up: true}:  true,
+		scaleJob{region: "us-west-2", id: "nightly", up: false}:      true,
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d scale jobs, got %d", len(expected), len(entries))
+	}
+
+	// a Monday
+	monday := time.Date(2016, time.May, 2, 18, 30, 0, 0, time.Local)
+	for _, entry := range entries {
+		job, ok := entry.Job.(scaleJob)
+		if !ok || !expected[job] {
+			t.Errorf("unexpected scale job %#v", entry.Job)
+			continue
+		}
+		delete(expected, job)
+		if job.id == "office-hours" && !job.up {
+			// the tag has no seconds field
+			if next := entry.Schedule.Next(monday); !next.Equal(time.Date(2016, time.May, 2, 19, 0, 0, 0, time.Local)) {
+				t.Errorf("expected office-hours to scale down at 19:00, got %s", next)
+			}
+		}
+	}
+}
+
+func TestParseScaleSchedule(t *testing.T) {
+	for _, spec := range []string{"0 19 * * 1-5", "*/15 * * * *", "@weekly", "@every 12h"} {
+		if _, err := parseScaleSchedule(spec); err != nil {
+			t.Errorf("expected %q to be valid: %s", spec, err.Error())
+		}
+	}
+	for _, spec := range []string{"", "0 19 * *", "0 0 19 * * 1-5", "0 19 * * mon-fry", "@sometimes"} {
+		if _, err := parseScaleSchedule(spec); err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+}
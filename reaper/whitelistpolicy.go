@@ -0,0 +1,70 @@
+package reaper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/mozilla-services/reaper/filters"
+)
+
+// whitelistRule protects resources tagged with Key, and if Values
+// are set, with one of Values
+type whitelistRule struct {
+	Key    string
+	Values []string
+}
+
+// matches returns whether the filterable is protected by the rule
+func (rule whitelistRule) matches(filterable filters.Filterable) bool {
+	if len(rule.Values) == 0 {
+		return filterable.Filter(*filters.NewFilter("Tagged", []string{rule.Key}))
+	}
+	return filterable.Filter(*filters.NewFilter("TagInSet", append([]string{rule.Key}, rule.Values...)))
+}
+
+// tagPolicy is the part of an AWS Organizations tag policy that names
+// tag keys and their values, such as
+// {"tags": {"protected": {"tag_key": {"@@assign": "Protected"}, "tag_value": {"@@assign": ["true"]}}}}
+type tagPolicy struct {
+	Tags map[string]struct {
+		TagKey struct {
+			Assign string `json:"@@assign"`
+		} `json:"tag_key"`
+		TagValue struct {
+			Assign []string `json:"@@assign"`
+		} `json:"tag_value"`
+	} `json:"tags"`
+}
+
+// readWhitelistPolicy reads the tag keys, and their values, that protect
+// resources from a tag policy document
+// a tag without values protects resources with any value
+func readWhitelistPolicy(path string) ([]whitelistRule, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy tagPolicy
+	if err := json.Unmarshal(b, &policy); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+
+	// sorted by the policy's names, so that rules are applied in the same order every time
+	var names []string
+	for name := range policy.Tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rules []whitelistRule
+	for _, name := range names {
+		tag := policy.Tags[name]
+		if tag.TagKey.Assign == "" {
+			return nil, fmt.Errorf("%s: tag %q has no tag_key", path, name)
+		}
+		rules = append(rules, whitelistRule{Key: tag.TagKey.Assign, Values: tag.TagValue.Assign})
+	}
+	return rules, nil
+}
@@ -0,0 +1,101 @@
+package reaper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	reaperaws "github.com/mozilla-services/reaper/aws"
+	"github.com/mozilla-services/reaper/filters"
+)
+
+func TestWhitelistPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reaper-whitelistpolicy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.json")
+	policy := `{"tags": {
+		"environment": {"tag_key": {"@@assign": "Environment"}, "tag_value": {"@@assign": ["production", "staging"]}},
+		"legalhold": {"tag_key": {"@@assign": "LegalHold"}}
+	}}`
+	if err := ioutil.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := readWhitelistPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer setTestNeverReap()()
+	c := &Config{
+		WhitelistTag: "REAPER_SPARE_ME",
+		Instances: ResourceConfig{
+			FilterGroups: map[string]filters.FilterGroup{
+				"Owned": filters.FilterGroup{"1": *filters.NewFilter("Tagged", []string{"Owner"})},
+			},
+		},
+	}
+	c.whitelistRules = rules
+	defer setTestConfig(c)()
+	reaperaws.SetConfig(&reaperaws.Config{})
+	defer reaperaws.SetConfig(nil)
+
+	tests := []struct {
+		name      string
+		tags      map[string]string
+		protected bool
+	}{
+		{"production", map[string]string{"Environment": "production"}, true},
+		{"staging", map[string]string{"Environment": "staging"}, true},
+		{"development", map[string]string{"Environment": "development"}, false},
+		{"legal hold with any value", map[string]string{"LegalHold": "case-123"}, true},
+		{"whitelist tag", map[string]string{"REAPER_SPARE_ME": "true"}, true},
+		{"untagged", nil, false},
+	}
+	for _, test := range tests {
+		instance := &ec2.Instance{
+			InstanceId: aws.String("i-" + test.name),
+			Tags:       []*ec2.Tag{&ec2.Tag{Key: aws.String("Owner"), Value: aws.String("jdoe")}},
+		}
+		for k, v := range test.tags {
+			instance.Tags = append(instance.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		i := reaperaws.NewInstance("us-west-2", instance)
+		if isWhitelisted(i) != test.protected {
+			t.Errorf("%s: expected isWhitelisted to be %t", test.name, test.protected)
+		}
+		if matchesFilters(i) == test.protected {
+			t.Errorf("%s: expected matchesFilters to be %t", test.name, !test.protected)
+		}
+	}
+}
+
+func TestReadWhitelistPolicyErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reaper-whitelistpolicy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, policy := range map[string]string{
+		"invalid json":   `{"tags": `,
+		"missing tagkey": `{"tags": {"environment": {"tag_value": {"@@assign": ["production"]}}}}`,
+	} {
+		path := filepath.Join(dir, "policy.json")
+		if err := ioutil.WriteFile(path, []byte(policy), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readWhitelistPolicy(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := readWhitelistPolicy(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing policy")
+	}
+}
@@ -1,15 +1,35 @@
 package reaperlog
 
 import (
+	"runtime"
+	"strings"
+	"sync/atomic"
+
 	log "github.com/Sirupsen/logrus"
 	"github.com/rifflock/lfshook"
 	"go.mozilla.org/mozlogrus"
 )
 
-var config LogConfig
+var (
+	config LogConfig
+
+	// minimum level logged, unless overridden for a module
+	level = log.InfoLevel
+	// per module (package name) minimum levels
+	moduleLevels = make(map[string]log.Level)
+
+	// number of calls to Error, see ErrorCount
+	errorCount uint64
+)
 
 type LogConfig struct {
 	Extras bool
+
+	// minimum level logged, one of debug, info, warning, error
+	// defaults to info
+	Level string
+	// per module overrides of Level, keyed by package name (e.g. aws, prices)
+	Modules map[string]string
 }
 
 func EnableExtras() {
@@ -26,6 +46,27 @@ func Extras() bool {
 
 func SetConfig(c *LogConfig) {
 	config = *c
+
+	level = log.InfoLevel
+	if config.Level != "" {
+		if l, err := log.ParseLevel(config.Level); err == nil {
+			level = l
+		} else {
+			Warning("Invalid log level %s, using %s", config.Level, level.String())
+		}
+	}
+
+	moduleLevels = make(map[string]log.Level)
+	for module, moduleLevel := range config.Modules {
+		if l, err := log.ParseLevel(moduleLevel); err == nil {
+			moduleLevels[module] = l
+		} else {
+			Warning("Invalid log level %s for module %s, using %s", moduleLevel, module, level.String())
+		}
+	}
+
+	// filtering is done here, so logrus should let everything through
+	log.SetLevel(log.DebugLevel)
 }
 
 func AddLogFile(filename string) {
@@ -39,16 +80,58 @@ func AddLogFile(filename string) {
 	}))
 }
 
+// callerModule returns the package name of the function
+// that called into reaperlog
+func callerModule() string {
+	// skip callerModule, enabled, and the reaperlog function
+	pc, _, _, ok := runtime.Caller(3)
+	if !ok {
+		return ""
+	}
+	f := runtime.FuncForPC(pc)
+	if f == nil {
+		return ""
+	}
+	// e.g. github.com/mozilla-services/reaper/aws.(*Instance).Filter
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// enabled returns whether a message at level l should be logged
+// for the calling module
+func enabled(l log.Level) bool {
+	threshold := level
+	if len(moduleLevels) > 0 {
+		if moduleLevel, ok := moduleLevels[callerModule()]; ok {
+			threshold = moduleLevel
+		}
+	}
+	// logrus levels are ordered from most to least severe
+	return l <= threshold
+}
+
 func Debug(format string, args ...interface{}) {
-	log.Debugf(format, args...)
+	if enabled(log.DebugLevel) {
+		log.Debugf(format, args...)
+	}
 }
 
 func Info(format string, args ...interface{}) {
-	log.Infof(format, args...)
+	if enabled(log.InfoLevel) {
+		log.Infof(format, args...)
+	}
 }
 
 func Warning(format string, args ...interface{}) {
-	log.Warningf(format, args...)
+	if enabled(log.WarnLevel) {
+		log.Warningf(format, args...)
+	}
 }
 
 func Fatal(format string, args ...interface{}) {
@@ -60,5 +143,14 @@ func Panic(format string, args ...interface{}) {
 }
 
 func Error(format string, args ...interface{}) {
-	log.Errorf(format, args...)
+	atomic.AddUint64(&errorCount, 1)
+	if enabled(log.ErrorLevel) {
+		log.Errorf(format, args...)
+	}
+}
+
+// ErrorCount returns the number of errors reported with Error so far,
+// including those below the logged level
+func ErrorCount() uint64 {
+	return atomic.LoadUint64(&errorCount)
 }
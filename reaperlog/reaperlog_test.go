@@ -0,0 +1,76 @@
+package reaperlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func captureOutput() (*bytes.Buffer, func()) {
+	buf := new(bytes.Buffer)
+	log.SetOutput(buf)
+	return buf, func() {
+		log.SetOutput(new(bytes.Buffer))
+		SetConfig(&LogConfig{})
+	}
+}
+
+func TestLevelThreshold(t *testing.T) {
+	buf, restore := captureOutput()
+	defer restore()
+
+	SetConfig(&LogConfig{Level: "warning"})
+	Debug("debug message")
+	Info("info message")
+	Warning("warning message")
+	Error("error message")
+
+	out := buf.String()
+	for _, suppressed := range []string{"debug message", "info message"} {
+		if strings.Contains(out, suppressed) {
+			t.Errorf("expected %q to be suppressed", suppressed)
+		}
+	}
+	for _, logged := range []string{"warning message", "error message"} {
+		if !strings.Contains(out, logged) {
+			t.Errorf("expected %q to be logged", logged)
+		}
+	}
+}
+
+func TestDefaultLevelIsInfo(t *testing.T) {
+	buf, restore := captureOutput()
+	defer restore()
+
+	SetConfig(&LogConfig{})
+	Debug("debug message")
+	Info("info message")
+
+	if strings.Contains(buf.String(), "debug message") {
+		t.Error("expected debug to be suppressed by default")
+	}
+	if !strings.Contains(buf.String(), "info message") {
+		t.Error("expected info to be logged by default")
+	}
+}
+
+func TestModuleOverride(t *testing.T) {
+	buf, restore := captureOutput()
+	defer restore()
+
+	// calls from this test are attributed to the reaperlog module
+	SetConfig(&LogConfig{Level: "error", Modules: map[string]string{"reaperlog": "debug"}})
+	Debug("module debug message")
+	if !strings.Contains(buf.String(), "module debug message") {
+		t.Error("expected the module override to allow debug messages")
+	}
+
+	buf.Reset()
+	SetConfig(&LogConfig{Level: "debug", Modules: map[string]string{"reaperlog": "error"}})
+	Info("module info message")
+	if strings.Contains(buf.String(), "module info message") {
+		t.Error("expected the module override to suppress info messages")
+	}
+}
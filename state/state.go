@@ -25,6 +25,7 @@ func (d *Duration) UnmarshalText(text []byte) (err error) {
 
 type StatesConfig struct {
 	Interval            Duration
+	IntervalJitter      Duration
 	FirstStateDuration  Duration
 	SecondStateDuration Duration
 	ThirdStateDuration  Duration
@@ -32,6 +33,10 @@ type StatesConfig struct {
 
 type StateEnum int
 
+// notifiedTag is the last field of a saved State whose
+// NotifiedForCurrentState is set
+const notifiedTag = "notified"
+
 // StateEnum.String() in stateenum_string.go
 
 type State struct {
@@ -42,12 +47,33 @@ type State struct {
 
 	Updated bool
 
+	// NotifiedForCurrentState is whether a reapable event was sent for State
+	// a new State, such as one from IncrementState, hasn't been
+	// it is saved after LastNotified
+	NotifiedForCurrentState bool
+
 	// State must be maintained until this time
 	Until time.Time
+
+	// LastNotified is when owners were last notified of the resource,
+	// whatever its State, zero if never
+	// it is carried over to new States, and saved after Until
+	LastNotified time.Time
 }
 
 func (s *State) String() string {
-	return s.State.String() + s.reaperTagSeparator + s.Until.Format(s.reaperTagTimeFormat)
+	str := s.State.String() + s.reaperTagSeparator + s.Until.Format(s.reaperTagTimeFormat)
+	if !s.LastNotified.IsZero() || s.NotifiedForCurrentState {
+		var lastNotified string
+		if !s.LastNotified.IsZero() {
+			lastNotified = s.LastNotified.Format(s.reaperTagTimeFormat)
+		}
+		str += s.reaperTagSeparator + lastNotified
+	}
+	if s.NotifiedForCurrentState {
+		str += s.reaperTagSeparator + notifiedTag
+	}
+	return str
 }
 
 func NewState() *State {
@@ -87,7 +113,8 @@ func NewStateWithTag(state string) *State {
 
 	s := strings.Split(state, NewState().reaperTagSeparator)
 
-	if len(s) != 2 {
+	// LastNotified and NotifiedForCurrentState are optional
+	if len(s) < 2 || len(s) > 4 {
 		return NewState()
 	}
 
@@ -111,5 +138,12 @@ func NewStateWithTag(state string) *State {
 		return NewState()
 	}
 
-	return NewStateWithUntilAndState(t, stateEnum)
+	parsed := NewStateWithUntilAndState(t, stateEnum)
+	if len(s) >= 3 && s[2] != "" {
+		if lastNotified, err := time.Parse(parsed.reaperTagTimeFormat, s[2]); err == nil {
+			parsed.LastNotified = lastNotified
+		}
+	}
+	parsed.NotifiedForCurrentState = len(s) == 4 && s[3] == notifiedTag
+	return parsed
 }
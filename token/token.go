@@ -34,6 +34,8 @@ const (
 	J_TERMINATE
 	J_WHITELIST
 	J_STOP
+	J_SNOOZE_OWNER
+	J_TERMINATE_BATCH
 )
 
 // Not very scalable but good enough for our requirements
@@ -45,6 +47,12 @@ type JobToken struct {
 	ValidUntil      time.Time
 	ScaleDownString string
 	ScaleUpString   string
+
+	// Owner is the email address of the owner whose resources a J_SNOOZE_OWNER job delays
+	Owner string
+
+	// Manifest is the region/id pairs a J_TERMINATE_BATCH job terminates
+	Manifest []string
 }
 
 func (j *JobToken) JSON() []byte {
@@ -100,6 +108,26 @@ func NewStopJob(region, ID string) *JobToken {
 	}
 }
 
+// NewSnoozeOwnerJob returns a job that delays every resource of owner
+func NewSnoozeOwnerJob(owner string, until time.Duration) *JobToken {
+	return &JobToken{
+		Action:      J_SNOOZE_OWNER,
+		Owner:       owner,
+		IgnoreUntil: until,
+		ValidUntil:  time.Now().Add(tokenDuration),
+	}
+}
+
+// NewTerminateBatchJob returns a job that terminates every region/id pair
+// in manifest
+func NewTerminateBatchJob(manifest []string) *JobToken {
+	return &JobToken{
+		Action:     J_TERMINATE_BATCH,
+		Manifest:   manifest,
+		ValidUntil: time.Now().Add(tokenDuration),
+	}
+}
+
 func encryptToken(key []byte, j *JobToken) ([]byte, error) {
 
 	jsonData := j.JSON()
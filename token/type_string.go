@@ -4,9 +4,9 @@ package token
 
 import "fmt"
 
-const _Type_name = "J_DELAYJ_TERMINATE"
+const _Type_name = "J_DELAYJ_TERMINATEJ_WHITELISTJ_STOPJ_SNOOZE_OWNERJ_TERMINATE_BATCH"
 
-var _Type_index = [...]uint8{0, 7, 18}
+var _Type_index = [...]uint8{0, 7, 18, 29, 35, 49, 66}
 
 func (i Type) String() string {
 	if i < 0 || i+1 >= Type(len(_Type_index)) {